@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+// AbsorbOptions groups configuration for finishing a worktree's work
+type AbsorbOptions struct {
+	// Into is the branch to merge into; it must be checked out in the primary worktree (default: its current branch)
+	Into string
+	// Rebase rebases the worktree's branch onto Into and fast-forwards Into instead of creating a merge commit
+	Rebase bool
+	// Force skips the confirmation
+	Force bool
+}
+
+// AbsorbWorktree merges a worktree's branch into the branch checked out in the primary worktree, then
+// removes the worktree and deletes the merged branch. Conflicts abort the merge or rebase and keep everything.
+func AbsorbWorktree(ctx context.Context, name string, opts AbsorbOptions) error {
+	target, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+	if target.Branch == "" {
+		return fmt.Errorf("worktree '%s' is in detached HEAD mode; there is no branch to absorb", target.Name)
+	}
+	primary, err := getRepoRoot(ctx)
+	if err != nil {
+		return err
+	}
+	if normalizePath(target.Path) == normalizePath(primary) {
+		return fmt.Errorf("cannot absorb the primary worktree")
+	}
+	into, err := defaultCompareBase(ctx)
+	if err != nil {
+		return err
+	}
+	if opts.Into != "" && opts.Into != into {
+		return fmt.Errorf("the primary worktree is on '%s'; check out '%s' there first", into, opts.Into)
+	}
+
+	m := newManager(ctx)
+	changes, err := m.Changes(ctx, target)
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("%w; commit or stash them before absorbing", &wtm.WorktreeError{Name: target.Name, Err: wtm.ErrWorktreeDirty})
+	}
+	// A conflicting merge is aborted with reset --merge, which git cannot promise restores uncommitted changes
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range worktrees {
+		if normalizePath(worktrees[i].Path) != normalizePath(primary) {
+			continue
+		}
+		changes, err := m.Changes(ctx, &worktrees[i])
+		if err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			return fmt.Errorf("%w; commit or stash them in the primary worktree before absorbing", &wtm.WorktreeError{Name: worktrees[i].Name, Err: wtm.ErrWorktreeDirty})
+		}
+	}
+
+	if !opts.Force {
+		method := "Merge"
+		if opts.Rebase {
+			method = "Rebase and fast-forward"
+		}
+		ok, err := confirm(ctx, fmt.Sprintf("%s '%s' into '%s', then remove worktree '%s' and delete its branch?", method, target.Branch, into, target.Name))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errAborted
+		}
+	}
+
+	if opts.Rebase {
+		if _, err := runGitMutation(ctx, "-C", target.Path, "rebase", into); err != nil {
+			// Leave the branch as it was; the abort fails harmlessly when the rebase never started
+			runGitCommand(ctx, "-C", target.Path, "rebase", "--abort")
+			return fmt.Errorf("kept worktree '%s': rebase onto '%s' failed: %w", target.Name, into, err)
+		}
+		if _, err := runGitMutation(ctx, "-C", primary, "merge", "--ff-only", target.Branch); err != nil {
+			return fmt.Errorf("kept worktree '%s': cannot fast-forward '%s': %w", target.Name, into, err)
+		}
+	} else {
+		if _, err := runGitMutation(ctx, "-C", primary, "merge", "--no-edit", target.Branch); err != nil {
+			runGitCommand(ctx, "-C", primary, "merge", "--abort")
+			return fmt.Errorf("kept worktree '%s': merging into '%s' failed: %w", target.Name, into, err)
+		}
+	}
+	report("✓ Absorbed %s into %s\n", target.Branch, into)
+
+	return removeWorktreeTarget(ctx, target, RemoveOptions{BranchDelete: BranchDeleteSafe})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+func TestAbsorbWorktree(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	// commitFile commits a file in dir
+	commitFile := func(dir, file, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", file}, {"commit", "-m", "Update " + file}} {
+			if _, err := runGitCommand(ctx, append([]string{"-C", dir}, args...)...); err != nil {
+				t.Fatalf("git %v failed: %v", args, err)
+			}
+		}
+	}
+	addWithCommit := func(name, file, content string) *Worktree {
+		t.Helper()
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+		wt, err := findWorktree(ctx, name)
+		if err != nil {
+			t.Fatalf("findWorktree failed: %v", err)
+		}
+		commitFile(wt.Path, file, content)
+		return wt
+	}
+
+	t.Run("merge", func(t *testing.T) {
+		addWithCommit("merged", "merged.txt", "done")
+		if err := AbsorbWorktree(ctx, "merged", AbsorbOptions{Force: true}); err != nil {
+			t.Fatalf("AbsorbWorktree failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(repoPath, "merged.txt")); err != nil {
+			t.Errorf("expected the change in the primary worktree: %v", err)
+		}
+		if _, err := findWorktree(ctx, "merged"); err == nil {
+			t.Error("expected the worktree to be removed")
+		}
+		if refExists(ctx, "refs/heads/merged") {
+			t.Error("expected the branch to be deleted")
+		}
+	})
+
+	t.Run("rebase keeps history linear", func(t *testing.T) {
+		addWithCommit("rebased", "rebased.txt", "done")
+		commitFile(repoPath, "primary.txt", "meanwhile")
+		if err := AbsorbWorktree(ctx, "rebased", AbsorbOptions{Rebase: true, Force: true}); err != nil {
+			t.Fatalf("AbsorbWorktree failed: %v", err)
+		}
+		merges, err := runGitCommand(ctx, "rev-list", "--merges", "--count", "HEAD~2..HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(merges) != "0" {
+			t.Errorf("expected no merge commit, got %s", merges)
+		}
+		if _, err := os.Stat(filepath.Join(repoPath, "rebased.txt")); err != nil {
+			t.Errorf("expected the change in the primary worktree: %v", err)
+		}
+	})
+
+	t.Run("dirty primary worktree is refused", func(t *testing.T) {
+		addWithCommit("pending", "pending.txt", "done")
+		if err := os.WriteFile(filepath.Join(repoPath, "README.md"), []byte("local edit\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		err := AbsorbWorktree(ctx, "pending", AbsorbOptions{Force: true})
+		if !errors.Is(err, wtm.ErrWorktreeDirty) {
+			t.Errorf("expected ErrWorktreeDirty, got %v", err)
+		}
+		if _, err := findWorktree(ctx, "pending"); err != nil {
+			t.Errorf("expected the worktree to be kept: %v", err)
+		}
+		if _, err := runGitCommand(ctx, "checkout", "--", "README.md"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("conflict keeps everything", func(t *testing.T) {
+		addWithCommit("conflict", "shared.txt", "worktree")
+		commitFile(repoPath, "shared.txt", "primary")
+		if err := AbsorbWorktree(ctx, "conflict", AbsorbOptions{Force: true}); err == nil {
+			t.Fatal("expected the conflicting merge to fail")
+		}
+		if _, err := findWorktree(ctx, "conflict"); err != nil {
+			t.Errorf("expected the worktree to be kept: %v", err)
+		}
+		status, err := runGitCommand(ctx, "status", "--porcelain")
+		if err != nil || strings.TrimSpace(status) != "" {
+			t.Errorf("expected the merge to be aborted, got status %q, %v", status, err)
+		}
+	})
+}
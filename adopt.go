@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+// AdoptOptions groups configuration for bringing a worktree created with plain git under wtm management
+type AdoptOptions struct {
+	// Name is the name the worktree is known by; it must be the directory name unless Move is set
+	Name string
+	// Move moves the worktree into the worktree root, under Name
+	Move bool
+	// Base records the branch the worktree is compared and rebased against
+	Base string
+	// Labels tag the worktree for wtm list --group-by label
+	Labels []string
+}
+
+// AdoptWorktree brings a worktree created with `git worktree add` under wtm management: it checks that
+// its name is unique, optionally moves it into the worktree root, records the base branch and labels,
+// and allocates ports like wtm add does
+func AdoptWorktree(ctx context.Context, path string, opts AdoptOptions) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	repoRoot, err := getRepoRoot(ctx)
+	if err != nil {
+		return err
+	}
+
+	var target *Worktree
+	for i := range worktrees {
+		if normalizePath(worktrees[i].Path) == normalizePath(abs) {
+			target = &worktrees[i]
+			break
+		}
+	}
+	switch {
+	case target == nil:
+		return fmt.Errorf("'%s' is not a worktree of this repository (see git worktree list)", path)
+	case target.Bare || normalizePath(target.Path) == normalizePath(repoRoot):
+		return fmt.Errorf("'%s' is the primary worktree, which wtm always manages", path)
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	name := target.Name
+	if opts.Name != "" {
+		name = cfg.Names.sanitize(opts.Name)
+	}
+	validate := wtm.ValidateName
+	if cfg.AllowNestedNames {
+		validate = wtm.ValidateNestedName
+	}
+	if err := validate(name); err != nil {
+		return err
+	}
+	if name != target.Name && !opts.Move {
+		return fmt.Errorf("a worktree is named after its directory '%s'; pass --move to move it into the worktree root as '%s'", target.Name, name)
+	}
+	for _, wt := range worktrees {
+		if wt.Name == name && normalizePath(wt.Path) != normalizePath(target.Path) {
+			if opts.Move {
+				return &wtm.WorktreeError{Name: name, Err: wtm.ErrWorktreeExists}
+			}
+			return fmt.Errorf("another worktree is also named '%s' (%s); pass --name and --move to give this one a unique name", name, wt.Path)
+		}
+	}
+	labels, err := normalizeLabels(opts.Labels)
+	if err != nil {
+		return err
+	}
+
+	if opts.Move {
+		if err := moveIntoRoot(ctx, target, name); err != nil {
+			return err
+		}
+	}
+
+	if opts.Base != "" {
+		if err := setWorktreeMeta(ctx, name, metaBase, opts.Base); err != nil {
+			return err
+		}
+	}
+	if len(labels) > 0 {
+		if err := setWorktreeMeta(ctx, name, metaLabels, strings.Join(labels, ",")); err != nil {
+			return err
+		}
+	}
+	if target.Port == 0 {
+		port, err := allocatePort(ctx, cfg.Ports)
+		if err != nil {
+			return err
+		}
+		if port > 0 {
+			if err := recordPort(ctx, name, port); err != nil {
+				return err
+			}
+		}
+	}
+
+	report("✓ Adopted worktree: %s\n", name)
+	fmt.Printf("  Path: %s\n", target.Path)
+	return nil
+}
+
+// moveIntoRoot moves a worktree to <worktree root>/<name> and carries its metadata over to the new name
+func moveIntoRoot(ctx context.Context, target *Worktree, name string) error {
+	base, err := resolveWorktreeBase(ctx)
+	if err != nil {
+		return err
+	}
+	dest := filepath.Join(base, filepath.FromSlash(name))
+	if normalizePath(dest) == normalizePath(target.Path) {
+		return nil
+	}
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("cannot move worktree '%s': %s already exists", target.Name, dest)
+	}
+	if !planFileOp("mkdir -p %s", filepath.Dir(dest)) {
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return err
+		}
+	}
+	if _, err := runGitMutation(ctx, "worktree", "move", target.Path, dest); err != nil {
+		return fmt.Errorf("failed to move worktree '%s': %w", target.Name, err)
+	}
+	if err := renameWorktreeMeta(ctx, target.Name, name); err != nil {
+		return err
+	}
+	target.Path = dest
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAdoptWorktree(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	external := filepath.Join(t.TempDir(), "ext-hotfix")
+	if _, err := runGitCommand(ctx, "worktree", "add", "-b", "hotfix", external); err != nil {
+		t.Fatalf("git worktree add failed: %v", err)
+	}
+
+	if err := AdoptWorktree(ctx, t.TempDir(), AdoptOptions{}); err == nil {
+		t.Error("expected adopting a directory that is not a worktree to fail")
+	}
+	if err := AdoptWorktree(ctx, repoPath, AdoptOptions{}); err == nil {
+		t.Error("expected adopting the primary worktree to fail")
+	}
+	if err := AdoptWorktree(ctx, external, AdoptOptions{Name: "hotfix"}); err == nil {
+		t.Error("expected renaming without --move to fail")
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return AdoptWorktree(ctx, external, AdoptOptions{Name: "hotfix", Move: true, Base: "trunk", Labels: []string{"urgent"}})
+	}); err != nil {
+		t.Fatalf("AdoptWorktree failed: %v", err)
+	}
+
+	wt, err := findWorktree(ctx, "hotfix")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	base, err := resolveWorktreeBase(ctx)
+	if err != nil {
+		t.Fatalf("resolveWorktreeBase failed: %v", err)
+	}
+	if normalizePath(wt.Path) != normalizePath(filepath.Join(base, "hotfix")) {
+		t.Errorf("expected the worktree to be moved into %s, got %s", base, wt.Path)
+	}
+	if wt.Base != "trunk" || len(wt.Labels) != 1 || wt.Labels[0] != "urgent" {
+		t.Errorf("expected the base and labels to be recorded, got %+v", wt)
+	}
+	if _, err := os.Stat(external); !os.IsNotExist(err) {
+		t.Errorf("expected the old directory to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArchiveOptions groups configuration for exporting a worktree
+type ArchiveOptions struct {
+	// Output is the archive file; its extension (.tar.gz, .tgz, .tar or .zip) selects the format.
+	// Defaults to <name>.tar.gz in the current directory.
+	Output string
+	// Untracked archives the working tree as it is, including uncommitted changes and untracked files
+	// not ignored by .gitignore or .wtmignore, instead of the committed HEAD via `git archive`
+	Untracked bool
+}
+
+// archiveFormat returns the format for an output file name
+func archiveFormat(output string) (string, error) {
+	lower := strings.ToLower(output)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz", nil
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar", nil
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip", nil
+	default:
+		return "", fmt.Errorf("cannot tell the archive format of %s: use .tar.gz, .tgz, .tar or .zip", output)
+	}
+}
+
+// ArchiveWorktree writes a tarball or zip of a worktree, with every entry under a <name>/ directory
+func ArchiveWorktree(ctx context.Context, name string, opts ArchiveOptions) error {
+	wt, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+	output := opts.Output
+	if output == "" {
+		output = wt.Name + ".tar.gz"
+	}
+	format, err := archiveFormat(output)
+	if err != nil {
+		return err
+	}
+	prefix := wt.Name + "/"
+
+	if !opts.Untracked {
+		if _, err := runGitCommand(ctx, "-C", wt.Path, "archive", "--format="+format, "--prefix="+prefix, "-o", absPath(output), "HEAD"); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Archived %s (HEAD) to %s\n", wt.Name, output)
+		return nil
+	}
+
+	files, err := worktreeFiles(ctx, wt.Path)
+	if err != nil {
+		return err
+	}
+	if err := writeArchive(output, format, wt.Path, prefix, files); err != nil {
+		os.Remove(output)
+		return err
+	}
+	fmt.Printf("✓ Archived %s (%d files) to %s\n", wt.Name, len(files), output)
+	return nil
+}
+
+// absPath resolves a path given on the command line, since git runs in the worktree
+func absPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return p
+}
+
+// worktreeFiles lists the tracked files present in a worktree plus its untracked, unignored files
+func worktreeFiles(ctx context.Context, worktreePath string) ([]string, error) {
+	output, err := runGitCommand(ctx, "-C", worktreePath, "ls-files", "-z", "--cached")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := listUntrackedFiles(ctx, worktreePath)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var files []string
+	for _, file := range append(strings.Split(output, "\x00"), untracked...) {
+		if file == "" || seen[file] {
+			continue
+		}
+		seen[file] = true
+		// Deleted files and submodule directories have no regular file to archive
+		info, err := os.Lstat(filepath.Join(worktreePath, file))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// writeArchive writes files, relative to root, into an archive of the given format
+func writeArchive(output, format, root, prefix string, files []string) error {
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if format == "zip" {
+		zw := zip.NewWriter(f)
+		for _, file := range files {
+			if err := addZipEntry(zw, root, prefix, file); err != nil {
+				return err
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if format == "tar.gz" {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for _, file := range files {
+		if err := addTarEntry(tw, root, prefix, file); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
+
+func addTarEntry(tw *tar.Writer, root, prefix, file string) error {
+	path := filepath.Join(root, file)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	var link string
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = prefix + filepath.ToSlash(file)
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	return copyFileTo(tw, path)
+}
+
+func addZipEntry(zw *zip.Writer, root, prefix, file string) error {
+	path := filepath.Join(root, file)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = prefix + filepath.ToSlash(file)
+	hdr.Method = zip.Deflate
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, link)
+		return err
+	}
+	return copyFileTo(w, path)
+}
+
+func copyFileTo(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestArchiveWorktree(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "snapshot", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "snapshot")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	for file, content := range map[string]string{
+		"notes.txt":        "draft",
+		"build/out.bin":    "binary",
+		wtmIgnoreFile:      "build/\n",
+		"src/untracked.go": "package src",
+	} {
+		path := filepath.Join(wt.Path, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	outDir := t.TempDir()
+
+	t.Run("untracked tarball honors .wtmignore", func(t *testing.T) {
+		output := filepath.Join(outDir, "snapshot.tar.gz")
+		if err := ArchiveWorktree(ctx, "snapshot", ArchiveOptions{Output: output, Untracked: true}); err != nil {
+			t.Fatalf("ArchiveWorktree failed: %v", err)
+		}
+		f, err := os.Open(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				break
+			}
+			names = append(names, hdr.Name)
+		}
+		want := []string{"snapshot/.wtmignore", "snapshot/README.md", "snapshot/notes.txt", "snapshot/src/untracked.go"}
+		if !slices.Equal(names, want) {
+			t.Errorf("expected entries %v, got %v", want, names)
+		}
+	})
+
+	t.Run("committed HEAD as zip", func(t *testing.T) {
+		output := filepath.Join(outDir, "snapshot.zip")
+		if err := ArchiveWorktree(ctx, "snapshot", ArchiveOptions{Output: output}); err != nil {
+			t.Fatalf("ArchiveWorktree failed: %v", err)
+		}
+		zr, err := zip.OpenReader(output)
+		if err != nil {
+			t.Fatalf("expected a zip archive: %v", err)
+		}
+		defer zr.Close()
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		if !slices.Contains(names, "snapshot/README.md") || slices.Contains(names, "snapshot/notes.txt") {
+			t.Errorf("expected only committed files, got %v", names)
+		}
+	})
+
+	t.Run("unknown extension fails", func(t *testing.T) {
+		if err := ArchiveWorktree(ctx, "snapshot", ArchiveOptions{Output: filepath.Join(outDir, "snapshot.rar")}); err == nil {
+			t.Error("expected an error for an unknown archive format")
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readNamesFile reads worktree names for a batch add, one per line. Blank lines and lines starting
+// with # are ignored, and "-" reads standard input.
+func readNamesFile(path string) ([]string, error) {
+	var r io.Reader = stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, scanner.Err()
+}
+
+// AddWorktrees creates several worktrees one after another with the same options, each on a branch
+// of its own. A failure does not stop the batch; a summary table is printed at the end.
+func AddWorktrees(ctx context.Context, names []string, opts AddOptions) error {
+	if opts.Branch != "" || opts.Checkout != "" || opts.Detach != "" || opts.Review > 0 || opts.Issue > 0 {
+		return fmt.Errorf("-b, -B, --detach, --pr/--mr and --issue name a single branch and cannot be used with several worktrees")
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no worktree names given")
+	}
+
+	rows := make([][]string, 0, len(names))
+	var failed []string
+	for _, name := range names {
+		if ctx.Err() != nil {
+			rows = append(rows, []string{name, "", "skipped: interrupted"})
+			failed = append(failed, name)
+			continue
+		}
+		wt, err := addWorktree(ctx, name, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create worktree '%s': %v\n", name, err)
+			rows = append(rows, []string{name, "", "failed: " + err.Error()})
+			failed = append(failed, name)
+			continue
+		}
+		rows = append(rows, []string{wt.Name, formatBranch(*wt), "created"})
+	}
+
+	if !planning() {
+		fmt.Println()
+		printTable([]string{"NAME", "BRANCH", "RESULT"}, rows)
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("interrupted after creating %d of %d worktree(s): %w", len(names)-len(failed), len(names), err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to create %d of %d worktree(s): %s", len(failed), len(names), strings.Join(failed, ", "))
+	}
+	return nil
+}
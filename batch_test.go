@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAddWorktrees(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "taken", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	output, err := captureStdout(t, func() error {
+		return AddWorktrees(ctx, []string{"fix-a", "taken", "fix-b"}, AddOptions{})
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to create 1 of 3 worktree(s): taken") {
+		t.Errorf("expected the failure to be reported, got %v", err)
+	}
+	for _, want := range []string{"NAME", "fix-a", "created", "failed: "} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in the summary, got:\n%s", want, output)
+		}
+	}
+
+	specFile := filepath.Join(t.TempDir(), "worktrees.txt")
+	if err := os.WriteFile(specFile, []byte("# agent sandboxes\nagent-1\n\n  agent-2  \n"), 0o644); err != nil {
+		t.Fatalf("Failed to write spec file: %v", err)
+	}
+	names, err := readNamesFile(specFile)
+	if err != nil {
+		t.Fatalf("readNamesFile failed: %v", err)
+	}
+	if !slices.Equal(names, []string{"agent-1", "agent-2"}) {
+		t.Errorf("readNamesFile = %q", names)
+	}
+
+	root := newRootCmd()
+	root.SetArgs([]string{"add", "--from-file", specFile})
+	if _, err := captureStdout(t, root.Execute); err != nil {
+		t.Fatalf("wtm add --from-file failed: %v", err)
+	}
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		t.Fatalf("getWorktrees failed: %v", err)
+	}
+	var created []string
+	for _, wt := range worktrees {
+		created = append(created, wt.Name)
+	}
+	for _, want := range []string{"fix-a", "fix-b", "agent-1", "agent-2"} {
+		if !slices.Contains(created, want) {
+			t.Errorf("expected worktree %s, got %v", want, created)
+		}
+	}
+
+	if err := AddWorktrees(ctx, []string{"x", "y"}, AddOptions{Branch: "shared"}); err == nil {
+		t.Error("expected -b to be rejected for several worktrees")
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// BuildCacheConfig points the build tools of every worktree at caches shared by all worktrees of the
+// repository, so a new worktree does not download and compile everything again
+type BuildCacheConfig struct {
+	// Presets names the ecosystems whose caches are shared: go, cargo, pnpm, yarn or npm
+	Presets []string `toml:"presets"`
+	// Dir holds the shared caches; relative paths resolve from the repository root.
+	// Defaults to wtm/build-cache in the repository's shared git directory.
+	Dir string `toml:"dir"`
+}
+
+// buildCacheVar is a variable set by a preset, to a path inside the cache directory or to a fixed value
+type buildCacheVar struct {
+	name string
+	// path is relative to the cache directory; value is used as is when path is empty
+	path, value string
+}
+
+// buildCachePresets maps each preset to the variables it sets
+var buildCachePresets = map[string][]buildCacheVar{
+	"go": {
+		{name: "GOCACHE", path: "go/build"},
+		{name: "GOMODCACHE", path: "go/mod"},
+	},
+	// One target directory for all worktrees; cargo locks it while building
+	"cargo": {
+		{name: "CARGO_TARGET_DIR", path: "cargo/target"},
+	},
+	// pnpm hard-links node_modules from its content-addressable store
+	"pnpm": {
+		{name: "npm_config_store_dir", path: "pnpm/store"},
+	},
+	// YARN_CACHE_FOLDER is read by Yarn 1, the global folder and cache by Yarn 2 and later
+	"yarn": {
+		{name: "YARN_CACHE_FOLDER", path: "yarn/cache"},
+		{name: "YARN_GLOBAL_FOLDER", path: "yarn/global"},
+		{name: "YARN_ENABLE_GLOBAL_CACHE", value: "true"},
+	},
+	"npm": {
+		{name: "npm_config_cache", path: "npm"},
+	},
+}
+
+// buildCachePresetNames returns the preset names in a stable order for error messages
+func buildCachePresetNames() []string {
+	names := make([]string, 0, len(buildCachePresets))
+	for name := range buildCachePresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// buildCacheEnv returns the variables of the configured presets, in the order they are listed,
+// with the cache paths resolved against the cache directory
+func buildCacheEnv(ctx context.Context, cfg BuildCacheConfig, repoRoot string) ([]envVar, error) {
+	if len(cfg.Presets) == 0 {
+		return nil, nil
+	}
+	dir, err := buildCacheDir(ctx, cfg, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	var vars []envVar
+	for _, preset := range cfg.Presets {
+		presetVars, ok := buildCachePresets[preset]
+		if !ok {
+			return nil, fmt.Errorf("unknown buildCache preset '%s' (valid: %s)", preset, strings.Join(buildCachePresetNames(), ", "))
+		}
+		for _, v := range presetVars {
+			value := v.value
+			if v.path != "" {
+				value = filepath.Join(dir, filepath.FromSlash(v.path))
+			}
+			vars = append(vars, envVar{Name: v.name, Value: value})
+		}
+	}
+	return vars, nil
+}
+
+// buildCacheDir returns the absolute directory the shared caches are kept in
+func buildCacheDir(ctx context.Context, cfg BuildCacheConfig, repoRoot string) (string, error) {
+	if dir := strings.TrimSpace(cfg.Dir); dir != "" {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(repoRoot, dir)
+		}
+		return filepath.Clean(dir), nil
+	}
+	commonDir, err := gitCommonDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(commonDir, "wtm", "build-cache"), nil
+}
+
+// exportLines formats variables as shell export statements, one per line, for .envrc templates
+func exportLines(vars []envVar) string {
+	var b strings.Builder
+	for _, v := range vars {
+		fmt.Fprintf(&b, "export %s=%s\n", v.Name, shellJoin([]string{v.Value}))
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildCacheEnv(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	vars, err := buildCacheEnv(ctx, BuildCacheConfig{}, repoPath)
+	if err != nil || len(vars) != 0 {
+		t.Fatalf("expected no variables without presets, got %v, %v", vars, err)
+	}
+
+	vars, err = buildCacheEnv(ctx, BuildCacheConfig{Presets: []string{"go", "yarn"}}, repoPath)
+	if err != nil {
+		t.Fatalf("buildCacheEnv failed: %v", err)
+	}
+	got := map[string]string{}
+	var names []string
+	for _, v := range vars {
+		got[v.Name] = v.Value
+		names = append(names, v.Name)
+	}
+	if want := "GOCACHE GOMODCACHE YARN_CACHE_FOLDER YARN_GLOBAL_FOLDER YARN_ENABLE_GLOBAL_CACHE"; strings.Join(names, " ") != want {
+		t.Errorf("expected variables %s, got %s", want, strings.Join(names, " "))
+	}
+	wantDir := filepath.Join(repoPath, ".git", "wtm", "build-cache")
+	if normalizePath(filepath.Dir(filepath.Dir(got["GOCACHE"]))) != normalizePath(wantDir) {
+		t.Errorf("expected GOCACHE under %s, got %s", wantDir, got["GOCACHE"])
+	}
+	if got["YARN_ENABLE_GLOBAL_CACHE"] != "true" {
+		t.Errorf("expected YARN_ENABLE_GLOBAL_CACHE=true, got %q", got["YARN_ENABLE_GLOBAL_CACHE"])
+	}
+
+	vars, err = buildCacheEnv(ctx, BuildCacheConfig{Presets: []string{"cargo"}, Dir: "../shared cache"}, repoPath)
+	if err != nil {
+		t.Fatalf("buildCacheEnv failed: %v", err)
+	}
+	want := filepath.Join(filepath.Dir(repoPath), "shared cache", "cargo", "target")
+	if len(vars) != 1 || vars[0].Value != want {
+		t.Errorf("expected CARGO_TARGET_DIR=%s, got %v", want, vars)
+	}
+	if lines := exportLines(vars); lines != "export CARGO_TARGET_DIR='"+want+"'\n" {
+		t.Errorf("unexpected export lines %q", lines)
+	}
+
+	if _, err := buildCacheEnv(ctx, BuildCacheConfig{Presets: []string{"maven"}}, repoPath); err == nil || !strings.Contains(err.Error(), "cargo, go, npm, pnpm, yarn") {
+		t.Errorf("expected an unknown preset error listing the presets, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// The list cache keeps the result of listing worktrees in <git common dir>/wtm/cache.json, so shell
+// prompts and completion can call wtm many times per second without running git worktree list,
+// reading the metadata and stating every worktree each time. Each entry records the modification
+// time and size of the files git touches when worktrees, branches or metadata change, and of the
+// config files; a single differing stamp, or a change in the config values worktree names depend
+// on, discards the whole cache.
+
+const (
+	listCacheFile    = "cache.json"
+	listCacheVersion = 4
+)
+
+// noCache bypasses the list cache, set by the global --no-cache flag
+var noCache bool
+
+// CacheConfig controls the worktree list cache
+type CacheConfig struct {
+	// Enabled caches worktree listings in the git directory
+	Enabled bool `toml:"enabled"`
+}
+
+type listCache struct {
+	Version int `json:"version"`
+	// Settings are the config values worktree names were derived with, see listCacheSettings
+	Settings  string               `json:"settings"`
+	Stamps    map[string]fileStamp `json:"stamps"`
+	Worktrees []Worktree           `json:"worktrees"`
+}
+
+type fileStamp struct {
+	ModTime int64 `json:"modTime"`
+	Size    int64 `json:"size"`
+}
+
+func stampFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{ModTime: -1}
+	}
+	return fileStamp{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+}
+
+// listCacheEnabled reports whether listings may be served from the cache
+func listCacheEnabled(ctx context.Context) bool {
+	if noCache || planning() {
+		return false
+	}
+	cfg, err := loadConfig(ctx)
+	return err == nil && cfg.Cache.Enabled
+}
+
+// listCacheSettings returns the config values worktree names depend on, and the global and local
+// config files they are read from
+func listCacheSettings(ctx context.Context) (string, []string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	settings := fmt.Sprintf("worktreeRoot=%q layout=%q allowNestedNames=%t", cfg.WorktreeRoot, cfg.Layout, cfg.AllowNestedNames)
+	var files []string
+	if path, err := configFilePath(); err == nil {
+		files = append(files, path)
+	}
+	if repoRoot, err := getRepoRoot(ctx); err == nil {
+		files = append(files, filepath.Join(repoRoot, localConfigFile))
+	}
+	return settings, files, nil
+}
+
+func listCachePath(commonDir string) string {
+	return filepath.Join(commonDir, "wtm", listCacheFile)
+}
+
+// cachedWorktrees returns the cached listing when it was made with settings and every stamp still matches
+func cachedWorktrees(commonDir, settings string) ([]Worktree, bool) {
+	data, err := os.ReadFile(listCachePath(commonDir))
+	if err != nil {
+		return nil, false
+	}
+	var cache listCache
+	if err := json.Unmarshal(data, &cache); err != nil || cache.Version != listCacheVersion || cache.Settings != settings {
+		return nil, false
+	}
+	for path, stamp := range cache.Stamps {
+		if stampFile(path) != stamp {
+			return nil, false
+		}
+	}
+	return cache.Worktrees, true
+}
+
+// writeListCache stores a listing together with the settings and stamps that invalidate it, stamping
+// configFiles as well. Failing to write is not an error: the cache is only an optimization.
+func writeListCache(commonDir, settings string, configFiles []string, worktrees []Worktree) {
+	paths := append([]string{
+		filepath.Join(commonDir, "config"),
+		filepath.Join(commonDir, "HEAD"),
+		filepath.Join(commonDir, "packed-refs"),
+		filepath.Join(commonDir, "worktrees"),
+	}, configFiles...)
+	if entries, err := os.ReadDir(filepath.Join(commonDir, "worktrees")); err == nil {
+		for _, entry := range entries {
+			admin := filepath.Join(commonDir, "worktrees", entry.Name())
+			paths = append(paths, admin, filepath.Join(admin, "HEAD"))
+		}
+	}
+	for _, wt := range worktrees {
+		paths = append(paths, wt.Path)
+		if wt.Branch != "" {
+			paths = append(paths, filepath.Join(commonDir, "refs", "heads", filepath.FromSlash(wt.Branch)))
+		}
+	}
+
+	cache := listCache{Version: listCacheVersion, Settings: settings, Stamps: map[string]fileStamp{}, Worktrees: worktrees}
+	for _, path := range paths {
+		cache.Stamps[path] = stampFile(path)
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	path := listCachePath(commonDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	// Write through a temporary file so concurrent readers never see a partial cache
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// invalidateListCache removes the cache after a mutating command
+func invalidateListCache(ctx context.Context) {
+	commonDir, err := gitCommonDir(ctx)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(listCachePath(commonDir)); err != nil && !errors.Is(err, os.ErrNotExist) && logger != nil {
+		logger.DebugContext(ctx, "failed to remove list cache", "error", err)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListCache(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[cache]\nenabled = true\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	commonDir, err := gitCommonDir(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheFile := listCachePath(commonDir)
+
+	if err := AddWorktree(ctx, "api", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "api")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if _, err := os.Stat(cacheFile); err != nil {
+		t.Fatalf("expected the listing to be cached: %v", err)
+	}
+
+	settings, _, err := listCacheSettings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("cache hit", func(t *testing.T) {
+		if _, ok := cachedWorktrees(commonDir, settings); !ok {
+			t.Error("expected the cache to be valid while nothing changed")
+		}
+	})
+
+	t.Run("config changes invalidate", func(t *testing.T) {
+		if _, ok := cachedWorktrees(commonDir, settings+" changed"); ok {
+			t.Error("expected different config values to miss the cache")
+		}
+		if err := os.WriteFile(filepath.Join(repoPath, localConfigFile), []byte("allowNestedNames = true\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(filepath.Join(repoPath, localConfigFile))
+		if _, ok := cachedWorktrees(commonDir, settings); ok {
+			t.Error("expected editing .wtm.toml to invalidate the cache")
+		}
+	})
+
+	t.Run("commits invalidate", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(wt.Path, "new.txt"), []byte("new"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", "new.txt"}, {"commit", "-m", "Add new"}} {
+			if _, err := runGitCommand(ctx, append([]string{"-C", wt.Path}, args...)...); err != nil {
+				t.Fatalf("git %v failed: %v", args, err)
+			}
+		}
+		head, err := runGitCommand(ctx, "-C", wt.Path, "rev-parse", "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := findWorktree(ctx, "api")
+		if err != nil {
+			t.Fatalf("findWorktree failed: %v", err)
+		}
+		if got.HEAD+"\n" != head {
+			t.Errorf("expected HEAD %s, got stale %s", head, got.HEAD)
+		}
+	})
+
+	t.Run("worktrees added outside wtm invalidate", func(t *testing.T) {
+		other := filepath.Join(t.TempDir(), "manual")
+		if _, err := runGitCommand(ctx, "worktree", "add", "-b", "manual", other); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := findWorktree(ctx, "manual"); err != nil {
+			t.Errorf("expected the new worktree to be listed: %v", err)
+		}
+	})
+
+	t.Run("metadata changes invalidate", func(t *testing.T) {
+		if err := setWorktreeMeta(ctx, "api", metaIssue, "https://example.com/issues/1"); err != nil {
+			t.Fatal(err)
+		}
+		got, err := findWorktree(ctx, "api")
+		if err != nil {
+			t.Fatalf("findWorktree failed: %v", err)
+		}
+		if got.Issue == "" {
+			t.Error("expected the new metadata to be listed")
+		}
+	})
+
+	t.Run("corrupt cache is ignored", func(t *testing.T) {
+		if err := os.WriteFile(cacheFile, []byte("{"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := findWorktree(ctx, "api"); err != nil {
+			t.Errorf("findWorktree failed: %v", err)
+		}
+	})
+
+	t.Run("--no-cache bypasses", func(t *testing.T) {
+		if err := os.Remove(cacheFile); err != nil {
+			t.Fatal(err)
+		}
+		noCache = true
+		defer func() { noCache = false }()
+		if _, err := getWorktrees(ctx); err != nil {
+			t.Fatalf("getWorktrees failed: %v", err)
+		}
+		if _, err := os.Stat(cacheFile); err == nil {
+			t.Error("expected no cache to be written with --no-cache")
+		}
+	})
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+// CleanOptions groups configuration for removing finished worktrees
+type CleanOptions struct {
+	// PRMerged checks GitHub for a merged or closed pull request instead of the local merge state,
+	// which also catches squash and rebase merges
+	PRMerged bool
+	// Force skips the interactive confirmation
+	Force bool
+	// BranchDelete controls whether and how to delete the branches of removed worktrees
+	BranchDelete BranchDeleteMode
+}
+
+// cleanCandidate is a worktree selected for cleanup along with the reason it was selected
+type cleanCandidate struct {
+	Worktree Worktree
+	Reason   string
+}
+
+// CleanWorktrees removes worktrees whose branches are finished, either merged locally or with a closed pull request
+func CleanWorktrees(ctx context.Context, opts CleanOptions) error {
+	targets, err := matchWorktrees(ctx, "*")
+	if err != nil {
+		return err
+	}
+
+	var candidates []cleanCandidate
+	if opts.PRMerged {
+		candidates, err = findPRCleanCandidates(ctx, targets)
+	} else {
+		candidates, err = findMergedCleanCandidates(ctx, targets)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(candidates) == 0 {
+		fmt.Println("No worktrees to clean")
+		return nil
+	}
+
+	fmt.Println("Worktrees to clean:")
+	for _, c := range candidates {
+		fmt.Printf("  %s (branch: %s, %s)\n", c.Worktree.Name, c.Worktree.Branch, c.Reason)
+	}
+
+	if !opts.Force {
+		prompt := fmt.Sprintf("Remove %d worktree(s)", len(candidates))
+		ok, err := confirm(ctx, withBranchDeleteSuffix(prompt, opts.BranchDelete))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errAborted
+		}
+	}
+
+	var failed []string
+	for i := range candidates {
+		wt := &candidates[i].Worktree
+		if err := removeWorktreeTarget(ctx, wt, RemoveOptions{BranchDelete: opts.BranchDelete}); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", wt.Name, err)
+			failed = append(failed, wt.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to clean %d worktree(s): %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// findMergedCleanCandidates selects worktrees whose branches are merged into their recorded base,
+// or into the current HEAD for worktrees without one
+func findMergedCleanCandidates(ctx context.Context, worktrees []Worktree) ([]cleanCandidate, error) {
+	states := map[string]*mergeState{}
+
+	var candidates []cleanCandidate
+	for _, wt := range worktrees {
+		if wt.Branch == "" {
+			continue
+		}
+		base := "HEAD"
+		if wt.Base != "" && revisionExists(ctx, wt.Base) {
+			base = wt.Base
+		}
+		state, ok := states[base]
+		if !ok {
+			var err error
+			if state, err = loadMergeState(ctx, base); err != nil {
+				return nil, err
+			}
+			states[base] = state
+		}
+		if state.forkPoints[wt.HEAD] {
+			continue
+		}
+		if state.merged[wt.Branch] {
+			reason := "merged"
+			if base != "HEAD" {
+				reason = "merged into " + base
+			}
+			candidates = append(candidates, cleanCandidate{Worktree: wt, Reason: reason})
+		}
+	}
+	return candidates, nil
+}
+
+// mergeState holds the branches merged into a base and the commits on its first-parent chain
+type mergeState struct {
+	merged     map[string]bool
+	forkPoints map[string]bool
+}
+
+func loadMergeState(ctx context.Context, base string) (*mergeState, error) {
+	output, err := runGitCommand(ctx, "branch", "--merged", base, "--format=%(refname:short)")
+	if err != nil {
+		return nil, err
+	}
+	state := &mergeState{merged: map[string]bool{}, forkPoints: map[string]bool{}}
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			state.merged[line] = true
+		}
+	}
+
+	// Commits on the base's first-parent chain are fork points rather than merged work:
+	// a branch still sitting on one has simply not diverged yet, so it is not considered finished
+	chain, err := runGitCommand(ctx, "rev-list", "--first-parent", base)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(chain, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			state.forkPoints[line] = true
+		}
+	}
+	return state, nil
+}
+
+// findPRCleanCandidates selects worktrees whose branches have a merged or closed pull request on GitHub
+func findPRCleanCandidates(ctx context.Context, worktrees []Worktree) ([]cleanCandidate, error) {
+	var candidates []cleanCandidate
+	for _, wt := range worktrees {
+		if wt.Branch == "" {
+			continue
+		}
+		pr, err := findPullRequest(ctx, wt.Branch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up pull request for '%s': %w", wt.Branch, err)
+		}
+		if pr == nil || pr.State == "OPEN" {
+			continue
+		}
+		reason := fmt.Sprintf("PR #%d %s", pr.Number, strings.ToLower(pr.State))
+		candidates = append(candidates, cleanCandidate{Worktree: wt, Reason: reason})
+	}
+	return candidates, nil
+}
+
+// GCCandidate is something garbage collection would delete
+type GCCandidate struct {
+	// Kind is "stale" for records of worktrees whose directory is gone, "orphan" for worktree
+	// directories git no longer knows about, "merged" for clean worktrees of merged branches,
+	// and "ephemeral" for worktrees created with --ephemeral
+	Kind   string `json:"kind" jsonschema:"stale, orphan, merged or ephemeral"`
+	Name   string `json:"name" jsonschema:"worktree name"`
+	Path   string `json:"path,omitempty" jsonschema:"worktree directory"`
+	Reason string `json:"reason" jsonschema:"why the worktree is collected"`
+	// hasMeta reports whether wtm metadata is stored for a stale worktree
+	hasMeta bool
+}
+
+const (
+	gcStale  = "stale"
+	gcOrphan = "orphan"
+	gcMerged = "merged"
+	// gcEphemeral worktrees are removed with their changes and the branch created for them
+	gcEphemeral = "ephemeral"
+)
+
+// PruneWorktrees drops git's records of worktrees whose directories are gone, along with their wtm metadata
+func PruneWorktrees(ctx context.Context) error {
+	candidates, err := findStaleWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	return deleteGarbage(ctx, candidates)
+}
+
+// GCOptions groups configuration for garbage collection
+type GCOptions struct {
+	// Force skips the interactive confirmation
+	Force bool
+}
+
+// collectGarbage prunes stale records and removes orphaned directories, merged worktrees that have no local
+// changes, and ephemeral worktrees, after listing them and asking for confirmation
+func collectGarbage(ctx context.Context, opts GCOptions) error {
+	candidates, err := findGarbage(ctx)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		fmt.Println("Nothing to collect")
+		return nil
+	}
+
+	fmt.Println("Garbage to collect:")
+	for _, c := range candidates {
+		name := c.Name
+		if c.Kind == gcOrphan {
+			name = c.Path
+		}
+		fmt.Printf("  %s: %s (%s)\n", c.Kind, name, c.Reason)
+	}
+
+	if !opts.Force {
+		ok, err := confirm(ctx, fmt.Sprintf("Delete %d item(s)?", len(candidates)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errAborted
+		}
+	}
+	return deleteGarbage(ctx, candidates)
+}
+
+// findGarbage lists everything collectGarbage would delete, without changing anything
+func findGarbage(ctx context.Context) ([]GCCandidate, error) {
+	candidates, err := findStaleWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orphans, err := findOrphanedDirs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	candidates = append(candidates, orphans...)
+
+	worktrees, err := matchWorktrees(ctx, "*")
+	if err != nil {
+		return nil, err
+	}
+	meta, err := loadWorktreeMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Ephemeral worktrees are removed whatever their state, so they are not checked for merges,
+	// unless the command they were created for is still running in them
+	kept := worktrees[:0]
+	for _, wt := range worktrees {
+		if wt.Ephemeral {
+			if ephemeralOwnerAlive(meta[wt.Name]) {
+				continue
+			}
+			candidates = append(candidates, GCCandidate{Kind: gcEphemeral, Name: wt.Name, Path: wt.Path, Reason: "ephemeral worktree"})
+			continue
+		}
+		kept = append(kept, wt)
+	}
+	merged, err := findMergedGarbage(ctx, kept)
+	if err != nil {
+		return nil, err
+	}
+	return append(candidates, merged...), nil
+}
+
+// findSafeGarbage lists the part of findGarbage that loses no work: stale records and merged worktrees
+// without local changes. Orphaned directories and ephemeral worktrees are left for an explicit wtm gc.
+func findSafeGarbage(ctx context.Context) ([]GCCandidate, error) {
+	candidates, err := findStaleWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	worktrees, err := matchWorktrees(ctx, "*")
+	if err != nil {
+		return nil, err
+	}
+	worktrees = slices.DeleteFunc(worktrees, func(wt Worktree) bool { return wt.Ephemeral })
+	merged, err := findMergedGarbage(ctx, worktrees)
+	if err != nil {
+		return nil, err
+	}
+	return append(candidates, merged...), nil
+}
+
+// findMergedGarbage lists the worktrees whose branches were merged and that have no local changes
+func findMergedGarbage(ctx context.Context, worktrees []Worktree) ([]GCCandidate, error) {
+	merged, err := findMergedCleanCandidates(ctx, worktrees)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []GCCandidate
+	for _, c := range merged {
+		if _, err := os.Stat(c.Worktree.Path); err != nil {
+			continue
+		}
+		changes, err := newManager(ctx).Changes(ctx, &c.Worktree)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) > 0 {
+			// Local changes are never collected
+			continue
+		}
+		candidates = append(candidates, GCCandidate{Kind: gcMerged, Name: c.Worktree.Name, Path: c.Worktree.Path, Reason: "branch merged, no local changes"})
+	}
+	return candidates, nil
+}
+
+// findStaleWorktrees lists worktree records and wtm metadata whose directories no longer exist
+func findStaleWorktrees(ctx context.Context) ([]GCCandidate, error) {
+	m, err := worktreeManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stale, err := m.Stale(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []GCCandidate
+	for _, s := range stale {
+		reason := "directory is gone"
+		if s.Path == "" {
+			reason = "metadata of a removed worktree"
+		}
+		candidates = append(candidates, GCCandidate{Kind: gcStale, Name: s.Name, Path: s.Path, Reason: reason, hasMeta: s.HasMeta})
+	}
+	return candidates, nil
+}
+
+// findOrphanedDirs lists directories in the worktree root that were worktrees of this repository (their .git
+// file points into its worktrees directory) but are no longer registered with git, e.g. after the
+// administrative files were pruned. Directories holding files whose contents git does not store are kept.
+func findOrphanedDirs(ctx context.Context) ([]GCCandidate, error) {
+	base, err := resolveWorktreeBase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, wt := range worktrees {
+		known[normalizePath(wt.Path)] = true
+	}
+	commonDir, err := gitCommonDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	adminDir := normalizePath(filepath.Join(commonDir, "worktrees"))
+
+	var candidates []GCCandidate
+	for _, entry := range entries {
+		dir := filepath.Join(base, entry.Name())
+		if !entry.IsDir() || known[normalizePath(dir)] {
+			continue
+		}
+		// A shared or absolute worktree root may hold the worktrees of other repositories
+		if gitDir, ok := readGitFile(dir); !ok || normalizePath(filepath.Dir(gitDir)) != adminDir {
+			continue
+		}
+		stored, err := storedInGit(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		if !stored {
+			continue
+		}
+		candidates = append(candidates, GCCandidate{Kind: gcOrphan, Name: entry.Name(), Path: dir, Reason: "not registered with git, files stored in git"})
+	}
+	return candidates, nil
+}
+
+// readGitFile returns the git directory named by the .git file of a linked worktree
+func readGitFile(dir string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, ".git"))
+	if err != nil {
+		return "", false
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok || gitDir == "" {
+		return "", false
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return filepath.Clean(gitDir), true
+}
+
+// storedInGit reports whether the repository stores the contents of every file under dir other than its
+// .git file, so deleting dir loses nothing. Symbolic links and other special files count as not stored.
+func storedInGit(ctx context.Context, dir string) (bool, error) {
+	var paths []string
+	stored := true
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case d.IsDir() || path == filepath.Join(dir, ".git"):
+		case d.Type().IsRegular() && !strings.Contains(path, "\n"):
+			paths = append(paths, path)
+		default:
+			stored = false
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil || !stored || len(paths) == 0 {
+		return stored, err
+	}
+
+	hashes, err := gitWithInput(ctx, strings.Join(paths, "\n")+"\n", "hash-object", "--no-filters", "--stdin-paths")
+	if err != nil {
+		return false, err
+	}
+	objects, err := gitWithInput(ctx, hashes, "cat-file", "--batch-check")
+	if err != nil {
+		return false, err
+	}
+	return !strings.Contains(objects, " missing"), nil
+}
+
+// gitWithInput runs a read-only git command in the selected repository with input on its stdin
+func gitWithInput(ctx context.Context, input string, args ...string) (string, error) {
+	cmd := repoCommand(ctx, "git", args...)
+	cmd.Stdin = strings.NewReader(input)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return string(output), nil
+}
+
+// deleteGarbage deletes the given candidates, recording the operations instead when planning
+func deleteGarbage(ctx context.Context, candidates []GCCandidate) error {
+	pruned := false
+	for _, c := range candidates {
+		switch c.Kind {
+		case gcStale:
+			if !pruned {
+				if _, err := runGitMutation(ctx, "worktree", "prune"); err != nil {
+					return err
+				}
+				pruned = true
+			}
+			if c.hasMeta {
+				if err := clearWorktreeMeta(ctx, c.Name); err != nil {
+					return err
+				}
+			}
+			report("Pruned %s\n", c.Name)
+		case gcOrphan:
+			if planFileOp("rm -rf %s", c.Path) {
+				continue
+			}
+			// Read-only worktrees must be made writable before their files can be deleted
+			if err := wtm.SetWritable(c.Path, true); err != nil {
+				return err
+			}
+			if err := os.RemoveAll(c.Path); err != nil {
+				return err
+			}
+			report("Removed orphaned directory %s\n", c.Path)
+		case gcMerged:
+			wt, err := findWorktree(ctx, c.Name)
+			if err != nil {
+				return err
+			}
+			if err := removeWorktreeTarget(ctx, wt, RemoveOptions{}); err != nil {
+				return err
+			}
+		case gcEphemeral:
+			wt, err := findWorktree(ctx, c.Name)
+			if err != nil {
+				return err
+			}
+			if err := removeEphemeral(ctx, wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanWorktreesMerged(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	for _, name := range []string{"done", "fresh"} {
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree %s failed: %v", name, err)
+		}
+	}
+
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		t.Fatalf("getWorktrees failed: %v", err)
+	}
+	var donePath string
+	for _, wt := range worktrees {
+		if wt.Name == "done" {
+			donePath = wt.Path
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(donePath, "done.txt"), []byte("done"), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	for _, args := range [][]string{
+		{"-C", donePath, "add", "done.txt"},
+		{"-C", donePath, "commit", "-m", "done"},
+		{"merge", "--no-ff", "-m", "merge done", "done"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return CleanWorktrees(ctx, CleanOptions{Force: true, BranchDelete: BranchDeleteSafe})
+	}); err != nil {
+		t.Fatalf("CleanWorktrees failed: %v", err)
+	}
+
+	worktrees, err = getWorktrees(ctx)
+	if err != nil {
+		t.Fatalf("getWorktrees failed: %v", err)
+	}
+	names := map[string]bool{}
+	for _, wt := range worktrees {
+		names[wt.Name] = true
+	}
+	if names["done"] {
+		t.Error("expected merged worktree 'done' to be removed")
+	}
+	if !names["fresh"] {
+		t.Error("expected worktree 'fresh' without commits to remain")
+	}
+}
+
+func TestCleanWorktreesUsesRecordedBase(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	// fix branches off release and is merged there, but not into the primary worktree's HEAD
+	if _, err := runGitCommand(ctx, "branch", "release"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddWorktree(ctx, "release", AddOptions{Checkout: "release"}); err != nil {
+		t.Fatalf("AddWorktree release failed: %v", err)
+	}
+	if err := AddWorktree(ctx, "fix", AddOptions{Base: "release"}); err != nil {
+		t.Fatalf("AddWorktree fix failed: %v", err)
+	}
+	fix, err := findWorktree(ctx, "fix")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if fix.Base != "release" {
+		t.Errorf("expected recorded base 'release', got %q", fix.Base)
+	}
+	release, err := findWorktree(ctx, "release")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(fix.Path, "fix.txt"), []byte("fix"), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	for _, args := range [][]string{
+		{"-C", fix.Path, "add", "fix.txt"},
+		{"-C", fix.Path, "commit", "-m", "fix"},
+		{"-C", release.Path, "merge", "--no-ff", "-m", "merge fix", "fix"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+
+	out, err := captureStdout(t, func() error {
+		return CleanWorktrees(ctx, CleanOptions{Force: true})
+	})
+	if err != nil {
+		t.Fatalf("CleanWorktrees failed: %v", err)
+	}
+	if !strings.Contains(out, "merged into release") {
+		t.Errorf("expected fix to be cleaned as merged into release, got:\n%s", out)
+	}
+	if _, err := findWorktree(ctx, "fix"); err == nil {
+		t.Error("expected worktree 'fix' to be removed")
+	}
+	if _, err := findWorktree(ctx, "release"); err != nil {
+		t.Errorf("expected worktree 'release' to remain: %v", err)
+	}
+}
+
+func TestCollectGarbageTool(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	for _, name := range []string{"gone", "orphan", "edited"} {
+		if _, err := captureStdout(t, func() error { return AddWorktree(ctx, name, AddOptions{}) }); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+	}
+	gone, _ := findWorktree(ctx, "gone")
+	orphan, _ := findWorktree(ctx, "orphan")
+	edited, _ := findWorktree(ctx, "edited")
+	if err := os.RemoveAll(gone.Path); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(edited.Path, "notes.txt"), []byte("not in git\n"), 0o644); err != nil {
+		t.Fatalf("write notes.txt failed: %v", err)
+	}
+	// Dropping git's administrative files leaves the directories behind as orphans
+	for _, wt := range []*Worktree{orphan, edited} {
+		gitDir, ok := readGitFile(wt.Path)
+		if !ok {
+			t.Fatalf("expected %s to have a .git file", wt.Path)
+		}
+		if err := os.RemoveAll(gitDir); err != nil {
+			t.Fatalf("RemoveAll failed: %v", err)
+		}
+	}
+	// A worktree of another repository sharing the worktree root
+	foreign := filepath.Join(filepath.Dir(orphan.Path), "foreign")
+	if err := os.MkdirAll(foreign, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(foreign, ".git"), []byte("gitdir: /elsewhere/.git/worktrees/foreign\n"), 0o644); err != nil {
+		t.Fatalf("write .git failed: %v", err)
+	}
+
+	handler := collectGarbageHandler(findGarbage)
+	_, out, err := handler(context.Background(), nil, CollectGarbageInput{DryRun: true, Confirm: true})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	kinds := map[string]string{}
+	for _, c := range out.Candidates {
+		kinds[c.Name] = c.Kind
+	}
+	if out.Removed || kinds["gone"] != gcStale || kinds["orphan"] != gcOrphan {
+		t.Fatalf("unexpected dry run result: %+v", out)
+	}
+	if kinds["edited"] == gcOrphan {
+		t.Error("expected an orphan with files git does not store to be kept")
+	}
+	if _, ok := kinds["foreign"]; ok {
+		t.Error("expected a worktree of another repository to be kept")
+	}
+	if _, err := os.Stat(orphan.Path); err != nil {
+		t.Fatal("dry run must not delete anything")
+	}
+
+	_, out, err = handler(context.Background(), nil, CollectGarbageInput{Confirm: true})
+	if err != nil {
+		t.Fatalf("confirmed gc failed: %v", err)
+	}
+	if !out.Removed {
+		t.Errorf("expected the candidates to be removed: %+v", out)
+	}
+	if _, err := os.Stat(orphan.Path); !os.IsNotExist(err) {
+		t.Error("expected the orphaned directory to be deleted")
+	}
+	for _, dir := range []string{edited.Path, foreign} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("expected %s to be kept: %v", dir, err)
+		}
+	}
+	if _, err := findWorktree(ctx, "gone"); err == nil {
+		t.Error("expected the stale worktree record to be pruned")
+	}
+}
+
+func TestCollectGarbageConfirms(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if _, err := captureStdout(t, func() error { return AddWorktree(ctx, "gone", AddOptions{}) }); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	gone, _ := findWorktree(ctx, "gone")
+	if err := os.RemoveAll(gone.Path); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+	originalStdin := stdin
+	stdin = r
+	defer func() { stdin = originalStdin }()
+
+	out, err := captureStdout(t, func() error { return collectGarbage(ctx, GCOptions{}) })
+	if err == nil || !strings.Contains(err.Error(), "not a terminal") {
+		t.Fatalf("expected gc to ask for confirmation, got %v", err)
+	}
+	if !strings.Contains(out, "stale: gone") {
+		t.Errorf("expected the candidates to be listed, got:\n%s", out)
+	}
+	if _, err := findWorktree(ctx, "gone"); err != nil {
+		t.Fatalf("nothing may be deleted without confirmation: %v", err)
+	}
+
+	if _, err := captureStdout(t, func() error { return collectGarbage(ctx, GCOptions{Force: true}) }); err != nil {
+		t.Fatalf("gc --force failed: %v", err)
+	}
+	if _, err := findWorktree(ctx, "gone"); err == nil {
+		t.Error("expected gc --force to prune the stale worktree")
+	}
+}
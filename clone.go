@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+// bareDir is where a bare clone keeps its repository inside the clone directory
+const bareDir = ".bare"
+
+// CloneOptions groups configuration for bootstrapping a repository
+type CloneOptions struct {
+	// Bare clones into <dir>/.bare and keeps every branch, including the default one, in a sibling worktree
+	Bare bool
+}
+
+// CloneRepository clones url into dir, prepares the worktree root and writes a repository-local config.
+// A regular clone is the primary worktree for the default branch; a bare clone gets a worktree for it.
+func CloneRepository(ctx context.Context, url, dir string, opts CloneOptions) error {
+	if dir == "" {
+		dir = cloneDirName(url)
+	}
+	if dir == "" {
+		return fmt.Errorf("cannot derive a directory name from '%s'", url)
+	}
+	if !filepath.IsAbs(dir) {
+		cwd, err := workingDir(ctx)
+		if err != nil {
+			return err
+		}
+		dir = filepath.Join(cwd, dir)
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("destination '%s' already exists", dir)
+	}
+
+	if opts.Bare {
+		if err := cloneBare(ctx, url, dir); err != nil {
+			return err
+		}
+	} else if err := runGitInteractive(ctx, "clone", url, dir); err != nil {
+		return err
+	}
+
+	if err := setRepoDir(dir); err != nil {
+		return err
+	}
+
+	resetConfigCache()
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	worktreeRoot := defaultRootFor(cfg.Layout, repoDir)
+	if opts.Bare {
+		// Worktrees live next to .bare, so every branch is a plain directory of the clone
+		worktreeRoot = "."
+		if cfg.Layout == wtm.LayoutSibling {
+			worktreeRoot = filepath.Base(repoDir)
+		}
+	}
+	if err := writeLocalConfig(ctx, worktreeRoot); err != nil {
+		return err
+	}
+	resetConfigCache()
+	if _, err := registerRepo(repoDir); err != nil {
+		return err
+	}
+
+	base, err := resolveWorktreeBase(ctx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(base, 0o755); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Cloned %s into %s\n", url, repoDir)
+	if !opts.Bare {
+		return nil
+	}
+
+	defaultBranch, err := runGitCommand(ctx, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to determine the default branch: %w", err)
+	}
+	defaultBranch = strings.TrimSpace(defaultBranch)
+	if _, err := runGitCommand(ctx, "branch", "--set-upstream-to=origin/"+defaultBranch, defaultBranch); err != nil {
+		return err
+	}
+	return AddWorktree(ctx, "", AddOptions{Checkout: defaultBranch})
+}
+
+// cloneBare clones url as a bare repository in dir/.bare and points dir/.git at it,
+// so git and wtm commands run from dir operate on the bare repository
+func cloneBare(ctx context.Context, url, dir string) error {
+	gitDir := filepath.Join(dir, bareDir)
+	if err := runGitInteractive(ctx, "clone", "--bare", url, gitDir); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git"), []byte("gitdir: ./"+bareDir+"\n"), 0o644); err != nil {
+		return err
+	}
+	// A bare clone maps remote branches straight onto local ones; restore remote-tracking refs
+	// so worktree branches can track origin
+	if err := runGitInteractive(ctx, "-C", gitDir, "config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+		return err
+	}
+	return runGitInteractive(ctx, "-C", gitDir, "fetch", "origin")
+}
+
+// writeLocalConfig creates the repository-local config unless one exists, e.g. committed upstream
+func writeLocalConfig(ctx context.Context, worktreeRoot string) error {
+	repoRoot, err := getRepoRoot(ctx)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(repoRoot, localConfigFile)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	content := fmt.Sprintf(starterLocalConfig, worktreeRoot)
+	if !planFileOp("write %s", path) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	return excludeFromGit(ctx, localConfigFile)
+}
+
+const starterLocalConfig = `# wtm settings for this repository; they override ~/.config/wtm/config.toml
+worktreeRoot = %q
+
+# [tasks]
+# test = "go test ./..."
+`
+
+// excludeFromGit adds a pattern to .git/info/exclude so a generated file does not show up as untracked
+func excludeFromGit(ctx context.Context, pattern string) error {
+	commonDir, err := gitCommonDir(ctx)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(commonDir, "info", "exclude")
+	if planFileOp("append /%s to %s", pattern, path) {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "/"+pattern {
+			return nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		if _, err := f.WriteString("\n"); err != nil {
+			return err
+		}
+	}
+	_, err = f.WriteString("/" + pattern + "\n")
+	return err
+}
+
+// cloneDirName derives the destination directory from a clone URL the way git clone does
+func cloneDirName(url string) string {
+	name := strings.TrimRight(url, `/\`)
+	if idx := strings.LastIndexAny(name, `/\:`); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return strings.TrimSuffix(name, ".git")
+}
+
+// runGitInteractive runs git with its progress output attached to the terminal
+func runGitInteractive(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	start := time.Now()
+	err := cmd.Run()
+	logCommand(ctx, "git", args, start, err)
+	if err != nil {
+		return &wtm.GitError{Args: args, Err: err}
+	}
+	return nil
+}
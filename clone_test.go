@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCloneDirName(t *testing.T) {
+	cases := map[string]string{
+		"https://github.com/choplin/wtm.git": "wtm",
+		"git@github.com:choplin/wtm.git":     "wtm",
+		"/srv/git/project/":                  "project",
+		"../local":                           "local",
+		`C:\src\project.git`:                 "project",
+	}
+	for url, want := range cases {
+		if got := cloneDirName(url); got != want {
+			t.Errorf("cloneDirName(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestCloneRepository(t *testing.T) {
+	ctx := t.Context()
+
+	source := setupTestRepo(t)
+	defer cleanupTestRepo(t, source)
+
+	t.Setenv("WTM_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.toml"))
+	resetConfigCache()
+	defer resetConfigCache()
+	defer func() { repoDir = "" }()
+
+	t.Run("regular", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "regular")
+		if _, err := captureStdout(t, func() error {
+			return CloneRepository(ctx, source, dest, CloneOptions{})
+		}); err != nil {
+			t.Fatalf("CloneRepository failed: %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dest, localConfigFile))
+		if err != nil {
+			t.Fatalf("expected %s to be written: %v", localConfigFile, err)
+		}
+		if !strings.Contains(string(data), `worktreeRoot = ".git/wtm/worktrees"`) {
+			t.Errorf("unexpected local config:\n%s", data)
+		}
+		if _, err := os.Stat(filepath.Join(dest, ".git", "wtm", "worktrees")); err != nil {
+			t.Errorf("expected worktree root to be created: %v", err)
+		}
+		status, err := runGitCommand(ctx, "status", "--porcelain")
+		if err != nil {
+			t.Fatalf("git status failed: %v", err)
+		}
+		if strings.TrimSpace(status) != "" {
+			t.Errorf("expected the local config to be excluded from git, got status %q", status)
+		}
+	})
+
+	t.Run("bare", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "bare")
+		if _, err := captureStdout(t, func() error {
+			return CloneRepository(ctx, source, dest, CloneOptions{Bare: true})
+		}); err != nil {
+			t.Fatalf("CloneRepository failed: %v", err)
+		}
+
+		branch, err := runGitCommand(ctx, "-C", source, "symbolic-ref", "--short", "HEAD")
+		if err != nil {
+			t.Fatalf("failed to read source branch: %v", err)
+		}
+		branch = strings.TrimSpace(branch)
+
+		if _, err := os.Stat(filepath.Join(dest, branch, "README.md")); err != nil {
+			t.Errorf("expected a worktree for %s next to .bare: %v", branch, err)
+		}
+
+		worktrees, err := matchWorktrees(ctx, "*")
+		if err != nil {
+			t.Fatalf("matchWorktrees failed: %v", err)
+		}
+		if len(worktrees) != 1 || worktrees[0].Name != branch {
+			t.Errorf("expected only the %s worktree to be managed, got %+v", branch, worktrees)
+		}
+
+		upstream, err := runGitCommand(ctx, "rev-parse", "--abbrev-ref", branch+"@{upstream}")
+		if err != nil {
+			t.Fatalf("expected %s to track origin: %v", branch, err)
+		}
+		if strings.TrimSpace(upstream) != "origin/"+branch {
+			t.Errorf("expected upstream origin/%s, got %q", branch, upstream)
+		}
+	})
+}
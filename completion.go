@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Shell completion candidates are written as "value\tdescription"; shells that support descriptions
+// (zsh, fish, PowerShell) show them next to the value.
+
+// completionFunc is the signature cobra expects for argument and flag value completion
+type completionFunc = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// completionContext prepares a completion request. Cobra skips the persistent pre-run hooks while
+// completing, so an explicit -C is applied here.
+func completionContext(cmd *cobra.Command) context.Context {
+	if flag := cmd.Flags().Lookup("repo"); flag != nil && flag.Changed {
+		_ = setRepoDir(flag.Value.String())
+	}
+	return cmd.Context()
+}
+
+// completeWorktreeNames completes the first argument with worktree names, described by their branch
+func completeWorktreeNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return worktreeNameCandidates(completionContext(cmd)), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeWorktreePair completes both worktree arguments of commands such as wtm transfer
+func completeWorktreePair(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 1 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return worktreeNameCandidates(completionContext(cmd)), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeWorktreeFlag completes a flag value with worktree names, described by their branch
+func completeWorktreeFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return worktreeNameCandidates(completionContext(cmd)), cobra.ShellCompDirectiveNoFileComp
+}
+
+func worktreeNameCandidates(ctx context.Context) []string {
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, wt := range worktrees {
+		candidates = append(candidates, wt.Name+"\t"+formatBranch(wt))
+	}
+	return candidates
+}
+
+// completeBranches completes local and remote branch names, described by where they live and their last commit
+func completeBranches(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	output, err := runGitCommand(completionContext(cmd), "for-each-ref",
+		"--format=%(refname)%09%(symref)%09%(subject)", "refs/heads", "refs/remotes")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var candidates []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		ref, rest, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		symref, subject, _ := strings.Cut(rest, "\t")
+		if symref != "" {
+			// Skip aliases such as origin/HEAD
+			continue
+		}
+		if name, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+			candidates = append(candidates, fmt.Sprintf("%s\tbranch: %s", name, subject))
+		} else if name, ok := strings.CutPrefix(ref, "refs/remotes/"); ok {
+			candidates = append(candidates, fmt.Sprintf("%s\tremote branch: %s", name, subject))
+		}
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTags completes tag names, newest first, described by their subject
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	output, err := runGitCommand(completionContext(cmd), "for-each-ref", "--sort=-creatordate",
+		"--format=%(refname:strip=2)%09%(subject)", "refs/tags")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var candidates []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line != "" {
+			candidates = append(candidates, line)
+		}
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// showFieldDescriptions describes every field accepted by wtm show --field, in the order they are offered
+var showFieldDescriptions = []struct{ name, description string }{
+	{"name", "worktree name"},
+	{"branch", "checked out branch"},
+	{"path", "absolute worktree path"},
+	{"head", "commit checked out"},
+	{"created", "creation time (RFC 3339)"},
+	{"readonly", "whether the worktree is read-only"},
+	{"issue", "linked issue URL"},
+	{"base", "recorded base branch"},
+	{"tag", "tag checked out or branched from"},
+	{"port", "first allocated port"},
+	{"labels", "comma-separated labels"},
+	{"ephemeral", "whether the next gc removes the worktree"},
+	{"hidden", "whether wtm hide left the worktree out of wtm list"},
+	{"status.dirty", "whether there are uncommitted changes"},
+	{"status.upstream", "upstream branch"},
+	{"status.ahead", "commits ahead of upstream"},
+	{"status.behind", "commits behind upstream"},
+	{"status.base", "branch compared against"},
+	{"status.baseahead", "commits ahead of the base"},
+	{"status.basebehind", "commits behind the base"},
+}
+
+// completeFields completes the comma-separated --field list, offering the fields not listed yet
+func completeFields(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix = toComplete[:i+1]
+	}
+	var listed []string
+	for _, field := range strings.Split(prefix, ",") {
+		listed = append(listed, strings.TrimSpace(field))
+	}
+
+	var candidates []string
+	for _, field := range showFieldDescriptions {
+		if !slices.Contains(listed, field.name) {
+			candidates = append(candidates, prefix+field.name+"\t"+field.description)
+		}
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeTemplates completes template names from the [templates] config, described by what they set up
+func completeTemplates(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := loadConfig(completionContext(cmd))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var candidates []string
+	for _, name := range templateNames(cfg) {
+		candidates = append(candidates, name+"\t"+describeTemplate(cfg.Templates[name]))
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTaskArgs completes wtm run: a task from the config, described by its command, then a worktree name
+func completeTaskArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	ctx := completionContext(cmd)
+	switch len(args) {
+	case 0:
+		cfg, err := loadConfig(ctx)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		names := make([]string, 0, len(cfg.Tasks))
+		for name := range cfg.Tasks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		candidates := make([]string, len(names))
+		for i, name := range names {
+			candidates[i] = name + "\t" + cfg.Tasks[name]
+		}
+		return candidates, cobra.ShellCompDirectiveNoFileComp
+	case 1:
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return worktreeNameCandidates(ctx), cobra.ShellCompDirectiveNoFileComp
+	default:
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+}
+
+// registerFlagCompletion attaches fn to the named flag of cmd; a missing flag is a programming error
+func registerFlagCompletion(cmd *cobra.Command, flag string, fn completionFunc) {
+	if err := cmd.RegisterFlagCompletionFunc(flag, fn); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompletion(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[tasks]\ntest = \"go test ./...\"\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	if err := AddWorktree(ctx, "api", AddOptions{Branch: "feature/api"}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		notWant []string
+	}{
+		{name: "worktree names", args: []string{"show", ""}, want: []string{"api\tfeature/api"}},
+		{name: "branches", args: []string{"add", "--base", ""}, want: []string{"master\tbranch: Initial commit", "feature/api\tbranch: Initial commit"}},
+		{name: "fields", args: []string{"show", "api", "--field", "name,"}, want: []string{"name,path\tabsolute worktree path", "name,status.ahead\t"}, notWant: []string{"name,name\t"}},
+		{name: "tasks", args: []string{"run", ""}, want: []string{"test\tgo test ./..."}},
+		{name: "task worktree", args: []string{"run", "test", ""}, want: []string{"api\tfeature/api"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newRootCmd()
+			root.SetArgs(append([]string{"__complete"}, tt.args...))
+			output, err := captureStdout(t, root.Execute)
+			if err != nil {
+				t.Fatalf("completion failed: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("expected %q in completions, got:\n%s", want, output)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(output, notWant) {
+					t.Errorf("did not expect %q in completions, got:\n%s", notWant, output)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+const defaultComposeProjectName = "{{base .RepoRoot}}-{{.Name}}"
+
+// ComposeConfig keeps Docker Compose projects of different worktrees apart
+type ComposeConfig struct {
+	// ProjectName is a template for COMPOSE_PROJECT_NAME over the worktree template fields;
+	// defaults to "{{base .RepoRoot}}-{{.Name}}"
+	ProjectName string `toml:"projectName"`
+}
+
+// composeProjectName renders the worktree's Compose project name and reduces it to the characters
+// Compose accepts: lower-case letters, digits, '-' and '_', starting with a letter or digit
+func composeProjectName(cfg ComposeConfig, data worktreeTemplateData) (string, error) {
+	text := cfg.ProjectName
+	if text == "" {
+		text = defaultComposeProjectName
+	}
+	name, err := renderTemplate("compose.projectName", text, data)
+	if err != nil {
+		return "", fmt.Errorf("invalid compose.projectName: %w", err)
+	}
+	name = strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+	name = strings.TrimLeft(name, "-_")
+	if name == "" {
+		return "", fmt.Errorf("compose.projectName rendered an empty project name for '%s'", data.Name)
+	}
+	return name, nil
+}
+
+// RunCompose runs `docker compose` inside a worktree with COMPOSE_PROJECT_NAME and the WTM_* variables set
+func RunCompose(ctx context.Context, name string, args []string) error {
+	wt, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		return fmt.Errorf("docker is not installed")
+	}
+
+	cmd := exec.CommandContext(ctx, "docker", append([]string{"compose"}, args...)...)
+	cmd.Dir = wt.Path
+	if err := setWorktreeEnv(ctx, cmd, wt); err != nil {
+		return err
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker compose failed in worktree '%s': %w", wt.Name, err)
+	}
+	return nil
+}
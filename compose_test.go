@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestComposeProjectName(t *testing.T) {
+	data := worktreeTemplateData{Name: "Login Fix", Branch: "feature/login", RepoRoot: "/src/My.App"}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"", "my-app-login-fix"},
+		{"{{.Branch}}", "feature-login"},
+		{"__{{.Name}}", "login-fix"},
+	}
+	for _, tt := range tests {
+		got, err := composeProjectName(ComposeConfig{ProjectName: tt.template}, data)
+		if err != nil {
+			t.Errorf("composeProjectName(%q) failed: %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("composeProjectName(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+	if _, err := composeProjectName(ComposeConfig{ProjectName: "--"}, data); err == nil {
+		t.Error("expected an error for an empty project name")
+	}
+}
+
+func TestRunCompose(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "api", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "api")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+
+	// A stub docker records its arguments, directory and project name
+	binDir := t.TempDir()
+	logFile := filepath.Join(t.TempDir(), "docker.log")
+	script := "#!/bin/sh\necho \"$* $(pwd) $COMPOSE_PROJECT_NAME $WTM_NAME\" > " + logFile + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if err := RunCompose(ctx, "api", []string{"up", "-d"}); err != nil {
+		t.Fatalf("RunCompose failed: %v", err)
+	}
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("docker was not run: %v", err)
+	}
+	project := strings.ToLower(filepath.Base(repoPath)) + "-api"
+	want := "compose up -d " + normalizePath(wt.Path) + " " + project + " api\n"
+	if got := string(data); got != want {
+		t.Errorf("unexpected docker call:\nwant: %q\ngot:  %q", want, got)
+	}
+}
@@ -1,17 +1,157 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 
+	"github.com/choplin/wtm/pkg/wtm"
 	toml "github.com/pelletier/go-toml/v2"
 )
 
 type Config struct {
 	WorktreeRoot string `toml:"worktreeRoot"`
+	// Layout is "nested" (worktrees inside the repository, the default) or "sibling" (next to it,
+	// with worktreeRoot resolved against the repository's parent directory)
+	Layout     string       `toml:"layout"`
+	RemoteType string       `toml:"remoteType"`
+	Disk       DiskConfig   `toml:"disk"`
+	Open       OpenConfig   `toml:"open"`
+	Tmux       TmuxConfig   `toml:"tmux"`
+	Direnv     DirenvConfig `toml:"direnv"`
+	// Tasks maps task names to shell commands run by `wtm run`
+	Tasks map[string]string `toml:"tasks"`
+	MCP   MCPConfig         `toml:"mcp"`
+	Log   LogConfig         `toml:"log"`
+	Names NamesConfig       `toml:"names"`
+	Hooks HooksConfig       `toml:"hooks"`
+	Setup SetupConfig       `toml:"setup"`
+	// Sparse maps profile names to the directories checked out by `wtm add --sparse-profile`
+	Sparse map[string][]string `toml:"sparse"`
+	Ports  PortsConfig         `toml:"ports"`
+	// RenderFiles maps files written into every new worktree to the templates they are rendered from
+	// (e.g. ".env.local" = ".env.local.tmpl")
+	RenderFiles map[string]string `toml:"renderFiles"`
+	Compose     ComposeConfig     `toml:"compose"`
+	BuildCache  BuildCacheConfig  `toml:"buildCache"`
+	Cache       CacheConfig       `toml:"cache"`
+	// Templates maps template names to the settings applied by `wtm add --template <name>`
+	Templates map[string]WorktreeTemplate `toml:"templates"`
+	// AllowNestedNames lets worktree names contain '/' (e.g. team/alice/login-fix), creating nested
+	// directories under the worktree root
+	AllowNestedNames bool `toml:"allowNestedNames"`
+	// Ignore lists name patterns (e.g. "legacy-*") of worktrees left out of wtm list unless --all is given
+	Ignore []string `toml:"ignore"`
+	// Time chooses between relative and absolute times in tables
+	Time TimeConfig `toml:"time"`
+}
+
+// SetupConfig lists steps run in every new worktree after its files are checked out
+type SetupConfig struct {
+	// Submodules runs `git submodule update --init --recursive`
+	Submodules bool `toml:"submodules"`
+	// LFS runs `git lfs install --local` and `git lfs pull`
+	LFS bool `toml:"lfs"`
+}
+
+// HooksConfig shares a hooks directory with new worktrees
+type HooksConfig struct {
+	// Path is the hooks directory (e.g. ".githooks"); relative paths resolve inside each worktree,
+	// or from the repository root when copying
+	Path string `toml:"path"`
+	// Copy copies the directory into each new worktree's git directory instead of setting the shared core.hooksPath
+	Copy bool `toml:"copy"`
+}
+
+// NamesConfig sanitizes worktree names given to `wtm add` before they are validated
+type NamesConfig struct {
+	// Lowercase converts names to lower case
+	Lowercase bool `toml:"lowercase"`
+	// ReplaceSpaces replaces each run of whitespace with this string (e.g. "-")
+	ReplaceSpaces string `toml:"replaceSpaces"`
+	// NameTemplate renders the worktree name from the branch when `wtm add -B <branch>` omits it
+	// (e.g. "review-{{base .Branch}}"); the last path segment of the branch is used when empty
+	NameTemplate string `toml:"nameTemplate"`
+	// IssueTemplate renders the worktree and branch name for `wtm add --issue` from .Number, .Title
+	// and .Slug, the lower-cased title with dashes; defaults to "{{.Number}}-{{.Slug}}"
+	IssueTemplate string `toml:"issueTemplate"`
+	// BranchTemplate renders the default branch name from the worktree name (e.g. "alice/{{.Name}}")
+	BranchTemplate string `toml:"branchTemplate"`
+}
+
+// sanitize applies the configured name rules
+func (c NamesConfig) sanitize(name string) string {
+	if c.ReplaceSpaces != "" {
+		name = strings.Join(strings.Fields(name), c.ReplaceSpaces)
+	}
+	if c.Lowercase {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// LogConfig controls where --verbose and --debug write
+type LogConfig struct {
+	// File receives the log instead of stderr
+	File string `toml:"file"`
+}
+
+// MCPConfig controls what the MCP server lets agents do; it is only read from the global config file
+type MCPConfig struct {
+	// ExecAllow lists the commands MCP clients may run (e.g. "go test" or "npm run *"): wtm_exec commands
+	// starting with an entry, and setup and editor commands matching one word for word. Empty denies all.
+	ExecAllow []string `toml:"execAllow"`
+	// ExecDeny lists commands refused even when ExecAllow matches (e.g. "git push")
+	ExecDeny []string `toml:"execDeny"`
+	// Token is the bearer token wtm mcp --http requires from clients
+	Token string `toml:"token"`
+	// TLSCert and TLSKey are the certificate and key files wtm mcp --http serves HTTPS with
+	TLSCert string `toml:"tlsCert"`
+	TLSKey  string `toml:"tlsKey"`
+}
+
+// DirenvConfig renders an .envrc into every new worktree
+type DirenvConfig struct {
+	// Template is an inline text/template for the .envrc
+	Template string `toml:"template"`
+	// TemplateFile is a template file path, relative to the repository root unless absolute
+	TemplateFile string `toml:"templateFile"`
+	// Allow runs `direnv allow` after writing the file
+	Allow bool `toml:"allow"`
+}
+
+// TmuxConfig controls the tmux integration
+type TmuxConfig struct {
+	// AutoCreate creates a detached tmux session for every new worktree
+	AutoCreate bool `toml:"autoCreate"`
+}
+
+// OpenConfig controls how `wtm open` launches an editor
+type OpenConfig struct {
+	// Command is a template over the worktree (e.g. "code {{.Path}}"); $EDITOR is used when empty
+	Command string `toml:"command"`
+}
+
+// DiskConfig sets disk usage limits checked before creating worktrees
+type DiskConfig struct {
+	// MinFreeGB is the minimum free space required on the worktree filesystem
+	MinFreeGB float64 `toml:"minFreeGB"`
+	// MaxTotalWorktreeGB caps the combined size of all non-primary worktrees
+	MaxTotalWorktreeGB float64 `toml:"maxTotalWorktreeGB"`
+	// AutoGC runs garbage collection instead of failing when a limit is exceeded
+	AutoGC bool `toml:"autoGc"`
+}
+
+// defaultRootFor returns the worktree root written into a new local config for the configured layout
+func defaultRootFor(layout, repoRoot string) string {
+	if layout == wtm.LayoutSibling {
+		return wtm.DefaultSiblingRoot(repoRoot)
+	}
+	return defaultWorktreeRoot
 }
 
 var (
@@ -21,32 +161,89 @@ var (
 )
 
 const (
-	defaultWorktreeRoot = ".git/wtm/worktrees"
+	defaultWorktreeRoot = wtm.DefaultRoot
 	configFileEnv       = "WTM_CONFIG_FILE"
+	// localConfigFile is the repository-local config in the repository root, overriding the global one
+	localConfigFile = ".wtm.toml"
 )
 
-func loadConfig() (Config, error) {
+// repoConfig is the cached config of a repository selected by an MCP call
+type repoConfig struct {
+	once sync.Once
+	cfg  Config
+	err  error
+}
+
+// repoConfigs caches the config of each repository selected with withRepoDir, keyed by its directory
+var repoConfigs sync.Map
+
+func loadConfig(ctx context.Context) (Config, error) {
+	if dir, ok := ctx.Value(repoDirKey{}).(string); ok {
+		entry, _ := repoConfigs.LoadOrStore(dir, &repoConfig{})
+		rc := entry.(*repoConfig)
+		rc.once.Do(func() {
+			rc.cfg, rc.err = readConfig(ctx)
+		})
+		return rc.cfg, rc.err
+	}
 	configOnce.Do(func() {
-		path, err := configFilePath()
-		if err != nil {
-			configErr = err
-			return
-		}
-		data, err := os.ReadFile(path)
-		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				return
-			}
-			configErr = err
-			return
-		}
-		if err := toml.Unmarshal(data, &cachedConfig); err != nil {
-			configErr = err
-		}
+		cachedConfig, configErr = readConfig(ctx)
 	})
 	return cachedConfig, configErr
 }
 
+// readConfig merges the global config file and the local one of the repository selected by ctx
+func readConfig(ctx context.Context) (Config, error) {
+	var cfg Config
+	path, err := configFilePath()
+	if err != nil {
+		return cfg, err
+	}
+	if err := mergeConfigFile(&cfg, path); err != nil {
+		return cfg, err
+	}
+	// Outside a repository there is no local config to apply. The result is cached, so a
+	// cancelled first caller must not make every later lookup skip the local config.
+	if repoRoot, err := getRepoRoot(context.WithoutCancel(ctx)); err == nil {
+		// [mcp] decides what agents may run, so a repository cannot grant itself commands
+		mcp := cfg.MCP
+		cfg.MCP = MCPConfig{}
+		err := mergeConfigFile(&cfg, filepath.Join(repoRoot, localConfigFile))
+		cfg.MCP = mcp
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// loadMCPConfig reads the [mcp] table of the global config file. It is not cached like loadConfig, so
+// a running MCP server applies edits to the allowlist on its next call.
+func loadMCPConfig() (MCPConfig, error) {
+	path, err := configFilePath()
+	if err != nil {
+		return MCPConfig{}, err
+	}
+	var cfg Config
+	if err := mergeConfigFile(&cfg, path); err != nil {
+		return MCPConfig{}, err
+	}
+	return cfg.MCP, nil
+}
+
+// mergeConfigFile overlays the keys set in a TOML file onto cfg; a missing file is not an error
+func mergeConfigFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := toml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return nil
+}
+
 func configFilePath() (string, error) {
 	if override := strings.TrimSpace(os.Getenv(configFileEnv)); override != "" {
 		return filepath.Clean(override), nil
@@ -67,4 +264,5 @@ func resetConfigCache() {
 	configOnce = sync.Once{}
 	cachedConfig = Config{}
 	configErr = nil
+	repoConfigs.Clear()
 }
@@ -8,6 +8,8 @@ import (
 )
 
 func TestResolveWorktreeBaseDefault(t *testing.T) {
+	ctx := t.Context()
+
 	repoPath := setupTestRepo(t)
 	defer cleanupTestRepo(t, repoPath)
 
@@ -27,7 +29,7 @@ func TestResolveWorktreeBaseDefault(t *testing.T) {
 	resetConfigCache()
 	defer resetConfigCache()
 
-	base, err := resolveWorktreeBase()
+	base, err := resolveWorktreeBase(ctx)
 	if err != nil {
 		t.Fatalf("resolveWorktreeBase failed: %v", err)
 	}
@@ -39,6 +41,8 @@ func TestResolveWorktreeBaseDefault(t *testing.T) {
 }
 
 func TestResolveWorktreeBaseWithConfigFile(t *testing.T) {
+	ctx := t.Context()
+
 	repoPath := setupTestRepo(t)
 	defer cleanupTestRepo(t, repoPath)
 
@@ -61,7 +65,7 @@ func TestResolveWorktreeBaseWithConfigFile(t *testing.T) {
 	resetConfigCache()
 	defer resetConfigCache()
 
-	base, err := resolveWorktreeBase()
+	base, err := resolveWorktreeBase(ctx)
 	if err != nil {
 		t.Fatalf("resolveWorktreeBase failed: %v", err)
 	}
@@ -73,7 +77,9 @@ func TestResolveWorktreeBaseWithConfigFile(t *testing.T) {
 }
 
 func relativeToRepoRoot(t *testing.T, path string) string {
-	commonDir, err := runGitCommand("rev-parse", "--git-common-dir")
+	ctx := t.Context()
+
+	commonDir, err := runGitCommand(ctx, "rev-parse", "--git-common-dir")
 	if err != nil {
 		t.Fatalf("Failed to get git common dir: %v", err)
 	}
@@ -93,3 +99,20 @@ func relativeToRepoRoot(t *testing.T, path string) string {
 	}
 	return filepath.Clean(rel)
 }
+
+func TestNamesConfigSanitize(t *testing.T) {
+	tests := []struct {
+		cfg  NamesConfig
+		name string
+		want string
+	}{
+		{NamesConfig{}, "Login Fix", "Login Fix"},
+		{NamesConfig{Lowercase: true}, "Login-Fix", "login-fix"},
+		{NamesConfig{Lowercase: true, ReplaceSpaces: "-"}, "  Login  Fix ", "login-fix"},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.sanitize(tt.name); got != tt.want {
+			t.Errorf("%+v.sanitize(%q) = %q, want %q", tt.cfg, tt.name, got, tt.want)
+		}
+	}
+}
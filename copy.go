@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// CopyOptions groups configuration for duplicating a worktree
+type CopyOptions struct {
+	// Branch names the new branch (defaults to the new worktree name)
+	Branch string
+	// WithChanges also copies the source's uncommitted changes and untracked files
+	WithChanges bool
+}
+
+// CopyWorktree creates a worktree on a new branch at the commit of another worktree, carrying over its
+// base branch and labels, so an in-progress approach can be forked without touching the original
+func CopyWorktree(ctx context.Context, name, newName string, opts CopyOptions) error {
+	src, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+	if src.Bare {
+		return fmt.Errorf("worktree '%s' is the bare repository and cannot be copied", src.Name)
+	}
+
+	wt, err := addWorktree(ctx, newName, AddOptions{From: src.Name, Branch: opts.Branch, Labels: src.Labels})
+	if err != nil {
+		return err
+	}
+	// Compare and rebase the copy against the same base as the original rather than the original's branch
+	if src.Base != "" {
+		if err := setWorktreeMeta(ctx, wt.Name, metaBase, src.Base); err != nil {
+			return err
+		}
+	}
+
+	if !opts.WithChanges {
+		return nil
+	}
+	if planFileOp("copy the uncommitted changes of %s to %s", src.Path, wt.Path) {
+		return nil
+	}
+	// Nothing to copy when the only changes are files TransferChanges leaves behind
+	tracked, untracked, err := transferablePaths(ctx, src, true)
+	if err != nil || len(tracked) == 0 && len(untracked) == 0 {
+		return err
+	}
+	if err := TransferChanges(ctx, src.Name, wt.Name, TransferOptions{Untracked: true, Keep: true}); err != nil {
+		return fmt.Errorf("created worktree '%s' but failed to copy changes: %w", wt.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCopyWorktree(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return AddWorktree(ctx, "approach", AddOptions{Labels: []string{"spike"}})
+	}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	src, err := findWorktree(ctx, "approach")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src.Path, "idea.go"), []byte("package idea\n"), 0o644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if _, err := runGitCommand(ctx, "-C", src.Path, "add", "idea.go"); err != nil {
+		t.Fatalf("git add failed: %v", err)
+	}
+	if _, err := runGitCommand(ctx, "-C", src.Path, "commit", "-m", "Start approach"); err != nil {
+		t.Fatalf("git commit failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src.Path, "wip.txt"), []byte("half done\n"), 0o644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return CopyWorktree(ctx, "approach", "approach-alt", CopyOptions{WithChanges: true})
+	}); err != nil {
+		t.Fatalf("CopyWorktree failed: %v", err)
+	}
+
+	copied, err := findWorktree(ctx, "approach-alt")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if copied.Branch != "approach-alt" {
+		t.Errorf("expected a new branch approach-alt, got %q", copied.Branch)
+	}
+	srcHead, _ := runGitCommand(ctx, "-C", src.Path, "rev-parse", "HEAD")
+	copyHead, _ := runGitCommand(ctx, "-C", copied.Path, "rev-parse", "HEAD")
+	if srcHead == "" || srcHead != copyHead {
+		t.Errorf("expected the copy at %q, got %q", srcHead, copyHead)
+	}
+	if !slices.Equal(copied.Labels, []string{"spike"}) {
+		t.Errorf("expected the labels to be copied, got %q", copied.Labels)
+	}
+	if data, err := os.ReadFile(filepath.Join(copied.Path, "wip.txt")); err != nil || string(data) != "half done\n" {
+		t.Errorf("expected the untracked file to be copied, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(src.Path, "wip.txt")); err != nil {
+		t.Errorf("expected the original to keep its changes: %v", err)
+	}
+
+	if err := CopyWorktree(ctx, "approach", "approach-alt", CopyOptions{}); err == nil {
+		t.Error("expected copying to an existing name to fail")
+	}
+
+	// A worktree whose only untracked files are wtm-ignored has nothing to copy
+	if err := os.Remove(filepath.Join(src.Path, "wip.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(src.Path, "logs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// git status reports the directory as a whole, the ignore patterns only match the files inside
+	for _, f := range []string{".wtmignore", "logs/run.log"} {
+		content := "x\n"
+		if f == ".wtmignore" {
+			content = "*.log\n.wtmignore\n"
+		}
+		if err := os.WriteFile(filepath.Join(src.Path, f), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := captureStdout(t, func() error {
+		return CopyWorktree(ctx, "approach", "approach-clean", CopyOptions{WithChanges: true})
+	}); err != nil {
+		t.Fatalf("expected copying with only ignored files to succeed, got %v", err)
+	}
+	clean, err := findWorktree(ctx, "approach-clean")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(clean.Path, "logs")); !os.IsNotExist(err) {
+		t.Errorf("expected ignored files not to be copied, got %v", err)
+	}
+}
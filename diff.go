@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// DiffOptions groups configuration for showing a worktree's changes
+type DiffOptions struct {
+	// Base is the ref to compare against; defaults to the worktree's base branch
+	Base string
+	// Stat shows a diffstat instead of the patch
+	Stat bool
+	// NameOnly shows only the names of changed files
+	NameOnly bool
+}
+
+// DiffWorktree shows `git diff <base>...HEAD` for a worktree: the changes its branch made since it forked from the base
+func DiffWorktree(ctx context.Context, name string, opts DiffOptions) error {
+	wt, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+	base, err := resolveCompareBase(ctx, wt, opts.Base)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-C", wt.Path, "diff"}
+	switch {
+	case opts.NameOnly:
+		args = append(args, "--name-only")
+	case opts.Stat:
+		args = append(args, "--stat")
+	}
+	args = append(args, base+"...HEAD", "--")
+	if err := runGitInteractive(ctx, args...); err != nil {
+		return fmt.Errorf("cannot diff worktree '%s' against '%s': %w", wt.Name, base, err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffWorktree(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "feature", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "feature")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(wt.Path, "feature.txt"), []byte("feature line\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"add", "feature.txt"}, {"commit", "-m", "Add feature"}} {
+		if _, err := runGitCommand(ctx, append([]string{"-C", wt.Path}, args...)...); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+	// Changes on the base after the fork are not part of the worktree's diff
+	if err := os.WriteFile(filepath.Join(repoPath, "base.txt"), []byte("base\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"add", "base.txt"}, {"commit", "-m", "Add base"}} {
+		if _, err := runGitCommand(ctx, args...); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		opts    DiffOptions
+		want    string
+		notWant string
+	}{
+		{name: "patch", want: "+feature line", notWant: "base.txt"},
+		{name: "stat", opts: DiffOptions{Stat: true}, want: "1 file changed", notWant: "+feature line"},
+		{name: "name only", opts: DiffOptions{NameOnly: true}, want: "feature.txt", notWant: "base.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := captureStdout(t, func() error {
+				return DiffWorktree(ctx, "feature", tt.opts)
+			})
+			if err != nil {
+				t.Fatalf("DiffWorktree failed: %v", err)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("expected %q in output, got:\n%s", tt.want, out)
+			}
+			if strings.Contains(out, tt.notWant) {
+				t.Errorf("did not expect %q in output, got:\n%s", tt.notWant, out)
+			}
+		})
+	}
+}
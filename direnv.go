@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const envrcFile = ".envrc"
+
+// worktreeTemplateData is exposed to templates rendered into new worktrees
+type worktreeTemplateData struct {
+	Name     string
+	Branch   string
+	Path     string
+	RepoRoot string
+	// Port is the first port allocated to the worktree, 0 when port allocation is disabled
+	Port int
+	// ComposeProject is the worktree's COMPOSE_PROJECT_NAME
+	ComposeProject string
+	// BuildCacheEnv exports the variables of the [buildCache] presets, one per line, for .envrc templates
+	BuildCacheEnv string
+
+	buildCache []envVar
+}
+
+func newWorktreeTemplateData(ctx context.Context, wt *Worktree) (worktreeTemplateData, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return worktreeTemplateData{}, err
+	}
+	repoRoot, err := getRepoRoot(ctx)
+	if err != nil {
+		return worktreeTemplateData{}, err
+	}
+	data := worktreeTemplateData{Name: wt.Name, Branch: wt.Branch, Path: wt.Path, RepoRoot: repoRoot, Port: wt.Port}
+	if data.ComposeProject, err = composeProjectName(cfg.Compose, data); err != nil {
+		return worktreeTemplateData{}, err
+	}
+	if data.buildCache, err = buildCacheEnv(ctx, cfg.BuildCache, repoRoot); err != nil {
+		return worktreeTemplateData{}, err
+	}
+	data.BuildCacheEnv = exportLines(data.buildCache)
+	return data, nil
+}
+
+// writeEnvrc renders the configured direnv template into a new worktree and optionally runs `direnv allow`
+func writeEnvrc(ctx context.Context, data worktreeTemplateData) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	text, err := direnvTemplate(cfg.Direnv, data.RepoRoot)
+	if err != nil || text == "" {
+		return err
+	}
+
+	envrcPath := filepath.Join(data.Path, envrcFile)
+	if _, err := os.Stat(envrcPath); err == nil {
+		// Never overwrite an .envrc that the branch already tracks
+		fmt.Printf("Skipped %s: file already exists in the worktree\n", envrcFile)
+		return nil
+	}
+
+	content, err := renderTemplate("direnv", text, data)
+	if err != nil {
+		return fmt.Errorf("invalid direnv template: %w", err)
+	}
+	if !planFileOp("write %s", envrcPath) {
+		if err := os.WriteFile(envrcPath, []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+
+	if !cfg.Direnv.Allow {
+		return nil
+	}
+	if _, err := exec.LookPath("direnv"); err != nil {
+		fmt.Fprintln(os.Stderr, "Skipped direnv allow: direnv is not installed")
+		return nil
+	}
+	return runMutation(ctx, "direnv", "allow", data.Path)
+}
+
+// direnvTemplate returns the inline template, or the contents of templateFile resolved from the repository root
+func direnvTemplate(cfg DirenvConfig, repoRoot string) (string, error) {
+	if cfg.Template != "" {
+		return cfg.Template, nil
+	}
+	file := strings.TrimSpace(cfg.TemplateFile)
+	if file == "" {
+		return "", nil
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(repoRoot, file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("direnv template file not found: %s", file)
+		}
+		return "", err
+	}
+	return string(data), nil
+}
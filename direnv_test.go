@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddWorktreeWritesEnvrc(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	templateFile := filepath.Join(repoPath, "envrc.tmpl")
+	if err := os.WriteFile(templateFile, []byte("export WORKTREE={{.Name}}\nexport BRANCH={{.Branch}}\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[direnv]\ntemplateFile = \"envrc.tmpl\"\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	if err := AddWorktree(ctx, "envrc", AddOptions{Branch: "feature/envrc"}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	wt, err := findWorktree(ctx, "envrc")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(wt.Path, envrcFile))
+	if err != nil {
+		t.Fatalf("expected .envrc to be written: %v", err)
+	}
+	if want := "export WORKTREE=envrc\nexport BRANCH=feature/envrc\n"; string(data) != want {
+		t.Errorf("unexpected .envrc content:\nwant: %q\ngot:  %q", want, string(data))
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const bytesPerGB = 1 << 30
+
+// checkDiskQuota enforces the configured disk limits before a worktree is created under base.
+// When autoGc is enabled, exceeding a limit prunes stale records and removes merged worktrees without
+// local changes, then checks again; orphaned directories and ephemeral worktrees are left to wtm gc.
+func checkDiskQuota(ctx context.Context, base string) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg.Disk.MinFreeGB <= 0 && cfg.Disk.MaxTotalWorktreeGB <= 0 {
+		return nil
+	}
+
+	err = evaluateDiskQuota(ctx, cfg.Disk, base)
+	if err == nil {
+		return nil
+	}
+	if !cfg.Disk.AutoGC {
+		return fmt.Errorf("%w; run 'wtm clean' to remove finished worktrees", err)
+	}
+
+	// stdout carries JSON-RPC under wtm mcp
+	fmt.Fprintf(os.Stderr, "%v; running garbage collection\n", err)
+	candidates, err := findSafeGarbage(ctx)
+	if err != nil {
+		return err
+	}
+	if err := deleteGarbage(ctx, candidates); err != nil {
+		return err
+	}
+	return evaluateDiskQuota(ctx, cfg.Disk, base)
+}
+
+func evaluateDiskQuota(ctx context.Context, disk DiskConfig, base string) error {
+	if disk.MinFreeGB > 0 {
+		free, err := freeDiskSpace(existingParent(base))
+		if err != nil {
+			return err
+		}
+		if freeGB := float64(free) / bytesPerGB; freeGB < disk.MinFreeGB {
+			return fmt.Errorf("only %.1f GB free, below disk.minFreeGB = %g", freeGB, disk.MinFreeGB)
+		}
+	}
+
+	if disk.MaxTotalWorktreeGB > 0 {
+		total, err := totalWorktreeSize(ctx)
+		if err != nil {
+			return err
+		}
+		if totalGB := float64(total) / bytesPerGB; totalGB >= disk.MaxTotalWorktreeGB {
+			return fmt.Errorf("worktrees use %.1f GB, at or above disk.maxTotalWorktreeGB = %g", totalGB, disk.MaxTotalWorktreeGB)
+		}
+	}
+	return nil
+}
+
+// totalWorktreeSize sums the size of every non-primary worktree, honoring .wtmignore
+func totalWorktreeSize(ctx context.Context) (int64, error) {
+	worktrees, err := matchWorktrees(ctx, "*")
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, wt := range worktrees {
+		size, err := worktreeSize(wt.Path)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// worktreeSize returns the size of the files in a worktree that are not excluded by .wtmignore
+func worktreeSize(root string) (int64, error) {
+	m, err := loadIgnoreMatcher(root)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	err = walkUnignored(root, m, func(rel string, d fs.DirEntry) error {
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	return size, err
+}
+
+// existingParent returns the closest ancestor of p that exists on disk
+func existingParent(p string) string {
+	for {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return p
+		}
+		p = parent
+	}
+}
+
+// checkCheckoutSpace estimates the size of a new checkout from the files tracked at HEAD (or, for a
+// sparse checkout, under its directories) and refuses to create a worktree under base when the
+// filesystem has less space free. It warns when the checkout would take more than half of it.
+func checkCheckoutSpace(ctx context.Context, base string, sparse []string) error {
+	estimate, err := checkoutSize(ctx, sparse)
+	if err != nil {
+		return err
+	}
+	free, err := freeDiskSpace(existingParent(base))
+	if err != nil {
+		return err
+	}
+	warning, err := evaluateCheckoutSpace(estimate, free)
+	if warning != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return err
+}
+
+// evaluateCheckoutSpace compares the estimated checkout size with the free space
+func evaluateCheckoutSpace(estimate, free uint64) (warning string, err error) {
+	switch {
+	case estimate > free:
+		return "", fmt.Errorf("not enough disk space: the checkout needs about %.1f GB but only %.1f GB is free; free up space or pass --skip-space-check",
+			float64(estimate)/bytesPerGB, float64(free)/bytesPerGB)
+	case estimate > free/2:
+		return fmt.Sprintf("the checkout needs about %.1f GB, more than half of the %.1f GB free", float64(estimate)/bytesPerGB, float64(free)/bytesPerGB), nil
+	}
+	return "", nil
+}
+
+// checkoutSize sums the sizes of the blobs tracked at HEAD, limited to the sparse directories when given.
+// A repository without commits has nothing to check out.
+func checkoutSize(ctx context.Context, sparse []string) (uint64, error) {
+	if _, err := runGitCommand(ctx, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return 0, nil
+	}
+	args := []string{"ls-tree", "-r", "-l", "--full-tree", "HEAD"}
+	if len(sparse) > 0 {
+		args = append(append(args, "--"), sparse...)
+	}
+	output, err := runGitCommand(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	var total uint64
+	for _, line := range strings.Split(output, "\n") {
+		// <mode> <type> <object> <size>\t<path>; submodules and symlinks report "-" or a tiny size
+		meta, _, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 4 {
+			continue
+		}
+		if size, err := strconv.ParseUint(fields[3], 10, 64); err == nil {
+			total += size
+		}
+	}
+	return total, nil
+}
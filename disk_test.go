@@ -0,0 +1,172 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddWorktreeEnforcesDiskQuota(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[disk]\nmaxTotalWorktreeGB = 0.000001\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	if err := AddWorktree(ctx, "first", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree below quota failed: %v", err)
+	}
+
+	// Push the existing worktrees over the ~1 KB limit
+	if err := os.WriteFile(filepath.Join(repoPath, ".git", "wtm", "worktrees", "first", "big.bin"), make([]byte, 4096), 0o644); err != nil {
+		t.Fatalf("Failed to write large file: %v", err)
+	}
+
+	err = AddWorktree(ctx, "second", AddOptions{})
+	if err == nil {
+		t.Fatal("expected AddWorktree to fail when the worktree quota is exceeded")
+	}
+	if !strings.Contains(err.Error(), "disk.maxTotalWorktreeGB") || !strings.Contains(err.Error(), "wtm clean") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAutoGCKeepsEphemeralWorktrees(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[disk]\nmaxTotalWorktreeGB = 0.000001\nautoGc = true\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	if _, err := captureStdout(t, func() error { return AddWorktree(ctx, "scratch", AddOptions{Ephemeral: true}) }); err != nil {
+		t.Fatalf("AddWorktree below quota failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, ".git", "wtm", "worktrees", "scratch", "big.bin"), make([]byte, 4096), 0o644); err != nil {
+		t.Fatalf("Failed to write large file: %v", err)
+	}
+
+	output, err := captureStdout(t, func() error { return AddWorktree(ctx, "second", AddOptions{}) })
+	if err == nil {
+		t.Fatal("expected AddWorktree to fail when only an ephemeral worktree could free space")
+	}
+	if strings.Contains(output, "garbage collection") {
+		t.Errorf("expected the autoGc notice on stderr, got stdout %q", output)
+	}
+	if _, err := findWorktree(ctx, "scratch"); err != nil {
+		t.Errorf("expected autoGc to leave the ephemeral worktree to wtm gc: %v", err)
+	}
+}
+
+func TestWorktreeSizeHonorsWtmignore(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "cache"), 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "cache", "blob"), make([]byte, 1000), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "main.go"), make([]byte, 10), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, wtmIgnoreFile), []byte("cache/\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	size, err := worktreeSize(root)
+	if err != nil {
+		t.Fatalf("worktreeSize failed: %v", err)
+	}
+	if want := int64(10 + len("cache/\n")); size != want {
+		t.Errorf("expected size %d, got %d", want, size)
+	}
+}
+
+func TestCheckoutSpace(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	before, err := checkoutSize(ctx, nil)
+	if err != nil {
+		t.Fatalf("checkoutSize failed: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(repoPath, "assets"), 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "assets", "big.bin"), make([]byte, 5000), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, err := runGitCommand(ctx, "add", "assets"); err != nil {
+		t.Fatalf("git add failed: %v", err)
+	}
+	if _, err := runGitCommand(ctx, "commit", "-m", "Add assets"); err != nil {
+		t.Fatalf("git commit failed: %v", err)
+	}
+
+	after, err := checkoutSize(ctx, nil)
+	if err != nil {
+		t.Fatalf("checkoutSize failed: %v", err)
+	}
+	if after-before != 5000 {
+		t.Errorf("expected the checkout to grow by 5000 bytes, got %d -> %d", before, after)
+	}
+	if sparse, err := checkoutSize(ctx, []string{"assets"}); err != nil || sparse != 5000 {
+		t.Errorf("checkoutSize(assets) = %d, %v; want 5000", sparse, err)
+	}
+
+	if warning, err := evaluateCheckoutSpace(100, 1000); warning != "" || err != nil {
+		t.Errorf("expected plenty of space to pass silently, got %q, %v", warning, err)
+	}
+	if warning, err := evaluateCheckoutSpace(600, 1000); warning == "" || err != nil {
+		t.Errorf("expected a warning when the checkout takes most of the free space, got %q, %v", warning, err)
+	}
+	if _, err := evaluateCheckoutSpace(2000, 1000); err == nil || !strings.Contains(err.Error(), "--skip-space-check") {
+		t.Errorf("expected an error pointing at --skip-space-check, got %v", err)
+	}
+}
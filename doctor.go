@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+// DoctorOptions groups configuration for checking a repository's wtm setup
+type DoctorOptions struct {
+	// Fix moves misplaced worktrees into the worktree root of the configured layout
+	Fix bool
+}
+
+// Doctor checks that every worktree lives under the worktree root of the configured layout. After
+// switching between the nested and sibling layouts, existing worktrees stay where they were until
+// they are migrated; with opts.Fix they are moved, otherwise they are reported and an error returned.
+func Doctor(ctx context.Context, opts DoctorOptions) error {
+	if _, err := getRepoRoot(ctx); err != nil {
+		return wtm.ErrNotARepo
+	}
+	base, err := resolveWorktreeBase(ctx)
+	if err != nil {
+		return err
+	}
+	misplaced, err := misplacedWorktrees(ctx, base)
+	if err != nil {
+		return err
+	}
+	if len(misplaced) == 0 {
+		report("✓ All worktrees are under %s\n", base)
+		return nil
+	}
+
+	if !opts.Fix {
+		for _, wt := range misplaced {
+			fmt.Printf("✗ %s is outside the worktree root: %s\n", wt.Name, wt.Path)
+		}
+		return fmt.Errorf("%d worktrees are outside the worktree root %s; run wtm doctor --fix to move them", len(misplaced), base)
+	}
+	if !planFileOp("mkdir -p %s", base) {
+		if err := os.MkdirAll(base, 0o755); err != nil {
+			return err
+		}
+	}
+	return migrateWorktrees(ctx, base)
+}
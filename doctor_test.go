@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoctorMigratesNestedToSibling(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	// Worktrees created with the default nested layout
+	for _, name := range []string{"api", "web"} {
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+	}
+	if _, err := captureStdout(t, func() error { return Doctor(ctx, DoctorOptions{}) }); err != nil {
+		t.Fatalf("expected no problems with the nested layout, got %v", err)
+	}
+
+	if err := os.WriteFile(configFile, []byte("layout = \"sibling\"\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	resetConfigCache()
+
+	output, err := captureStdout(t, func() error { return Doctor(ctx, DoctorOptions{}) })
+	if err == nil || !strings.Contains(err.Error(), "wtm doctor --fix") {
+		t.Fatalf("expected the nested worktrees to be reported, got %v", err)
+	}
+	for _, name := range []string{"api", "web"} {
+		if !strings.Contains(output, name+" is outside the worktree root") {
+			t.Errorf("expected %s to be reported, got:\n%s", name, output)
+		}
+	}
+
+	if _, err := captureStdout(t, func() error { return Doctor(ctx, DoctorOptions{Fix: true}) }); err != nil {
+		t.Fatalf("Doctor --fix failed: %v", err)
+	}
+	base := filepath.Join(filepath.Dir(repoPath), filepath.Base(repoPath)+"-worktrees")
+	for _, name := range []string{"api", "web"} {
+		wt, err := findWorktree(ctx, name)
+		if err != nil {
+			t.Fatalf("findWorktree failed: %v", err)
+		}
+		if want := filepath.Join(base, name); normalizePath(wt.Path) != normalizePath(want) {
+			t.Errorf("expected %s to be moved to %s, got %s", name, want, wt.Path)
+		}
+	}
+	if _, err := captureStdout(t, func() error { return Doctor(ctx, DoctorOptions{}) }); err != nil {
+		t.Errorf("expected no problems after the migration, got %v", err)
+	}
+}
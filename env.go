@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+// EnvOptions groups configuration for printing a worktree's environment
+type EnvOptions struct {
+	// JSON prints a JSON object instead of shell assignments
+	JSON bool
+	// Export prefixes each assignment with export
+	Export bool
+}
+
+// envVar is a single WTM_* variable describing a worktree
+type envVar struct {
+	Name  string
+	Value string
+}
+
+// worktreeEnv returns the WTM_* variables describing a worktree, in a stable order, followed by
+// COMPOSE_PROJECT_NAME and the shared build cache variables. WTM_BASE is empty when no base branch can be determined, WTM_PRIMARY_PATH
+// in bare repositories, which have no primary checkout, and WTM_PORT when no port was allocated.
+func worktreeEnv(ctx context.Context, wt *Worktree) ([]envVar, error) {
+	base, err := resolveCompareBase(ctx, wt, "")
+	if err != nil {
+		base = ""
+	}
+	data, err := newWorktreeTemplateData(ctx, wt)
+	if err != nil {
+		return nil, err
+	}
+	primary, err := primaryWorktreePath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vars := []envVar{
+		{Name: "WTM_NAME", Value: wt.Name},
+		{Name: "WTM_BRANCH", Value: wt.Branch},
+		{Name: "WTM_PATH", Value: wt.Path},
+		{Name: "WTM_BASE", Value: base},
+		{Name: "WTM_PRIMARY_PATH", Value: primary},
+		{Name: "WTM_REPO_ROOT", Value: data.RepoRoot},
+		{Name: "WTM_PORT", Value: formatPort(wt.Port)},
+		{Name: "COMPOSE_PROJECT_NAME", Value: data.ComposeProject},
+	}
+	return append(vars, data.buildCache...), nil
+}
+
+// primaryWorktreePath returns the path of the main worktree, the first one git lists, or "" when it is bare
+func primaryWorktreePath(ctx context.Context) (string, error) {
+	worktrees, err := getWorktrees(ctx)
+	if err != nil || len(worktrees) == 0 || worktrees[0].Bare {
+		return "", err
+	}
+	return worktrees[0].Path, nil
+}
+
+// setWorktreeEnv makes cmd inherit the environment of wtm plus the variables describing wt, so hooks,
+// setup commands, tasks and exec children all see the same WTM_* variables
+func setWorktreeEnv(ctx context.Context, cmd *exec.Cmd, wt *Worktree) error {
+	vars, err := worktreeEnv(ctx, wt)
+	if err != nil {
+		return err
+	}
+	cmd.Env = os.Environ()
+	for _, v := range vars {
+		cmd.Env = append(cmd.Env, v.Name+"="+v.Value)
+	}
+	return nil
+}
+
+// PrintEnv prints the environment of the named worktree, or of the one containing the current directory
+func PrintEnv(ctx context.Context, name string, opts EnvOptions) error {
+	var wt *Worktree
+	var err error
+	if name == "" {
+		wt, err = currentWorktree(ctx)
+	} else {
+		wt, err = findWorktree(ctx, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	vars, err := worktreeEnv(ctx, wt)
+	if err != nil {
+		return err
+	}
+	if opts.JSON {
+		obj := make(map[string]string, len(vars))
+		for _, v := range vars {
+			obj[v.Name] = v.Value
+		}
+		data, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	prefix := ""
+	if opts.Export {
+		prefix = "export "
+	}
+	for _, v := range vars {
+		fmt.Printf("%s%s=%s\n", prefix, v.Name, wtm.ShellJoin([]string{v.Value}))
+	}
+	return nil
+}
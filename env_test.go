@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPrintEnv(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "api", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "api")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+
+	t.Run("shell", func(t *testing.T) {
+		out, err := captureStdout(t, func() error {
+			return PrintEnv(ctx, "api", EnvOptions{Export: true})
+		})
+		if err != nil {
+			t.Fatalf("PrintEnv failed: %v", err)
+		}
+		for _, want := range []string{"export WTM_NAME=api\n", "export WTM_BRANCH=api\n", "export WTM_PATH=" + wt.Path + "\n", "export WTM_BASE=" + wt.Base + "\n"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected %q in output, got:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("current worktree as json", func(t *testing.T) {
+		if err := os.Chdir(wt.Path); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chdir(repoPath)
+
+		out, err := captureStdout(t, func() error {
+			return PrintEnv(ctx, "", EnvOptions{JSON: true})
+		})
+		if err != nil {
+			t.Fatalf("PrintEnv failed: %v", err)
+		}
+		var env map[string]string
+		if err := json.Unmarshal([]byte(out), &env); err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, out)
+		}
+		if env["WTM_NAME"] != "api" || env["WTM_PATH"] != wt.Path {
+			t.Errorf("unexpected env: %v", env)
+		}
+		if normalizePath(env["WTM_PRIMARY_PATH"]) != normalizePath(repoPath) || normalizePath(env["WTM_REPO_ROOT"]) != normalizePath(repoPath) {
+			t.Errorf("expected WTM_PRIMARY_PATH and WTM_REPO_ROOT to be %s, got %v", repoPath, env)
+		}
+	})
+}
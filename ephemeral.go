@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ephemeralKeepBranch is the MetaEphemeral value of a worktree on a branch that existed before it
+const ephemeralKeepBranch = "keep-branch"
+
+// markEphemeral records that a new worktree is ephemeral. A branch that existed before the worktree
+// survives its removal; a branch created for it goes with it.
+func markEphemeral(ctx context.Context, wt *Worktree, opts AddOptions) error {
+	// The owner is recorded first so that a concurrent gc never sees the worktree as ephemeral without it
+	if opts.owner > 0 {
+		if err := setWorktreeMeta(ctx, wt.Name, metaOwner, strconv.Itoa(opts.owner)); err != nil {
+			return fmt.Errorf("created worktree '%s' but failed to record its owner: %w", wt.Name, err)
+		}
+	}
+	value := "true"
+	if !wt.CreatedBranch {
+		value = ephemeralKeepBranch
+	}
+	if err := setWorktreeMeta(ctx, wt.Name, metaEphemeral, value); err != nil {
+		return fmt.Errorf("created worktree '%s' but failed to mark it ephemeral: %w", wt.Name, err)
+	}
+	wt.Ephemeral = true
+	return nil
+}
+
+// ephemeralOwnerAlive reports whether the process that runs a command in an ephemeral worktree is
+// still running, in which case the worktree is in use and gc leaves it alone
+func ephemeralOwnerAlive(meta map[string]string) bool {
+	pid, err := strconv.Atoi(meta[metaOwner])
+	return err == nil && pid > 0 && processAlive(pid)
+}
+
+// AddEphemeral creates an ephemeral worktree, runs argv in it, and removes the worktree and the
+// branch created for it once the command exits, whether it succeeded, failed or was interrupted
+func AddEphemeral(ctx context.Context, name string, opts AddOptions, argv []string) error {
+	opts.Ephemeral = true
+	opts.owner = os.Getpid()
+	wt, err := addWorktree(ctx, name, opts)
+	if err != nil {
+		return err
+	}
+
+	var runErr error
+	if planning() {
+		activePlan.RecordCommand(argv[0], argv[1:]...)
+	} else {
+		runErr = execAttached(ctx, wt, argv)
+	}
+	// Clean up even when the command was interrupted with Ctrl-C
+	return errors.Join(runErr, removeEphemeral(context.WithoutCancel(ctx), wt))
+}
+
+// removeEphemeral removes an ephemeral worktree with its uncommitted changes and, when it was
+// created for the worktree, its branch
+func removeEphemeral(ctx context.Context, wt *Worktree) error {
+	meta, err := loadWorktreeMeta(ctx)
+	if err != nil {
+		return err
+	}
+	opts := RemoveOptions{BranchDelete: BranchDeleteForce, DiscardChanges: true}
+	if meta[wt.Name][metaEphemeral] == ephemeralKeepBranch {
+		opts.BranchDelete = BranchDeleteNone
+	}
+	return removeWorktreeTarget(ctx, wt, opts)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEphemeralWorktrees(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	t.Run("removed when the command exits", func(t *testing.T) {
+		_, err := captureStdout(t, func() error {
+			return AddEphemeral(ctx, "tmp", AddOptions{}, []string{"sh", "-c", "echo scratch > notes.txt; exit 3"})
+		})
+		if err == nil {
+			t.Error("expected the failing command's error to be returned")
+		}
+		if _, err := findWorktree(ctx, "tmp"); err == nil {
+			t.Error("expected the ephemeral worktree to be removed after the command exited")
+		}
+		if refExists(ctx, "refs/heads/tmp") {
+			t.Error("expected the branch created for the ephemeral worktree to be deleted")
+		}
+	})
+
+	t.Run("removed by gc", func(t *testing.T) {
+		if _, err := runGitCommand(ctx, "branch", "existing"); err != nil {
+			t.Fatalf("git branch failed: %v", err)
+		}
+		if _, err := runGitCommand(ctx, "remote", "add", "origin", repoPath); err != nil {
+			t.Fatalf("git remote add failed: %v", err)
+		}
+		if _, err := runGitCommand(ctx, "update-ref", "refs/remotes/origin/feature", "HEAD"); err != nil {
+			t.Fatalf("git update-ref failed: %v", err)
+		}
+		if _, err := captureStdout(t, func() error {
+			if err := AddWorktree(ctx, "scratch", AddOptions{Ephemeral: true}); err != nil {
+				return err
+			}
+			if err := AddWorktree(ctx, "keeper", AddOptions{Checkout: "existing", Ephemeral: true}); err != nil {
+				return err
+			}
+			if err := AddWorktree(ctx, "tracking", AddOptions{Checkout: "origin/feature", Ephemeral: true}); err != nil {
+				return err
+			}
+			return AddWorktree(ctx, "regular", AddOptions{})
+		}); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+
+		wt, err := findWorktree(ctx, "scratch")
+		if err != nil {
+			t.Fatalf("findWorktree failed: %v", err)
+		}
+		if !wt.Ephemeral {
+			t.Error("expected the worktree to be marked ephemeral")
+		}
+
+		candidates, err := findGarbage(ctx)
+		if err != nil {
+			t.Fatalf("findGarbage failed: %v", err)
+		}
+		kinds := map[string]string{}
+		for _, c := range candidates {
+			kinds[c.Name] = c.Kind
+		}
+		if kinds["scratch"] != gcEphemeral || kinds["keeper"] != gcEphemeral || kinds["tracking"] != gcEphemeral || kinds["regular"] != "" {
+			t.Errorf("expected only the ephemeral worktrees to be collected, got %v", kinds)
+		}
+
+		if _, err := captureStdout(t, func() error { return collectGarbage(ctx, GCOptions{Force: true}) }); err != nil {
+			t.Fatalf("collectGarbage failed: %v", err)
+		}
+		for _, name := range []string{"scratch", "keeper", "tracking"} {
+			if _, err := findWorktree(ctx, name); err == nil {
+				t.Errorf("expected gc to remove ephemeral worktree %s", name)
+			}
+		}
+		if _, err := findWorktree(ctx, "regular"); err != nil {
+			t.Errorf("expected gc to keep the regular worktree: %v", err)
+		}
+		if refExists(ctx, "refs/heads/scratch") {
+			t.Error("expected the branch created for scratch to be deleted")
+		}
+		if refExists(ctx, "refs/heads/feature") {
+			t.Error("expected the tracking branch created for origin/feature to be deleted")
+		}
+		if !refExists(ctx, "refs/heads/existing") {
+			t.Error("expected the branch checked out with -B to be kept")
+		}
+	})
+
+	t.Run("kept by gc while its command runs", func(t *testing.T) {
+		if _, err := captureStdout(t, func() error {
+			return AddWorktree(ctx, "busy", AddOptions{Ephemeral: true, owner: os.Getpid()})
+		}); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+
+		candidates, err := findGarbage(ctx)
+		if err != nil {
+			t.Fatalf("findGarbage failed: %v", err)
+		}
+		for _, c := range candidates {
+			if c.Name == "busy" {
+				t.Errorf("expected gc to skip an ephemeral worktree whose owner is alive, got %+v", c)
+			}
+		}
+	})
+}
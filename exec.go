@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// ExecInWorktree runs a program, without a shell, inside the named worktree with the WTM_* variables
+// set, like the wtm_exec MCP tool but attached to the terminal
+func ExecInWorktree(ctx context.Context, name string, argv []string) error {
+	target, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	markUsed(ctx, target)
+	return execAttached(ctx, target, argv)
+}
+
+// execAttached runs a program inside a worktree with the terminal attached
+func execAttached(ctx context.Context, target *Worktree, argv []string) error {
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = target.Path
+	if err := setWorktreeEnv(ctx, cmd, target); err != nil {
+		return err
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("'%s' failed in worktree '%s': %w", shellJoin(argv), target.Name, err)
+	}
+	return nil
+}
+
+// execResult is one element of the JSON array printed by wtm exec --all
+type execResult struct {
+	Name     string `json:"name"`
+	ExitCode int    `json:"exitCode"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	// Duration is the run time in seconds
+	Duration float64 `json:"duration"`
+}
+
+// ExecAll runs a program, without a shell, in every worktree and prints a JSON array with the exit
+// code, captured output and duration of each run. It fails when the program failed anywhere.
+func ExecAll(ctx context.Context, argv []string, opts ForeachOptions) error {
+	targets, err := foreachTargets(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	results := make([]execResult, len(targets))
+	inParallel(len(targets), min(max(opts.Parallel, 1), len(targets)), func(i int) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		res := runCmdInWorktree(ctx, &targets[i], cmd, &stdout, &stderr)
+		results[i] = execResult{
+			Name:     res.Name,
+			ExitCode: res.ExitCode,
+			Stdout:   stdout.String(),
+			Stderr:   stderr.String(),
+			Duration: res.Duration,
+		}
+	})
+
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+
+	failed := 0
+	for _, r := range results {
+		if r.ExitCode != 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("'%s' failed in %d of %d worktree(s)", shellJoin(argv), failed, len(results))
+	}
+	return nil
+}
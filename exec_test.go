@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExecInWorktree(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if _, err := captureStdout(t, func() error { return AddWorktree(ctx, "api", AddOptions{}) }); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	output, err := captureStdout(t, func() error {
+		return ExecInWorktree(ctx, "api", []string{"git", "rev-parse", "--abbrev-ref", "HEAD"})
+	})
+	if err != nil {
+		t.Fatalf("ExecInWorktree failed: %v", err)
+	}
+	if strings.TrimSpace(output) != "api" {
+		t.Errorf("expected the command to run in the api worktree, got %q", output)
+	}
+
+	wt, err := findWorktree(ctx, "api")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if wt.LastUsed.IsZero() {
+		t.Error("expected exec to record the use of the worktree")
+	}
+	if err := os.Chdir(wt.Path); err != nil {
+		t.Fatalf("Failed to change to worktree: %v", err)
+	}
+	output, err = captureStdout(t, func() error {
+		return ExecInWorktree(ctx, ".", []string{"sh", "-c", "echo $WTM_NAME"})
+	})
+	if err != nil {
+		t.Fatalf("ExecInWorktree . failed: %v", err)
+	}
+	if strings.TrimSpace(output) != "api" {
+		t.Errorf("expected . to resolve to the current worktree with WTM_NAME set, got %q", output)
+	}
+
+	if err := ExecInWorktree(ctx, "api", []string{"git", "rev-parse", "--verify", "missing-ref"}); err == nil {
+		t.Error("expected a failing command to return an error")
+	}
+}
+
+func TestExecAll(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	for _, name := range []string{"api", "web"} {
+		if _, err := captureStdout(t, func() error { return AddWorktree(ctx, name, AddOptions{}) }); err != nil {
+			t.Fatalf("AddWorktree %s failed: %v", name, err)
+		}
+	}
+
+	output, err := captureStdout(t, func() error {
+		return ExecAll(ctx, []string{"sh", "-c", `echo "out $WTM_NAME"; echo "err $WTM_NAME" >&2; [ "$WTM_NAME" = api ]`}, ForeachOptions{Parallel: 2})
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Errorf("expected the run to fail in 1 of 2 worktrees, got %v", err)
+	}
+
+	var results []execResult
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatalf("failed to parse output %q: %v", output, err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	for _, r := range results {
+		wantExit := 1
+		if r.Name == "api" {
+			wantExit = 0
+		}
+		if r.ExitCode != wantExit || r.Stdout != "out "+r.Name+"\n" || r.Stderr != "err "+r.Name+"\n" {
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+}
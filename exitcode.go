@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/choplin/wtm/pkg/wtm"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes let scripts react to classes of failure; they are documented by `wtm help exit-codes`
+const (
+	exitError     = 1
+	exitNotFound  = 2
+	exitExists    = 3
+	exitUnmerged  = 4
+	exitGitFailed = 5
+	exitAborted   = 10
+)
+
+// errAborted is returned when the user declines a confirmation prompt
+var errAborted = errors.New("aborted")
+
+// exitCode maps an error returned by a command to the process exit code
+func exitCode(err error) int {
+	var gitErr *wtm.GitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errAborted), errors.Is(err, context.Canceled):
+		return exitAborted
+	case errors.Is(err, wtm.ErrWorktreeNotFound):
+		return exitNotFound
+	case errors.Is(err, wtm.ErrWorktreeExists), errors.Is(err, wtm.ErrBranchExists):
+		return exitExists
+	case errors.Is(err, wtm.ErrBranchNotMerged), errors.Is(err, wtm.ErrWorktreeDirty):
+		return exitUnmerged
+	case errors.As(err, &gitErr):
+		return exitGitFailed
+	default:
+		return exitError
+	}
+}
+
+func newExitCodesHelpTopic() *cobra.Command {
+	return &cobra.Command{
+		Use:   "exit-codes",
+		Short: "Exit codes returned by wtm",
+		Long: `wtm exits with a code that tells scripts what kind of failure occurred:
+
+   0  success
+   1  any other error, such as invalid flags or configuration
+   2  the worktree was not found
+   3  a worktree or branch with that name already exists
+   4  work would be lost: the branch is not fully merged (git branch -d refused to
+      delete it) or the worktree has uncommitted changes
+   5  a git command failed
+  10  aborted: a confirmation was declined or the command was interrupted`,
+	}
+}
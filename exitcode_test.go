@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+func TestExitCode(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+	m := wtm.New(repoPath)
+
+	_, notFound := m.Show(ctx, "missing")
+	_, gitFailed := m.Git(ctx, "rev-parse", "--verify", "missing-ref")
+	if _, err := m.Add(ctx, "feature", wtm.AddOptions{}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	_, exists := m.Add(ctx, "feature", wtm.AddOptions{})
+
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("invalid flag"), exitError},
+		{notFound, exitNotFound},
+		{exists, exitExists},
+		{&wtm.BranchError{Name: "x", Err: wtm.ErrBranchExists}, exitExists},
+		{fmt.Errorf("deleted worktree but failed to delete branch: %w", &wtm.GitError{Output: "error: the branch 'x' is not fully merged", Err: &exec.ExitError{}}), exitUnmerged},
+		{fmt.Errorf("%w; pass --stash", &wtm.WorktreeError{Name: "x", Err: wtm.ErrWorktreeDirty}), exitUnmerged},
+		{gitFailed, exitGitFailed},
+		{errAborted, exitAborted},
+		{context.Canceled, exitAborted},
+	}
+	for _, c := range cases {
+		if got := exitCode(c.err); got != c.want {
+			t.Errorf("exitCode(%v) = %d, want %d", c.err, got, c.want)
+		}
+	}
+}
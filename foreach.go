@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ForeachOptions groups configuration for running a command across worktrees
+type ForeachOptions struct {
+	// IncludePrimary also runs the command in the primary worktree
+	IncludePrimary bool
+	// Parallel is the number of worktrees processed concurrently; values below 2 run sequentially
+	Parallel int
+	// JSON prints an aggregate machine-readable report instead of prefixed output
+	JSON bool
+}
+
+// foreachResult records how a command finished in one worktree
+type foreachResult struct {
+	Name     string  `json:"name"`
+	Path     string  `json:"path"`
+	ExitCode int     `json:"exitCode"`
+	Duration float64 `json:"durationSeconds"`
+	Output   string  `json:"output,omitempty"`
+}
+
+// foreachReport is the aggregate JSON report printed with --json
+type foreachReport struct {
+	Command string          `json:"command"`
+	Failed  int             `json:"failed"`
+	Results []foreachResult `json:"results"`
+}
+
+// Foreach runs a shell command in every managed worktree, prefixing output with the worktree name
+// and printing a summary of exit codes
+func Foreach(ctx context.Context, command string, opts ForeachOptions) error {
+	targets, err := foreachTargets(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Println("No worktrees")
+		return nil
+	}
+
+	results := runForeach(ctx, targets, command, opts)
+	if opts.JSON {
+		return reportForeachJSON(command, results)
+	}
+	return summarizeForeach(results)
+}
+
+// runForeach runs command in each target using a pool of opts.Parallel workers. With more than one
+// worker, output is buffered per worktree and written in one piece when that worktree finishes.
+func runForeach(ctx context.Context, targets []Worktree, command string, opts ForeachOptions) []foreachResult {
+	workers := min(max(opts.Parallel, 1), len(targets))
+	results := make([]foreachResult, len(targets))
+
+	var outputMu sync.Mutex
+	inParallel(len(targets), workers, func(i int) {
+		wt := &targets[i]
+		switch {
+		case opts.JSON:
+			var buf bytes.Buffer
+			results[i] = runInWorktree(ctx, wt, command, &buf, &buf)
+			results[i].Output = buf.String()
+		case workers > 1:
+			var outBuf, errBuf bytes.Buffer
+			stdout := newPrefixWriter(&outBuf, wt.Name)
+			stderr := newPrefixWriter(&errBuf, wt.Name)
+			results[i] = runInWorktree(ctx, wt, command, stdout, stderr)
+			stdout.Flush()
+			stderr.Flush()
+			outputMu.Lock()
+			os.Stdout.Write(outBuf.Bytes())
+			os.Stderr.Write(errBuf.Bytes())
+			outputMu.Unlock()
+		default:
+			stdout := newPrefixWriter(os.Stdout, wt.Name)
+			stderr := newPrefixWriter(os.Stderr, wt.Name)
+			results[i] = runInWorktree(ctx, wt, command, stdout, stderr)
+			stdout.Flush()
+			stderr.Flush()
+		}
+	})
+	return results
+}
+
+// inParallel calls fn with every index below n from a pool of workers goroutines and waits for all of them
+func inParallel(n, workers int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func foreachTargets(ctx context.Context, opts ForeachOptions) ([]Worktree, error) {
+	if !opts.IncludePrimary {
+		return matchWorktrees(ctx, "*")
+	}
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// A bare repository has no checkout to run commands in
+	targets := worktrees[:0]
+	for _, wt := range worktrees {
+		if !wt.Bare {
+			targets = append(targets, wt)
+		}
+	}
+	return targets, nil
+}
+
+// runInWorktree runs a shell command inside a worktree and records its exit code (-1 if it could not start)
+func runInWorktree(ctx context.Context, wt *Worktree, command string, stdout, stderr io.Writer) foreachResult {
+	return runCmdInWorktree(ctx, wt, shellCommand(ctx, command), stdout, stderr)
+}
+
+// runCmdInWorktree runs cmd inside a worktree with the WTM_* variables set and records its exit code
+// (-1 if it could not start)
+func runCmdInWorktree(ctx context.Context, wt *Worktree, cmd *exec.Cmd, stdout, stderr io.Writer) foreachResult {
+	cmd.Dir = wt.Path
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	result := foreachResult{Name: wt.Name, Path: wt.Path}
+	if err := setWorktreeEnv(ctx, cmd, wt); err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		result.ExitCode = -1
+		return result
+	}
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration = time.Since(start).Seconds()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			fmt.Fprintf(stderr, "%v\n", err)
+			result.ExitCode = -1
+		}
+	}
+	return result
+}
+
+func reportForeachJSON(command string, results []foreachResult) error {
+	report := foreachReport{Command: command, Results: results}
+	for _, r := range results {
+		if r.ExitCode != 0 {
+			report.Failed++
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+
+	if report.Failed > 0 {
+		return fmt.Errorf("command failed in %d of %d worktree(s)", report.Failed, len(results))
+	}
+	return nil
+}
+
+func summarizeForeach(results []foreachResult) error {
+	width := 0
+	for _, r := range results {
+		width = max(width, len(r.Name))
+	}
+
+	failed := 0
+	fmt.Println()
+	fmt.Println("Summary:")
+	for _, r := range results {
+		mark := "✓"
+		if r.ExitCode != 0 {
+			mark = "✗"
+			failed++
+		}
+		fmt.Printf("  %s %-*s  exit %d\n", mark, width, r.Name, r.ExitCode)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("command failed in %d of %d worktree(s)", failed, len(results))
+	}
+	return nil
+}
+
+// prefixWriter prefixes every line written to it with "[name] "
+type prefixWriter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	prefix []byte
+	buf    bytes.Buffer
+}
+
+func newPrefixWriter(out io.Writer, name string) *prefixWriter {
+	return &prefixWriter{out: out, prefix: []byte("[" + name + "] ")}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		idx := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if idx < 0 {
+			break
+		}
+		line := w.buf.Next(idx + 1)
+		if _, err := w.out.Write(append(append([]byte{}, w.prefix...), line...)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes any trailing output that did not end with a newline
+func (w *prefixWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buf.Len() == 0 {
+		return
+	}
+	w.out.Write(append(append(append([]byte{}, w.prefix...), w.buf.Bytes()...), '\n'))
+	w.buf.Reset()
+}
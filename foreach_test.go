@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestForeach(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	for _, name := range []string{"alpha", "beta"} {
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree %s failed: %v", name, err)
+		}
+	}
+	alpha, err := findWorktree(ctx, "alpha")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(alpha.Path, "marker"), nil, 0o644); err != nil {
+		t.Fatalf("Failed to write marker: %v", err)
+	}
+
+	output, err := captureStdout(t, func() error {
+		return Foreach(ctx, "echo hello; test -f marker || exit 3", ForeachOptions{})
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Errorf("expected failure summary for one worktree, got %v", err)
+	}
+	for _, want := range []string{"[alpha] hello", "[beta] hello", "✓ alpha  exit 0", "✗ beta   exit 3"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "(primary)") || strings.Contains(output, "["+filepath.Base(repoPath)+"]") {
+		t.Errorf("primary worktree should be skipped by default:\n%s", output)
+	}
+
+	output, err = captureStdout(t, func() error {
+		return Foreach(ctx, `echo "$WTM_NAME on $WTM_BRANCH"`, ForeachOptions{})
+	})
+	if err != nil {
+		t.Fatalf("Foreach failed: %v", err)
+	}
+	for _, want := range []string{"[alpha] alpha on alpha", "[beta] beta on beta"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected the WTM_* variables in the environment, missing %q in:\n%s", want, output)
+		}
+	}
+}
+
+func TestForeachParallelJSONReport(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	names := []string{"p1", "p2", "p3"}
+	for _, name := range names {
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree %s failed: %v", name, err)
+		}
+	}
+
+	output, err := captureStdout(t, func() error {
+		return Foreach(ctx, "basename \"$PWD\"", ForeachOptions{Parallel: 3, JSON: true})
+	})
+	if err != nil {
+		t.Fatalf("Foreach failed: %v", err)
+	}
+
+	var report foreachReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("expected JSON report, got %q: %v", output, err)
+	}
+	if report.Failed != 0 || len(report.Results) != len(names) {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	for i, r := range report.Results {
+		if r.Name != names[i] {
+			t.Errorf("expected results in worktree order, got %s at %d", r.Name, i)
+		}
+		if strings.TrimSpace(r.Output) != names[i] {
+			t.Errorf("expected output %q for %s, got %q", names[i], r.Name, r.Output)
+		}
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := newPrefixWriter(&out, "api")
+	w.Write([]byte("one\ntw"))
+	w.Write([]byte("o\nthree"))
+	w.Flush()
+
+	want := "[api] one\n[api] two\n[api] three\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\nwant: %q\ngot:  %q", want, out.String())
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// forgeType identifies the hosting service behind the origin remote
+type forgeType string
+
+const (
+	forgeGitHub forgeType = "github"
+	forgeGitLab forgeType = "gitlab"
+)
+
+// detectForge returns the configured remoteType, or guesses it from the origin URL (defaulting to GitHub)
+func detectForge(ctx context.Context) (forgeType, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	switch configured := forgeType(strings.ToLower(strings.TrimSpace(cfg.RemoteType))); configured {
+	case forgeGitHub, forgeGitLab:
+		return configured, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown remoteType '%s': expected github or gitlab", cfg.RemoteType)
+	}
+
+	repo, err := originRepo(ctx)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(strings.ToLower(repo.Host), "gitlab") {
+		return forgeGitLab, nil
+	}
+	return forgeGitHub, nil
+}
+
+// reviewRef returns the remote ref holding the head of a pull or merge request
+func (f forgeType) reviewRef(number int) string {
+	if f == forgeGitLab {
+		return fmt.Sprintf("refs/merge-requests/%d/head", number)
+	}
+	return fmt.Sprintf("refs/pull/%d/head", number)
+}
+
+// reviewBranch returns the default local branch name for a pull or merge request
+func (f forgeType) reviewBranch(number int) string {
+	if f == forgeGitLab {
+		return fmt.Sprintf("mr-%d", number)
+	}
+	return fmt.Sprintf("pr-%d", number)
+}
+
+// fetchReview fetches a pull or merge request from origin into a local branch and returns the branch name
+func fetchReview(ctx context.Context, number int, branch string) (string, error) {
+	forge, err := detectForge(ctx)
+	if err != nil {
+		return "", err
+	}
+	if branch == "" {
+		branch = forge.reviewBranch(number)
+	}
+	if refExists(ctx, "refs/heads/"+branch) {
+		return "", fmt.Errorf("branch '%s' already exists", branch)
+	}
+
+	refspec := fmt.Sprintf("%s:refs/heads/%s", forge.reviewRef(number), branch)
+	if _, err := runGitMutation(ctx, "fetch", "origin", refspec); err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", forge.reviewRef(number), err)
+	}
+	return branch, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+func TestDetectForgeFromOrigin(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	t.Setenv("WTM_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.toml"))
+	resetConfigCache()
+	defer resetConfigCache()
+
+	cases := []struct {
+		url  string
+		want forgeType
+	}{
+		{"git@gitlab.example.com:group/project.git", forgeGitLab},
+		{"https://github.com/choplin/wtm.git", forgeGitHub},
+	}
+	for _, tc := range cases {
+		if out, err := exec.Command("git", "remote", "add", "origin", tc.url).CombinedOutput(); err != nil {
+			t.Fatalf("git remote add failed: %v: %s", err, out)
+		}
+		got, err := detectForge(ctx)
+		if err != nil {
+			t.Fatalf("detectForge failed: %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("detectForge for %s = %s, want %s", tc.url, got, tc.want)
+		}
+		if out, err := exec.Command("git", "remote", "remove", "origin").CombinedOutput(); err != nil {
+			t.Fatalf("git remote remove failed: %v: %s", err, out)
+		}
+	}
+}
+
+func TestAddWorktreeFromMergeRequest(t *testing.T) {
+	ctx := t.Context()
+
+	upstream := setupTestRepo(t)
+	defer cleanupTestRepo(t, upstream)
+	if out, err := exec.Command("git", "-C", upstream, "update-ref", "refs/merge-requests/7/head", "HEAD").CombinedOutput(); err != nil {
+		t.Fatalf("git update-ref failed: %v: %s", err, out)
+	}
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+	if out, err := exec.Command("git", "remote", "add", "origin", upstream).CombinedOutput(); err != nil {
+		t.Fatalf("git remote add failed: %v: %s", err, out)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("remoteType = \"gitlab\"\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	if err := AddWorktree(ctx, "review-7", AddOptions{Review: 7}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		t.Fatalf("getWorktrees failed: %v", err)
+	}
+	found := false
+	for _, wt := range worktrees {
+		if wt.Name == "review-7" {
+			found = true
+			if wt.Branch != "mr-7" {
+				t.Errorf("Expected branch 'mr-7', got '%s'", wt.Branch)
+			}
+		}
+	}
+	if !found {
+		t.Error("Worktree 'review-7' was not created")
+	}
+
+	// A failed add deletes the branch it fetched
+	if err := AddWorktree(ctx, "review-7", AddOptions{Review: 7, Branch: "mr-7-again"}); !errors.Is(err, wtm.ErrWorktreeExists) {
+		t.Fatalf("expected ErrWorktreeExists, got %v", err)
+	}
+	if refExists(ctx, "refs/heads/mr-7-again") {
+		t.Error("expected the fetched branch to be deleted after the add failed")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+// findWorktreeFuzzy resolves a query to a single worktree. An exact name wins; otherwise the query
+// must be a substring of exactly one worktree's name or branch, or failing that, match exactly one of
+// them as a subsequence of characters ("apirf" matches "api-refactor"). Matching ignores case.
+func findWorktreeFuzzy(ctx context.Context, query string) (*Worktree, error) {
+	if query == previousWorktreeName {
+		return previousWorktree(ctx)
+	}
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range worktrees {
+		if worktrees[i].Name == query {
+			return &worktrees[i], nil
+		}
+	}
+
+	q := strings.ToLower(query)
+	for _, match := range []func(s, sub string) bool{strings.Contains, isSubsequence} {
+		var candidates []Worktree
+		for _, wt := range worktrees {
+			if wt.Bare {
+				continue
+			}
+			if match(strings.ToLower(wt.Name), q) || match(strings.ToLower(wt.Branch), q) {
+				candidates = append(candidates, wt)
+			}
+		}
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			return &candidates[0], nil
+		default:
+			return nil, ambiguousMatchError(query, candidates)
+		}
+	}
+	return nil, &wtm.WorktreeError{Name: query, Err: wtm.ErrWorktreeNotFound, Suggestions: wtm.Suggest(query, worktrees)}
+}
+
+// isSubsequence reports whether the characters of sub appear in s in order, like strings.Contains
+// without requiring them to be adjacent
+func isSubsequence(s, sub string) bool {
+	for _, r := range sub {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+utf8.RuneLen(r):]
+	}
+	return true
+}
+
+// ambiguousMatchError lists the worktrees a query matched, one per line with its branch
+func ambiguousMatchError(query string, candidates []Worktree) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "'%s' matches %d worktrees:", query, len(candidates))
+	for _, wt := range candidates {
+		fmt.Fprintf(&b, "\n  %s (%s)", wt.Name, formatBranch(wt))
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+// resolveFuzzyName returns the name of the worktree a --fuzzy argument resolves to
+func resolveFuzzyName(ctx context.Context, query string) (string, error) {
+	wt, err := findWorktreeFuzzy(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	return wt.Name, nil
+}
+
+// Jump prints the path of the worktree a query resolves to, for cd "$(wtm jump api)"
+func Jump(ctx context.Context, query string) error {
+	wt, err := findWorktreeFuzzy(ctx, query)
+	if err != nil {
+		return err
+	}
+	markUsed(ctx, wt)
+	fmt.Println(wt.Path)
+	return nil
+}
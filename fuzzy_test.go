@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+func TestFindWorktreeFuzzy(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	for _, name := range []string{"api", "api-refactor", "web-login"} {
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree %s failed: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"api", "api"},
+		{"REFAC", "api-refactor"},
+		{"login", "web-login"},
+		{"apirf", "api-refactor"},
+		{"wlgn", "web-login"},
+	}
+	for _, tt := range tests {
+		wt, err := findWorktreeFuzzy(ctx, tt.query)
+		if err != nil {
+			t.Errorf("findWorktreeFuzzy(%q) failed: %v", tt.query, err)
+			continue
+		}
+		if wt.Name != tt.want {
+			t.Errorf("findWorktreeFuzzy(%q) = %s, want %s", tt.query, wt.Name, tt.want)
+		}
+	}
+
+	_, err = findWorktreeFuzzy(ctx, "ap")
+	if err == nil || !strings.Contains(err.Error(), "api-refactor") || !strings.Contains(err.Error(), "  api (api)") {
+		t.Errorf("expected an ambiguous match error listing the candidates, got %v", err)
+	}
+
+	_, err = findWorktreeFuzzy(ctx, "xyz")
+	if !errors.Is(err, wtm.ErrWorktreeNotFound) || exitCode(err) != exitNotFound {
+		t.Errorf("expected a not found error with exit code %d, got %v", exitNotFound, err)
+	}
+
+	wt, err := findWorktree(ctx, "web-login")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	output, err := captureStdout(t, func() error {
+		return Jump(ctx, "web")
+	})
+	if err != nil {
+		t.Fatalf("Jump failed: %v", err)
+	}
+	if output != wt.Path+"\n" {
+		t.Errorf("Jump printed %q, want %q", output, wt.Path+"\n")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build gogit
+
+package main
+
+import "github.com/choplin/wtm/pkg/wtm"
+
+func init() {
+	gitBackend = wtm.GoGitRunner{}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// remoteRepo identifies a hosted repository parsed from a git remote URL
+type remoteRepo struct {
+	Host  string
+	Owner string
+	Name  string
+}
+
+// issueInfo summarizes a GitHub issue
+type issueInfo struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+// defaultIssueTemplate names worktrees started with `wtm add --issue`
+const defaultIssueTemplate = "{{.Number}}-{{.Slug}}"
+
+// maxIssueSlugLength keeps names derived from long issue titles manageable
+const maxIssueSlugLength = 40
+
+// pullRequestInfo summarizes a GitHub pull request for a branch
+type pullRequestInfo struct {
+	Number int    `json:"number"`
+	State  string `json:"state"` // OPEN, CLOSED or MERGED
+	URL    string `json:"url"`
+}
+
+const githubAPIURL = "https://api.github.com"
+
+// githubAPIBase returns the REST API root for a repository hosted on host: api.github.com for
+// github.com and https://<host>/api/v3 for GitHub Enterprise Server. GH_HOST, as with the gh CLI,
+// overrides the host, e.g. when the remote uses an ssh alias.
+func githubAPIBase(host string) string {
+	if env := strings.TrimSpace(os.Getenv("GH_HOST")); env != "" {
+		host = env
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://"), "/")
+	if host == "" || strings.EqualFold(host, "github.com") || strings.EqualFold(host, "ssh.github.com") {
+		return githubAPIURL
+	}
+	return "https://" + host + "/api/v3"
+}
+
+// parseRemoteURL extracts host, owner and repository name from ssh, scp-like or https remote URLs
+func parseRemoteURL(raw string) (remoteRepo, error) {
+	raw = strings.TrimSpace(raw)
+	var host, repoPath string
+
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return remoteRepo{}, err
+		}
+		host = u.Hostname()
+		repoPath = u.Path
+	} else if at := strings.Index(raw, "@"); at >= 0 && strings.Contains(raw[at:], ":") {
+		// scp-like syntax: git@github.com:owner/repo.git
+		rest := raw[at+1:]
+		colon := strings.Index(rest, ":")
+		host = rest[:colon]
+		repoPath = rest[colon+1:]
+	} else {
+		return remoteRepo{}, fmt.Errorf("unsupported remote URL: %s", raw)
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	idx := strings.LastIndex(repoPath, "/")
+	if host == "" || idx <= 0 {
+		return remoteRepo{}, fmt.Errorf("unsupported remote URL: %s", raw)
+	}
+	return remoteRepo{Host: host, Owner: repoPath[:idx], Name: repoPath[idx+1:]}, nil
+}
+
+// originRepo parses the URL of the origin remote
+func originRepo(ctx context.Context) (remoteRepo, error) {
+	out, err := runGitCommand(ctx, "remote", "get-url", "origin")
+	if err != nil {
+		return remoteRepo{}, fmt.Errorf("failed to read origin remote: %w", err)
+	}
+	return parseRemoteURL(out)
+}
+
+// findPullRequest returns the most recent pull request whose head is branch, or nil if none exists.
+// It uses the gh CLI when available and falls back to the REST API with GITHUB_TOKEN or GH_TOKEN.
+func findPullRequest(ctx context.Context, branch string) (*pullRequestInfo, error) {
+	if _, err := exec.LookPath("gh"); err == nil {
+		return findPullRequestWithGH(ctx, branch)
+	}
+	token := githubToken()
+	if token == "" {
+		return nil, errors.New("GitHub lookup requires the gh CLI or a GITHUB_TOKEN/GH_TOKEN environment variable")
+	}
+	repo, err := originRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return findPullRequestWithAPI(repo, branch, token)
+}
+
+func githubToken() string {
+	if token := strings.TrimSpace(os.Getenv("GITHUB_TOKEN")); token != "" {
+		return token
+	}
+	return strings.TrimSpace(os.Getenv("GH_TOKEN"))
+}
+
+func findPullRequestWithGH(ctx context.Context, branch string) (*pullRequestInfo, error) {
+	cmd := repoCommand(ctx, "gh", "pr", "list", "--head", branch, "--state", "all", "--limit", "1", "--json", "number,state,url")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("gh pr list failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+
+	var prs []pullRequestInfo
+	if err := json.Unmarshal(output, &prs); err != nil {
+		return nil, fmt.Errorf("failed to parse gh output: %w", err)
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return &prs[0], nil
+}
+
+func findPullRequestWithAPI(repo remoteRepo, branch, token string) (*pullRequestInfo, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/pulls?state=all&per_page=1&head=%s",
+		githubAPIBase(repo.Host), repo.Owner, repo.Name, url.QueryEscape(repo.Owner+":"+branch))
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API returned %s", resp.Status)
+	}
+
+	var prs []struct {
+		Number   int     `json:"number"`
+		State    string  `json:"state"`
+		MergedAt *string `json:"merged_at"`
+		HTMLURL  string  `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&prs); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub API response: %w", err)
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+
+	pr := prs[0]
+	state := strings.ToUpper(pr.State)
+	if pr.MergedAt != nil {
+		state = "MERGED"
+	}
+	return &pullRequestInfo{Number: pr.Number, State: state, URL: pr.HTMLURL}, nil
+}
+
+// findIssue fetches a GitHub issue of the origin repository by number, using the gh CLI when
+// available and the REST API with GITHUB_TOKEN or GH_TOKEN otherwise
+func findIssue(ctx context.Context, number int) (*issueInfo, error) {
+	if _, err := exec.LookPath("gh"); err == nil {
+		return findIssueWithGH(ctx, number)
+	}
+	token := githubToken()
+	if token == "" {
+		return nil, errors.New("GitHub lookup requires the gh CLI or a GITHUB_TOKEN/GH_TOKEN environment variable")
+	}
+	repo, err := originRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return findIssueWithAPI(ctx, repo, number, token)
+}
+
+func findIssueWithGH(ctx context.Context, number int) (*issueInfo, error) {
+	cmd := repoCommand(ctx, "gh", "issue", "view", strconv.Itoa(number), "--json", "number,title,url")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("gh issue view failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+
+	var issue issueInfo
+	if err := json.Unmarshal(output, &issue); err != nil {
+		return nil, fmt.Errorf("failed to parse gh output: %w", err)
+	}
+	return &issue, nil
+}
+
+func findIssueWithAPI(ctx context.Context, repo remoteRepo, number int, token string) (*issueInfo, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/issues/%d", githubAPIBase(repo.Host), repo.Owner, repo.Name, number)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API returned %s for issue #%d", resp.Status, number)
+	}
+
+	var issue struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&issue); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub API response: %w", err)
+	}
+	return &issueInfo{Number: issue.Number, Title: issue.Title, URL: issue.HTMLURL}, nil
+}
+
+// issueSlug lower-cases a title and joins its letters and digits with dashes
+func issueSlug(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	slug := []rune(strings.Join(words, "-"))
+	if len(slug) > maxIssueSlugLength {
+		slug = slug[:maxIssueSlugLength]
+	}
+	return strings.TrimRight(string(slug), "-")
+}
+
+// renderIssueName renders names.issueTemplate for an issue
+func renderIssueName(cfg NamesConfig, issue *issueInfo) (string, error) {
+	text := cfg.IssueTemplate
+	if text == "" {
+		text = defaultIssueTemplate
+	}
+	data := struct {
+		Number int
+		Title  string
+		Slug   string
+	}{issue.Number, issue.Title, issueSlug(issue.Title)}
+	name, err := renderTemplate("issueTemplate", text, data)
+	if err != nil {
+		return "", fmt.Errorf("invalid names.issueTemplate: %w", err)
+	}
+	return name, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseRemoteURL(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want remoteRepo
+	}{
+		{"git@github.com:choplin/wtm.git", remoteRepo{Host: "github.com", Owner: "choplin", Name: "wtm"}},
+		{"https://github.com/choplin/wtm.git", remoteRepo{Host: "github.com", Owner: "choplin", Name: "wtm"}},
+		{"https://github.com/choplin/wtm", remoteRepo{Host: "github.com", Owner: "choplin", Name: "wtm"}},
+		{"ssh://git@gitlab.example.com:2222/group/sub/project.git", remoteRepo{Host: "gitlab.example.com", Owner: "group/sub", Name: "project"}},
+	}
+
+	for _, tc := range cases {
+		got, err := parseRemoteURL(tc.raw)
+		if err != nil {
+			t.Errorf("parseRemoteURL(%q) failed: %v", tc.raw, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("parseRemoteURL(%q) = %+v, want %+v", tc.raw, got, tc.want)
+		}
+	}
+
+	if _, err := parseRemoteURL("/local/path/repo"); err == nil {
+		t.Error("Expected error for local path remote, got nil")
+	}
+}
+
+func TestGitHubAPIBase(t *testing.T) {
+	t.Setenv("GH_HOST", "")
+	cases := map[string]string{
+		"github.com":             "https://api.github.com",
+		"GitHub.com":             "https://api.github.com",
+		"github.example.com":     "https://github.example.com/api/v3",
+		"git.corp.internal:8443": "https://git.corp.internal:8443/api/v3",
+	}
+	for host, want := range cases {
+		if got := githubAPIBase(host); got != want {
+			t.Errorf("githubAPIBase(%q) = %q, want %q", host, got, want)
+		}
+	}
+
+	t.Setenv("GH_HOST", "github.example.com")
+	if got := githubAPIBase("work-alias"); got != "https://github.example.com/api/v3" {
+		t.Errorf("expected GH_HOST to override the remote host, got %q", got)
+	}
+}
+
+func TestIssueName(t *testing.T) {
+	issue := &issueInfo{Number: 42, Title: "Fix: login fails for `admin` users!", URL: "https://github.com/choplin/wtm/issues/42"}
+	if got, want := issueSlug(issue.Title), "fix-login-fails-for-admin-users"; got != want {
+		t.Errorf("issueSlug = %q, want %q", got, want)
+	}
+	if got := issueSlug("a very long issue title that keeps going and going and going"); len(got) > maxIssueSlugLength || got[len(got)-1] == '-' {
+		t.Errorf("issueSlug did not truncate cleanly: %q", got)
+	}
+
+	name, err := renderIssueName(NamesConfig{}, issue)
+	if err != nil || name != "42-fix-login-fails-for-admin-users" {
+		t.Errorf("renderIssueName = %q, %v", name, err)
+	}
+	name, err = renderIssueName(NamesConfig{IssueTemplate: "issue-{{.Number}}"}, issue)
+	if err != nil || name != "issue-42" {
+		t.Errorf("renderIssueName with template = %q, %v", name, err)
+	}
+}
+
+func TestIssueMetadataIsShown(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "42-fix", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	url := "https://github.com/choplin/wtm/issues/42"
+	if err := setWorktreeMeta(ctx, "42-fix", metaIssue, url); err != nil {
+		t.Fatalf("setWorktreeMeta failed: %v", err)
+	}
+
+	output, err := captureStdout(t, func() error {
+		return ShowWorktree(ctx, "42-fix", ShowOptions{Format: "pretty"})
+	})
+	if err != nil {
+		t.Fatalf("ShowWorktree failed: %v", err)
+	}
+	if !strings.Contains(output, "Issue:    "+url) {
+		t.Errorf("expected issue URL in show output, got %q", output)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// groupByKeys are the values wtm list --group-by accepts
+var groupByKeys = []string{"prefix", "label", "base"}
+
+// worktreeGroup is a heading in a grouped listing and the worktrees under it, in list order
+type worktreeGroup struct {
+	Key       string
+	Worktrees []Worktree
+}
+
+// normalizeLabels trims labels given to wtm add --label and drops empty and repeated ones
+func normalizeLabels(labels []string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" || seen[label] {
+			continue
+		}
+		if strings.ContainsAny(label, ",\n") {
+			return nil, fmt.Errorf("invalid label %q: labels cannot contain commas or newlines", label)
+		}
+		seen[label] = true
+		out = append(out, label)
+	}
+	return out, nil
+}
+
+// groupKeys returns the groups a worktree belongs to; with label it is listed once per label
+func groupKeys(wt Worktree, by string) []string {
+	switch by {
+	case "prefix":
+		if i := strings.LastIndex(wt.Branch, "/"); i > 0 {
+			return []string{wt.Branch[:i] + "/*"}
+		}
+		return []string{"(no prefix)"}
+	case "label":
+		if len(wt.Labels) == 0 {
+			return []string{"(no label)"}
+		}
+		return wt.Labels
+	case "base":
+		if wt.Base == "" {
+			return []string{"(no base)"}
+		}
+		return []string{wt.Base}
+	}
+	return nil
+}
+
+// groupWorktrees sorts worktrees into groups ordered by key, with the catch-all "(no ...)" group last
+func groupWorktrees(worktrees []Worktree, by string) ([]worktreeGroup, error) {
+	if !slices.Contains(groupByKeys, by) {
+		return nil, fmt.Errorf("unknown --group-by value %q (valid: %s)", by, strings.Join(groupByKeys, ", "))
+	}
+	index := map[string]int{}
+	var groups []worktreeGroup
+	for _, wt := range worktrees {
+		if wt.Bare {
+			continue
+		}
+		for _, key := range groupKeys(wt, by) {
+			i, ok := index[key]
+			if !ok {
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, worktreeGroup{Key: key})
+			}
+			groups[i].Worktrees = append(groups[i].Worktrees, wt)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		iNone, jNone := strings.HasPrefix(groups[i].Key, "(no "), strings.HasPrefix(groups[j].Key, "(no ")
+		if iNone != jNone {
+			return jNone
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups, nil
+}
+
+// printGroupedTable prints the table format with a heading line before each group's rows.
+// Columns are aligned across all groups so the listing reads as one table.
+func printGroupedTable(groups []worktreeGroup, primaryPath string) {
+	if len(groups) == 0 {
+		return
+	}
+	headers := []string{"NAME", "BRANCH", "CREATED"}
+	var rows [][]string
+	for _, g := range groups {
+		for _, wt := range g.Worktrees {
+			rows = append(rows, []string{
+				"  " + formatWorktreeName(wt, primaryPath),
+				formatBranch(wt),
+				formatTime(wt.Created),
+			})
+		}
+	}
+	widths := columnWidths(headers, rows)
+
+	printTableRow(headers, widths)
+	row := 0
+	for _, g := range groups {
+		fmt.Println(g.Key)
+		for range g.Worktrees {
+			printTableRow(rows[row], widths)
+			row++
+		}
+	}
+}
+
+// printTree prints each group as a node with its worktrees as branches below it
+func printTree(groups []worktreeGroup, primaryPath string) {
+	width := 0
+	for _, g := range groups {
+		for _, wt := range g.Worktrees {
+			width = max(width, utf8.RuneCountInString(formatWorktreeName(wt, primaryPath)))
+		}
+	}
+	for _, g := range groups {
+		fmt.Println(g.Key)
+		for i, wt := range g.Worktrees {
+			connector := "├── "
+			if i == len(g.Worktrees)-1 {
+				connector = "└── "
+			}
+			fmt.Printf("%s%-*s  %s\n", connector, width, formatWorktreeName(wt, primaryPath), formatBranch(wt))
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGroupWorktrees(t *testing.T) {
+	worktrees := []Worktree{
+		{Name: "main", Branch: "main", Path: "/repo"},
+		{Name: "login", Branch: "feature/login", Base: "main", Labels: []string{"auth", "ui"}},
+		{Name: "crash", Branch: "fix/crash", Base: "release"},
+		{Name: "signup", Branch: "feature/signup", Base: "main", Labels: []string{"ui"}},
+	}
+
+	keys := func(groups []worktreeGroup) []string {
+		var out []string
+		for _, g := range groups {
+			names := make([]string, len(g.Worktrees))
+			for i, wt := range g.Worktrees {
+				names[i] = wt.Name
+			}
+			out = append(out, g.Key+"="+strings.Join(names, ","))
+		}
+		return out
+	}
+
+	tests := []struct {
+		by   string
+		want []string
+	}{
+		{"prefix", []string{"feature/*=login,signup", "fix/*=crash", "(no prefix)=main"}},
+		{"label", []string{"auth=login", "ui=login,signup", "(no label)=main,crash"}},
+		{"base", []string{"main=login,signup", "release=crash", "(no base)=main"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			groups, err := groupWorktrees(worktrees, tt.by)
+			if err != nil {
+				t.Fatalf("groupWorktrees failed: %v", err)
+			}
+			if got := keys(groups); !slices.Equal(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := groupWorktrees(worktrees, "owner"); err == nil {
+		t.Error("expected an error for an unknown --group-by value")
+	}
+}
+
+func TestPrintTree(t *testing.T) {
+	groups, err := groupWorktrees([]Worktree{
+		{Name: "login", Branch: "feature/login"},
+		{Name: "signup-flow", Branch: "feature/signup"},
+		{Name: "crash", Branch: "fix/crash"},
+	}, "prefix")
+	if err != nil {
+		t.Fatalf("groupWorktrees failed: %v", err)
+	}
+
+	output, err := captureStdout(t, func() error {
+		printTree(groups, "")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("printTree failed: %v", err)
+	}
+	want := "feature/*\n" +
+		"├── login        feature/login\n" +
+		"└── signup-flow  feature/signup\n" +
+		"fix/*\n" +
+		"└── crash        fix/crash\n"
+	if output != want {
+		t.Errorf("unexpected tree:\n%s\nwant:\n%s", output, want)
+	}
+}
+
+func TestListWorktreesGroupByLabel(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return AddWorktree(ctx, "api", AddOptions{Labels: []string{" backend ", "backend", ""}})
+	}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	if _, err := captureStdout(t, func() error {
+		return AddWorktree(ctx, "web", AddOptions{})
+	}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	wt, err := findWorktree(ctx, "api")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if !slices.Equal(wt.Labels, []string{"backend"}) {
+		t.Errorf("expected labels [backend], got %q", wt.Labels)
+	}
+
+	output, err := captureStdout(t, func() error {
+		return ListWorktrees(ctx, ListOptions{Format: "table", GroupBy: "label"})
+	})
+	if err != nil {
+		t.Fatalf("ListWorktrees failed: %v", err)
+	}
+	backend := strings.Index(output, "\nbackend\n")
+	noLabel := strings.Index(output, "\n(no label)\n")
+	if backend == -1 || noLabel == -1 || backend > noLabel {
+		t.Errorf("expected a backend group followed by (no label), got:\n%s", output)
+	}
+	if !strings.Contains(output[backend:noLabel], "  api") {
+		t.Errorf("expected api under the backend group, got:\n%s", output)
+	}
+
+	if err := ListWorktrees(ctx, ListOptions{Format: "json", Tree: true}); err == nil {
+		t.Error("expected --tree to be rejected with --format json")
+	}
+	if err := AddWorktree(ctx, "bad", AddOptions{Labels: []string{"a,b"}}); err == nil {
+		t.Error("expected a label with a comma to be rejected")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+)
+
+// HideWorktree leaves a worktree out of wtm list until it is unhidden, or shows it again when hide is false
+func HideWorktree(ctx context.Context, name string, hide bool) error {
+	target, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+	primary, err := getRepoRoot(ctx)
+	if err != nil {
+		return err
+	}
+	if normalizePath(target.Path) == normalizePath(primary) {
+		return fmt.Errorf("the primary worktree cannot be hidden")
+	}
+
+	if !hide {
+		if err := unsetWorktreeMeta(ctx, target.Name, metaHidden); err != nil {
+			return err
+		}
+		report("✓ Unhid worktree: %s\n", target.Name)
+		return nil
+	}
+	if err := setWorktreeMeta(ctx, target.Name, metaHidden, "true"); err != nil {
+		return err
+	}
+	report("✓ Hid worktree: %s (wtm list --all shows it)\n", target.Name)
+	return nil
+}
+
+// visibleWorktrees marks worktrees matching an ignore pattern from the config as hidden and, unless all
+// is set, leaves out every hidden worktree
+func visibleWorktrees(ctx context.Context, worktrees []Worktree, all bool) ([]Worktree, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var visible []Worktree
+	for _, wt := range worktrees {
+		for _, pattern := range cfg.Ignore {
+			if ok, _ := path.Match(pattern, wt.Name); ok {
+				wt.Hidden = true
+				break
+			}
+		}
+		if all || !wt.Hidden {
+			visible = append(visible, wt)
+		}
+	}
+	return visible, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHideWorktree(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	for _, name := range []string{"nightly", "feature"} {
+		if _, err := captureStdout(t, func() error {
+			return AddWorktree(ctx, name, AddOptions{})
+		}); err != nil {
+			t.Fatalf("AddWorktree %s failed: %v", name, err)
+		}
+	}
+
+	if err := HideWorktree(ctx, ".", true); err == nil {
+		t.Error("expected hiding the primary worktree to fail")
+	}
+	if _, err := captureStdout(t, func() error {
+		return HideWorktree(ctx, "nightly", true)
+	}); err != nil {
+		t.Fatalf("HideWorktree failed: %v", err)
+	}
+
+	list := func(all bool) string {
+		t.Helper()
+		output, err := captureStdout(t, func() error {
+			return ListWorktrees(ctx, ListOptions{Format: "names", All: all})
+		})
+		if err != nil {
+			t.Fatalf("ListWorktrees failed: %v", err)
+		}
+		return output
+	}
+	if output := list(false); strings.Contains(output, "nightly") || !strings.Contains(output, "feature") {
+		t.Errorf("expected only nightly to be hidden, got:\n%s", output)
+	}
+	if output := list(true); !strings.Contains(output, "nightly") {
+		t.Errorf("expected --all to show nightly, got:\n%s", output)
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return HideWorktree(ctx, "nightly", false)
+	}); err != nil {
+		t.Fatalf("UnhideWorktree failed: %v", err)
+	}
+	if output := list(false); !strings.Contains(output, "nightly") {
+		t.Errorf("expected nightly to be listed after unhide, got:\n%s", output)
+	}
+}
+
+func TestVisibleWorktreesIgnore(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+	if err := os.WriteFile(".wtm.toml", []byte("ignore = [\"legacy-*\"]\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	resetConfigCache()
+	defer resetConfigCache()
+
+	worktrees := []Worktree{{Name: "legacy-v1"}, {Name: "feature"}, {Name: "old", Hidden: true}}
+	visible, err := visibleWorktrees(ctx, worktrees, false)
+	if err != nil {
+		t.Fatalf("visibleWorktrees failed: %v", err)
+	}
+	if len(visible) != 1 || visible[0].Name != "feature" {
+		t.Errorf("expected only feature to be visible, got %+v", visible)
+	}
+
+	all, err := visibleWorktrees(ctx, worktrees, true)
+	if err != nil {
+		t.Fatalf("visibleWorktrees failed: %v", err)
+	}
+	if len(all) != 3 || !all[0].Hidden {
+		t.Errorf("expected --all to keep every worktree and mark legacy-v1 hidden, got %+v", all)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// installHooks applies the [hooks] config to a new worktree. Without copy, core.hooksPath is set in the
+// shared config so every worktree runs the hooks from its own checkout of the hooks directory. With copy,
+// the directory is copied from the repository root into the worktree's git directory and core.hooksPath
+// is set in the worktree-specific config, so the hooks apply even when the branch does not track them.
+func installHooks(ctx context.Context, wt *Worktree) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	hooksPath := strings.TrimSpace(cfg.Hooks.Path)
+	if hooksPath == "" {
+		return nil
+	}
+
+	if !cfg.Hooks.Copy {
+		current, _ := runGitCommand(ctx, "config", "--get", "core.hooksPath")
+		if strings.TrimSpace(current) == hooksPath {
+			return nil
+		}
+		_, err := runGitMutation(ctx, "config", "core.hooksPath", hooksPath)
+		return err
+	}
+
+	repoRoot, err := getRepoRoot(ctx)
+	if err != nil {
+		return err
+	}
+	src := hooksPath
+	if !filepath.IsAbs(src) {
+		src = filepath.Join(repoRoot, src)
+	}
+	if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		return fmt.Errorf("hooks directory not found: %s", src)
+	}
+
+	gitDir, err := worktreeGitDir(ctx, wt)
+	if err != nil {
+		return err
+	}
+	dst := filepath.Join(gitDir, "hooks")
+	if !planFileOp("cp -R %s %s", src, dst) {
+		if err := copyDir(src, dst); err != nil {
+			return err
+		}
+	}
+	if err := enableWorktreeConfig(ctx); err != nil {
+		return err
+	}
+	_, err = runGitMutation(ctx, "-C", wt.Path, "config", "--worktree", "core.hooksPath", dst)
+	return err
+}
+
+// enableWorktreeConfig sets extensions.worktreeConfig. Like git sparse-checkout, it first moves core.bare
+// (when true) and core.worktree from the shared config into the main worktree's config.worktree: once the
+// extension is on, linked worktrees read them from the shared config, and a bare clone's core.bare = true
+// would make every one of its worktrees bare. Repeating the move repairs repositories where the extension
+// was enabled without it.
+func enableWorktreeConfig(ctx context.Context) error {
+	commonDir, err := gitCommonDir(ctx)
+	if err != nil {
+		return err
+	}
+	sharedConfig := filepath.Join(commonDir, "config")
+	mainConfig := filepath.Join(commonDir, "config.worktree")
+	if bare, _ := runGitCommand(ctx, "config", "--file", sharedConfig, "--bool", "--get", "core.bare"); strings.TrimSpace(bare) == "true" {
+		if err := moveConfigValue(ctx, "core.bare", "true", sharedConfig, mainConfig); err != nil {
+			return err
+		}
+	}
+	if worktree, err := runGitCommand(ctx, "config", "--file", sharedConfig, "--get", "core.worktree"); err == nil {
+		if err := moveConfigValue(ctx, "core.worktree", strings.TrimSpace(worktree), sharedConfig, mainConfig); err != nil {
+			return err
+		}
+	}
+	_, err = runGitMutation(ctx, "config", "--file", sharedConfig, "extensions.worktreeConfig", "true")
+	return err
+}
+
+// moveConfigValue sets key in the config file to and removes it from the config file from
+func moveConfigValue(ctx context.Context, key, value, from, to string) error {
+	if _, err := runGitMutation(ctx, "config", "--file", to, key, value); err != nil {
+		return err
+	}
+	_, err := runGitMutation(ctx, "config", "--file", from, "--unset", key)
+	return err
+}
+
+// worktreeGitDir returns the administrative directory git keeps for a linked worktree
+func worktreeGitDir(ctx context.Context, wt *Worktree) (string, error) {
+	if planning() {
+		// The worktree does not exist yet; git names its directory after the worktree directory
+		commonDir, err := gitCommonDir(ctx)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(commonDir, "worktrees", filepath.Base(wt.Path)), nil
+	}
+	out, err := runGitCommand(ctx, "-C", wt.Path, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// copyDir copies a directory tree, keeping file modes so hook scripts stay executable
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
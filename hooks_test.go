@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddWorktreeInstallsHooks(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	hooksDir := filepath.Join(repoPath, ".githooks")
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		t.Fatalf("Failed to create hooks dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(hooksDir, "pre-commit"), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+
+	for _, copyHooks := range []bool{false, true} {
+		configFile := filepath.Join(t.TempDir(), "config.toml")
+		content := "[hooks]\npath = \".githooks\"\n"
+		if copyHooks {
+			content += "copy = true\n"
+		}
+		if err := os.WriteFile(configFile, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		t.Setenv("WTM_CONFIG_FILE", configFile)
+		resetConfigCache()
+
+		name := "hooks-shared"
+		if copyHooks {
+			name = "hooks-copied"
+		}
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+		wt, err := findWorktree(ctx, name)
+		if err != nil {
+			t.Fatalf("findWorktree failed: %v", err)
+		}
+
+		out, err := runGitCommand(ctx, "-C", wt.Path, "config", "--get", "core.hooksPath")
+		if err != nil {
+			t.Fatalf("core.hooksPath is not set: %v", err)
+		}
+		hooksPath := strings.TrimSpace(out)
+		if !copyHooks {
+			if hooksPath != ".githooks" {
+				t.Errorf("expected shared core.hooksPath .githooks, got %q", hooksPath)
+			}
+			continue
+		}
+		info, err := os.Stat(filepath.Join(hooksPath, "pre-commit"))
+		if err != nil {
+			t.Fatalf("expected the hook to be copied to %s: %v", hooksPath, err)
+		}
+		if info.Mode().Perm()&0o100 == 0 {
+			t.Errorf("expected the copied hook to stay executable, got %v", info.Mode())
+		}
+	}
+	resetConfigCache()
+}
+
+func TestCopyHooksInBareClone(t *testing.T) {
+	ctx := t.Context()
+
+	source := setupTestRepo(t)
+	defer cleanupTestRepo(t, source)
+
+	hooksDir := filepath.Join(t.TempDir(), "hooks")
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		t.Fatalf("Failed to create hooks dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(hooksDir, "pre-commit"), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[hooks]\npath = \""+filepath.ToSlash(hooksDir)+"\"\ncopy = true\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+	defer func() { repoDir = "" }()
+
+	dest := filepath.Join(t.TempDir(), "bare")
+	if _, err := captureStdout(t, func() error {
+		return CloneRepository(ctx, source, dest, CloneOptions{Bare: true})
+	}); err != nil {
+		t.Fatalf("CloneRepository failed: %v", err)
+	}
+	if _, err := captureStdout(t, func() error { return AddWorktree(ctx, "feature", AddOptions{}) }); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		t.Fatalf("getWorktrees failed: %v", err)
+	}
+	for _, wt := range worktrees {
+		if wt.Path == "" || wt.Bare {
+			continue
+		}
+		// Enabling per-worktree config must not make the linked worktrees of a bare clone bare
+		out, err := runGitCommand(ctx, "-C", wt.Path, "rev-parse", "--is-bare-repository")
+		if err != nil || strings.TrimSpace(out) != "false" {
+			t.Errorf("expected %s to stay a working tree, got %q, %v", wt.Path, out, err)
+		}
+		if _, err := runGitCommand(ctx, "-C", wt.Path, "status"); err != nil {
+			t.Errorf("git status failed in %s: %v", wt.Path, err)
+		}
+	}
+	bare, err := runGitCommand(ctx, "-C", filepath.Join(dest, ".bare"), "rev-parse", "--is-bare-repository")
+	if err != nil || strings.TrimSpace(bare) != "true" {
+		t.Errorf("expected the repository to stay bare, got %q, %v", bare, err)
+	}
+}
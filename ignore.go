@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const wtmIgnoreFile = ".wtmignore"
+
+// ignorePattern is a single gitignore-style rule read from a .wtmignore file
+type ignorePattern struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreMatcher decides which paths wtm skips when copying, archiving, sizing or summarizing a worktree
+type ignoreMatcher struct {
+	patterns []ignorePattern
+}
+
+// loadIgnoreMatcher reads .wtmignore from the given directory. A missing file yields an empty matcher.
+func loadIgnoreMatcher(dir string) (*ignoreMatcher, error) {
+	f, err := os.Open(filepath.Join(dir, wtmIgnoreFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &ignoreMatcher{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &ignoreMatcher{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m.add(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *ignoreMatcher) add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if strings.HasPrefix(line, "/") {
+		p.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	} else if strings.Contains(line, "/") {
+		// Patterns with an inner slash are relative to the root, as in .gitignore
+		p.anchored = true
+	}
+	if line == "" {
+		return
+	}
+	p.pattern = line
+	m.patterns = append(m.patterns, p)
+}
+
+// Match reports whether relPath (slash or OS separated, relative to the worktree root) is ignored.
+// The last matching pattern wins, so negated patterns can re-include paths.
+func (m *ignoreMatcher) Match(relPath string, isDir bool) bool {
+	if m == nil || len(m.patterns) == 0 {
+		return false
+	}
+	relPath = filepath.ToSlash(filepath.Clean(relPath))
+
+	ignored := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.matches(relPath) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+func (p ignorePattern) matches(relPath string) bool {
+	if p.anchored {
+		ok, _ := path.Match(p.pattern, relPath)
+		return ok
+	}
+	ok, _ := path.Match(p.pattern, path.Base(relPath))
+	return ok
+}
+
+// walkUnignored walks root and calls fn for every entry not excluded by m.
+// Ignored directories are skipped entirely, as is the .git entry of a worktree.
+func walkUnignored(root string, m *ignoreMatcher, fn func(rel string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if rel == ".git" || m.Match(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return fn(rel, d)
+	})
+}
+
+// listUntrackedFiles returns untracked files in a worktree that are neither git-ignored nor wtm-ignored
+func listUntrackedFiles(ctx context.Context, worktreePath string) ([]string, error) {
+	output, err := runGitCommand(ctx, "-C", worktreePath, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := loadIgnoreMatcher(worktreePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, path := range nulFields(output) {
+		if !isIgnoredPath(m, path) {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// isIgnoredPath checks a file path and each of its parent directories against m
+func isIgnoredPath(m *ignoreMatcher, relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.Match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.Match(relPath, false)
+}
+
+// filterIgnoredPaths drops the paths of a worktree that its .wtmignore excludes
+func filterIgnoredPaths(worktreePath string, paths []string) ([]string, error) {
+	m, err := loadIgnoreMatcher(worktreePath)
+	if err != nil {
+		return nil, err
+	}
+	var kept []string
+	for _, p := range paths {
+		if !isIgnoredPath(m, p) {
+			kept = append(kept, p)
+		}
+	}
+	return kept, nil
+}
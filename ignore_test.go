@@ -0,0 +1,164 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	m := &ignoreMatcher{}
+	for _, line := range []string{
+		"# generated output",
+		"node_modules/",
+		"*.log",
+		"!keep.log",
+		"/build",
+		"docs/*.pdf",
+	} {
+		m.add(line)
+	}
+
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"node_modules", true, true},
+		{"web/node_modules", true, true},
+		{"node_modules", false, false},
+		{"debug.log", false, true},
+		{"logs/debug.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"src/build", true, false},
+		{"docs/manual.pdf", false, true},
+		{"manual.pdf", false, false},
+		{"main.go", false, false},
+	}
+
+	for _, tc := range cases {
+		if got := m.Match(tc.path, tc.isDir); got != tc.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+}
+
+func TestWalkUnignored(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		"main.go",
+		"node_modules/pkg/index.js",
+		"logs/app.log",
+		"src/lib.go",
+	}
+	for _, f := range files {
+		p := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir failed: %v", err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, wtmIgnoreFile), []byte("node_modules/\n*.log\n"), 0o644); err != nil {
+		t.Fatalf("write .wtmignore failed: %v", err)
+	}
+
+	m, err := loadIgnoreMatcher(root)
+	if err != nil {
+		t.Fatalf("loadIgnoreMatcher failed: %v", err)
+	}
+
+	var got []string
+	err = walkUnignored(root, m, func(rel string, d fs.DirEntry) error {
+		if !d.IsDir() {
+			got = append(got, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkUnignored failed: %v", err)
+	}
+	sort.Strings(got)
+
+	want := []string{".wtmignore", "main.go", "src/lib.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestListUntrackedFilesRespectsWtmignore(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	for _, f := range []string{"notes.txt", "dist/bundle.js"} {
+		p := filepath.Join(repoPath, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir failed: %v", err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, wtmIgnoreFile), []byte("dist/\n.wtmignore\n"), 0o644); err != nil {
+		t.Fatalf("write .wtmignore failed: %v", err)
+	}
+
+	files, err := listUntrackedFiles(ctx, repoPath)
+	if err != nil {
+		t.Fatalf("listUntrackedFiles failed: %v", err)
+	}
+	if !reflect.DeepEqual(files, []string{"notes.txt"}) {
+		t.Errorf("expected only notes.txt, got %v", files)
+	}
+}
+
+func TestDirtyStateRespectsWtmignore(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	if err := os.WriteFile(filepath.Join(repoPath, wtmIgnoreFile), []byte("dist/\n*.log\n.wtmignore\n"), 0o644); err != nil {
+		t.Fatalf("write .wtmignore failed: %v", err)
+	}
+	// git reports logs/ as a whole; only the files inside match *.log
+	for _, f := range []string{"dist/bundle.js", "logs/run.log"} {
+		p := filepath.Join(repoPath, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir failed: %v", err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	wt := &Worktree{Name: "main", Path: repoPath}
+	changes, err := newManager(ctx).Changes(ctx, wt)
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected ignored files not to count as changes, got %q", changes)
+	}
+	status, err := worktreeStatus(ctx, wt)
+	if err != nil {
+		t.Fatalf("worktreeStatus failed: %v", err)
+	}
+	if status.Dirty || len(status.Changes) != 0 {
+		t.Errorf("expected a clean status, got %+v", status)
+	}
+
+	if err := os.WriteFile(filepath.Join(repoPath, "notes.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if status, err = worktreeStatus(ctx, wt); err != nil || !reflect.DeepEqual(status.Changes, []string{"notes.txt"}) {
+		t.Errorf("expected notes.txt to make the worktree dirty, got %+v, %v", status, err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+// InitOptions groups configuration for adopting an existing repository
+type InitOptions struct {
+	// Migrate moves existing worktrees that live outside the worktree root into it
+	Migrate bool
+}
+
+// InitRepository prepares the current repository for wtm: it creates the worktree root, writes a starter
+// .wtm.toml, registers the repository globally and optionally moves existing worktrees under wtm management
+func InitRepository(ctx context.Context, opts InitOptions) error {
+	repoRoot, err := getRepoRoot(ctx)
+	if err != nil {
+		return wtm.ErrNotARepo
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	worktreeRoot := cfg.WorktreeRoot
+	if worktreeRoot == "" {
+		worktreeRoot = defaultRootFor(cfg.Layout, repoRoot)
+	}
+
+	if err := writeLocalConfig(ctx, worktreeRoot); err != nil {
+		return err
+	}
+	base, err := resolveWorktreeBase(ctx)
+	if err != nil {
+		return err
+	}
+	if !planFileOp("mkdir -p %s", base) {
+		if err := os.MkdirAll(base, 0o755); err != nil {
+			return err
+		}
+	}
+	repo, err := registerRepo(repoRoot)
+	if err != nil {
+		return err
+	}
+	report("✓ Initialized %s (registered as %s)\n", repoRoot, repo.Name)
+
+	if opts.Migrate {
+		return migrateWorktrees(ctx, base)
+	}
+	return nil
+}
+
+// misplacedWorktrees returns the worktrees that live outside the worktree root base, such as those
+// created with plain git or left behind by a switch between the nested and sibling layouts
+func misplacedWorktrees(ctx context.Context, base string) ([]Worktree, error) {
+	worktrees, err := matchWorktrees(ctx, "*")
+	if err != nil {
+		return nil, err
+	}
+	var misplaced []Worktree
+	for _, wt := range worktrees {
+		if !pathWithin(wt.Path, base) {
+			misplaced = append(misplaced, wt)
+		}
+	}
+	return misplaced, nil
+}
+
+// migrateWorktrees moves worktrees created outside wtm into the worktree root, keeping their names
+func migrateWorktrees(ctx context.Context, base string) error {
+	worktrees, err := misplacedWorktrees(ctx, base)
+	if err != nil {
+		return err
+	}
+	for _, wt := range worktrees {
+		target := filepath.Join(base, wt.Name)
+		if _, err := os.Stat(target); err == nil {
+			fmt.Printf("Skipped %s: %s already exists\n", wt.Name, target)
+			continue
+		}
+		if _, err := runGitMutation(ctx, "worktree", "move", wt.Path, target); err != nil {
+			return fmt.Errorf("failed to move worktree '%s': %w", wt.Name, err)
+		}
+		report("✓ Moved %s to %s\n", wt.Name, target)
+	}
+	return nil
+}
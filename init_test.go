@@ -0,0 +1,206 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitRepository(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	configDir := t.TempDir()
+	t.Setenv("WTM_CONFIG_FILE", filepath.Join(configDir, "config.toml"))
+	resetConfigCache()
+	defer resetConfigCache()
+
+	// A worktree created with plain git, outside the worktree root
+	outside := filepath.Join(t.TempDir(), "legacy")
+	if _, err := runGitCommand(ctx, "worktree", "add", "-b", "legacy", outside); err != nil {
+		t.Fatalf("git worktree add failed: %v", err)
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return InitRepository(ctx, InitOptions{Migrate: true})
+	}); err != nil {
+		t.Fatalf("InitRepository failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(repoPath, localConfigFile)); err != nil {
+		t.Errorf("expected %s to be written: %v", localConfigFile, err)
+	}
+
+	wt, err := findWorktree(ctx, "legacy")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	want := filepath.Join(repoPath, ".git", "wtm", "worktrees", "legacy")
+	if normalizePath(wt.Path) != normalizePath(want) {
+		t.Errorf("expected legacy worktree to be moved to %s, got %s", want, wt.Path)
+	}
+
+	reg, err := loadRegistry()
+	if err != nil {
+		t.Fatalf("loadRegistry failed: %v", err)
+	}
+	if len(reg.Repos) != 1 || normalizePath(reg.Repos[0].Path) != normalizePath(repoPath) {
+		t.Fatalf("expected the repository to be registered, got %+v", reg.Repos)
+	}
+
+	// Running init again must not duplicate the registry entry
+	if _, err := captureStdout(t, func() error {
+		return InitRepository(ctx, InitOptions{})
+	}); err != nil {
+		t.Fatalf("second InitRepository failed: %v", err)
+	}
+	if reg, _ := loadRegistry(); len(reg.Repos) != 1 {
+		t.Errorf("expected a single registry entry, got %+v", reg.Repos)
+	}
+}
+
+func TestInitSiblingLayout(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("layout = \"sibling\"\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	outside := filepath.Join(t.TempDir(), "legacy")
+	if _, err := runGitCommand(ctx, "worktree", "add", "-b", "legacy", outside); err != nil {
+		t.Fatalf("git worktree add failed: %v", err)
+	}
+	if _, err := captureStdout(t, func() error {
+		return InitRepository(ctx, InitOptions{Migrate: true})
+	}); err != nil {
+		t.Fatalf("InitRepository failed: %v", err)
+	}
+
+	siblingRoot := filepath.Base(repoPath) + "-worktrees"
+	data, err := os.ReadFile(filepath.Join(repoPath, localConfigFile))
+	if err != nil || !strings.Contains(string(data), "worktreeRoot = \""+siblingRoot+"\"") {
+		t.Errorf("expected the local config to use %s, got %q (%v)", siblingRoot, data, err)
+	}
+	resetConfigCache()
+
+	if err := AddWorktree(ctx, "feature", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	base := filepath.Join(filepath.Dir(repoPath), siblingRoot)
+	for _, name := range []string{"legacy", "feature"} {
+		wt, err := findWorktree(ctx, name)
+		if err != nil {
+			t.Fatalf("findWorktree failed: %v", err)
+		}
+		if want := filepath.Join(base, name); normalizePath(wt.Path) != normalizePath(want) {
+			t.Errorf("expected %s at %s, got %s", name, want, wt.Path)
+		}
+	}
+}
+
+func TestRegisterRepoDisambiguatesNames(t *testing.T) {
+	t.Setenv("WTM_CONFIG_FILE", filepath.Join(t.TempDir(), "config.toml"))
+
+	first, err := registerRepo("/src/a/app")
+	if err != nil {
+		t.Fatalf("registerRepo failed: %v", err)
+	}
+	second, err := registerRepo("/src/b/app")
+	if err != nil {
+		t.Fatalf("registerRepo failed: %v", err)
+	}
+	if first.Name != "app" || second.Name != "app-2" {
+		t.Errorf("expected app and app-2, got %s and %s", first.Name, second.Name)
+	}
+}
+
+func TestListAllReposAndRepoRefs(t *testing.T) {
+	ctx := t.Context()
+
+	first := setupTestRepo(t)
+	defer cleanupTestRepo(t, first)
+	second := setupTestRepo(t)
+	defer cleanupTestRepo(t, second)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+	defer func() { repoDir = "" }()
+
+	t.Setenv("WTM_CONFIG_FILE", filepath.Join(t.TempDir(), "config.toml"))
+	resetConfigCache()
+	defer resetConfigCache()
+
+	for _, repo := range []string{first, second} {
+		if err := setRepoDir(repo); err != nil {
+			t.Fatalf("setRepoDir failed: %v", err)
+		}
+		if err := AddWorktree(ctx, "feature", AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+		if _, err := registerRepo(repo); err != nil {
+			t.Fatalf("registerRepo failed: %v", err)
+		}
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	repoDir = ""
+
+	output, err := captureStdout(t, func() error {
+		return ListAllRepos(ctx, ListOptions{Format: "plain"})
+	})
+	if err != nil {
+		t.Fatalf("ListAllRepos failed: %v", err)
+	}
+	for _, repo := range []string{first, second} {
+		if !strings.Contains(output, filepath.Base(repo)+":feature ") {
+			t.Errorf("expected %s:feature in output, got:\n%s", filepath.Base(repo), output)
+		}
+	}
+	if repoDir != "" {
+		t.Error("ListAllRepos should restore the selected repository")
+	}
+
+	root := newRootCmd()
+	root.SetArgs([]string{"show", filepath.Base(second) + ":feature", "--field", "path"})
+	output, err = captureStdout(t, root.Execute)
+	if err != nil {
+		t.Fatalf("show repo:name failed: %v", err)
+	}
+	if !strings.HasPrefix(normalizePath(strings.TrimSpace(output)), normalizePath(second)) {
+		t.Errorf("expected a path inside %s, got %q", second, output)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDiffMaxBytes = 100 * 1024
+	defaultLogLimit     = 100
+)
+
+// WorktreeDiff is the change set of a worktree's branch relative to its base
+type WorktreeDiff struct {
+	Base       string `json:"base" jsonschema:"revision the diff is taken against"`
+	MergeBase  string `json:"mergeBase" jsonschema:"commit where the branch forked from the base"`
+	Stat       string `json:"stat" jsonschema:"diffstat summary"`
+	Diff       string `json:"diff" jsonschema:"unified diff, possibly truncated"`
+	TotalBytes int    `json:"totalBytes" jsonschema:"size of the full diff in bytes"`
+	Truncated  bool   `json:"truncated" jsonschema:"whether the diff was cut at maxBytes"`
+}
+
+// Commit summarizes a commit for log output
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+}
+
+// WorktreeLog lists the commits of a worktree's branch that are not on its base
+type WorktreeLog struct {
+	Base      string   `json:"base" jsonschema:"revision the commits are compared against"`
+	Commits   []Commit `json:"commits" jsonschema:"commits unique to the branch, newest first"`
+	Total     int      `json:"total" jsonschema:"number of commits unique to the branch"`
+	Truncated bool     `json:"truncated" jsonschema:"whether commits were omitted because of the limit"`
+}
+
+// defaultCompareBase returns the branch checked out in the primary worktree, which new worktrees fork from by default
+func defaultCompareBase(ctx context.Context) (string, error) {
+	repoRoot, err := getRepoRoot(ctx)
+	if err != nil {
+		return "", err
+	}
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, wt := range worktrees {
+		if normalizePath(wt.Path) == normalizePath(repoRoot) && wt.Branch != "" {
+			return wt.Branch, nil
+		}
+	}
+	return "", fmt.Errorf("cannot determine a base branch; specify one explicitly")
+}
+
+// resolveCompareBase returns base when given, otherwise the base recorded when the worktree's branch was created
+// if it still exists, falling back to defaultCompareBase. A given base must name a commit.
+func resolveCompareBase(ctx context.Context, wt *Worktree, base string) (string, error) {
+	if base != "" {
+		// A base starting with '-' would reach git as an option, e.g. --output=<file>
+		if strings.HasPrefix(base, "-") || !revisionExists(ctx, base) {
+			return "", fmt.Errorf("base '%s' is not a commit", base)
+		}
+		return base, nil
+	}
+	if wt != nil && wt.Base != "" && revisionExists(ctx, wt.Base) {
+		return wt.Base, nil
+	}
+	return defaultCompareBase(ctx)
+}
+
+// revisionExists reports whether rev names a commit, accepting short names like main or origin/main
+func revisionExists(ctx context.Context, rev string) bool {
+	_, err := runGitCommand(ctx, "rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+	return err == nil
+}
+
+// worktreeDiff returns the diff between the merge base with base and the worktree's HEAD,
+// or its working tree when uncommitted is set, cut at maxBytes
+func worktreeDiff(ctx context.Context, wt *Worktree, base string, uncommitted bool, maxBytes int) (*WorktreeDiff, error) {
+	base, err := resolveCompareBase(ctx, wt, base)
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultDiffMaxBytes
+	}
+
+	mergeBase, err := runGitCommand(ctx, "-C", wt.Path, "merge-base", base, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("no common history between '%s' and worktree '%s': %w", base, wt.Name, err)
+	}
+	mergeBase = strings.TrimSpace(mergeBase)
+
+	args := []string{"-C", wt.Path, "diff", mergeBase}
+	if !uncommitted {
+		args = append(args, "HEAD")
+	}
+	stat, err := runGitCommand(ctx, append(args, "--stat")...)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := runGitCommand(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WorktreeDiff{Base: base, MergeBase: mergeBase, Stat: stat, Diff: diff, TotalBytes: len(diff)}
+	if len(diff) > maxBytes {
+		// Cut at a line boundary so the remaining hunks stay readable
+		cut := diff[:maxBytes]
+		if idx := strings.LastIndex(cut, "\n"); idx > 0 {
+			cut = cut[:idx+1]
+		}
+		result.Diff = cut
+		result.Truncated = true
+	}
+	return result, nil
+}
+
+// worktreeLog returns up to limit commits reachable from the worktree's HEAD but not from base
+func worktreeLog(ctx context.Context, wt *Worktree, base string, limit int) (*WorktreeLog, error) {
+	base, err := resolveCompareBase(ctx, wt, base)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultLogLimit
+	}
+
+	revRange := base + "..HEAD"
+	count, err := runGitCommand(ctx, "-C", wt.Path, "rev-list", "--count", revRange)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compare worktree '%s' with '%s': %w", wt.Name, base, err)
+	}
+	total, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := runGitCommand(ctx, "-C", wt.Path, "log", "-n", strconv.Itoa(limit), "--format=%H%x00%an%x00%aI%x00%s", revRange)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WorktreeLog{Base: base, Commits: []Commit{}, Total: total, Truncated: total > limit}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[2])
+		result.Commits = append(result.Commits, Commit{Hash: fields[0], Author: fields[1], Date: date, Subject: fields[3]})
+	}
+	return result, nil
+}
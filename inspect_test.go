@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorktreeDiffAndLog(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if _, err := captureStdout(t, func() error { return AddWorktree(ctx, "feature", AddOptions{}) }); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "feature")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+
+	for _, content := range []string{"one\n", "one\ntwo\n", "one\ntwo\nthree\n"} {
+		if err := os.WriteFile(filepath.Join(wt.Path, "notes.txt"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		for _, args := range [][]string{
+			{"add", "notes.txt"},
+			{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-qm", "step"},
+		} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = wt.Path
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v failed: %v: %s", args, err, output)
+			}
+		}
+	}
+
+	log, err := worktreeLog(ctx, wt, "", 2)
+	if err != nil {
+		t.Fatalf("worktreeLog failed: %v", err)
+	}
+	if log.Total != 3 || len(log.Commits) != 2 || !log.Truncated {
+		t.Errorf("expected 2 of 3 commits with truncation, got %+v", log)
+	}
+
+	diff, err := worktreeDiff(ctx, wt, "", false, 0)
+	if err != nil {
+		t.Fatalf("worktreeDiff failed: %v", err)
+	}
+	if diff.Truncated || !strings.Contains(diff.Diff, "+three") || !strings.Contains(diff.Stat, "notes.txt") {
+		t.Errorf("unexpected diff: %+v", diff)
+	}
+
+	small, err := worktreeDiff(ctx, wt, "", false, 20)
+	if err != nil {
+		t.Fatalf("worktreeDiff failed: %v", err)
+	}
+	if !small.Truncated || len(small.Diff) > 20 || small.TotalBytes != diff.TotalBytes {
+		t.Errorf("expected a truncated diff of at most 20 bytes, got %+v", small)
+	}
+
+	primary, err := defaultCompareBase(ctx)
+	if err != nil {
+		t.Fatalf("defaultCompareBase failed: %v", err)
+	}
+	if _, err := worktreeLog(ctx, wt, primary, 0); err != nil {
+		t.Errorf("expected an explicit branch base to be accepted: %v", err)
+	}
+	target := filepath.Join(t.TempDir(), "out")
+	for _, base := range []string{"--output=" + target, "missing-branch"} {
+		if _, err := worktreeLog(ctx, wt, base, 0); err == nil || !strings.Contains(err.Error(), "is not a commit") {
+			t.Errorf("worktreeLog(%q): expected the base to be rejected, got %v", base, err)
+		}
+		if _, err := worktreeDiff(ctx, wt, base, false, 0); err == nil || !strings.Contains(err.Error(), "is not a commit") {
+			t.Errorf("worktreeDiff(%q): expected the base to be rejected, got %v", base, err)
+		}
+	}
+	if matches, _ := filepath.Glob(target + "*"); len(matches) > 0 {
+		t.Errorf("expected no file written through the base, got %v", matches)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// logger receives diagnostics enabled by --verbose and --debug; it is nil while logging is off
+var logger *slog.Logger
+
+// logFile is the configured log file, closed when the process exits
+var logFile *os.File
+
+// setupLogging enables logging of executed commands at info level (--verbose) or, with --debug,
+// their outputs as well. Logs go to log.file from the config, or stderr when it is unset.
+func setupLogging(ctx context.Context, verbose, debug bool) error {
+	// Nested invocations such as wtm plan keep the logger of the outer command
+	if logger != nil || (!verbose && !debug) {
+		return nil
+	}
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
+	}
+
+	var w io.Writer = os.Stderr
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg.Log.File != "" {
+		f, err := os.OpenFile(cfg.Log.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("cannot open log file: %w", err)
+		}
+		logFile = f
+		w = f
+	}
+	logger = slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+	return nil
+}
+
+func closeLogFile() {
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
+	}
+}
+
+// logCommand logs an external command run outside the library manager, e.g. interactive git or tmux
+func logCommand(ctx context.Context, name string, args []string, start time.Time, err error) {
+	if logger == nil {
+		return
+	}
+	attrs := []slog.Attr{slog.String("cmd", shellJoin(append([]string{name}, args...))), slog.Duration("duration", time.Since(start))}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "exec", attrs...)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDebugLogsToConfiguredFile(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "wtm.log")
+	configFile := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(configFile, []byte("[log]\nfile = \""+filepath.ToSlash(logPath)+"\"\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+	defer func() {
+		closeLogFile()
+		logger = nil
+	}()
+
+	root := newRootCmd()
+	root.SetArgs([]string{"--debug", "list", "--format", "plain"})
+	if _, err := captureStdout(t, root.Execute); err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	closeLogFile()
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("expected the log file to be written: %v", err)
+	}
+	log := string(data)
+	for _, want := range []string{`cmd="git worktree list --porcelain"`, "duration=", "parsed worktrees"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("expected log to contain %q:\n%s", want, log)
+		}
+	}
+}
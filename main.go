@@ -4,21 +4,44 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var version = "dev"
 
 func main() {
+	// Cancel running git commands and MCP sessions on Ctrl-C or termination
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	rootCmd := newRootCmd()
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.ExecuteContext(ctx)
+	closeLogFile()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
+// annotationDryRun marks commands whose side effects can be previewed with --dry-run and wtm plan
+const annotationDryRun = "wtm/dry-run"
+
+var dryRunAnnotation = map[string]string{annotationDryRun: "true"}
+
+func supportsDryRun(cmd *cobra.Command) bool {
+	return cmd.Annotations[annotationDryRun] == "true"
+}
+
 func newRootCmd() *cobra.Command {
+	var dryRun bool
+	var repo string
+	var verbose, debug bool
+
 	cmd := &cobra.Command{
 		Use:           "wtm",
 		Short:         "Worktree Manager",
@@ -27,15 +50,81 @@ func newRootCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cmd.Help()
 		},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Only an explicit -C changes the target, so nested invocations such as wtm plan keep it
+			if cmd.Flags().Changed("repo") {
+				if err := setRepoDir(repo); err != nil {
+					return err
+				}
+			}
+			if err := setupLogging(cmd.Context(), verbose, debug); err != nil {
+				return err
+			}
+			if !dryRun {
+				return nil
+			}
+			if !supportsDryRun(cmd) {
+				return fmt.Errorf("--dry-run is not supported by '%s'", cmd.CommandPath())
+			}
+			activePlan = &Plan{Command: describeInvocation(cmd, args), Steps: []string{}}
+			return nil
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			if planning() {
+				printPlan(activePlan)
+				activePlan = nil
+				return nil
+			}
+			if supportsDryRun(cmd) && listCacheEnabled(cmd.Context()) {
+				invalidateListCache(cmd.Context())
+			}
+			return nil
+		},
 	}
 
+	cmd.PersistentFlags().StringVarP(&repo, "repo", "C", "", "Run as if wtm was started in `path`")
+	cmd.PersistentFlags().BoolVarP(&dryRun, "dry-run", "n", false, "Print the git commands and file operations instead of running them")
+	cmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Answer yes to every confirmation prompt")
+	cmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Log every executed command with its duration")
+	cmd.PersistentFlags().BoolVar(&debug, "debug", false, "Like --verbose, and also log command outputs and parsed results")
+	cmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Do not read or write the worktree list cache")
+	cmd.PersistentFlags().BoolVar(&absoluteTime, "absolute-time", false, "Print timestamps (RFC 3339 or time.format from the config) instead of relative times in tables")
+
 	cmd.AddCommand(
 		newAddCmd(),
 		newListCmd(),
 		newShowCmd(),
+		newPathCmd(),
+		newJumpCmd(),
+		newRecentCmd(),
 		newRemoveCmd(),
+		newTransferCmd(),
+		newCopyCmd(),
+		newHideCmd(false),
+		newHideCmd(true),
+		newAbsorbCmd(),
+		newSyncCmd(),
+		newRebaseCmd(),
+		newDiffCmd(),
+		newEnvCmd(),
+		newComposeCmd(),
+		newOpenCmd(),
+		newArchiveCmd(),
+		newTmuxCmd(),
+		newRunCmd(),
+		newExecCmd(),
+		newForeachCmd(),
+		newCleanCmd(),
+		newPruneCmd(),
+		newGCCmd(),
+		newCloneCmd(),
+		newInitCmd(),
+		newDoctorCmd(),
+		newAdoptCmd(),
+		newPlanCmd(),
 		newVersionCmd(),
 		newMCPCmd(),
+		newExitCodesHelpTopic(),
 	)
 
 	return cmd
@@ -45,16 +134,100 @@ func newAddCmd() *cobra.Command {
 	var branch string
 	var checkout string
 	var base string
+	var from string
+	var readOnly bool
+	var review int
+	var issue int
+	var detach string
+	var tag string
+	var noCheckout bool
+	var sparse []string
+	var sparseProfile string
+	var recurseSubmodules bool
+	var open bool
+	var template string
+	var fromFile string
+	var labels []string
+	var skipSpaceCheck bool
+	var ephemeral bool
 
 	cmd := &cobra.Command{
-		Use:   "add <name>",
-		Short: "Create a new worktree",
-		Args:  cobra.ExactArgs(1),
+		Use:         "add [name...]",
+		Short:       "Create a new worktree",
+		Annotations: dryRunAnnotation,
+		Long: `Create a new worktree.
+
+The name may be omitted when -b or -B is given; it is then derived from the branch
+(the last path segment, or names.nameTemplate from the config). With --issue it is
+rendered from the issue title with names.issueTemplate (default "{{.Number}}-{{.Slug}}").
+
+Several names, or --from-file with one name per line, create a worktree for each one after
+another with the same flags and print a summary; a failure does not stop the others.
+
+--ephemeral marks the worktree for removal, with the branch created for it, by the next
+wtm gc. Given a command after --, the worktree is removed as soon as the command exits.`,
+		Example: "  wtm add fix-a fix-b fix-c --base main\n  wtm add --from-file worktrees.txt --template review\n  wtm add api-tests --from api\n  wtm add tmp --ephemeral -- make test",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			name := args[0]
-			if err := AddWorktree(name, branch, checkout, base); err != nil {
+			names := args
+			var command []string
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				names, command = args[:dash], args[dash:]
+				if !ephemeral {
+					return fmt.Errorf("a command after -- requires --ephemeral")
+				}
+				if len(command) == 0 {
+					return fmt.Errorf("pass the command to run after --")
+				}
+			}
+			if fromFile != "" {
+				fileNames, err := readNamesFile(fromFile)
+				if err != nil {
+					return err
+				}
+				names = append(names, fileNames...)
+			}
+			var name string
+			if len(names) > 0 {
+				name = names[0]
+			}
+			opts := AddOptions{
+				Branch:            branch,
+				Checkout:          checkout,
+				Base:              base,
+				From:              from,
+				Detach:            detach,
+				Tag:               tag,
+				Review:            review,
+				Issue:             issue,
+				ReadOnly:          readOnly,
+				NoCheckout:        noCheckout,
+				Sparse:            sparse,
+				SparseProfile:     sparseProfile,
+				RecurseSubmodules: recurseSubmodules,
+				Template:          template,
+				Labels:            labels,
+				SkipSpaceCheck:    skipSpaceCheck,
+				Ephemeral:         ephemeral,
+			}
+			if len(names) > 1 || fromFile != "" {
+				if open || command != nil {
+					return fmt.Errorf("--open and a command cannot be used with several worktrees")
+				}
+				return AddWorktrees(cmd.Context(), names, opts)
+			}
+			if command != nil {
+				if open {
+					return fmt.Errorf("--open cannot be used with a command")
+				}
+				return AddEphemeral(cmd.Context(), name, opts, command)
+			}
+			wt, err := addWorktree(cmd.Context(), name, opts)
+			if err != nil {
 				return err
 			}
+			if open {
+				return OpenWorktree(cmd.Context(), wt.Name)
+			}
 			return nil
 		},
 	}
@@ -62,50 +235,200 @@ func newAddCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&branch, "branch", "b", "", "Create new branch with specified name")
 	cmd.Flags().StringVarP(&checkout, "checkout", "B", "", "Use existing branch")
 	cmd.Flags().StringVar(&base, "base", "", "Base branch for new branch")
+	cmd.Flags().StringVar(&from, "from", "", "Base the new branch on the branch (or detached commit) of another worktree")
+	cmd.Flags().BoolVar(&noCheckout, "no-checkout", false, "Create the worktree without checking out files (populate later, e.g. after sparse-checkout)")
+	cmd.Flags().StringSliceVar(&sparse, "sparse", nil, "Check out only these directories with sparse-checkout (repeatable or comma-separated)")
+	cmd.Flags().StringVar(&sparseProfile, "sparse-profile", "", "Check out only the directories of a profile from the [sparse] config")
+	cmd.Flags().BoolVar(&recurseSubmodules, "recurse-submodules", false, "Initialize and check out submodules in the new worktree")
+	cmd.Flags().BoolVar(&open, "open", false, "Open the new worktree in the configured editor")
+	cmd.Flags().BoolVar(&readOnly, "read-only", false, "Remove write permissions for review or bisect checkouts")
+	cmd.Flags().StringVar(&detach, "detach", "", "Check out a commit, tag or ref in detached HEAD mode")
+	cmd.Flags().StringVar(&tag, "tag", "", "Check out a tag, detached or on a new branch from it with -b, and record the tag")
+	cmd.Flags().IntVar(&review, "pr", 0, "Check out a pull or merge request by number")
+	cmd.Flags().IntVar(&review, "mr", 0, "Alias for --pr (GitLab merge request IID)")
+	cmd.Flags().IntVar(&issue, "issue", 0, "Start work on a GitHub issue: name the worktree and branch after its title")
+	cmd.Flags().StringVar(&fromFile, "from-file", "", "Create a worktree for every name in a file, one per line (- reads stdin)")
+	cmd.Flags().BoolVar(&ephemeral, "ephemeral", false, "Remove the worktree and its new branch when the command after -- exits, or at the next wtm gc")
+	cmd.Flags().BoolVar(&skipSpaceCheck, "skip-space-check", false, "Create the worktree even if the checkout looks larger than the free disk space")
+	cmd.Flags().StringSliceVar(&labels, "label", nil, "Tag the worktree for wtm list --group-by label (repeatable or comma-separated)")
+	cmd.Flags().StringVar(&template, "template", "", "Apply a named template from the [templates] config (base, sparse profile, copied files, setup commands)")
+	cmd.MarkFlagsMutuallyExclusive("pr", "mr")
+	registerFlagCompletion(cmd, "template", completeTemplates)
+	registerFlagCompletion(cmd, "checkout", completeBranches)
+	registerFlagCompletion(cmd, "base", completeBranches)
+	registerFlagCompletion(cmd, "from", completeWorktreeFlag)
+	registerFlagCompletion(cmd, "tag", completeTags)
 
 	return cmd
 }
 
 func newListCmd() *cobra.Command {
-	var format string
+	var opts ListOptions
+	var allRepos bool
+	var watchList bool
+	var interval time.Duration
+	var porcelain bool
+	var names, paths bool
 
 	cmd := &cobra.Command{
-		Use:   "list",
-		Short: "List all worktrees",
+		Use:     "list",
+		Short:   "List all worktrees",
 		Aliases: []string{"ls"},
-		Args:  cobra.NoArgs,
+		Args:    cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := ListWorktrees(format); err != nil {
-				return err
+			switch {
+			case porcelain:
+				opts.Format = "porcelain"
+			case names:
+				opts.Format = "names"
+			case paths:
+				opts.Format = "paths"
 			}
-			return nil
+			if allRepos && (opts.GroupBy != "" || opts.Tree) {
+				return fmt.Errorf("--group-by and --tree cannot be used with --all-repos")
+			}
+			list := func() error {
+				if allRepos {
+					return ListAllRepos(cmd.Context(), opts)
+				}
+				return ListWorktrees(cmd.Context(), opts)
+			}
+			if watchList {
+				if opts.NullTerminated {
+					return fmt.Errorf("--watch cannot be used with -z")
+				}
+				return watch(cmd.Context(), cmd.CommandPath(), interval, list)
+			}
+			return list()
 		},
 	}
 
-	cmd.Flags().StringVar(&format, "format", "table", "Output format: table, plain, json")
+	cmd.Flags().StringVar(&opts.Format, "format", "table", "Output format: table, plain, json, porcelain, names, paths")
+	cmd.Flags().BoolVar(&porcelain, "porcelain", false, "Stable, versioned output for scripts (same as --format porcelain)")
+	cmd.Flags().BoolVarP(&names, "names", "q", false, "Print only worktree names, one per line (same as --format names)")
+	cmd.Flags().BoolVar(&paths, "paths", false, "Print only worktree paths, one per line (same as --format paths)")
+	cmd.Flags().BoolVarP(&opts.NullTerminated, "null", "z", false, "With --format plain, --porcelain, --names or --paths, terminate every field or line with a NUL byte")
+	cmd.Flags().BoolVar(&allRepos, "all-repos", false, "List worktrees of every registered repository")
+	cmd.Flags().BoolVarP(&watchList, "watch", "w", false, "Re-render the list every --interval until interrupted")
+	cmd.Flags().DurationVar(&interval, "interval", defaultWatchInterval, "Refresh interval for --watch")
+	cmd.Flags().BoolVarP(&opts.All, "all", "a", false, "Include worktrees hidden with wtm hide or matched by the ignore config")
+	cmd.Flags().StringVar(&opts.Since, "since", "", "Only worktrees created at or after this time: a duration ago (2w, 10d, 36h) or a date (2024-01-01)")
+	cmd.Flags().StringVar(&opts.Before, "before", "", "Only worktrees created before this time: a duration ago (2w, 10d, 36h) or a date (2024-01-01)")
+	cmd.Flags().StringVar(&opts.TimeField, "time-field", "created", "Time --since and --before compare: created, or commit for the last commit")
+	cmd.Flags().StringVar(&opts.GroupBy, "group-by", "", "Group the table by prefix (branch up to the last /), label or base")
+	cmd.Flags().BoolVar(&opts.Tree, "tree", false, "Print worktrees as a tree under their groups (by prefix unless --group-by is set)")
+	cmd.MarkFlagsMutuallyExclusive("porcelain", "names", "paths")
+	registerFlagCompletion(cmd, "group-by", cobra.FixedCompletions(groupByKeys, cobra.ShellCompDirectiveNoFileComp))
+	registerFlagCompletion(cmd, "time-field", cobra.FixedCompletions(timeFields, cobra.ShellCompDirectiveNoFileComp))
 
 	return cmd
 }
 
 func newShowCmd() *cobra.Command {
-	var format string
-	var field string
+	var opts ShowOptions
+	var porcelain bool
+	var fuzzy bool
+	var byBranch bool
 
 	cmd := &cobra.Command{
-		Use:   "show <name>",
-		Short: "Show worktree details",
-		Args:  cobra.ExactArgs(1),
+		Use:               "show <name>",
+		Short:             "Show worktree details",
+		Long:              "Show worktree details. Use . for the worktree containing the current directory, or repo:name to show a worktree of another registered repository.",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			name := args[0]
-			if err := ShowWorktree(name, format, field); err != nil {
+			name, err := useRepoRef(args[0])
+			if err != nil {
+				return err
+			}
+			switch {
+			case fuzzy:
+				if name, err = resolveFuzzyName(cmd.Context(), name); err != nil {
+					return err
+				}
+			case byBranch:
+				if name, err = resolveBranchName(cmd.Context(), name); err != nil {
+					return err
+				}
+			}
+			if porcelain {
+				opts.Format = "porcelain"
+			}
+			if err := ShowWorktree(cmd.Context(), name, opts); err != nil {
 				return err
 			}
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVar(&format, "format", "pretty", "Output format: pretty, json")
-	cmd.Flags().StringVarP(&field, "field", "f", "", "Output specific field only")
+	cmd.Flags().StringVar(&opts.Format, "format", "pretty", "Output format: pretty, json, porcelain")
+	cmd.Flags().BoolVar(&porcelain, "porcelain", false, "Stable, versioned output for scripts, including status (same as --format porcelain)")
+	cmd.Flags().StringVarP(&opts.Field, "field", "f", "", "Output specific fields only, comma-separated (e.g. name,path or status.ahead)")
+	cmd.Flags().BoolVar(&fuzzy, "fuzzy", false, "Resolve the name like wtm jump, by a unique partial name or branch")
+	cmd.Flags().BoolVar(&byBranch, "by-branch", false, "Treat the argument as a branch and show the worktree that has it checked out")
+	cmd.MarkFlagsMutuallyExclusive("fuzzy", "by-branch")
+	cmd.Flags().BoolVar(&opts.Tab, "tab", false, "Print multiple --field values on one tab-separated line")
+	cmd.Flags().BoolVarP(&opts.NullTerminated, "null", "z", false, "Terminate the --field values or --porcelain lines with a NUL byte instead of a newline")
+	registerFlagCompletion(cmd, "field", completeFields)
+
+	return cmd
+}
+
+func newPathCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "path <name>",
+		Short: "Print the absolute path of a worktree",
+		Long: `Print the absolute path of a worktree and nothing else, like wtm show -f path but without
+reading metadata, for shell helpers such as cd "$(wtm path api)" and editor tooling.
+Exits with code 2 when the worktree does not exist. Use repo:name for another registered repository,
+or - for the previously used worktree.`,
+		Example:           "  cd \"$(wtm path api)\"",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := useRepoRef(args[0])
+			if err != nil {
+				return err
+			}
+			return PrintPath(cmd.Context(), name)
+		},
+	}
+}
+
+func newJumpCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "jump <query>",
+		Short: "Print the path of the worktree matching a partial name or branch",
+		Long: `Print the path of the single worktree whose name or branch contains the query, ignoring case.
+When no name or branch contains it, the query's characters may also match in order ("apirf" finds
+"api-refactor"). An exact name always wins. When several worktrees match, the command fails and
+lists them. - jumps back to the previously used worktree, like cd -.`,
+		Example:           "  cd \"$(wtm jump refac)\"\n  cd \"$(wtm jump -)\"",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Jump(cmd.Context(), args[0])
+		},
+	}
+}
+
+func newRecentCmd() *cobra.Command {
+	var opts RecentOptions
+
+	cmd := &cobra.Command{
+		Use:   "recent",
+		Short: "List the most recently used worktrees first",
+		Long: `List worktrees by when they were last used, most recent first. Use is recorded by wtm jump,
+wtm open, wtm run, wtm exec, wtm tmux and the wtm_exec MCP tool; worktrees that were never used are left out.`,
+		Example: "  cd \"$(wtm path \"$(wtm recent -q | fzf)\")\"",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ListRecent(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().IntVarP(&opts.Limit, "limit", "n", 10, "Show at most this many worktrees (0 for all)")
+	cmd.Flags().BoolVarP(&opts.Names, "names", "q", false, "Print only worktree names, one per line")
 
 	return cmd
 }
@@ -114,20 +437,35 @@ func newRemoveCmd() *cobra.Command {
 	var force bool
 	var deleteBranch bool
 	var deleteBranchForce bool
+	var pattern string
+	var after string
+	var stash bool
+	var discardChanges bool
+	var andCD bool
+	var fuzzy bool
+	var byBranch bool
 
 	cmd := &cobra.Command{
-		Use:   "remove <name>",
-		Short: "Remove a worktree",
-		Aliases: []string{"rm"},
-		Args:  cobra.ExactArgs(1),
+		Use:         "remove <name>",
+		Short:       "Remove a worktree",
+		Annotations: dryRunAnnotation,
+		Aliases:     []string{"rm"},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if pattern != "" {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		ValidArgsFunction: completeWorktreeNames,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			name := args[0]
-
 			if deleteBranch && deleteBranchForce {
 				return fmt.Errorf("cannot combine --delete-branch and --delete-branch-force")
 			}
 
-			opts := RemoveOptions{Force: force}
+			if stash && discardChanges {
+				return fmt.Errorf("cannot combine --stash and --discard-changes")
+			}
+			opts := RemoveOptions{Force: force, After: after, Stash: stash, DiscardChanges: discardChanges, AndCD: andCD}
 			switch {
 			case deleteBranch:
 				opts.BranchDelete = BranchDeleteSafe
@@ -135,7 +473,29 @@ func newRemoveCmd() *cobra.Command {
 				opts.BranchDelete = BranchDeleteForce
 			}
 
-			if err := RemoveWorktree(name, opts); err != nil {
+			if pattern != "" && (fuzzy || byBranch) {
+				return fmt.Errorf("cannot combine --pattern with --fuzzy or --by-branch")
+			}
+			if pattern == "" && !fuzzy && !byBranch && isGlobPattern(args[0]) {
+				pattern = args[0]
+			}
+			if pattern != "" {
+				return RemoveWorktreesByPattern(cmd.Context(), pattern, opts)
+			}
+
+			name := args[0]
+			var err error
+			switch {
+			case fuzzy:
+				if name, err = resolveFuzzyName(cmd.Context(), name); err != nil {
+					return err
+				}
+			case byBranch:
+				if name, err = resolveBranchName(cmd.Context(), name); err != nil {
+					return err
+				}
+			}
+			if err := RemoveWorktree(cmd.Context(), name, opts); err != nil {
 				return err
 			}
 			return nil
@@ -143,6 +503,14 @@ func newRemoveCmd() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation")
+	cmd.Flags().StringVarP(&pattern, "pattern", "p", "", "Remove all worktrees whose names match a glob pattern")
+	cmd.Flags().BoolVar(&fuzzy, "fuzzy", false, "Resolve the name like wtm jump, by a unique partial name or branch")
+	cmd.Flags().BoolVar(&byBranch, "by-branch", false, "Treat the argument as a branch and remove the worktree that has it checked out")
+	cmd.MarkFlagsMutuallyExclusive("fuzzy", "by-branch")
+	cmd.Flags().BoolVar(&stash, "stash", false, "Save uncommitted and untracked changes with git stash before removing")
+	cmd.Flags().BoolVar(&discardChanges, "discard-changes", false, "Remove even if the worktree has uncommitted or untracked changes")
+	cmd.Flags().StringVar(&after, "after", "", "Run a command inside the worktree and remove it only if the command succeeds")
+	cmd.Flags().BoolVar(&andCD, "and-cd", false, "Allow removing the worktree you are in: move to the primary worktree and have the shell helper cd there")
 	cmd.Flags().BoolVarP(&deleteBranch, "delete-branch", "d", false, "Delete associated branch (git branch -d)")
 	cmd.Flags().BoolVarP(&deleteBranchForce, "delete-branch-force", "D", false, "Force delete associated branch (git branch -D)")
 	cmd.MarkFlagsMutuallyExclusive("delete-branch", "delete-branch-force")
@@ -150,6 +518,635 @@ func newRemoveCmd() *cobra.Command {
 	return cmd
 }
 
+func newTransferCmd() *cobra.Command {
+	var opts TransferOptions
+
+	cmd := &cobra.Command{
+		Use:   "transfer <from> <to>",
+		Short: "Move uncommitted changes from one worktree to another",
+		Long: `Move the uncommitted changes of one worktree to another, for work started in the wrong place.
+The changes are applied to the destination only if they apply cleanly; otherwise nothing changes.
+The moved files are then stashed in the source worktree (see git stash list) unless --keep is given.`,
+		Example:           "  wtm transfer . feature-login\n  wtm transfer main fix-typo --include-untracked",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeWorktreePair,
+		Annotations:       dryRunAnnotation,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return TransferChanges(cmd.Context(), args[0], args[1], opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.Untracked, "include-untracked", "u", false, "Also move untracked files that are not ignored")
+	cmd.Flags().BoolVar(&opts.Keep, "keep", false, "Leave the changes in the source worktree as well")
+
+	return cmd
+}
+
+func newCopyCmd() *cobra.Command {
+	var opts CopyOptions
+
+	cmd := &cobra.Command{
+		Use:     "cp <name> <newname>",
+		Aliases: []string{"copy"},
+		Short:   "Duplicate a worktree on a new branch at the same commit",
+		Long: `Create a worktree on a new branch pointing at the same commit as an existing worktree, to fork an
+in-progress approach without disturbing the original. The base branch and labels are carried over;
+--with-changes also copies the uncommitted changes and untracked files.`,
+		Example:           "  wtm cp feature-auth feature-auth-alt\n  wtm cp . experiment --with-changes",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeWorktreeNames,
+		Annotations:       dryRunAnnotation,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return CopyWorktree(cmd.Context(), args[0], args[1], opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Branch, "branch", "b", "", "Name of the new branch (defaults to the new worktree name)")
+	cmd.Flags().BoolVar(&opts.WithChanges, "with-changes", false, "Also copy uncommitted changes and untracked files")
+
+	return cmd
+}
+
+// newHideCmd returns wtm hide, or wtm unhide when unhide is set
+func newHideCmd(unhide bool) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "hide <name>",
+		Short:             "Leave a worktree out of wtm list",
+		Long:              "Leave a long-lived worktree out of wtm list; wtm list --all still shows it. Other commands are not affected.",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		Annotations:       dryRunAnnotation,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return HideWorktree(cmd.Context(), args[0], !unhide)
+		},
+	}
+	if unhide {
+		cmd.Use = "unhide <name>"
+		cmd.Short = "Show a hidden worktree in wtm list again"
+		cmd.Long = ""
+	}
+	return cmd
+}
+
+func newAbsorbCmd() *cobra.Command {
+	var opts AbsorbOptions
+
+	cmd := &cobra.Command{
+		Use:         "absorb <name>",
+		Short:       "Merge a worktree's branch, then remove the worktree and the branch",
+		Annotations: dryRunAnnotation,
+		Long: `Finish a piece of work: merge the worktree's branch into the branch checked out in the
+primary worktree (or rebase it and fast-forward with --rebase), then remove the worktree and
+delete the merged branch. The worktree and the primary worktree must both be clean. On
+conflicts the merge or rebase is aborted and nothing is removed.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return AbsorbWorktree(cmd.Context(), args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Into, "into", "", "Branch to merge into; must be checked out in the primary worktree (default: its current branch)")
+	registerFlagCompletion(cmd, "into", completeBranches)
+	cmd.Flags().BoolVar(&opts.Rebase, "rebase", false, "Rebase onto the target and fast-forward instead of creating a merge commit")
+	cmd.Flags().BoolVarP(&opts.Force, "force", "f", false, "Skip confirmation")
+
+	return cmd
+}
+
+func newSyncCmd() *cobra.Command {
+	var opts SyncOptions
+
+	cmd := &cobra.Command{
+		Use:         "sync [name]",
+		Short:       "Rebase or merge worktrees onto their base branch",
+		Annotations: dryRunAnnotation,
+		Long: `Fetch, then rebase every worktree's branch (or the named one; globs are accepted) onto
+its recorded base branch, or merge the base with --merge. The base's upstream is used when it has one.
+Worktrees with uncommitted changes are skipped, conflicts are aborted, and a summary is printed.`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pattern := "*"
+			if len(args) > 0 {
+				pattern = args[0]
+			}
+			return SyncWorktrees(cmd.Context(), pattern, opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Merge, "merge", false, "Merge the base into each branch instead of rebasing")
+	cmd.Flags().BoolVar(&opts.NoFetch, "no-fetch", false, "Do not fetch from the remotes first")
+
+	return cmd
+}
+
+func newRebaseCmd() *cobra.Command {
+	var opts RebaseOptions
+
+	cmd := &cobra.Command{
+		Use:         "rebase <name>",
+		Short:       "Rebase a worktree's branch onto its base",
+		Annotations: dryRunAnnotation,
+		Long: `Rebase a worktree's branch onto its base branch (recorded when wtm created the branch, otherwise
+the branch checked out in the primary worktree), or onto --onto <ref>, running git inside the worktree. On conflicts the rebase is left in progress:
+resolve and stage the files, then run 'wtm rebase <name> --continue', or '--abort' to give up.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.Abort && opts.Continue {
+				return fmt.Errorf("--abort and --continue cannot be used together")
+			}
+			if opts.Onto != "" && (opts.Abort || opts.Continue) {
+				return fmt.Errorf("--onto cannot be used with --abort or --continue")
+			}
+			return RebaseWorktree(cmd.Context(), args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Onto, "onto", "", "Ref to rebase onto (default: the base branch)")
+	registerFlagCompletion(cmd, "onto", completeBranches)
+	cmd.Flags().BoolVar(&opts.Abort, "abort", false, "Abort a rebase in progress")
+	cmd.Flags().BoolVar(&opts.Continue, "continue", false, "Continue a rebase after resolving conflicts")
+
+	return cmd
+}
+
+func newDiffCmd() *cobra.Command {
+	var opts DiffOptions
+
+	cmd := &cobra.Command{
+		Use:   "diff <name>",
+		Short: "Show what a worktree changes relative to its base",
+		Long: `Show git diff <base>...<branch> for a worktree: the commits made on its branch since it forked
+from its base branch (recorded when wtm created the branch, or --base <ref>).
+Uncommitted changes are not included.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.Stat && opts.NameOnly {
+				return fmt.Errorf("--stat and --name-only cannot be used together")
+			}
+			return DiffWorktree(cmd.Context(), args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Base, "base", "", "Ref to compare against (default: the base branch)")
+	registerFlagCompletion(cmd, "base", completeBranches)
+	cmd.Flags().BoolVar(&opts.Stat, "stat", false, "Show a diffstat instead of the patch")
+	cmd.Flags().BoolVar(&opts.NameOnly, "name-only", false, "Show only the names of changed files")
+
+	return cmd
+}
+
+func newEnvCmd() *cobra.Command {
+	var opts EnvOptions
+
+	cmd := &cobra.Command{
+		Use:   "env [name]",
+		Short: "Print a worktree's WTM_* variables for scripts",
+		Long: `Print WTM_NAME, WTM_BRANCH, WTM_PATH, WTM_BASE, WTM_PORT and COMPOSE_PROJECT_NAME for a worktree
+(default: the one containing the current directory) as shell assignments, or as a JSON object with --json.
+
+  eval "$(wtm env --export api)"`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.JSON && opts.Export {
+				return fmt.Errorf("--json and --export cannot be used together")
+			}
+			var name string
+			if len(args) > 0 {
+				name = args[0]
+			}
+			return PrintEnv(cmd.Context(), name, opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print a JSON object instead of shell assignments")
+	cmd.Flags().BoolVar(&opts.Export, "export", false, "Prefix each assignment with export")
+
+	return cmd
+}
+
+func newComposeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "compose <name> [-- args...]",
+		Short: "Run docker compose in a worktree with its own project name",
+		Long: `Run docker compose inside a worktree with COMPOSE_PROJECT_NAME set from compose.projectName
+(default: <repository directory>-<worktree name>), so containers, networks and volumes of different
+worktrees do not clobber each other. The WTM_* variables of wtm env are set too.`,
+		Example:           "  wtm compose api -- up -d\n  wtm compose api -- logs -f web",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dash := cmd.ArgsLenAtDash(); dash > 1 || (dash < 0 && len(args) > 1) {
+				return fmt.Errorf("pass docker compose arguments after --")
+			}
+			return RunCompose(cmd.Context(), args[0], args[1:])
+		},
+	}
+}
+
+func newOpenCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "open <name>",
+		Short:             "Open a worktree in the configured editor",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := OpenWorktree(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func newArchiveCmd() *cobra.Command {
+	var opts ArchiveOptions
+
+	cmd := &cobra.Command{
+		Use:   "archive <name>",
+		Short: "Export a worktree as a tarball or zip",
+		Long: `Export a worktree as a tarball or zip, e.g. before removing experimental work.
+
+By default the committed HEAD is archived with git archive. With --untracked the working tree
+is archived as it is, including uncommitted changes and untracked files that are not ignored
+by .gitignore or .wtmignore.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := useRepoRef(args[0])
+			if err != nil {
+				return err
+			}
+			return ArchiveWorktree(cmd.Context(), name, opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "", "Archive file; .tar.gz, .tgz, .tar or .zip (default <name>.tar.gz)")
+	cmd.Flags().BoolVar(&opts.Untracked, "untracked", false, "Archive the working tree including uncommitted changes and untracked files")
+
+	return cmd
+}
+
+func newTmuxCmd() *cobra.Command {
+	var window bool
+
+	cmd := &cobra.Command{
+		Use:               "tmux <name>",
+		Short:             "Create or attach to a tmux session for a worktree",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := TmuxWorktree(cmd.Context(), args[0], window); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&window, "window", "w", false, "Use a window in the current tmux session instead of a separate session")
+
+	return cmd
+}
+
+func newRunCmd() *cobra.Command {
+	var all bool
+	var foreachOpts ForeachOptions
+
+	cmd := &cobra.Command{
+		Use:   "run [task] [name] [-- args...]",
+		Short: "Run a configured task in a worktree",
+		Long: "Run a task from the [tasks] table of the config file inside the named worktree,\n" +
+			"or the worktree containing the current directory. Without arguments, list the tasks.",
+		Example:           "  wtm run test api\n  wtm run test -- -run TestAdd\n  wtm run test --all --parallel 4",
+		ValidArgsFunction: completeTaskArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var extra []string
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				args, extra = args[:dash], args[dash:]
+			}
+			if len(args) > 2 {
+				return fmt.Errorf("accepts at most 2 arg(s) before --, received %d", len(args))
+			}
+
+			if all {
+				if len(args) != 1 {
+					return fmt.Errorf("--all requires exactly one task and no worktree name")
+				}
+				return RunTaskAll(cmd.Context(), args[0], extra, foreachOpts)
+			}
+
+			switch len(args) {
+			case 0:
+				return ListTasks(cmd.Context())
+			case 1:
+				return RunTask(cmd.Context(), args[0], "", extra)
+			default:
+				return RunTask(cmd.Context(), args[0], args[1], extra)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&all, "all", "a", false, "Run the task in every worktree")
+	addForeachFlags(cmd, &foreachOpts)
+
+	return cmd
+}
+
+func newExecCmd() *cobra.Command {
+	var all bool
+	var opts ForeachOptions
+
+	cmd := &cobra.Command{
+		Use:   "exec <name> -- <command> [args...]",
+		Short: "Run a command in a worktree",
+		Long: `Run a program inside a worktree, without a shell, with the WTM_* variables of wtm env set.
+Use . for the worktree containing the current directory, or - for the previously used one.
+
+With --all, the program runs in every worktree created by wtm and a JSON array with the name,
+exitCode, stdout, stderr and duration (in seconds) of each run is printed; wtm exits non-zero
+when it failed in any worktree.`,
+		Example:           "  wtm exec api -- go test ./...\n  wtm exec . -- git status --short\n  wtm exec --all -j 4 -- go vet ./...",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeWorktreeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all {
+				if cmd.ArgsLenAtDash() != 0 {
+					return fmt.Errorf("--all takes no worktree name; pass the command after --")
+				}
+				return ExecAll(cmd.Context(), args, opts)
+			}
+			if opts.IncludePrimary || opts.Parallel != 1 {
+				return fmt.Errorf("--include-primary and --parallel require --all")
+			}
+			if cmd.ArgsLenAtDash() != 1 || len(args) < 2 {
+				return fmt.Errorf("pass the worktree name, then the command after --")
+			}
+			return ExecInWorktree(cmd.Context(), args[0], args[1:])
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Run in every worktree and print the results as JSON")
+	cmd.Flags().BoolVar(&opts.IncludePrimary, "include-primary", false, "With --all, also run in the primary worktree")
+	cmd.Flags().IntVarP(&opts.Parallel, "parallel", "j", 1, "With --all, number of worktrees to run in concurrently")
+
+	return cmd
+}
+
+func newForeachCmd() *cobra.Command {
+	var opts ForeachOptions
+
+	cmd := &cobra.Command{
+		Use:   "foreach -- <command> [args...]",
+		Short: "Run a command in every worktree",
+		Long: "Run a command in every worktree created by wtm, prefixing output with the worktree name\n" +
+			"and reporting a summary of exit codes. A single argument is interpreted by the shell.",
+		Example: "  wtm foreach -- git status --short\n  wtm foreach -- 'git fetch && git status -sb'",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Foreach(cmd.Context(), foreachCommand(args), opts)
+		},
+	}
+
+	addForeachFlags(cmd, &opts)
+
+	return cmd
+}
+
+func addForeachFlags(cmd *cobra.Command, opts *ForeachOptions) {
+	cmd.Flags().BoolVar(&opts.IncludePrimary, "include-primary", false, "Also run in the primary worktree")
+	cmd.Flags().IntVarP(&opts.Parallel, "parallel", "j", 1, "Number of worktrees to process concurrently")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print an aggregate JSON report instead of prefixed output")
+}
+
+// foreachCommand turns command-line arguments into a shell command: a single argument is used
+// verbatim so it may contain shell syntax, several arguments are quoted individually
+func foreachCommand(args []string) string {
+	if len(args) == 1 {
+		return args[0]
+	}
+	return shellJoin(args)
+}
+
+func newCleanCmd() *cobra.Command {
+	var prMerged bool
+	var force bool
+	var deleteBranch bool
+	var deleteBranchForce bool
+
+	cmd := &cobra.Command{
+		Use:         "clean",
+		Short:       "Remove worktrees whose branches are merged",
+		Annotations: dryRunAnnotation,
+		Args:        cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := CleanOptions{PRMerged: prMerged, Force: force}
+			switch {
+			case deleteBranch:
+				opts.BranchDelete = BranchDeleteSafe
+			case deleteBranchForce:
+				opts.BranchDelete = BranchDeleteForce
+			}
+
+			if err := CleanWorktrees(cmd.Context(), opts); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&prMerged, "pr-merged", false, "Select worktrees whose GitHub pull request is merged or closed (uses gh or GITHUB_TOKEN)")
+	cmd.Flags().BoolVar(&prMerged, "remote", false, "Alias for --pr-merged")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation")
+	cmd.Flags().BoolVarP(&deleteBranch, "delete-branch", "d", false, "Delete associated branch (git branch -d)")
+	cmd.Flags().BoolVarP(&deleteBranchForce, "delete-branch-force", "D", false, "Force delete associated branch (git branch -D)")
+	cmd.MarkFlagsMutuallyExclusive("delete-branch", "delete-branch-force")
+
+	return cmd
+}
+
+func newPlanCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "plan <command> [args...]",
+		Short: "Print the git commands and file operations a command would run",
+		Long: "Run a mutating command (add, remove, clean, prune, gc, init) in plan mode: repository state is read to resolve\n" +
+			"names and paths, but every git command, hook and file operation is printed in order instead of executed.",
+		Example:            "  wtm plan add feature-auth --base main\n  wtm plan remove 'spike-*' -D",
+		Args:               cobra.MinimumNArgs(1),
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[0] == "-h" || args[0] == "--help" {
+				return cmd.Help()
+			}
+			root := newRootCmd()
+			if target, _, err := root.Find(args); err != nil || target == root || !supportsDryRun(target) {
+				return fmt.Errorf("cannot plan '%s': only add, remove, clean, prune, gc and init are supported", args[0])
+			}
+
+			p, err := runPlanned("wtm "+shellJoin(args), func() error {
+				root.SetArgs(args)
+				return root.ExecuteContext(cmd.Context())
+			})
+			if err != nil {
+				return err
+			}
+			printPlan(p)
+			return nil
+		},
+	}
+}
+
+// describeInvocation reconstructs the command line of cmd for plan output
+func describeInvocation(cmd *cobra.Command, args []string) string {
+	parts := append([]string{cmd.CommandPath()}, args...)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if f.Name == "dry-run" {
+			return
+		}
+		if f.Value.Type() == "bool" {
+			parts = append(parts, "--"+f.Name)
+			return
+		}
+		parts = append(parts, "--"+f.Name, f.Value.String())
+	})
+	return shellJoin(parts)
+}
+
+func newCloneCmd() *cobra.Command {
+	var opts CloneOptions
+
+	cmd := &cobra.Command{
+		Use:   "clone <url> [dir]",
+		Short: "Clone a repository and set it up for worktrees",
+		Long: "Clone a repository, create its worktree root and write a repository-local .wtm.toml.\n" +
+			"With --bare the repository is kept in <dir>/.bare and the default branch gets its own worktree\n" +
+			"next to it, so every branch is a sibling directory.",
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var dir string
+			if len(args) == 2 {
+				dir = args[1]
+			}
+			return CloneRepository(cmd.Context(), args[0], dir, opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Bare, "bare", false, "Clone as a bare repository with a worktree per branch")
+
+	return cmd
+}
+
+func newInitCmd() *cobra.Command {
+	var opts InitOptions
+
+	cmd := &cobra.Command{
+		Use:         "init",
+		Short:       "Set up the current repository for wtm",
+		Long:        "Create the worktree root, write a starter .wtm.toml and register the repository in the global registry.",
+		Args:        cobra.NoArgs,
+		Annotations: dryRunAnnotation,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return InitRepository(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Migrate, "migrate", false, "Move existing worktrees outside the worktree root into it")
+
+	return cmd
+}
+
+func newDoctorCmd() *cobra.Command {
+	var opts DoctorOptions
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that worktrees live under the worktree root of the configured layout",
+		Long: `Report worktrees outside the worktree root, such as those left behind after switching
+between the nested and sibling layouts. --fix moves them into the root with git worktree move.`,
+		Example:     "  wtm doctor\n  wtm doctor --fix --dry-run",
+		Args:        cobra.NoArgs,
+		Annotations: dryRunAnnotation,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Doctor(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Fix, "fix", false, "Move worktrees outside the worktree root into it")
+
+	return cmd
+}
+
+func newAdoptCmd() *cobra.Command {
+	var opts AdoptOptions
+
+	cmd := &cobra.Command{
+		Use:   "adopt <path>",
+		Short: "Bring a worktree created with git worktree add under wtm management",
+		Long: `Bring a worktree created with plain git worktree add under wtm management. Its name is its
+directory name; --name with --move gives it another one by moving it into the worktree root.
+The base branch and labels are recorded, and a port is allocated when [ports] is configured.`,
+		Example:     "  wtm adopt ../hotfix\n  wtm adopt ~/src/api-old --move --name api-legacy --base main",
+		Args:        cobra.ExactArgs(1),
+		Annotations: dryRunAnnotation,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return AdoptWorktree(cmd.Context(), args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Name, "name", "", "Name for the worktree (requires --move unless it is the directory name)")
+	cmd.Flags().BoolVar(&opts.Move, "move", false, "Move the worktree into the worktree root")
+	cmd.Flags().StringVar(&opts.Base, "base", "", "Record the branch the worktree is compared and rebased against")
+	cmd.Flags().StringSliceVar(&opts.Labels, "label", nil, "Tag the worktree for wtm list --group-by label (repeatable or comma-separated)")
+	registerFlagCompletion(cmd, "base", completeBranches)
+
+	return cmd
+}
+
+func newPruneCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:         "prune",
+		Short:       "Prune metadata of worktrees whose directories are gone",
+		Args:        cobra.NoArgs,
+		Annotations: dryRunAnnotation,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := PruneWorktrees(cmd.Context()); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func newGCCmd() *cobra.Command {
+	var opts GCOptions
+
+	cmd := &cobra.Command{
+		Use:         "gc",
+		Short:       "Prune stale metadata and remove merged worktrees without local changes",
+		Args:        cobra.NoArgs,
+		Annotations: dryRunAnnotation,
+		Long: `Prune stale metadata, delete orphaned worktree directories, and remove merged worktrees
+without local changes as well as worktrees created with wtm add --ephemeral. Everything is listed
+and deleted only after confirmation.
+
+An orphaned directory is one whose .git file points into this repository's worktrees directory
+but that git no longer knows about. It is only deleted when git stores the contents of all its files.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := collectGarbage(cmd.Context(), opts); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.Force, "force", "f", false, "Skip confirmation")
+
+	return cmd
+}
+
 func newVersionCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
@@ -162,16 +1159,45 @@ func newVersionCmd() *cobra.Command {
 }
 
 func newMCPCmd() *cobra.Command {
-	return &cobra.Command{
+	var httpAddr string
+	var httpOpts MCPHTTPOptions
+	var allowRepos []string
+
+	cmd := &cobra.Command{
 		Use:   "mcp",
 		Short: "Start MCP server",
-		Args:  cobra.NoArgs,
+		Long: "Start the MCP server on stdio, or with --http as a long-running server that IDEs and remote agents\n" +
+			"connect to at /mcp (streamable HTTP) or /sse (legacy SSE). Metrics are served at /metrics,\n" +
+			"and /healthz answers liveness probes.\n\n" +
+			"Over HTTP, set a bearer token with --token, $WTM_MCP_TOKEN or mcp.token so that only clients\n" +
+			"sending \"Authorization: Bearer <token>\" are served, and --tls-cert/--tls-key to serve HTTPS.\n\n" +
+			"Tools select another repository with repoPath only inside the client's roots, or inside the\n" +
+			"server's repository for clients without roots; --allow-repo allows more directories.",
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := context.Background()
+			ctx := cmd.Context()
+			if err := allowMCPRepos(allowRepos); err != nil {
+				return err
+			}
+			if httpAddr != "" {
+				opts, err := resolveMCPHTTPOptions(httpOpts)
+				if err != nil {
+					return err
+				}
+				return StartMCPHTTPServer(ctx, httpAddr, opts)
+			}
 			if err := StartMCPServer(ctx); err != nil {
 				return err
 			}
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVar(&httpAddr, "http", "", "Serve over HTTP on this address (e.g. :8080) instead of stdio")
+	cmd.Flags().StringVar(&httpOpts.Token, "token", "", "Require this bearer token from HTTP clients (default: $"+mcpTokenEnv+" or mcp.token)")
+	cmd.Flags().StringVar(&httpOpts.CertFile, "tls-cert", "", "Serve HTTPS with this certificate file (default: mcp.tlsCert)")
+	cmd.Flags().StringVar(&httpOpts.KeyFile, "tls-key", "", "Serve HTTPS with this key file (default: mcp.tlsKey)")
+	cmd.Flags().StringSliceVar(&allowRepos, "allow-repo", nil, "Let the repoPath of tools select repositories under this directory (repeatable)")
+
+	return cmd
 }
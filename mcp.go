@@ -1,19 +1,30 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
 
+	"github.com/choplin/wtm/pkg/wtm"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // Tool input/output structures
 
 type AddWorktreeInput struct {
-	Name     string `json:"name" jsonschema:"name of the worktree (used as directory name)"`
-	Branch   string `json:"branch,omitempty" jsonschema:"create new branch with this name (default: same as worktree name)"`
-	Checkout string `json:"checkout,omitempty" jsonschema:"use existing branch with this name"`
-	Base     string `json:"base,omitempty" jsonschema:"base branch for new branch (default: current HEAD)"`
+	RepoInput
+	Name       string `json:"name,omitempty" jsonschema:"name of the worktree (used as directory name; default: last path segment of branch or checkout)"`
+	Branch     string `json:"branch,omitempty" jsonschema:"create new branch with this name (default: same as worktree name)"`
+	Checkout   string `json:"checkout,omitempty" jsonschema:"use existing branch with this name"`
+	Base       string `json:"base,omitempty" jsonschema:"base branch for new branch (default: current HEAD)"`
+	Detach     string `json:"detach,omitempty" jsonschema:"check out this commit, tag or ref in detached HEAD mode instead of a branch"`
+	ReadOnly   bool   `json:"readOnly,omitempty" jsonschema:"remove write permissions for review or bisect checkouts"`
+	NoCheckout bool   `json:"noCheckout,omitempty" jsonschema:"create the worktree without checking out files"`
 }
 
 type AddWorktreeOutput struct {
@@ -22,13 +33,16 @@ type AddWorktreeOutput struct {
 	Path   string `json:"path" jsonschema:"absolute path to the worktree"`
 }
 
-type ListWorktreesInput struct{}
+type ListWorktreesInput struct {
+	RepoInput
+}
 
 type ListWorktreesOutput struct {
 	Worktrees []Worktree `json:"worktrees" jsonschema:"list of all worktrees"`
 }
 
 type ShowWorktreeInput struct {
+	RepoInput
 	Name string `json:"name" jsonschema:"name of the worktree to show"`
 }
 
@@ -38,11 +52,17 @@ type ShowWorktreeOutput struct {
 
 // RemoveWorktreeInput mirrors CLI options for removing a worktree
 type RemoveWorktreeInput struct {
+	RepoInput
 	Name string `json:"name" jsonschema:"name of the worktree to remove"`
 	// DeleteBranch requests safe branch deletion (git branch -d) after removal
 	DeleteBranch bool `json:"deleteBranch,omitempty" jsonschema:"delete associated branch using git branch -d"`
 	// DeleteBranchForce requests forceful branch deletion (git branch -D) after removal
 	DeleteBranchForce bool `json:"deleteBranchForce,omitempty" jsonschema:"force delete associated branch using git branch -D"`
+	// Force skips the confirmation that is otherwise requested from the user through MCP elicitation
+	Force bool `json:"force,omitempty" jsonschema:"remove without asking the user for confirmation"`
+	// Stash and DiscardChanges decide what happens to uncommitted changes; without either, removal of a dirty worktree fails
+	Stash          bool `json:"stash,omitempty" jsonschema:"save uncommitted and untracked changes with git stash before removing"`
+	DiscardChanges bool `json:"discardChanges,omitempty" jsonschema:"remove even if the worktree has uncommitted or untracked changes"`
 }
 
 type RemoveWorktreeOutput struct {
@@ -50,35 +70,84 @@ type RemoveWorktreeOutput struct {
 	Message string `json:"message" jsonschema:"result message"`
 }
 
+// CollectGarbageInput is shared by wtm_prune and wtm_gc
+type CollectGarbageInput struct {
+	RepoInput
+	DryRun  bool `json:"dryRun,omitempty" jsonschema:"only list the candidates"`
+	Confirm bool `json:"confirm,omitempty" jsonschema:"delete the candidates; without it the candidates are only listed"`
+}
+
+type CollectGarbageOutput struct {
+	Candidates []GCCandidate `json:"candidates" jsonschema:"stale records, orphaned directories, merged and ephemeral worktrees selected for deletion"`
+	Removed    bool          `json:"removed" jsonschema:"whether the candidates were deleted"`
+	Message    string        `json:"message" jsonschema:"result message"`
+}
+
+type DiffWorktreeInput struct {
+	RepoInput
+	Name        string `json:"name" jsonschema:"name of the worktree"`
+	Base        string `json:"base,omitempty" jsonschema:"branch or revision to compare against (default: the worktree's recorded base, else the primary worktree's branch)"`
+	Uncommitted bool   `json:"uncommitted,omitempty" jsonschema:"include uncommitted changes in the working tree"`
+	MaxBytes    int    `json:"maxBytes,omitempty" jsonschema:"truncate the diff after this many bytes (default: 102400)"`
+}
+
+type LogWorktreeInput struct {
+	RepoInput
+	Name  string `json:"name" jsonschema:"name of the worktree"`
+	Base  string `json:"base,omitempty" jsonschema:"branch or revision to compare against (default: the worktree's recorded base, else the primary worktree's branch)"`
+	Limit int    `json:"limit,omitempty" jsonschema:"maximum number of commits to return (default: 100)"`
+}
+
+type OpenWorktreeInput struct {
+	RepoInput
+	Name   string `json:"name" jsonschema:"name of the worktree to open"`
+	Launch bool   `json:"launch,omitempty" jsonschema:"also start the editor from the open.command config on the machine running wtm"`
+}
+
+type OpenWorktreeOutput struct {
+	Path      string `json:"path" jsonschema:"absolute path to the worktree"`
+	FileURI   string `json:"fileUri" jsonschema:"file:// URI of the worktree directory"`
+	VSCodeURI string `json:"vscodeUri" jsonschema:"vscode://file URI that opens the worktree in VS Code"`
+	Launched  bool   `json:"launched" jsonschema:"whether the editor was started"`
+	Command   string `json:"command,omitempty" jsonschema:"editor command that was started"`
+}
+
+type ExecWorktreeInput struct {
+	RepoInput
+	Name    string   `json:"name" jsonschema:"name of the worktree to run the command in"`
+	Command []string `json:"command" jsonschema:"program and arguments, run without a shell; must match an entry of mcp.execAllow"`
+}
+
+type ExecWorktreeOutput struct {
+	Stdout   string `json:"stdout" jsonschema:"standard output of the command"`
+	Stderr   string `json:"stderr" jsonschema:"standard error of the command"`
+	ExitCode int    `json:"exitCode" jsonschema:"exit code of the command"`
+}
+
 // Tool handlers
 
 func handleAddWorktree(ctx context.Context, req *mcp.CallToolRequest, input AddWorktreeInput) (*mcp.CallToolResult, AddWorktreeOutput, error) {
-	err := AddWorktree(input.Name, input.Branch, input.Checkout, input.Base)
+	wt, err := addWorktree(ctx, input.Name, AddOptions{
+		Branch:     input.Branch,
+		Checkout:   input.Checkout,
+		Base:       input.Base,
+		Detach:     input.Detach,
+		ReadOnly:   input.ReadOnly,
+		NoCheckout: input.NoCheckout,
+	})
 	if err != nil {
 		return nil, AddWorktreeOutput{}, fmt.Errorf("failed to add worktree: %w", err)
 	}
 
-	// Get the created worktree info
-	worktrees, err := getWorktrees()
-	if err != nil {
-		return nil, AddWorktreeOutput{}, fmt.Errorf("failed to get worktree info: %w", err)
-	}
-
-	for _, wt := range worktrees {
-		if wt.Name == input.Name {
-			return nil, AddWorktreeOutput{
-				Name:   wt.Name,
-				Branch: wt.Branch,
-				Path:   wt.Path,
-			}, nil
-		}
-	}
-
-	return nil, AddWorktreeOutput{}, fmt.Errorf("worktree created but not found")
+	return nil, AddWorktreeOutput{
+		Name:   wt.Name,
+		Branch: wt.Branch,
+		Path:   wt.Path,
+	}, nil
 }
 
 func handleListWorktrees(ctx context.Context, req *mcp.CallToolRequest, input ListWorktreesInput) (*mcp.CallToolResult, ListWorktreesOutput, error) {
-	worktrees, err := getWorktrees()
+	worktrees, err := getWorktrees(ctx)
 	if err != nil {
 		return nil, ListWorktreesOutput{}, fmt.Errorf("failed to list worktrees: %w", err)
 	}
@@ -87,30 +156,21 @@ func handleListWorktrees(ctx context.Context, req *mcp.CallToolRequest, input Li
 }
 
 func handleShowWorktree(ctx context.Context, req *mcp.CallToolRequest, input ShowWorktreeInput) (*mcp.CallToolResult, ShowWorktreeOutput, error) {
-	worktrees, err := getWorktrees()
+	wt, err := findWorktree(ctx, input.Name)
 	if err != nil {
-		return nil, ShowWorktreeOutput{}, fmt.Errorf("failed to get worktrees: %w", err)
-	}
-
-	for _, wt := range worktrees {
-		if wt.Name == input.Name {
-			return nil, ShowWorktreeOutput{Worktree: wt}, nil
-		}
+		return nil, ShowWorktreeOutput{}, err
 	}
 
-	return nil, ShowWorktreeOutput{}, fmt.Errorf("worktree '%s' not found", input.Name)
+	return nil, ShowWorktreeOutput{Worktree: *wt}, nil
 }
 
 func handleRemoveWorktree(ctx context.Context, req *mcp.CallToolRequest, input RemoveWorktreeInput) (*mcp.CallToolResult, RemoveWorktreeOutput, error) {
 	if input.DeleteBranch && input.DeleteBranchForce {
-		return nil, RemoveWorktreeOutput{
-			Removed: false,
-			Message: "Cannot combine deleteBranch and deleteBranchForce options",
-		}, nil
+		return nil, RemoveWorktreeOutput{}, errors.New("cannot combine deleteBranch and deleteBranchForce options")
 	}
 
-	// MCP runs non-interactively, so we always force removal
-	opts := RemoveOptions{Force: true}
+	// The server cannot read stdin, so confirmation goes through the client and removal itself is forced
+	opts := RemoveOptions{Force: true, Stash: input.Stash, DiscardChanges: input.DiscardChanges}
 	switch {
 	case input.DeleteBranch:
 		opts.BranchDelete = BranchDeleteSafe // safe deletion mirrors git branch -d
@@ -118,12 +178,37 @@ func handleRemoveWorktree(ctx context.Context, req *mcp.CallToolRequest, input R
 		opts.BranchDelete = BranchDeleteForce // force deletion mirrors git branch -D
 	}
 
-	err := RemoveWorktree(input.Name, opts)
+	target, err := findWorktree(ctx, input.Name)
 	if err != nil {
-		return nil, RemoveWorktreeOutput{
-			Removed: false,
-			Message: fmt.Sprintf("Failed to remove worktree: %v", err),
-		}, nil
+		return nil, RemoveWorktreeOutput{}, err
+	}
+	if !input.Force {
+		prompt := fmt.Sprintf("Remove worktree %s", target.Name)
+		if opts.BranchDelete != BranchDeleteNone && target.Branch != "" {
+			prompt = fmt.Sprintf("%s and delete branch %s", prompt, target.Branch)
+		}
+		ok, err := elicitConfirmation(ctx, req, prompt+"?")
+		if err != nil {
+			return nil, RemoveWorktreeOutput{}, err
+		}
+		if !ok {
+			return nil, RemoveWorktreeOutput{
+				Removed: false,
+				Message: "Removal was not confirmed",
+			}, nil
+		}
+	}
+
+	err = RemoveWorktree(ctx, input.Name, opts)
+	if errors.Is(err, wtm.ErrBranchNotMerged) {
+		// The worktree itself is gone; only git branch -d refused
+		te := newToolError(fmt.Errorf("removed worktree '%s' but kept branch '%s' (use deleteBranchForce to delete it): %w", input.Name, target.Branch, err))
+		te.Details["branch"] = target.Branch
+		te.Details["worktreeRemoved"] = true
+		return nil, RemoveWorktreeOutput{}, te
+	}
+	if err != nil {
+		return nil, RemoveWorktreeOutput{}, fmt.Errorf("failed to remove worktree: %w", err)
 	}
 
 	message := fmt.Sprintf("Removed worktree: %s", input.Name)
@@ -137,40 +222,329 @@ func handleRemoveWorktree(ctx context.Context, req *mcp.CallToolRequest, input R
 	}, nil
 }
 
-// StartMCPServer starts the MCP server over stdio transport
+func handleDiffWorktree(ctx context.Context, req *mcp.CallToolRequest, input DiffWorktreeInput) (*mcp.CallToolResult, WorktreeDiff, error) {
+	wt, err := findWorktree(ctx, input.Name)
+	if err != nil {
+		return nil, WorktreeDiff{}, err
+	}
+	diff, err := worktreeDiff(ctx, wt, input.Base, input.Uncommitted, input.MaxBytes)
+	if err != nil {
+		return nil, WorktreeDiff{}, err
+	}
+	return nil, *diff, nil
+}
+
+func handleLogWorktree(ctx context.Context, req *mcp.CallToolRequest, input LogWorktreeInput) (*mcp.CallToolResult, WorktreeLog, error) {
+	wt, err := findWorktree(ctx, input.Name)
+	if err != nil {
+		return nil, WorktreeLog{}, err
+	}
+	log, err := worktreeLog(ctx, wt, input.Base, input.Limit)
+	if err != nil {
+		return nil, WorktreeLog{}, err
+	}
+	return nil, *log, nil
+}
+
+func handleOpenWorktree(ctx context.Context, req *mcp.CallToolRequest, input OpenWorktreeInput) (*mcp.CallToolResult, OpenWorktreeOutput, error) {
+	wt, err := findWorktree(ctx, input.Name)
+	if err != nil {
+		return nil, OpenWorktreeOutput{}, err
+	}
+	output := OpenWorktreeOutput{Path: wt.Path}
+	output.FileURI, output.VSCodeURI = editorURIs(wt.Path)
+	if input.Launch {
+		if output.Command, err = launchEditor(ctx, wt); err != nil {
+			return nil, OpenWorktreeOutput{}, err
+		}
+		output.Launched = true
+	}
+	return nil, output, nil
+}
+
+// elicitConfirmation asks the user of the MCP client to confirm a destructive action.
+// Clients without elicitation support get an error telling the agent to pass force instead.
+func elicitConfirmation(ctx context.Context, req *mcp.CallToolRequest, message string) (bool, error) {
+	if req == nil || req.Session == nil || !supportsElicitation(req.Session.InitializeParams()) {
+		return false, fmt.Errorf("cannot ask for confirmation: the client does not support elicitation; set force=true to proceed")
+	}
+
+	result, err := req.Session.Elicit(ctx, &mcp.ElicitParams{
+		Message:         message,
+		RequestedSchema: map[string]any{"type": "object", "properties": map[string]any{}},
+	})
+	if err != nil {
+		return false, fmt.Errorf("confirmation request failed: %w", err)
+	}
+	return result.Action == "accept", nil
+}
+
+func supportsElicitation(params *mcp.InitializeParams) bool {
+	return params != nil && params.Capabilities != nil && params.Capabilities.Elicitation != nil
+}
+
+// collectGarbageHandler builds a two-step handler: the first call lists what find selects,
+// and a call with confirm deletes it
+func collectGarbageHandler(find func(context.Context) ([]GCCandidate, error)) mcp.ToolHandlerFor[CollectGarbageInput, CollectGarbageOutput] {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input CollectGarbageInput) (*mcp.CallToolResult, CollectGarbageOutput, error) {
+		candidates, err := find(ctx)
+		if err != nil {
+			return nil, CollectGarbageOutput{}, err
+		}
+		output := CollectGarbageOutput{Candidates: candidates}
+		if output.Candidates == nil {
+			output.Candidates = []GCCandidate{}
+		}
+
+		switch {
+		case len(candidates) == 0:
+			output.Message = "Nothing to collect"
+		case input.DryRun || !input.Confirm:
+			output.Message = fmt.Sprintf("%d candidate(s) found; call again with confirm=true to delete them", len(candidates))
+		default:
+			if err := deleteGarbage(ctx, candidates); err != nil {
+				return nil, CollectGarbageOutput{}, err
+			}
+			output.Removed = true
+			output.Message = fmt.Sprintf("Deleted %d candidate(s)", len(candidates))
+		}
+		return nil, output, nil
+	}
+}
+
+func handleExecWorktree(ctx context.Context, req *mcp.CallToolRequest, input ExecWorktreeInput) (*mcp.CallToolResult, ExecWorktreeOutput, error) {
+	if len(input.Command) == 0 {
+		return nil, ExecWorktreeOutput{}, fmt.Errorf("command is required")
+	}
+	cfg, err := loadMCPConfig()
+	if err != nil {
+		return nil, ExecWorktreeOutput{}, err
+	}
+	if err := checkMCPExec(cfg, input.Command); err != nil {
+		return nil, ExecWorktreeOutput{}, err
+	}
+	wt, err := findWorktree(ctx, input.Name)
+	if err != nil {
+		return nil, ExecWorktreeOutput{}, err
+	}
+
+	markUsed(ctx, wt)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, input.Command[0], input.Command[1:]...)
+	cmd.Dir = wt.Path
+	if err := setWorktreeEnv(ctx, cmd, wt); err != nil {
+		return nil, ExecWorktreeOutput{}, err
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+
+	output := ExecWorktreeOutput{Stdout: stdout.String(), Stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		// A failing command is a result for the agent to inspect, not a tool error
+		output.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return nil, ExecWorktreeOutput{}, fmt.Errorf("failed to run command: %w", err)
+	}
+	return nil, output, nil
+}
+
+// mcpShutdownTimeout bounds how long the HTTP server waits for in-flight requests after a signal
+const mcpShutdownTimeout = 5 * time.Second
+
+// StartMCPServer starts the MCP server over stdio transport. Cancelling ctx closes the session
+// and is a clean shutdown, not an error.
 func StartMCPServer(ctx context.Context) error {
 	server := newMCPServer()
 
 	// Run server over stdio transport
 	transport := &mcp.StdioTransport{}
-	return server.Run(ctx, transport)
+	if err := server.Run(ctx, transport); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// StartMCPHTTPServer serves MCP over the streamable HTTP transport on addr until ctx is cancelled,
+// then stops accepting connections and lets in-flight requests finish for up to mcpShutdownTimeout
+func StartMCPHTTPServer(ctx context.Context, addr string, opts MCPHTTPOptions) error {
+	httpServer := &http.Server{
+		Addr:    addr,
+		Handler: newMCPHTTPHandler(newMCPServer(), opts.Token),
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mcpShutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			// Long-lived event streams never go idle; cut them off once the grace period is over
+			httpServer.Close()
+		}
+	}()
+
+	scheme := "http"
+	if opts.CertFile != "" {
+		scheme = "https"
+	}
+	fmt.Fprintf(os.Stderr, "wtm MCP server listening on %s (%s, endpoint /mcp)\n", addr, scheme)
+	if opts.Token == "" {
+		fmt.Fprintf(os.Stderr, "warning: no token set; anyone who can reach %s can manage worktrees (use --token or %s)\n", addr, mcpTokenEnv)
+	}
+
+	var err error
+	if opts.CertFile != "" {
+		err = httpServer.ListenAndServeTLS(opts.CertFile, opts.KeyFile)
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-stopped
+	return nil
+}
+
+// newMCPHTTPHandler routes /mcp to the streamable HTTP transport, /sse to the legacy SSE transport
+// for older clients, /metrics to the tool metrics and /healthz to a liveness check. All sessions
+// share one server. A non-empty token is required on every route except /healthz.
+func newMCPHTTPHandler(server *mcp.Server, token string) http.Handler {
+	getServer := func(*http.Request) *mcp.Server { return server }
+
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", mcp.NewStreamableHTTPHandler(getServer, nil))
+	mux.Handle("/sse", mcp.NewSSEHandler(getServer, nil))
+	mux.Handle("/metrics", metricsHandler(toolMetrics, server))
+
+	root := http.NewServeMux()
+	root.Handle("/healthz", healthHandler())
+	root.Handle("/", requireBearerToken(token, mux))
+	return root
+}
+
+// Tool annotations let clients choose a confirmation policy per tool. Apart from wtm_exec, the tools
+// only touch the local repository.
+var (
+	readOnlyTool = &mcp.ToolAnnotations{ReadOnlyHint: true, OpenWorldHint: hint(false)}
+	// additiveTool creates worktrees or starts an editor without changing existing work
+	additiveTool = &mcp.ToolAnnotations{DestructiveHint: hint(false), OpenWorldHint: hint(false)}
+	// destructiveTool deletes worktrees, branches or records; repeating the call deletes nothing more
+	destructiveTool = &mcp.ToolAnnotations{DestructiveHint: hint(true), IdempotentHint: true, OpenWorldHint: hint(false)}
+	// execTool runs an allowed command, which may do anything
+	execTool = &mcp.ToolAnnotations{DestructiveHint: hint(true)}
+)
+
+func hint(b bool) *bool {
+	return &b
 }
 
 func newMCPServer() *mcp.Server {
 	server := mcp.NewServer(&mcp.Implementation{
 		Name:    "wtm",
 		Version: version,
-	}, nil)
-
-	mcp.AddTool(server, &mcp.Tool{
+	}, &mcp.ServerOptions{
+		// Subscriptions are tracked by the SDK; mutating tools publish updates via notifyWorktreeChanged
+		SubscribeHandler:   func(context.Context, *mcp.SubscribeRequest) error { return nil },
+		UnsubscribeHandler: func(context.Context, *mcp.UnsubscribeRequest) error { return nil },
+		// Roots are listed once per session and again after the client reports a change
+		RootsListChangedHandler: handleRootsListChanged,
+	})
+
+	addTool(server, &mcp.Tool{
 		Name:        "wtm_add",
 		Description: "Create a new git worktree. Worktree name is used as directory identifier, independent from branch name.",
-	}, handleAddWorktree)
+		Annotations: additiveTool,
+	}, withResourceUpdates(server, func(in AddWorktreeInput) string { return in.Name }, handleAddWorktree))
 
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "wtm_list",
 		Description: "List all git worktrees in the current repository with their details.",
+		Annotations: readOnlyTool,
 	}, handleListWorktrees)
 
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "wtm_show",
 		Description: "Show detailed information about a specific worktree by name.",
+		Annotations: readOnlyTool,
 	}, handleShowWorktree)
 
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "wtm_remove",
 		Description: "Remove a git worktree by name. Use force flag to skip confirmation. Optionally delete the associated branch.",
-	}, handleRemoveWorktree)
+		Annotations: destructiveTool,
+	}, withResourceUpdates(server, func(in RemoveWorktreeInput) string { return in.Name }, handleRemoveWorktree))
+
+	addTool(server, &mcp.Tool{
+		Name:        "wtm_diff",
+		Description: "Show the diff of a worktree's branch against its base branch since they forked, with a diffstat. Large diffs are truncated at maxBytes.",
+		Annotations: readOnlyTool,
+	}, handleDiffWorktree)
+
+	addTool(server, &mcp.Tool{
+		Name:        "wtm_log",
+		Description: "List the commits of a worktree's branch that are not on its base branch, newest first, up to limit.",
+		Annotations: readOnlyTool,
+	}, handleLogWorktree)
+
+	addTool(server, &mcp.Tool{
+		Name:        "wtm_open",
+		Description: "Return a worktree's absolute path with file:// and vscode:// URIs to hand off to an editor. With launch=true, also start the editor from the open.command config.",
+		Annotations: additiveTool,
+	}, handleOpenWorktree)
+
+	addTool(server, &mcp.Tool{
+		Name:        "wtm_prune",
+		Description: "Find worktree records and metadata whose directories are gone. Returns the candidates; pass confirm=true to prune them.",
+		Annotations: destructiveTool,
+	}, withResourceUpdates(server, func(CollectGarbageInput) string { return "" }, collectGarbageHandler(findStaleWorktrees)))
+
+	addTool(server, &mcp.Tool{
+		Name:        "wtm_gc",
+		Description: "Find stale worktree records, orphaned worktree directories and clean worktrees whose branches are merged. Returns the candidates; pass confirm=true to delete them.",
+		Annotations: destructiveTool,
+	}, withResourceUpdates(server, func(CollectGarbageInput) string { return "" }, collectGarbageHandler(findGarbage)))
+
+	addTool(server, &mcp.Tool{
+		Name:        "wtm_exec",
+		Description: "Run a command in a worktree's directory and return its stdout, stderr and exit code. Only commands allowed by the mcp.execAllow config are accepted.",
+		Annotations: execTool,
+	}, handleExecWorktree)
+
+	registerResources(server)
 
 	return server
 }
+
+// addTool registers a tool handler with call metrics attached. Handlers see a context marked by
+// withMCPCall, so the commands they run are checked against the MCP allowlist.
+func addTool[In, Out any](server *mcp.Server, tool *mcp.Tool, h mcp.ToolHandlerFor[In, Out]) {
+	marked := func(ctx context.Context, req *mcp.CallToolRequest, input In) (res *mcp.CallToolResult, out Out, err error) {
+		var repoPath string
+		if sel, ok := any(input).(repoSelector); ok {
+			repoPath = sel.selectedRepo()
+		}
+		var session *mcp.ServerSession
+		if req != nil {
+			session = req.Session
+		}
+		err = inMCPRepo(ctx, session, repoPath, func(ctx context.Context) error {
+			var herr error
+			res, out, herr = h(withMCPCall(ctx), req, input)
+			return herr
+		})
+		if err != nil {
+			// Handlers may return a toolError with details of their own
+			var te *toolError
+			if !errors.As(err, &te) {
+				te = newToolError(err)
+			}
+			return nil, out, te
+		}
+		return res, out, nil
+	}
+	mcp.AddTool(server, tool, instrumentTool(toolMetrics, tool.Name, marked))
+}
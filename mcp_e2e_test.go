@@ -2,6 +2,12 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -51,8 +57,16 @@ func TestMCPToolsListInMemory(t *testing.T) {
 		"wtm_list":   "List all git worktrees in the current repository with their details.",
 		"wtm_remove": "Remove a git worktree by name. Use force flag to skip confirmation. Optionally delete the associated branch.",
 		"wtm_show":   "Show detailed information about a specific worktree by name.",
+		"wtm_diff":   "Show the diff of a worktree's branch against its base branch since they forked, with a diffstat. Large diffs are truncated at maxBytes.",
+		"wtm_log":    "List the commits of a worktree's branch that are not on its base branch, newest first, up to limit.",
+		"wtm_open":   "Return a worktree's absolute path with file:// and vscode:// URIs to hand off to an editor. With launch=true, also start the editor from the open.command config.",
+		"wtm_prune":  "Find worktree records and metadata whose directories are gone. Returns the candidates; pass confirm=true to prune them.",
+		"wtm_gc":     "Find stale worktree records, orphaned worktree directories and clean worktrees whose branches are merged. Returns the candidates; pass confirm=true to delete them.",
+		"wtm_exec":   "Run a command in a worktree's directory and return its stdout, stderr and exit code. Only commands allowed by the mcp.execAllow config are accepted.",
 	}
 
+	readOnlyTools := map[string]bool{"wtm_list": true, "wtm_show": true, "wtm_diff": true, "wtm_log": true}
+
 	if len(res.Tools) != len(expectedDescriptions) {
 		t.Fatalf("expected %d tools, got %d", len(expectedDescriptions), len(res.Tools))
 	}
@@ -65,10 +79,13 @@ func TestMCPToolsListInMemory(t *testing.T) {
 		if tool.Description != want {
 			t.Fatalf("tool %s description mismatch\nwant: %s\ngot:  %s", tool.Name, want, tool.Description)
 		}
+		if tool.Annotations == nil || tool.Annotations.ReadOnlyHint != readOnlyTools[tool.Name] {
+			t.Errorf("tool %s: expected readOnlyHint %v, got %+v", tool.Name, readOnlyTools[tool.Name], tool.Annotations)
+		}
 
 		switch tool.Name {
 		case "wtm_add":
-			assertSchemaPropertyDescription(t, tool.InputSchema, "name", "name of the worktree (used as directory name)")
+			assertSchemaPropertyDescription(t, tool.InputSchema, "name", "name of the worktree (used as directory name; default: last path segment of branch or checkout)")
 			assertSchemaPropertyDescription(t, tool.InputSchema, "branch", "create new branch with this name (default: same as worktree name)")
 			assertSchemaPropertyDescription(t, tool.InputSchema, "checkout", "use existing branch with this name")
 			assertSchemaPropertyDescription(t, tool.InputSchema, "base", "base branch for new branch (default: current HEAD)")
@@ -81,11 +98,20 @@ func TestMCPToolsListInMemory(t *testing.T) {
 		assertSchemaPropertyDescription(t, tool.InputSchema, "name", "name of the worktree to remove")
 		assertSchemaPropertyDescription(t, tool.InputSchema, "deleteBranch", "delete associated branch using git branch -d")
 		assertSchemaPropertyDescription(t, tool.InputSchema, "deleteBranchForce", "force delete associated branch using git branch -D")
+			if a := tool.Annotations; a.DestructiveHint == nil || !*a.DestructiveHint || !a.IdempotentHint {
+				t.Errorf("expected wtm_remove to be annotated destructive and idempotent, got %+v", a)
+			}
 			assertSchemaPropertyDescription(t, tool.OutputSchema, "removed", "whether the worktree was removed")
 			assertSchemaPropertyDescription(t, tool.OutputSchema, "message", "result message")
 		case "wtm_show":
 			assertSchemaPropertyDescription(t, tool.InputSchema, "name", "name of the worktree to show")
 			assertSchemaPropertyDescription(t, tool.OutputSchema, "worktree", "worktree details")
+		case "wtm_open":
+			assertSchemaPropertyDescription(t, tool.InputSchema, "launch", "also start the editor from the open.command config on the machine running wtm")
+			assertSchemaPropertyDescription(t, tool.OutputSchema, "vscodeUri", "vscode://file URI that opens the worktree in VS Code")
+		case "wtm_exec":
+			assertSchemaPropertyDescription(t, tool.InputSchema, "command", "program and arguments, run without a shell; must match an entry of mcp.execAllow")
+			assertSchemaPropertyDescription(t, tool.OutputSchema, "exitCode", "exit code of the command")
 		}
 	}
 }
@@ -109,3 +135,202 @@ func assertSchemaPropertyDescription(t *testing.T, schema any, key, want string)
 		t.Fatalf("property %s description mismatch\nwant: %s\ngot:  %s", key, want, desc)
 	}
 }
+
+func TestMCPOverStreamableHTTP(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	httpServer := httptest.NewServer(newMCPHTTPHandler(newMCPServer(), ""))
+	defer httpServer.Close()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "wtm-test-client", Version: "0.0.1"}, nil)
+	session, err := client.Connect(ctx, &mcp.StreamableClientTransport{Endpoint: httpServer.URL + "/mcp"}, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer session.Close()
+
+	res, err := session.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatalf("tools/list: %v", err)
+	}
+	if len(res.Tools) == 0 {
+		t.Fatal("expected tools over HTTP")
+	}
+
+	resp, err := http.Get(httpServer.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected /metrics to be served, got %s", resp.Status)
+	}
+}
+
+func TestHandleExecWorktree(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[mcp]\nexecAllow = [\"git status\", \"git rev-parse\"]\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	ctx := context.Background()
+	if _, err := captureStdout(t, func() error { return AddWorktree(ctx, "agent", AddOptions{}) }); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	_, out, err := handleExecWorktree(ctx, nil, ExecWorktreeInput{Name: "agent", Command: []string{"git", "rev-parse", "--abbrev-ref", "HEAD"}})
+	if err != nil {
+		t.Fatalf("handleExecWorktree failed: %v", err)
+	}
+	if out.ExitCode != 0 || strings.TrimSpace(out.Stdout) != "agent" {
+		t.Errorf("expected the command to run in the agent worktree, got %+v", out)
+	}
+
+	_, out, err = handleExecWorktree(ctx, nil, ExecWorktreeInput{Name: "agent", Command: []string{"git", "rev-parse", "--verify", "missing-ref"}})
+	if err != nil {
+		t.Fatalf("a failing command should not be a tool error: %v", err)
+	}
+	if out.ExitCode == 0 || out.Stderr == "" {
+		t.Errorf("expected a non-zero exit code and stderr, got %+v", out)
+	}
+
+	if _, _, err := handleExecWorktree(ctx, nil, ExecWorktreeInput{Name: "agent", Command: []string{"rm", "-rf", "."}}); err == nil {
+		t.Error("expected a command outside the allowlist to be rejected")
+	}
+	if _, _, err := handleExecWorktree(ctx, nil, ExecWorktreeInput{Name: "agent", Command: []string{"git"}}); err == nil {
+		t.Error("expected a partial match of an allowlist entry to be rejected")
+	}
+}
+
+func TestHandleOpenWorktree(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.toml"))
+	t.Setenv("EDITOR", "vi")
+	resetConfigCache()
+	defer resetConfigCache()
+
+	ctx := context.Background()
+	if _, err := captureStdout(t, func() error { return AddWorktree(ctx, "agent", AddOptions{}) }); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	_, out, err := handleOpenWorktree(ctx, nil, OpenWorktreeInput{Name: "agent"})
+	if err != nil {
+		t.Fatalf("handleOpenWorktree failed: %v", err)
+	}
+	if !filepath.IsAbs(out.Path) || out.Launched {
+		t.Errorf("expected the absolute path without launching, got %+v", out)
+	}
+	if !strings.HasPrefix(out.FileURI, "file:///") || !strings.HasPrefix(out.VSCodeURI, "vscode://file/") {
+		t.Errorf("unexpected URIs: %+v", out)
+	}
+
+	if _, _, err := handleOpenWorktree(ctx, nil, OpenWorktreeInput{Name: "agent", Launch: true}); err == nil {
+		t.Error("expected launching without open.command to fail instead of starting $EDITOR")
+	}
+}
+
+func TestRemoveToolElicitsConfirmation(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	connect := func(opts *mcp.ClientOptions) *mcp.ClientSession {
+		t.Helper()
+		serverTransport, clientTransport := mcp.NewInMemoryTransports()
+		if _, err := newMCPServer().Connect(ctx, serverTransport, nil); err != nil {
+			t.Fatalf("server connect: %v", err)
+		}
+		session, err := mcp.NewClient(&mcp.Implementation{Name: "wtm-test-client", Version: "0.0.1"}, opts).Connect(ctx, clientTransport, nil)
+		if err != nil {
+			t.Fatalf("client connect: %v", err)
+		}
+		return session
+	}
+	remove := func(session *mcp.ClientSession, args map[string]any) (RemoveWorktreeOutput, bool) {
+		t.Helper()
+		res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "wtm_remove", Arguments: args})
+		if err != nil {
+			t.Fatalf("wtm_remove: %v", err)
+		}
+		var out RemoveWorktreeOutput
+		if res.StructuredContent != nil {
+			data, _ := json.Marshal(res.StructuredContent)
+			_ = json.Unmarshal(data, &out)
+		}
+		return out, res.IsError
+	}
+
+	if _, err := captureStdout(t, func() error { return AddWorktree(ctx, "feature", AddOptions{}) }); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	var prompt string
+	answer := "decline"
+	eliciting := connect(&mcp.ClientOptions{
+		ElicitationHandler: func(ctx context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+			prompt = req.Params.Message
+			return &mcp.ElicitResult{Action: answer}, nil
+		},
+	})
+	defer eliciting.Close()
+
+	out, _ := remove(eliciting, map[string]any{"name": "feature", "deleteBranch": true})
+	if out.Removed {
+		t.Fatal("declined removal must keep the worktree")
+	}
+	if prompt != "Remove worktree feature and delete branch feature?" {
+		t.Errorf("unexpected confirmation prompt: %q", prompt)
+	}
+
+	plain := connect(nil)
+	defer plain.Close()
+	if _, isError := remove(plain, map[string]any{"name": "feature"}); !isError {
+		t.Error("expected an error when the client cannot confirm and force is not set")
+	}
+
+	answer = "accept"
+	if out, _ := remove(eliciting, map[string]any{"name": "feature"}); !out.Removed {
+		t.Errorf("expected accepted removal to succeed: %+v", out)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// mcpTokenEnv sets the bearer token of the HTTP MCP server when --token is not given
+const mcpTokenEnv = "WTM_MCP_TOKEN"
+
+// MCPHTTPOptions secures the HTTP MCP transport
+type MCPHTTPOptions struct {
+	// Token must be sent by clients as "Authorization: Bearer <token>"; empty accepts every request
+	Token string
+	// CertFile and KeyFile serve HTTPS instead of plain HTTP
+	CertFile string
+	KeyFile  string
+}
+
+// resolveMCPHTTPOptions fills the options not given as flags from WTM_MCP_TOKEN and the [mcp] table of the global config
+func resolveMCPHTTPOptions(opts MCPHTTPOptions) (MCPHTTPOptions, error) {
+	cfg, err := loadMCPConfig()
+	if err != nil {
+		return opts, err
+	}
+	if opts.Token == "" {
+		opts.Token = strings.TrimSpace(os.Getenv(mcpTokenEnv))
+	}
+	if opts.Token == "" {
+		opts.Token = cfg.Token
+	}
+	if opts.CertFile == "" && opts.KeyFile == "" {
+		opts.CertFile, opts.KeyFile = cfg.TLSCert, cfg.TLSKey
+	}
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return opts, errors.New("TLS needs both a certificate and a key (--tls-cert and --tls-key)")
+	}
+	return opts, nil
+}
+
+// requireBearerToken rejects requests that do not carry the token, unless token is empty
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, got, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		// Compare in constant time so the token cannot be guessed from response times
+		if !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(got)), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="wtm"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// bearerTransport adds an Authorization header to every request
+type bearerTransport struct {
+	token string
+}
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestMCPHTTPRequiresToken(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	httpServer := httptest.NewServer(newMCPHTTPHandler(newMCPServer(), "s3cret"))
+	defer httpServer.Close()
+
+	for _, header := range []string{"", "Bearer wrong", "Basic s3cret"} {
+		req, _ := http.NewRequest(http.MethodGet, httpServer.URL+"/metrics", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /metrics: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("Authorization %q: expected 401 with a challenge, got %d", header, resp.StatusCode)
+		}
+	}
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "wtm-test-client", Version: "0.0.1"}, nil)
+	if _, err := client.Connect(ctx, &mcp.StreamableClientTransport{Endpoint: httpServer.URL + "/mcp"}, nil); err == nil {
+		t.Error("expected a client without the token to be rejected")
+	}
+	session, err := client.Connect(ctx, &mcp.StreamableClientTransport{
+		Endpoint:   httpServer.URL + "/mcp",
+		HTTPClient: &http.Client{Transport: bearerTransport{token: "s3cret"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("client connect with token: %v", err)
+	}
+	defer session.Close()
+	if _, err := session.ListTools(ctx, nil); err != nil {
+		t.Fatalf("tools/list with token: %v", err)
+	}
+}
+
+func TestResolveMCPHTTPOptions(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[mcp]\ntoken = \"from-config\"\ntlsCert = \"cert.pem\"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	t.Setenv(mcpTokenEnv, "")
+
+	if _, err := resolveMCPHTTPOptions(MCPHTTPOptions{}); err == nil {
+		t.Error("expected a certificate without a key to be rejected")
+	}
+
+	opts, err := resolveMCPHTTPOptions(MCPHTTPOptions{CertFile: "a.pem", KeyFile: "a.key"})
+	if err != nil {
+		t.Fatalf("resolveMCPHTTPOptions failed: %v", err)
+	}
+	if opts.Token != "from-config" || opts.CertFile != "a.pem" {
+		t.Errorf("expected the config token and the flag certificate, got %+v", opts)
+	}
+
+	t.Setenv(mcpTokenEnv, "from-env")
+	opts, _ = resolveMCPHTTPOptions(MCPHTTPOptions{CertFile: "a.pem", KeyFile: "a.key"})
+	if opts.Token != "from-env" {
+		t.Errorf("expected %s to override the config, got %q", mcpTokenEnv, opts.Token)
+	}
+	opts, _ = resolveMCPHTTPOptions(MCPHTTPOptions{Token: "from-flag", CertFile: "a.pem", KeyFile: "a.key"})
+	if opts.Token != "from-flag" {
+		t.Errorf("expected --token to override the environment, got %q", opts.Token)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+// Codes of failed MCP tool calls; they classify errors the same way exitCode does for the CLI
+const (
+	codeNotFound       = "NOT_FOUND"
+	codeAlreadyExists  = "ALREADY_EXISTS"
+	codeUnmergedBranch = "UNMERGED_BRANCH"
+	codeDirtyWorktree  = "DIRTY_WORKTREE"
+	codeInvalidName    = "INVALID_NAME"
+	codeNotARepo       = "NOT_A_REPO"
+	codeGitFailed      = "GIT_FAILED"
+	codeCancelled      = "CANCELLED"
+	codeError          = "ERROR"
+)
+
+// toolError is a failed tool call in a form agents can act on. The client receives it as the text of
+// the error result: {"error":{"code":"NOT_FOUND","message":"...","details":{"name":"..."}}}
+type toolError struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+	err     error
+}
+
+func (e *toolError) Error() string {
+	data, err := json.Marshal(struct {
+		Error *toolError `json:"error"`
+	}{e})
+	if err != nil {
+		return e.Message
+	}
+	return string(data)
+}
+
+func (e *toolError) Unwrap() error {
+	return e.err
+}
+
+// newToolError classifies err and collects the names, suggestions and git output it carries
+func newToolError(err error) *toolError {
+	te := &toolError{Code: codeError, Message: err.Error(), err: err}
+	details := map[string]any{}
+
+	var wtErr *wtm.WorktreeError
+	if errors.As(err, &wtErr) {
+		details["name"] = wtErr.Name
+		if len(wtErr.Suggestions) > 0 {
+			details["suggestions"] = wtErr.Suggestions
+		}
+	}
+	var nameErr *wtm.NameError
+	if errors.As(err, &nameErr) {
+		details["name"] = nameErr.Name
+		details["reason"] = nameErr.Reason
+	}
+	var gitErr *wtm.GitError
+	if errors.As(err, &gitErr) {
+		details["command"] = "git " + wtm.ShellJoin(gitErr.Args)
+		if gitErr.Output != "" {
+			details["output"] = gitErr.Output
+		}
+	}
+	te.Details = details
+
+	switch {
+	case errors.Is(err, errAborted), errors.Is(err, context.Canceled):
+		te.Code = codeCancelled
+	case errors.Is(err, wtm.ErrWorktreeNotFound):
+		te.Code = codeNotFound
+	case errors.Is(err, wtm.ErrWorktreeExists), errors.Is(err, wtm.ErrBranchExists):
+		te.Code = codeAlreadyExists
+	case errors.Is(err, wtm.ErrBranchNotMerged):
+		te.Code = codeUnmergedBranch
+	case errors.Is(err, wtm.ErrWorktreeDirty):
+		te.Code = codeDirtyWorktree
+	case errors.Is(err, wtm.ErrInvalidName):
+		te.Code = codeInvalidName
+	case errors.Is(err, wtm.ErrNotARepo):
+		te.Code = codeNotARepo
+	case gitErr != nil:
+		te.Code = codeGitFailed
+	}
+	return te
+}
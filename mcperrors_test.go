@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/choplin/wtm/pkg/wtm"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestNewToolError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{fmt.Errorf("failed to remove worktree: %w", &wtm.WorktreeError{Name: "x", Err: wtm.ErrWorktreeNotFound}), codeNotFound},
+		{&wtm.WorktreeError{Name: "x", Err: wtm.ErrWorktreeExists}, codeAlreadyExists},
+		{&wtm.BranchError{Name: "x", Err: wtm.ErrBranchExists}, codeAlreadyExists},
+		{&wtm.WorktreeError{Name: "x", Err: wtm.ErrWorktreeDirty}, codeDirtyWorktree},
+		{&wtm.GitError{Args: []string{"branch", "-d", "x"}, Output: "error: the branch 'x' is not fully merged", Err: errors.New("exit status 1")}, codeUnmergedBranch},
+		{&wtm.GitError{Args: []string{"status"}, Err: errors.New("exit status 128")}, codeGitFailed},
+		{&wtm.NameError{Name: "a b", Reason: "contains whitespace"}, codeInvalidName},
+		{fmt.Errorf("repoPath '/tmp': %w", wtm.ErrNotARepo), codeNotARepo},
+		{context.Canceled, codeCancelled},
+		{errors.New("boom"), codeError},
+	}
+	for _, tt := range tests {
+		if got := newToolError(tt.err); got.Code != tt.code {
+			t.Errorf("newToolError(%v).Code = %s, want %s", tt.err, got.Code, tt.code)
+		}
+	}
+
+	te := newToolError(&wtm.GitError{Args: []string{"branch", "-d", "x"}, Output: "error: the branch 'x' is not fully merged", Err: errors.New("exit status 1")})
+	if te.Details["command"] != "git branch -d x" || te.Details["output"] == nil {
+		t.Errorf("expected the git command and output in the details, got %v", te.Details)
+	}
+}
+
+func TestMCPToolErrorResult(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.toml"))
+	resetConfigCache()
+	defer resetConfigCache()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := captureStdout(t, func() error { return AddWorktree(ctx, "feature", AddOptions{}) }); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := newMCPServer().Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "wtm-test-client", Version: "0.0.1"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer session.Close()
+
+	call := func(name string, args map[string]any) toolError {
+		t.Helper()
+		res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !res.IsError || len(res.Content) != 1 {
+			t.Fatalf("expected %s to fail with one content block, got %+v", name, res)
+		}
+		var body struct {
+			Error toolError `json:"error"`
+		}
+		text := res.Content[0].(*mcp.TextContent).Text
+		if err := json.Unmarshal([]byte(text), &body); err != nil {
+			t.Fatalf("expected a JSON error, got %q", text)
+		}
+		return body.Error
+	}
+
+	got := call("wtm_show", map[string]any{"name": "featur"})
+	if got.Code != codeNotFound || got.Details["name"] != "featur" || got.Details["suggestions"] == nil {
+		t.Errorf("unexpected wtm_show error: %+v", got)
+	}
+	if got := call("wtm_add", map[string]any{"name": "feature"}); got.Code != codeAlreadyExists {
+		t.Errorf("unexpected wtm_add error: %+v", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(worktreePath(t, ctx, "feature"), "scratch.txt"), []byte("wip\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if got := call("wtm_remove", map[string]any{"name": "feature", "force": true}); got.Code != codeDirtyWorktree || got.Message == "" {
+		t.Errorf("unexpected wtm_remove error: %+v", got)
+	}
+
+	// A commit only on the branch makes git branch -d refuse after the worktree is gone
+	if _, err := captureStdout(t, func() error { return AddWorktree(ctx, "unmerged", AddOptions{}) }); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	unmergedPath := worktreePath(t, ctx, "unmerged")
+	if out, err := exec.Command("git", "-C", unmergedPath, "commit", "--allow-empty", "-m", "wip").CombinedOutput(); err != nil {
+		t.Fatalf("git commit failed: %v: %s", err, out)
+	}
+	got = call("wtm_remove", map[string]any{"name": "unmerged", "force": true, "deleteBranch": true})
+	if got.Code != codeUnmergedBranch || got.Details["branch"] != "unmerged" || got.Details["worktreeRemoved"] != true {
+		t.Errorf("unexpected wtm_remove error for an unmerged branch: %+v", got)
+	}
+	if _, err := os.Stat(unmergedPath); !os.IsNotExist(err) {
+		t.Errorf("expected the worktree to be removed, got %v", err)
+	}
+}
+
+func worktreePath(t *testing.T, ctx context.Context, name string) string {
+	t.Helper()
+	wt, err := findWorktree(ctx, name)
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	return wt.Path
+}
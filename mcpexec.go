@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Commands run on behalf of an MCP client are checked against mcp.execDeny and mcp.execAllow: the
+// argv of wtm_exec, and the shell commands of setup steps and the editor started by wtm_open, since a
+// client able to edit .wtm.toml could otherwise run anything. Nothing runs unless an entry allows it.
+// The lists come from the global config only, for the same reason.
+
+// mcpCallKey marks the context of a tool call made by an MCP client
+type mcpCallKey struct{}
+
+// withMCPCall marks ctx as serving an MCP tool call
+func withMCPCall(ctx context.Context) context.Context {
+	return context.WithValue(ctx, mcpCallKey{}, true)
+}
+
+// calledByMCP reports whether ctx serves an MCP tool call
+func calledByMCP(ctx context.Context) bool {
+	called, _ := ctx.Value(mcpCallKey{}).(bool)
+	return called
+}
+
+// checkMCPExec reports an error naming argv unless an mcp.execAllow entry matches its leading words
+// and no mcp.execDeny entry does
+func checkMCPExec(cfg MCPConfig, argv []string) error {
+	return checkMCPCommand(cfg, argv, false)
+}
+
+// shellMetachars are the characters that let a shell command do more than run one program with
+// arguments. A single word may hold them, as in build;rm${IFS}-rf${IFS}~, so matching words is not enough.
+const shellMetachars = ";|&$`<>(){}\n\r'\"\\"
+
+// checkMCPShellCommand checks a shell command run during an MCP tool call
+func checkMCPShellCommand(ctx context.Context, command string) error {
+	if !calledByMCP(ctx) {
+		return nil
+	}
+	cfg, err := loadMCPConfig()
+	if err != nil {
+		return err
+	}
+	return checkMCPShell(cfg, command)
+}
+
+// checkMCPShell refuses commands containing shell metacharacters, then requires an allow entry to
+// match all words, so "make *" allows "make build" but neither "make build && rm -rf ~" nor "make $(id)"
+func checkMCPShell(cfg MCPConfig, command string) error {
+	if i := strings.IndexAny(command, shellMetachars); i >= 0 {
+		return fmt.Errorf("command %q is not allowed for MCP clients: it contains the shell metacharacter %q", command, command[i])
+	}
+	return checkMCPCommand(cfg, strings.Fields(command), true)
+}
+
+func checkMCPCommand(cfg MCPConfig, argv []string, whole bool) error {
+	command := strings.Join(argv, " ")
+	for _, entry := range cfg.ExecDeny {
+		if commandMatches(entry, argv, false) {
+			return fmt.Errorf("command %q is blocked for MCP clients by mcp.execDeny entry %q", command, entry)
+		}
+	}
+	for _, entry := range cfg.ExecAllow {
+		if commandMatches(entry, argv, whole) {
+			return nil
+		}
+	}
+	return fmt.Errorf("command %q is not allowed for MCP clients; add it to mcp.execAllow in the config", command)
+}
+
+// commandMatches matches the words of an entry, each a pattern where * and ? match any text, against
+// the leading words of argv, or against all of them when whole is set
+func commandMatches(entry string, argv []string, whole bool) bool {
+	words := strings.Fields(entry)
+	if len(words) == 0 || len(words) > len(argv) || (whole && len(words) != len(argv)) {
+		return false
+	}
+	for i, word := range words {
+		if !wordMatches(word, argv[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// wordMatches matches a single word; unlike path.Match, * also matches slashes, as in ./...
+func wordMatches(pattern, word string) bool {
+	if !strings.ContainsAny(pattern, "*?") {
+		return pattern == word
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	matched, err := regexp.MatchString("^"+expr+"$", word)
+	return err == nil && matched
+}
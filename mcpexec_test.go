@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckMCPCommand(t *testing.T) {
+	cfg := MCPConfig{
+		ExecAllow: []string{"go test", "npm run *", "git *"},
+		ExecDeny:  []string{"git push", "git reset --hard*"},
+	}
+	tests := []struct {
+		argv  []string
+		whole bool
+		ok    bool
+	}{
+		{[]string{"go", "test", "./..."}, false, true},
+		{[]string{"go", "test", "./..."}, true, false},
+		{[]string{"go", "test"}, true, true},
+		{[]string{"go", "build"}, false, false},
+		{[]string{"npm", "run", "lint:fix"}, true, true},
+		{[]string{"npm", "run", "build", "&&", "rm", "-rf", "~"}, true, false},
+		{[]string{"git", "status"}, false, true},
+		{[]string{"git", "push", "origin", "main"}, false, false},
+		{[]string{"git", "reset", "--hard=HEAD~1"}, false, false},
+		{[]string{"rm", "-rf", "."}, false, false},
+	}
+	for _, tt := range tests {
+		err := checkMCPCommand(cfg, tt.argv, tt.whole)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkMCPCommand(%q, whole=%v) = %v, want allowed=%v", tt.argv, tt.whole, err, tt.ok)
+		}
+		if err != nil && !strings.Contains(err.Error(), strings.Join(tt.argv, " ")) {
+			t.Errorf("expected the error to name the blocked command, got %v", err)
+		}
+	}
+
+	if err := checkMCPCommand(MCPConfig{}, []string{"ls"}, false); err == nil {
+		t.Error("expected every command to be denied without an allowlist")
+	}
+	if err := checkMCPCommand(cfg, []string{"git", "push"}, false); err == nil || !strings.Contains(err.Error(), "execDeny") {
+		t.Errorf("expected the deny entry to be named, got %v", err)
+	}
+}
+
+func TestCheckMCPShell(t *testing.T) {
+	cfg := MCPConfig{ExecAllow: []string{"make *", "code *"}}
+	if err := checkMCPShell(cfg, "make build"); err != nil {
+		t.Errorf("expected make build to be allowed, got %v", err)
+	}
+	// Payloads without spaces are a single word that "make *" would otherwise match
+	for _, command := range []string{
+		"make build;rm${IFS}-rf${IFS}~",
+		"make $(id)",
+		"make `id`",
+		"make build&&id",
+		"make build|sh",
+		"make build>~/.bashrc",
+		"make 'a;b'",
+		"make build\nid",
+		"code {a,b}",
+	} {
+		if err := checkMCPShell(cfg, command); err == nil {
+			t.Errorf("expected %q to be refused", command)
+		}
+	}
+}
+
+func TestCheckMCPShellCommandOutsideMCP(t *testing.T) {
+	// Commands run by the CLI are not restricted
+	if err := checkMCPShellCommand(t.Context(), "rm -rf node_modules"); err != nil {
+		t.Errorf("expected commands outside MCP calls to be allowed, got %v", err)
+	}
+	if !calledByMCP(withMCPCall(t.Context())) || calledByMCP(t.Context()) {
+		t.Error("expected only marked contexts to count as MCP calls")
+	}
+}
+
+func TestMCPConfigOnlyFromGlobalConfig(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+	local := "[mcp]\nexecAllow = [\"touch *\"]\ntoken = \"from-repo\"\n"
+	if err := os.WriteFile(filepath.Join(repoPath, localConfigFile), []byte(local), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[mcp]\nexecAllow = [\"make *\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+	ctx := withMCPCall(withRepoDir(t.Context(), repoPath))
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if len(cfg.MCP.ExecAllow) != 1 || cfg.MCP.ExecAllow[0] != "make *" || cfg.MCP.Token != "" {
+		t.Errorf("expected [mcp] of %s to be ignored, got %+v", localConfigFile, cfg.MCP)
+	}
+	if err := checkMCPShellCommand(ctx, "touch pwned"); err == nil {
+		t.Error("expected the repository's execAllow not to be honored")
+	}
+
+	// A running server applies allowlist edits without a restart
+	if err := os.WriteFile(configFile, []byte("[mcp]\nexecAllow = [\"touch *\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkMCPShellCommand(ctx, "touch allowed"); err != nil {
+		t.Errorf("expected the edited allowlist to apply, got %v", err)
+	}
+}
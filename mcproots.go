@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/choplin/wtm/pkg/wtm"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Each tool call and resource read operates on the repository named by the tool's repoPath input,
+// which must lie inside one of the client's roots (see checkRepoPath), else on the first workspace root of the client that is inside a git repository, else on the
+// directory wtm mcp was started in (or its -C). The selected repository is passed to the handler
+// through its context, so calls on different repositories run side by side.
+
+// mcpRootsTimeout bounds how long a session waits for the client to list its roots
+const mcpRootsTimeout = 2 * time.Second
+
+// sessionRoots caches the repository selected by each session's roots until the client reports
+// that its roots changed
+var sessionRoots sync.Map
+
+// rootsRepo is the cached roots of a session; dir is the repository of the first root inside one, or ""
+type rootsRepo struct {
+	once  sync.Once
+	roots []string
+	dir   string
+}
+
+// mcpAllowedRepos lists the directories, set with wtm mcp --allow-repo, in which repoPath may also select
+// repositories. Otherwise it must lie inside the client's roots, or inside the server's repository for
+// clients without roots.
+var mcpAllowedRepos []string
+
+// RepoInput is embedded in the input of every tool to select the repository it operates on
+type RepoInput struct {
+	RepoPath string `json:"repoPath,omitempty" jsonschema:"path inside the repository to operate on, within one of the client's roots or, without roots, the server's repository (default: the client's first root inside a git repository, else the server's working directory)"`
+}
+
+func (in RepoInput) selectedRepo() string {
+	return in.RepoPath
+}
+
+// repoSelector is implemented by tool inputs that embed RepoInput
+type repoSelector interface {
+	selectedRepo() string
+}
+
+// inMCPRepo runs fn with a context selecting the repository of an MCP call
+func inMCPRepo(ctx context.Context, session *mcp.ServerSession, repoPath string, fn func(context.Context) error) error {
+	dir, err := mcpRepoDir(ctx, session, repoPath)
+	if err != nil {
+		return err
+	}
+	if dir == "" {
+		return fn(ctx)
+	}
+	return fn(withRepoDir(ctx, dir))
+}
+
+// allowMCPRepos adds the directories of wtm mcp --allow-repo to mcpAllowedRepos
+func allowMCPRepos(dirs []string) error {
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		mcpAllowedRepos = append(mcpAllowedRepos, abs)
+	}
+	return nil
+}
+
+// mcpRepoDir returns the top level of the repository a call selects, or "" for the default one
+func mcpRepoDir(ctx context.Context, session *mcp.ServerSession, repoPath string) (string, error) {
+	var rr *rootsRepo
+	if session != nil {
+		rr = sessionRootsRepo(ctx, session)
+	}
+	if repoPath == "" {
+		if rr == nil {
+			return "", nil
+		}
+		return rr.dir, nil
+	}
+
+	abs, err := filepath.Abs(repoPath)
+	if err != nil {
+		return "", err
+	}
+	// Check the path before running git in it, so clients cannot probe the whole file system
+	if err := checkRepoPath(ctx, repoPath, abs, rr); err != nil {
+		return "", err
+	}
+	top, err := (&wtm.Manager{Dir: abs}).RepoRoot(ctx)
+	if err != nil {
+		return "", fmt.Errorf("repoPath '%s': %w", repoPath, wtm.ErrNotARepo)
+	}
+	return top, nil
+}
+
+// checkRepoPath rejects a repoPath outside the client's roots, or outside the server's repository when the
+// client has no roots, unless it is inside a directory allowed with --allow-repo
+func checkRepoPath(ctx context.Context, repoPath, abs string, rr *rootsRepo) error {
+	for _, dir := range mcpAllowedRepos {
+		if pathWithin(abs, dir) {
+			return nil
+		}
+	}
+	if rr != nil && len(rr.roots) > 0 {
+		for _, root := range rr.roots {
+			if pathWithin(abs, root) {
+				return nil
+			}
+		}
+		return fmt.Errorf("repoPath '%s' is outside the client's roots; start wtm mcp with --allow-repo to allow it", repoPath)
+	}
+	if top, err := getRepoRoot(ctx); err == nil && pathWithin(abs, top) {
+		return nil
+	}
+	return fmt.Errorf("repoPath '%s' is outside the server's repository; start wtm mcp with --allow-repo to allow it", repoPath)
+}
+
+// sessionRootsRepo returns the roots of a session, listing them on its first call
+func sessionRootsRepo(ctx context.Context, session *mcp.ServerSession) *rootsRepo {
+	entry, loaded := sessionRoots.LoadOrStore(session, &rootsRepo{})
+	if !loaded {
+		go func() {
+			session.Wait()
+			sessionRoots.Delete(session)
+		}()
+	}
+	rr := entry.(*rootsRepo)
+	rr.once.Do(func() {
+		rr.roots, rr.dir = listRoots(context.WithoutCancel(ctx), session)
+	})
+	return rr
+}
+
+// listRoots asks the client for its roots and returns their local paths and the top level of the first
+// one inside a git repository. Clients without roots support answer with an error; nothing is returned then.
+func listRoots(ctx context.Context, session *mcp.ServerSession) (roots []string, repo string) {
+	rootsCtx, cancel := context.WithTimeout(ctx, mcpRootsTimeout)
+	defer cancel()
+	res, err := session.ListRoots(rootsCtx, nil)
+	if err != nil {
+		return nil, ""
+	}
+	for _, root := range res.Roots {
+		dir, ok := fileURIPath(root.URI)
+		if !ok {
+			continue
+		}
+		roots = append(roots, dir)
+		if repo != "" {
+			continue
+		}
+		if top, err := (&wtm.Manager{Dir: dir}).RepoRoot(ctx); err == nil {
+			repo = top
+		}
+	}
+	return roots, repo
+}
+
+// handleRootsListChanged drops the cached roots of the session, so its next call lists them again
+func handleRootsListChanged(_ context.Context, req *mcp.RootsListChangedRequest) {
+	sessionRoots.Delete(req.Session)
+}
+
+// fileURIPath converts a file:// URI to a local path
+func fileURIPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return "", false
+	}
+	p := u.Path
+	// file:///C:/src is the Windows path C:/src
+	if runtime.GOOS == "windows" && len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p), true
+}
+
+// resourceInMCPRepo reads a resource in the repository selected by the client's roots
+func resourceInMCPRepo(h mcp.ResourceHandler) mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		var res *mcp.ReadResourceResult
+		err := inMCPRepo(ctx, req.Session, "", func(ctx context.Context) error {
+			var err error
+			res, err = h(ctx, req)
+			return err
+		})
+		return res, err
+	}
+}
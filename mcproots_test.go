@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestMCPRepoSelection(t *testing.T) {
+	serverRepo := setupTestRepo(t)
+	defer cleanupTestRepo(t, serverRepo)
+	rootRepo := setupTestRepo(t)
+	defer cleanupTestRepo(t, rootRepo)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(serverRepo); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.toml"))
+	resetConfigCache()
+	defer resetConfigCache()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := newMCPServer().Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "wtm-test-client", Version: "0.0.1"}, nil)
+	client.AddRoots(
+		&mcp.Root{URI: "https://example.com/not-a-directory"},
+		&mcp.Root{URI: "file://" + filepath.ToSlash(t.TempDir())},
+		&mcp.Root{URI: "file://" + filepath.ToSlash(rootRepo)},
+	)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer session.Close()
+
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res
+	}
+	list := func(args map[string]any) []Worktree {
+		t.Helper()
+		res := call("wtm_list", args)
+		if res.IsError {
+			t.Fatalf("wtm_list failed: %+v", res.Content)
+		}
+		var out ListWorktreesOutput
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &out); err != nil || len(out.Worktrees) == 0 {
+			t.Fatalf("unexpected wtm_list output: %s", data)
+		}
+		return out.Worktrees
+	}
+
+	if res := call("wtm_add", map[string]any{"name": "from-root"}); res.IsError {
+		t.Fatalf("wtm_add failed: %+v", res.Content)
+	}
+	worktrees := list(nil)
+	if normalizePath(worktrees[0].Path) != normalizePath(rootRepo) || len(worktrees) != 2 || worktrees[1].Name != "from-root" {
+		t.Errorf("expected the worktree to be added in the client's root %s, got %+v", rootRepo, worktrees)
+	}
+
+	subdir := filepath.Join(rootRepo, "sub")
+	if err := os.Mkdir(subdir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	worktrees = list(map[string]any{"repoPath": subdir})
+	if normalizePath(worktrees[0].Path) != normalizePath(rootRepo) {
+		t.Errorf("expected repoPath %s to select its repository, got %+v", subdir, worktrees)
+	}
+	if res := call("wtm_list", map[string]any{"repoPath": serverRepo}); !res.IsError {
+		t.Error("expected a repoPath outside the client's roots to be rejected")
+	}
+	if repoDir != "" {
+		t.Errorf("expected calls to leave the server's repository alone, got %s", repoDir)
+	}
+
+	// Removing the root is reported with roots/list_changed and the cached roots are dropped
+	client.RemoveRoots("file://" + filepath.ToSlash(rootRepo))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		worktrees = list(nil)
+		if normalizePath(worktrees[0].Path) == normalizePath(serverRepo) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the server's repository once the root was removed, got %+v", worktrees)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMCPRepoPathWithoutRoots(t *testing.T) {
+	ctx := t.Context()
+	serverRepo := setupTestRepo(t)
+	defer cleanupTestRepo(t, serverRepo)
+	otherRepo := setupTestRepo(t)
+	defer cleanupTestRepo(t, otherRepo)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(serverRepo); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	dir, err := mcpRepoDir(ctx, nil, filepath.Join(serverRepo, "."))
+	if err != nil || normalizePath(dir) != normalizePath(serverRepo) {
+		t.Errorf("expected the server's repository to be selectable, got %q, %v", dir, err)
+	}
+	if _, err := mcpRepoDir(ctx, nil, otherRepo); err == nil || !strings.Contains(err.Error(), "--allow-repo") {
+		t.Errorf("expected a repoPath outside the server's repository to be rejected, got %v", err)
+	}
+
+	defer func() { mcpAllowedRepos = nil }()
+	if err := allowMCPRepos([]string{filepath.Dir(otherRepo)}); err != nil {
+		t.Fatal(err)
+	}
+	if dir, err := mcpRepoDir(ctx, nil, otherRepo); err != nil || normalizePath(dir) != normalizePath(otherRepo) {
+		t.Errorf("expected --allow-repo to allow %s, got %q, %v", otherRepo, dir, err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+const (
+	metaReadOnly  = wtm.MetaReadOnly
+	metaIssue     = wtm.MetaIssue
+	metaBase      = wtm.MetaBase
+	metaPort      = wtm.MetaPort
+	metaLastUsed  = wtm.MetaLastUsed
+	metaLabels    = wtm.MetaLabels
+	metaEphemeral = wtm.MetaEphemeral
+	metaOwner     = wtm.MetaOwner
+	metaHidden    = wtm.MetaHidden
+	metaTag       = wtm.MetaTag
+)
+
+// setWorktreeMeta stores a metadata value for a worktree
+func setWorktreeMeta(ctx context.Context, name, key, value string) error {
+	return newManager(ctx).SetMeta(ctx, name, key, value)
+}
+
+// unsetWorktreeMeta removes a single metadata value, ignoring keys that are not set
+func unsetWorktreeMeta(ctx context.Context, name, key string) error {
+	return newManager(ctx).UnsetMeta(ctx, name, key)
+}
+
+// clearWorktreeMeta removes every metadata value stored for a worktree
+func clearWorktreeMeta(ctx context.Context, name string) error {
+	return newManager(ctx).ClearMeta(ctx, name)
+}
+
+// loadWorktreeMeta returns all stored metadata keyed by worktree name and lower-cased key
+func loadWorktreeMeta(ctx context.Context) (map[string]map[string]string, error) {
+	return newManager(ctx).Meta(ctx)
+}
+
+// renameWorktreeMeta moves the metadata stored for a worktree to a new name
+func renameWorktreeMeta(ctx context.Context, oldName, newName string) error {
+	meta, err := loadWorktreeMeta(ctx)
+	if err != nil || len(meta[oldName]) == 0 {
+		return err
+	}
+	for key, value := range meta[oldName] {
+		if err := setWorktreeMeta(ctx, newName, key, value); err != nil {
+			return err
+		}
+	}
+	return clearWorktreeMeta(ctx, oldName)
+}
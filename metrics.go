@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// mcpMetrics collects counters for MCP tool calls and the git commands they run in a
+// Prometheus-compatible shape
+type mcpMetrics struct {
+	mu       sync.Mutex
+	calls    map[string]uint64
+	errors   map[string]uint64
+	duration map[string]float64
+	// git* are keyed by git subcommand
+	gitCalls    map[string]uint64
+	gitErrors   map[string]uint64
+	gitDuration map[string]float64
+}
+
+// toolMetrics is shared by every MCP server in the process
+var toolMetrics = newMCPMetrics()
+
+func newMCPMetrics() *mcpMetrics {
+	return &mcpMetrics{
+		calls:    map[string]uint64{},
+		errors:   map[string]uint64{},
+		duration: map[string]float64{},
+
+		gitCalls:    map[string]uint64{},
+		gitErrors:   map[string]uint64{},
+		gitDuration: map[string]float64{},
+	}
+}
+
+func (m *mcpMetrics) observe(tool string, elapsed time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls[tool]++
+	m.duration[tool] += elapsed.Seconds()
+	if failed {
+		m.errors[tool]++
+	}
+}
+
+func (m *mcpMetrics) observeGit(command string, elapsed time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gitCalls[command]++
+	m.gitDuration[command] += elapsed.Seconds()
+	if failed {
+		m.gitErrors[command]++
+	}
+}
+
+// timedRunner is the GitRunner of newManager; it records how long each git subcommand takes
+type timedRunner struct {
+	m *mcpMetrics
+}
+
+// Run implements wtm.GitRunner
+func (r timedRunner) Run(ctx context.Context, dir string, args ...string) (string, error) {
+	start := time.Now()
+	output, err := gitBackend.Run(ctx, dir, args...)
+	r.m.observeGit(gitSubcommand(args), time.Since(start), err != nil)
+	return output, err
+}
+
+// gitSubcommand returns the subcommand of git's arguments, skipping global options such as -C dir
+func gitSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-C" || arg == "-c":
+			i++
+		case !strings.HasPrefix(arg, "-"):
+			return arg
+		}
+	}
+	return "git"
+}
+
+// instrumentTool wraps a tool handler so every call is counted and timed
+func instrumentTool[In, Out any](m *mcpMetrics, name string, h mcp.ToolHandlerFor[In, Out]) mcp.ToolHandlerFor[In, Out] {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input In) (*mcp.CallToolResult, Out, error) {
+		start := time.Now()
+		res, out, err := h(ctx, req, input)
+		m.observe(name, time.Since(start), err != nil || (res != nil && res.IsError))
+		return res, out, err
+	}
+}
+
+// writeTo renders the metrics in the Prometheus text exposition format
+func (m *mcpMetrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tools := make([]string, 0, len(m.calls))
+	for tool := range m.calls {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+
+	fmt.Fprintln(w, "# HELP wtm_tool_calls_total Total number of MCP tool calls.")
+	fmt.Fprintln(w, "# TYPE wtm_tool_calls_total counter")
+	for _, tool := range tools {
+		fmt.Fprintf(w, "wtm_tool_calls_total{tool=%q} %d\n", tool, m.calls[tool])
+	}
+
+	fmt.Fprintln(w, "# HELP wtm_tool_errors_total Total number of MCP tool calls that failed.")
+	fmt.Fprintln(w, "# TYPE wtm_tool_errors_total counter")
+	for _, tool := range tools {
+		fmt.Fprintf(w, "wtm_tool_errors_total{tool=%q} %d\n", tool, m.errors[tool])
+	}
+
+	fmt.Fprintln(w, "# HELP wtm_tool_duration_seconds Time spent handling MCP tool calls.")
+	fmt.Fprintln(w, "# TYPE wtm_tool_duration_seconds summary")
+	for _, tool := range tools {
+		fmt.Fprintf(w, "wtm_tool_duration_seconds_sum{tool=%q} %g\n", tool, m.duration[tool])
+		fmt.Fprintf(w, "wtm_tool_duration_seconds_count{tool=%q} %d\n", tool, m.calls[tool])
+	}
+
+	commands := make([]string, 0, len(m.gitCalls))
+	for command := range m.gitCalls {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+
+	fmt.Fprintln(w, "# HELP wtm_git_command_errors_total Total number of git commands that failed.")
+	fmt.Fprintln(w, "# TYPE wtm_git_command_errors_total counter")
+	for _, command := range commands {
+		fmt.Fprintf(w, "wtm_git_command_errors_total{command=%q} %d\n", command, m.gitErrors[command])
+	}
+
+	fmt.Fprintln(w, "# HELP wtm_git_command_duration_seconds Time spent running git commands.")
+	fmt.Fprintln(w, "# TYPE wtm_git_command_duration_seconds summary")
+	for _, command := range commands {
+		fmt.Fprintf(w, "wtm_git_command_duration_seconds_sum{command=%q} %g\n", command, m.gitDuration[command])
+		fmt.Fprintf(w, "wtm_git_command_duration_seconds_count{command=%q} %d\n", command, m.gitCalls[command])
+	}
+}
+
+// metricsHandler serves /metrics, including the sessions connected to server (when not nil) and the
+// current worktree count of the repository
+func metricsHandler(m *mcpMetrics, server *mcp.Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.writeTo(w)
+
+		if server != nil {
+			sessions := 0
+			for range server.Sessions() {
+				sessions++
+			}
+			fmt.Fprintln(w, "# HELP wtm_active_sessions Current number of connected MCP sessions.")
+			fmt.Fprintln(w, "# TYPE wtm_active_sessions gauge")
+			fmt.Fprintf(w, "wtm_active_sessions %d\n", sessions)
+		}
+
+		worktrees, err := getWorktrees(r.Context())
+		if err != nil {
+			return
+		}
+		fmt.Fprintln(w, "# HELP wtm_worktrees Current number of worktrees in the repository.")
+		fmt.Fprintln(w, "# TYPE wtm_worktrees gauge")
+		fmt.Fprintf(w, "wtm_worktrees %d\n", len(worktrees))
+	})
+}
+
+// healthHandler serves /healthz for supervisors; it answers while the server accepts requests
+func healthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+}
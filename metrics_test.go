@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	m := newMCPMetrics()
+	ok := instrumentTool(m, "wtm_list", func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, struct{}, error) {
+		return nil, struct{}{}, nil
+	})
+	failing := instrumentTool(m, "wtm_add", func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, struct{}, error) {
+		return nil, struct{}{}, errors.New("boom")
+	})
+
+	ok(context.Background(), nil, struct{}{})
+	ok(context.Background(), nil, struct{}{})
+	failing(context.Background(), nil, struct{}{})
+
+	git := timedRunner{m: m}
+	if _, err := git.Run(context.Background(), "", "-C", repoPath, "rev-parse", "HEAD"); err != nil {
+		t.Fatalf("git rev-parse failed: %v", err)
+	}
+	if _, err := git.Run(context.Background(), "", "rev-parse", "no-such-rev"); err == nil {
+		t.Fatal("expected git rev-parse of a missing revision to fail")
+	}
+
+	rec := httptest.NewRecorder()
+	metricsHandler(m, nil).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`wtm_tool_calls_total{tool="wtm_list"} 2`,
+		`wtm_tool_errors_total{tool="wtm_list"} 0`,
+		`wtm_tool_calls_total{tool="wtm_add"} 1`,
+		`wtm_tool_errors_total{tool="wtm_add"} 1`,
+		`wtm_tool_duration_seconds_count{tool="wtm_list"} 2`,
+		`wtm_git_command_duration_seconds_count{command="rev-parse"} 2`,
+		`wtm_git_command_errors_total{command="rev-parse"} 1`,
+		"wtm_worktrees 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics output to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsServedOverHTTP(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	httpServer := httptest.NewServer(newMCPHTTPHandler(newMCPServer(), ""))
+	defer httpServer.Close()
+
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "wtm-test-client", Version: "0.0.1"}, nil).
+		Connect(ctx, &mcp.StreamableClientTransport{Endpoint: httpServer.URL + "/mcp"}, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer session.Close()
+	if res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "wtm_list", Arguments: map[string]any{}}); err != nil || res.IsError {
+		t.Fatalf("wtm_list failed: %v %+v", err, res)
+	}
+
+	// Tool calls made over /mcp are counted on the /metrics route of the same server
+	resp, err := http.Get(httpServer.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read /metrics: %v", err)
+	}
+	body := string(data)
+	for _, want := range []string{
+		`(?m)^wtm_tool_calls_total\{tool="wtm_list"\} [1-9]`,
+		`(?m)^wtm_tool_duration_seconds_count\{tool="wtm_list"\} [1-9]`,
+		`(?m)^wtm_git_command_duration_seconds_count\{command="worktree"\} [1-9]`,
+		`(?m)^wtm_active_sessions 1$`,
+		`(?m)^wtm_worktrees 1$`,
+	} {
+		if !regexp.MustCompile(want).MatchString(body) {
+			t.Errorf("expected /metrics to match %s, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestHealthAndSessionMetrics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := newMCPServer()
+	httpServer := httptest.NewServer(newMCPHTTPHandler(server, "s3cret"))
+	defer httpServer.Close()
+
+	// Supervisors probe /healthz without credentials
+	resp, err := http.Get(httpServer.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected /healthz to answer 200 without a token, got %d", resp.StatusCode)
+	}
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "wtm-test-client", Version: "0.0.1"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer session.Close()
+
+	rec := httptest.NewRecorder()
+	metricsHandler(newMCPMetrics(), server).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "wtm_active_sessions 1") {
+		t.Errorf("expected one active session, got:\n%s", body)
+	}
+}
+
+func TestGitSubcommand(t *testing.T) {
+	for args, want := range map[string]string{
+		"worktree list --porcelain":  "worktree",
+		"-C /repo rev-parse HEAD":    "rev-parse",
+		"-c core.quotepath=off diff": "diff",
+		"--version":                  "git",
+	} {
+		if got := gitSubcommand(strings.Fields(args)); got != want {
+			t.Errorf("gitSubcommand(%q) = %q, want %q", args, got, want)
+		}
+	}
+}
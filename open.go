@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OpenWorktree launches the configured editor command inside a worktree.
+// The command comes from open.command (a template over Name, Branch and Path) or falls back to $EDITOR.
+func OpenWorktree(ctx context.Context, name string) error {
+	if planning() {
+		activePlan.Record("open editor in worktree %s", name)
+		return nil
+	}
+
+	target, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	command, err := openCommand(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	markUsed(ctx, target)
+	cmd := shellCommand(ctx, command)
+	cmd.Dir = target.Path
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to open worktree '%s' with '%s': %w", target.Name, command, err)
+	}
+	return nil
+}
+
+// openCommand renders the shell command used to open a worktree
+func openCommand(ctx context.Context, wt *Worktree) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if tmpl := strings.TrimSpace(cfg.Open.Command); tmpl != "" {
+		command, err := renderTemplate("open.command", tmpl, wt)
+		if err != nil {
+			return "", fmt.Errorf("invalid open.command: %w", err)
+		}
+		return command, nil
+	}
+
+	editor := strings.TrimSpace(os.Getenv("EDITOR"))
+	if editor == "" {
+		return "", errors.New("no editor configured: set open.command in the config file or $EDITOR")
+	}
+	return editor + " " + shellJoin([]string{wt.Path}), nil
+}
+
+// launchEditor starts the open.command of a worktree without waiting for it or attaching it to wtm's
+// stdio, for callers such as the MCP server. $EDITOR is not used since it usually needs a terminal.
+func launchEditor(ctx context.Context, wt *Worktree) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(cfg.Open.Command) == "" {
+		return "", errors.New("no editor configured: set open.command in the config file ($EDITOR is not used without a terminal)")
+	}
+	command, err := openCommand(ctx, wt)
+	if err != nil {
+		return "", err
+	}
+	if err := checkMCPShellCommand(ctx, command); err != nil {
+		return "", err
+	}
+
+	// The editor outlives the request that launched it
+	cmd := shellCommand(context.WithoutCancel(ctx), command)
+	cmd.Dir = wt.Path
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to open worktree '%s' with '%s': %w", wt.Name, command, err)
+	}
+	go cmd.Wait()
+	markUsed(ctx, wt)
+	return command, nil
+}
+
+// editorURIs returns file:// and vscode://file URIs for an absolute path
+func editorURIs(path string) (fileURI, vscodeURI string) {
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		// Windows drive paths such as C:/src become /C:/src
+		p = "/" + p
+	}
+	fileURI = (&url.URL{Scheme: "file", Path: p}).String()
+	vscodeURI = (&url.URL{Scheme: "vscode", Host: "file", Path: p}).String()
+	return fileURI, vscodeURI
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenWorktree(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	marker := filepath.Join(t.TempDir(), "opened")
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	config := "[open]\ncommand = \"echo {{.Name}} {{.Branch}} > " + marker + "\"\n"
+	if err := os.WriteFile(configFile, []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	if err := AddWorktree(ctx, "editor", AddOptions{Branch: "feature/editor"}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	t.Run("open with configured command", func(t *testing.T) {
+		if err := OpenWorktree(ctx, "editor"); err != nil {
+			t.Fatalf("OpenWorktree failed: %v", err)
+		}
+		data, err := os.ReadFile(marker)
+		if err != nil {
+			t.Fatalf("expected open command to run: %v", err)
+		}
+		if string(data) != "editor feature/editor\n" {
+			t.Errorf("unexpected command output: %q", string(data))
+		}
+	})
+
+	t.Run("open unknown worktree should fail", func(t *testing.T) {
+		if err := OpenWorktree(ctx, "missing"); err == nil {
+			t.Error("Expected error for non-existent worktree, got nil")
+		}
+	})
+}
+
+func TestOpenCommandFallsBackToEditor(t *testing.T) {
+	ctx := t.Context()
+
+	t.Setenv("WTM_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.toml"))
+	resetConfigCache()
+	defer resetConfigCache()
+
+	t.Setenv("EDITOR", "vim")
+	got, err := openCommand(ctx, &Worktree{Path: "/tmp/my worktree"})
+	if err != nil {
+		t.Fatalf("openCommand failed: %v", err)
+	}
+	if want := "vim '/tmp/my worktree'"; got != want {
+		t.Errorf("openCommand = %q, want %q", got, want)
+	}
+
+	t.Setenv("EDITOR", "")
+	if _, err := openCommand(ctx, &Worktree{Path: "/tmp"}); err == nil {
+		t.Error("Expected error without open.command or $EDITOR, got nil")
+	}
+}
+
+func TestEditorURIs(t *testing.T) {
+	fileURI, vscodeURI := editorURIs("/home/me/src/my repo/.git/wtm/worktrees/feature")
+	if want := "file:///home/me/src/my%20repo/.git/wtm/worktrees/feature"; fileURI != want {
+		t.Errorf("expected %q, got %q", want, fileURI)
+	}
+	if want := "vscode://file/home/me/src/my%20repo/.git/wtm/worktrees/feature"; vscodeURI != want {
+		t.Errorf("expected %q, got %q", want, vscodeURI)
+	}
+}
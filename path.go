@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// PrintPath prints the absolute path of a worktree and nothing else, for `cd "$(wtm path api)"` and editor
+// integrations. It skips the metadata and status lookups of wtm show, and fails when the worktree does not exist.
+// "-" prints the previously used worktree, which does need the metadata.
+func PrintPath(ctx context.Context, name string) error {
+	if name == previousWorktreeName {
+		wt, err := previousWorktree(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Println(wt.Path)
+		return nil
+	}
+	m, err := worktreeManager(ctx)
+	if err != nil {
+		return err
+	}
+	path, err := m.Path(ctx, name)
+	if err != nil {
+		return err
+	}
+	fmt.Println(path)
+	return nil
+}
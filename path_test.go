@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+func TestPrintPath(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "api", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "api")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+
+	output, err := captureStdout(t, func() error {
+		return PrintPath(ctx, "api")
+	})
+	if err != nil {
+		t.Fatalf("PrintPath failed: %v", err)
+	}
+	if output != wt.Path+"\n" {
+		t.Errorf("PrintPath printed %q, want %q", output, wt.Path+"\n")
+	}
+
+	err = PrintPath(ctx, "missing")
+	if !errors.Is(err, wtm.ErrWorktreeNotFound) || exitCode(err) != exitNotFound {
+		t.Errorf("expected a not found error with exit code %d, got %v", exitNotFound, err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected the error to name the worktree, got %v", err)
+	}
+}
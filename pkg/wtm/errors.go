@@ -0,0 +1,114 @@
+package wtm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error kinds returned by Manager operations; match them with errors.Is
+var (
+	// ErrNotARepo means the manager's directory is not inside a git repository
+	ErrNotARepo = errors.New("not in a git repository")
+	// ErrWorktreeNotFound means no worktree has the requested name
+	ErrWorktreeNotFound = errors.New("worktree not found")
+	// ErrWorktreeExists means a worktree with the requested name already exists
+	ErrWorktreeExists = errors.New("worktree already exists")
+	// ErrBranchExists means the branch a new worktree would create already exists
+	ErrBranchExists = errors.New("branch already exists")
+	// ErrBranchNotMerged means git refused to delete a branch that is not fully merged
+	ErrBranchNotMerged = errors.New("branch is not fully merged")
+	// ErrWorktreeDirty means removing the worktree would discard uncommitted or untracked changes
+	ErrWorktreeDirty = errors.New("worktree has uncommitted changes")
+	// ErrInvalidName means a worktree name failed ValidateName
+	ErrInvalidName = errors.New("invalid worktree name")
+)
+
+// WorktreeError reports a named worktree that is missing, already exists or has uncommitted changes.
+// It matches ErrWorktreeNotFound, ErrWorktreeExists or ErrWorktreeDirty.
+type WorktreeError struct {
+	Name string
+	Err  error
+	// Suggestions are existing worktree names or branches close to Name, offered when it was not found
+	Suggestions []string
+}
+
+func (e *WorktreeError) Error() string {
+	switch e.Err {
+	case ErrWorktreeNotFound:
+		if len(e.Suggestions) > 0 {
+			return fmt.Sprintf("worktree '%s' not found; did you mean %s?", e.Name, quoteList(e.Suggestions))
+		}
+		return fmt.Sprintf("worktree '%s' not found", e.Name)
+	case ErrWorktreeExists:
+		return fmt.Sprintf("worktree '%s' already exists", e.Name)
+	case ErrWorktreeDirty:
+		return fmt.Sprintf("worktree '%s' has uncommitted or untracked changes", e.Name)
+	default:
+		return fmt.Sprintf("worktree '%s': %v", e.Name, e.Err)
+	}
+}
+
+func (e *WorktreeError) Unwrap() error {
+	return e.Err
+}
+
+// BranchError reports a branch that Add was asked to create but that already exists.
+// It matches ErrBranchExists.
+type BranchError struct {
+	Name string
+	Err  error
+}
+
+func (e *BranchError) Error() string {
+	if e.Err == ErrBranchExists {
+		return fmt.Sprintf("branch '%s' already exists; check it out with -B or pick another name", e.Name)
+	}
+	return fmt.Sprintf("branch '%s': %v", e.Name, e.Err)
+}
+
+func (e *BranchError) Unwrap() error {
+	return e.Err
+}
+
+// quoteList joins values as 'a', 'b' or 'c'
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "'" + v + "'"
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
+// GitError is a failed git command together with its output. It matches ErrNotARepo and
+// ErrBranchNotMerged when git's output says so, and unwraps to the underlying exit or context error.
+type GitError struct {
+	Args   []string
+	Output string
+	Err    error
+}
+
+func (e *GitError) Error() string {
+	if e.Output == "" {
+		return fmt.Sprintf("git %s: %v", ShellJoin(e.Args), e.Err)
+	}
+	return fmt.Sprintf("%v: %s", e.Err, e.Output)
+}
+
+func (e *GitError) Unwrap() error {
+	return e.Err
+}
+
+// Is classifies the failure by git's message, which is the only place git reports these conditions
+func (e *GitError) Is(target error) bool {
+	switch target {
+	case ErrNotARepo:
+		return strings.Contains(e.Output, "not a git repository")
+	case ErrBranchNotMerged:
+		return strings.Contains(e.Output, "is not fully merged")
+	}
+	return false
+}
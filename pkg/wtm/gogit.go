@@ -0,0 +1,550 @@
+//go:build gogit
+
+package wtm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GoGitRunner answers read-only queries in process with go-git instead of starting git: repository
+// paths (rev-parse --git-dir, --git-common-dir, --show-toplevel), revisions (rev-parse [--verify]),
+// the current branch (symbolic-ref), status --porcelain, config --get and --get-regexp, remote, and
+// log with a --format of common placeholders. Everything else, including every mutation, goes to
+// Fallback, so reads work without a git binary. It is only built with the gogit build tag.
+//
+// Unlike git, status lists each untracked file instead of collapsing untracked directories, and
+// config reads the repository's config file only.
+type GoGitRunner struct {
+	// Fallback runs the commands go-git does not answer; nil means ExecRunner
+	Fallback GitRunner
+}
+
+// errNotHandled sends a command to the fallback runner
+var errNotHandled = errors.New("not handled by go-git")
+
+// exitStatus is a failure reported the way git would: an exit code and git's message
+type exitStatus struct {
+	code    int
+	message string
+}
+
+func (e *exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// ExitCode implements the exit code contract of GitRunner
+func (e *exitStatus) ExitCode() int {
+	return e.code
+}
+
+// Run implements GitRunner
+func (r GoGitRunner) Run(ctx context.Context, dir string, args ...string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", &GitError{Args: args, Err: err}
+	}
+	runDir, rest := dir, args
+	for len(rest) >= 2 && rest[0] == "-C" {
+		runDir = resolveDir(runDir, rest[1])
+		rest = rest[2:]
+	}
+	if len(rest) > 0 {
+		output, err := goGitQuery(runDir, rest)
+		if !errors.Is(err, errNotHandled) {
+			var status *exitStatus
+			if errors.As(err, &status) {
+				return "", &GitError{Args: args, Output: status.message, Err: status}
+			}
+			if err != nil {
+				return "", &GitError{Args: args, Output: err.Error(), Err: &exitStatus{code: 128}}
+			}
+			return output, nil
+		}
+	}
+	fallback := r.Fallback
+	if fallback == nil {
+		fallback = ExecRunner{}
+	}
+	return fallback.Run(ctx, dir, args...)
+}
+
+// resolveDir applies git -C path to dir
+func resolveDir(dir, path string) string {
+	if filepath.IsAbs(path) || dir == "" {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func goGitQuery(dir string, args []string) (string, error) {
+	switch args[0] {
+	case "rev-parse":
+		return goGitRevParse(dir, args[1:])
+	case "symbolic-ref":
+		return goGitSymbolicRef(dir, args[1:])
+	case "status":
+		if len(args) < 2 || args[1] != "--porcelain" {
+			return "", errNotHandled
+		}
+		nul := false
+		for _, arg := range args[2:] {
+			switch arg {
+			case "-z":
+				nul = true
+			case "--untracked-files=all":
+				// go-git always lists untracked files one by one
+			default:
+				return "", errNotHandled
+			}
+		}
+		return goGitStatus(dir, nul)
+	case "config":
+		return goGitConfig(dir, args[1:])
+	case "remote":
+		if len(args) != 1 {
+			return "", errNotHandled
+		}
+		return goGitRemotes(dir)
+	case "log":
+		return goGitLog(dir, args[1:])
+	}
+	return "", errNotHandled
+}
+
+// gitLayout locates the directories of the repository containing a directory
+type gitLayout struct {
+	top, gitDir, commonDir string
+}
+
+func findGitLayout(dir string) (gitLayout, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return gitLayout{}, err
+	}
+	for current := abs; ; current = filepath.Dir(current) {
+		dotGit := filepath.Join(current, ".git")
+		info, err := os.Stat(dotGit)
+		if err == nil {
+			layout := gitLayout{top: current, gitDir: dotGit}
+			if !info.IsDir() {
+				// A linked worktree's .git file points at its directory inside the main repository
+				data, err := os.ReadFile(dotGit)
+				if err != nil {
+					return gitLayout{}, err
+				}
+				gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+				if !ok {
+					return gitLayout{}, errNotHandled
+				}
+				layout.gitDir = resolveDir(current, gitDir)
+			}
+			layout.commonDir = layout.gitDir
+			if data, err := os.ReadFile(filepath.Join(layout.gitDir, "commondir")); err == nil {
+				layout.commonDir = filepath.Clean(resolveDir(layout.gitDir, strings.TrimSpace(string(data))))
+			}
+			return layout, nil
+		}
+		if filepath.Dir(current) == current {
+			return gitLayout{}, &exitStatus{code: 128, message: "fatal: not a git repository (or any of the parent directories): .git"}
+		}
+	}
+}
+
+func openRepository(dir string) (*git.Repository, gitLayout, error) {
+	layout, err := findGitLayout(dir)
+	if err != nil {
+		return nil, layout, err
+	}
+	repo, err := git.PlainOpenWithOptions(layout.top, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	return repo, layout, err
+}
+
+func goGitRevParse(dir string, args []string) (string, error) {
+	if len(args) == 1 {
+		switch args[0] {
+		case "--git-dir", "--absolute-git-dir", "--git-common-dir", "--show-toplevel":
+			layout, err := findGitLayout(dir)
+			if err != nil {
+				return "", err
+			}
+			path := map[string]string{
+				"--git-dir":          layout.gitDir,
+				"--absolute-git-dir": layout.gitDir,
+				"--git-common-dir":   layout.commonDir,
+				"--show-toplevel":    layout.top,
+			}[args[0]]
+			return path + "\n", nil
+		}
+	}
+
+	verify, quiet, options := false, false, true
+	var revs []string
+	for _, arg := range args {
+		switch {
+		case !options:
+			revs = append(revs, arg)
+		case arg == "--end-of-options":
+			options = false
+		case arg == "--verify":
+			verify = true
+		case arg == "--quiet" || arg == "-q":
+			quiet = true
+		case strings.HasPrefix(arg, "-"):
+			return "", errNotHandled
+		default:
+			revs = append(revs, arg)
+		}
+	}
+	if len(revs) != 1 || strings.ContainsAny(revs[0], ":@") || strings.Contains(revs[0], "..") {
+		return "", errNotHandled
+	}
+
+	repo, _, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(strings.TrimSuffix(revs[0], "^{commit}")))
+	if err != nil {
+		switch {
+		case verify && quiet:
+			return "", &exitStatus{code: 1}
+		case verify:
+			return "", &exitStatus{code: 128, message: "fatal: Needed a single revision"}
+		default:
+			return "", &exitStatus{code: 128, message: fmt.Sprintf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree.", revs[0])}
+		}
+	}
+	return hash.String() + "\n", nil
+}
+
+func goGitSymbolicRef(dir string, args []string) (string, error) {
+	short, quiet := false, false
+	var names []string
+	for _, arg := range args {
+		switch arg {
+		case "--short":
+			short = true
+		case "-q", "--quiet":
+			quiet = true
+		default:
+			names = append(names, arg)
+		}
+	}
+	if len(names) != 1 || names[0] != "HEAD" {
+		return "", errNotHandled
+	}
+
+	repo, _, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", err
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		if quiet {
+			return "", &exitStatus{code: 1}
+		}
+		return "", &exitStatus{code: 128, message: "fatal: ref HEAD is not a symbolic ref"}
+	}
+	if short {
+		return head.Target().Short() + "\n", nil
+	}
+	return head.Target().String() + "\n", nil
+}
+
+func goGitStatus(dir string, nul bool) (string, error) {
+	repo, _, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return "", err
+	}
+
+	paths := make([]string, 0, len(status))
+	for path, file := range status {
+		if file.Staging != git.Unmodified || file.Worktree != git.Unmodified {
+			paths = append(paths, path)
+		}
+	}
+	// git lists changes to tracked files first, then untracked files, each by path
+	sort.Slice(paths, func(i, j int) bool {
+		iUntracked, jUntracked := status[paths[i]].Worktree == git.Untracked, status[paths[j]].Worktree == git.Untracked
+		if iUntracked != jUntracked {
+			return jUntracked
+		}
+		return paths[i] < paths[j]
+	})
+
+	var b strings.Builder
+	for _, path := range paths {
+		file := status[path]
+		if nul {
+			// -z prints paths verbatim and a rename's source after its destination
+			fmt.Fprintf(&b, "%c%c %s\x00", file.Staging, file.Worktree, path)
+			if file.Extra != "" {
+				fmt.Fprintf(&b, "%s\x00", file.Extra)
+			}
+			continue
+		}
+		if file.Extra != "" {
+			fmt.Fprintf(&b, "%c%c %s -> %s\n", file.Staging, file.Worktree, path, file.Extra)
+			continue
+		}
+		fmt.Fprintf(&b, "%c%c %s\n", file.Staging, file.Worktree, path)
+	}
+	return b.String(), nil
+}
+
+// configEntry is a config variable under the name git prints: section and key lowercased, the
+// subsection as written
+type configEntry struct {
+	name, value string
+}
+
+func goGitConfig(dir string, args []string) (string, error) {
+	if len(args) != 2 || (args[0] != "--get" && args[0] != "--get-regexp") {
+		return "", errNotHandled
+	}
+	repo, _, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+
+	var entries []configEntry
+	for _, section := range cfg.Raw.Sections {
+		prefix := strings.ToLower(section.Name) + "."
+		for _, opt := range section.Options {
+			entries = append(entries, configEntry{name: prefix + strings.ToLower(opt.Key), value: opt.Value})
+		}
+		for _, sub := range section.Subsections {
+			for _, opt := range sub.Options {
+				entries = append(entries, configEntry{name: prefix + sub.Name + "." + strings.ToLower(opt.Key), value: opt.Value})
+			}
+		}
+	}
+
+	if args[0] == "--get" {
+		name := canonicalConfigName(args[1])
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].name == name {
+				return entries[i].value + "\n", nil
+			}
+		}
+		return "", &exitStatus{code: 1}
+	}
+
+	pattern, err := regexp.Compile(args[1])
+	if err != nil {
+		return "", &exitStatus{code: 6, message: fmt.Sprintf("error: invalid pattern: %s", args[1])}
+	}
+	var b strings.Builder
+	for _, entry := range entries {
+		if !pattern.MatchString(entry.name) {
+			continue
+		}
+		if entry.value == "" {
+			fmt.Fprintln(&b, entry.name)
+		} else {
+			fmt.Fprintf(&b, "%s %s\n", entry.name, entry.value)
+		}
+	}
+	if b.Len() == 0 {
+		return "", &exitStatus{code: 1}
+	}
+	return b.String(), nil
+}
+
+// canonicalConfigName lowercases the section and key of section[.subsection].key
+func canonicalConfigName(name string) string {
+	first, last := strings.Index(name, "."), strings.LastIndex(name, ".")
+	if first < 0 {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(name[:first]) + name[first:last] + strings.ToLower(name[last:])
+}
+
+func goGitRemotes(dir string) (string, error) {
+	repo, _, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(remotes))
+	for _, remote := range remotes {
+		names = append(names, remote.Config().Name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return "", nil
+	}
+	return strings.Join(names, "\n") + "\n", nil
+}
+
+// goGitLog answers log [-N | -n N | --max-count=N] --format=<format> [<rev>]
+func goGitLog(dir string, args []string) (string, error) {
+	limit, format, rev := -1, "", "HEAD"
+	revSet := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-n" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return "", errNotHandled
+			}
+			limit = n
+			i++
+		case strings.HasPrefix(arg, "--max-count="):
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--max-count="))
+			if err != nil {
+				return "", errNotHandled
+			}
+			limit = n
+		case len(arg) > 1 && arg[0] == '-' && isDigits(arg[1:]):
+			limit, _ = strconv.Atoi(arg[1:])
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
+		case strings.HasPrefix(arg, "-") || revSet:
+			return "", errNotHandled
+		default:
+			rev, revSet = arg, true
+		}
+	}
+	if format == "" || strings.ContainsAny(rev, ":@") || strings.Contains(rev, "..") {
+		return "", errNotHandled
+	}
+	// Reject unknown placeholders before touching the repository
+	if _, ok := formatCommit(format, &object.Commit{}); !ok {
+		return "", errNotHandled
+	}
+
+	repo, _, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", &exitStatus{code: 128, message: fmt.Sprintf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree.", rev)}
+	}
+	commits, err := repo.Log(&git.LogOptions{From: *hash, Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return "", err
+	}
+	defer commits.Close()
+
+	var b strings.Builder
+	for n := 0; limit < 0 || n < limit; n++ {
+		commit, err := commits.Next()
+		if err != nil {
+			break
+		}
+		line, _ := formatCommit(format, commit)
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isoStrict is the layout of git's %aI and %cI, which never abbreviate UTC to Z
+const isoStrict = "2006-01-02T15:04:05-07:00"
+
+// formatCommit expands the --format placeholders wtm uses and reports false for any other
+func formatCommit(format string, c *object.Commit) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		rest := format[i+1:]
+		placeholder := ""
+		for _, p := range []string{"x00", "H", "h", "s", "an", "ae", "aI", "at", "cn", "ce", "cI", "ct", "n", "%"} {
+			if strings.HasPrefix(rest, p) {
+				placeholder = p
+				break
+			}
+		}
+		switch placeholder {
+		case "":
+			return "", false
+		case "x00":
+			b.WriteByte(0)
+		case "H":
+			b.WriteString(c.Hash.String())
+		case "h":
+			b.WriteString(c.Hash.String()[:7])
+		case "s":
+			b.WriteString(commitSubject(c.Message))
+		case "an":
+			b.WriteString(c.Author.Name)
+		case "ae":
+			b.WriteString(c.Author.Email)
+		case "aI":
+			b.WriteString(c.Author.When.Format(isoStrict))
+		case "at":
+			b.WriteString(strconv.FormatInt(c.Author.When.Unix(), 10))
+		case "cn":
+			b.WriteString(c.Committer.Name)
+		case "ce":
+			b.WriteString(c.Committer.Email)
+		case "cI":
+			b.WriteString(c.Committer.When.Format(isoStrict))
+		case "ct":
+			b.WriteString(strconv.FormatInt(c.Committer.When.Unix(), 10))
+		case "n":
+			b.WriteByte('\n')
+		case "%":
+			b.WriteByte('%')
+		}
+		i += len(placeholder)
+	}
+	return b.String(), true
+}
+
+// commitSubject joins the lines of the first paragraph of a message, as git's %s does
+func commitSubject(message string) string {
+	paragraph, _, _ := strings.Cut(strings.TrimLeft(message, "\n"), "\n\n")
+	lines := strings.Split(strings.TrimSpace(paragraph), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, " ")
+}
@@ -0,0 +1,135 @@
+//go:build gogit
+
+package wtm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// noFallback fails every command, so a test notices queries go-git did not answer itself
+type noFallback struct {
+	calls [][]string
+}
+
+func (r *noFallback) Run(ctx context.Context, dir string, args ...string) (string, error) {
+	r.calls = append(r.calls, args)
+	return "", errors.New("fallback not expected")
+}
+
+func TestGoGitRunnerMatchesGit(t *testing.T) {
+	ctx := t.Context()
+	dir := setupTestRepo(t)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tracked.txt"), []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "tracked.txt")
+	git("commit", "-m", "Add tracked\n\nWith a body")
+	git("config", "wtm.feature.readOnly", "true")
+	git("config", "wtm.feature.base", "main")
+	git("remote", "add", "origin", "https://example.com/repo.git")
+	linked := filepath.Join(t.TempDir(), "linked")
+	git("worktree", "add", "-b", "feature", linked)
+	if err := os.WriteFile(filepath.Join(dir, "tracked.txt"), []byte("two\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fallback := &noFallback{}
+	runner := GoGitRunner{Fallback: fallback}
+	for _, args := range [][]string{
+		{"rev-parse", "--show-toplevel"},
+		{"rev-parse", "--git-common-dir"},
+		{"-C", linked, "rev-parse", "--git-common-dir"},
+		{"-C", linked, "rev-parse", "--show-toplevel"},
+		{"rev-parse", "--verify", "--quiet", "HEAD^{commit}"},
+		{"rev-parse", "--verify", "--quiet", "--end-of-options", "HEAD^{commit}"},
+		{"rev-parse", "--verify", "--quiet", "refs/heads/feature"},
+		{"symbolic-ref", "--short", "-q", "HEAD"},
+		{"-C", linked, "symbolic-ref", "--short", "HEAD"},
+		{"status", "--porcelain"},
+		{"-C", linked, "status", "--porcelain"},
+		{"status", "--porcelain", "-z", "--untracked-files=all"},
+		{"config", "--get-regexp", `^wtm\.`},
+		{"config", "--get", "wtm.feature.readOnly"},
+		{"remote"},
+		{"log", "-1", "--format=%ct", "HEAD"},
+		{"log", "-n", "5", "--format=%H%x00%an%x00%aI%x00%s", "HEAD"},
+		{"log", "--format=%h %s", "feature"},
+	} {
+		want, err := ExecRunner{}.Run(ctx, dir, args...)
+		if err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+		got, err := runner.Run(ctx, dir, args...)
+		if err != nil {
+			t.Errorf("go-git %v failed: %v", args, err)
+			continue
+		}
+		if args[len(args)-1] == "--git-common-dir" {
+			// git prints .git relative to the directory it ran in
+			got, want = filepath.Clean(strings.TrimSpace(got)), strings.TrimSpace(want)
+			if !filepath.IsAbs(want) {
+				want = filepath.Join(dir, want)
+			}
+		}
+		if got != want {
+			t.Errorf("go-git %v = %q, git prints %q", args, got, want)
+		}
+	}
+	if len(fallback.calls) > 0 {
+		t.Errorf("expected go-git to answer every query, fell back for %q", fallback.calls)
+	}
+
+	// Exit codes match git's, so callers classify missing values the same way
+	for _, args := range [][]string{
+		{"rev-parse", "--verify", "--quiet", "refs/heads/missing"},
+		{"config", "--get", "wtm.missing.base"},
+		{"config", "--get-regexp", `^nothing\.`},
+	} {
+		if _, err := runner.Run(ctx, dir, args...); exitCode(err) != 1 {
+			t.Errorf("go-git %v: expected exit code 1, got %v", args, err)
+		}
+	}
+	if _, err := runner.Run(ctx, t.TempDir(), "rev-parse", "--git-dir"); !errors.Is(err, ErrNotARepo) {
+		t.Errorf("expected ErrNotARepo outside a repository, got %v", err)
+	}
+
+	// Mutations go to the fallback
+	if _, err := runner.Run(ctx, dir, "branch", "other"); err == nil || len(fallback.calls) != 1 {
+		t.Errorf("expected branch to be sent to the fallback, got %v", fallback.calls)
+	}
+}
+
+func TestManagerWithGoGitRunner(t *testing.T) {
+	ctx := t.Context()
+	m := &Manager{Dir: setupTestRepo(t), Runner: GoGitRunner{}}
+	if _, err := m.Add(ctx, "feature", AddOptions{}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := m.SetMeta(ctx, "feature", MetaReadOnly, "true"); err != nil {
+		t.Fatalf("SetMeta failed: %v", err)
+	}
+	worktrees, err := m.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(worktrees) != 2 || worktrees[1].Name != "feature" || !worktrees[1].ReadOnly {
+		t.Errorf("unexpected worktrees: %+v", worktrees)
+	}
+}
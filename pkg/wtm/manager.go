@@ -0,0 +1,672 @@
+// Package wtm manages git worktrees: it creates them under a worktree root, resolves them
+// by name, and removes them together with their branches and metadata.
+//
+// The wtm command line tool and its MCP server are thin adapters over this package.
+package wtm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultRoot is where worktrees are created, relative to the repository root
+const DefaultRoot = ".git/wtm/worktrees"
+
+// Layouts decide what a relative worktree root is resolved against
+const (
+	// LayoutNested keeps worktrees inside the repository, under DefaultRoot by default
+	LayoutNested = "nested"
+	// LayoutSibling keeps worktrees next to the repository, under ../<repo>-worktrees by default
+	LayoutSibling = "sibling"
+)
+
+// DefaultSiblingRoot returns the default worktree root of the sibling layout, relative to the repository's parent
+func DefaultSiblingRoot(repoRoot string) string {
+	return filepath.Base(repoRoot) + "-worktrees"
+}
+
+// Manager performs worktree operations on a single repository
+type Manager struct {
+	// Dir is the directory git commands run in; empty means the process working directory
+	Dir string
+	// Root is the worktree root, absolute or relative to the repository root, or to its parent
+	// directory with LayoutSibling (default DefaultRoot, or DefaultSiblingRoot with LayoutSibling)
+	Root string
+	// Layout is LayoutNested or LayoutSibling; empty means LayoutNested
+	Layout string
+	// AllowNestedNames lets names contain '/' to create worktrees in subdirectories of the worktree
+	// root, e.g. team/alice/login-fix; worktrees under the root are then named by their relative path
+	AllowNestedNames bool
+	// Plan, when set, receives the commands and file operations that change state instead of running them
+	Plan *Plan
+	// Runner executes git; nil means ExecRunner
+	Runner GitRunner
+	// Logger, when set, receives every git command with its duration at info level and its output at debug level
+	Logger *slog.Logger
+	// FilterUntracked, when set, returns the untracked paths of a worktree that Changes reports,
+	// e.g. to leave out generated files; nil reports all of them
+	FilterUntracked func(worktreePath string, paths []string) ([]string, error)
+}
+
+// New returns a manager for the repository containing dir
+func New(dir string) *Manager {
+	return &Manager{Dir: dir}
+}
+
+// AddOptions groups configuration for creating a worktree
+type AddOptions struct {
+	// Branch creates a new branch with this name (defaults to the worktree name)
+	Branch string
+	// Checkout uses an existing branch instead of creating one
+	Checkout string
+	// Base is the starting point for a new branch (defaults to the current HEAD)
+	Base string
+	// Detach checks out this commit, tag or ref in detached HEAD mode without creating a branch
+	Detach string
+	// ReadOnly removes write permissions from the checkout and marks it read-only
+	ReadOnly bool
+	// NoCheckout creates the worktree without populating files, e.g. to configure sparse-checkout first
+	NoCheckout bool
+	// Sparse limits the checkout to these directories with cone-mode sparse-checkout
+	Sparse []string
+	// Setup runs once the worktree exists and before it is made read-only, e.g. to write generated files.
+	// When it returns an error, Add removes the worktree, the branch it created and its metadata again.
+	Setup func(wt *Worktree) error
+}
+
+// BranchDeleteMode indicates how to handle the associated branch once the worktree is removed
+type BranchDeleteMode int
+
+const (
+	// BranchDeleteNone leaves the branch untouched
+	BranchDeleteNone BranchDeleteMode = iota
+	// BranchDeleteSafe deletes the branch via `git branch -d`, failing if it is not fully merged
+	BranchDeleteSafe
+	// BranchDeleteForce deletes the branch via `git branch -D`, even if it is not merged
+	BranchDeleteForce
+)
+
+// RemoveOptions groups configuration for removing a worktree
+type RemoveOptions struct {
+	// BranchDelete controls whether and how to delete the associated branch after removing the worktree
+	BranchDelete BranchDeleteMode
+	// Stash saves uncommitted and untracked changes with `git stash push` before removing the worktree
+	Stash bool
+	// DiscardChanges removes a worktree with uncommitted or untracked changes; otherwise Remove
+	// fails with ErrWorktreeDirty
+	DiscardChanges bool
+}
+
+// RemoveResult describes what Remove deleted
+type RemoveResult struct {
+	Worktree Worktree
+	// DeletedBranch is the branch that was deleted, empty when the branch was kept
+	DeletedBranch string
+	// Stashed reports whether uncommitted changes were saved as a stash
+	Stashed bool
+}
+
+// StaleWorktree is a worktree record or metadata section whose directory no longer exists
+type StaleWorktree struct {
+	Name string
+	// Path is empty when only metadata is left
+	Path string
+	// HasMeta reports whether wtm metadata is stored for the worktree
+	HasMeta bool
+}
+
+func (m *Manager) runner() GitRunner {
+	if m.Runner == nil {
+		return ExecRunner{}
+	}
+	return m.Runner
+}
+
+// Git runs a git command in the manager's directory and returns its output
+func (m *Manager) Git(ctx context.Context, args ...string) (string, error) {
+	start := time.Now()
+	output, err := m.runner().Run(ctx, m.Dir, args...)
+	if m.Logger != nil {
+		attrs := []slog.Attr{slog.String("cmd", ShellJoin(append([]string{"git"}, args...))), slog.Duration("duration", time.Since(start))}
+		if m.Dir != "" {
+			attrs = append(attrs, slog.String("dir", m.Dir))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		m.Logger.LogAttrs(ctx, slog.LevelInfo, "exec", attrs...)
+		m.Logger.LogAttrs(ctx, slog.LevelDebug, "output", slog.String("cmd", "git "+args[0]), slog.String("output", output))
+	}
+	return output, err
+}
+
+// gitQuiet runs git discarding its output; the error still carries the exit code for callers to inspect
+func (m *Manager) gitQuiet(ctx context.Context, args ...string) error {
+	_, err := m.Git(ctx, args...)
+	return err
+}
+
+// gitMutation runs a git command that changes repository state, or records it when planning
+func (m *Manager) gitMutation(ctx context.Context, args ...string) (string, error) {
+	if m.Plan != nil {
+		m.Plan.RecordCommand("git", args...)
+		return "", nil
+	}
+	return m.Git(ctx, args...)
+}
+
+// fileOp records a file operation when planning and reports whether the caller should skip it
+func (m *Manager) fileOp(format string, a ...any) bool {
+	if m.Plan == nil {
+		return false
+	}
+	m.Plan.Record(format, a...)
+	return true
+}
+
+// RepoRoot returns the root of the primary worktree
+func (m *Manager) RepoRoot(ctx context.Context) (string, error) {
+	commonDir, err := m.CommonDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Clean(filepath.Join(commonDir, "..")), nil
+}
+
+// CommonDir returns the absolute path of the repository's shared git directory
+func (m *Manager) CommonDir(ctx context.Context) (string, error) {
+	commonDir, err := m.Git(ctx, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+
+	commonDir = strings.TrimSpace(commonDir)
+	if !filepath.IsAbs(commonDir) {
+		dir := m.Dir
+		if dir == "" {
+			if dir, err = os.Getwd(); err != nil {
+				return "", err
+			}
+		}
+		commonDir = filepath.Join(dir, commonDir)
+	}
+	return commonDir, nil
+}
+
+// WorktreeBase returns the absolute directory new worktrees are created in
+func (m *Manager) WorktreeBase(ctx context.Context) (string, error) {
+	if m.Layout != "" && m.Layout != LayoutNested && m.Layout != LayoutSibling {
+		return "", fmt.Errorf("unknown layout '%s' (use %s or %s)", m.Layout, LayoutNested, LayoutSibling)
+	}
+	root := strings.TrimSpace(m.Root)
+	if filepath.IsAbs(root) {
+		return filepath.Clean(root), nil
+	}
+
+	repoRoot, err := m.RepoRoot(ctx)
+	if err != nil {
+		return "", err
+	}
+	if m.Layout == LayoutSibling {
+		if root == "" {
+			root = DefaultSiblingRoot(repoRoot)
+		}
+		return filepath.Clean(filepath.Join(filepath.Dir(repoRoot), root)), nil
+	}
+	if root == "" {
+		root = DefaultRoot
+	}
+	return filepath.Clean(filepath.Join(repoRoot, root)), nil
+}
+
+// RefExists reports whether a fully qualified ref such as refs/heads/main exists
+func (m *Manager) RefExists(ctx context.Context, ref string) bool {
+	return m.gitQuiet(ctx, "show-ref", "--verify", "--quiet", ref) == nil
+}
+
+// resolveRemoteCheckout maps a remote branch ref such as origin/feature/login, for which no local branch
+// of the same name exists, to the local branch to check out. create reports whether that local
+// tracking branch still has to be created.
+func (m *Manager) resolveRemoteCheckout(ctx context.Context, ref string) (local string, create bool, ok bool) {
+	if m.RefExists(ctx, "refs/heads/"+ref) || !m.RefExists(ctx, "refs/remotes/"+ref) {
+		return "", false, false
+	}
+
+	output, err := m.Git(ctx, "remote")
+	if err != nil {
+		return "", false, false
+	}
+	for _, remote := range strings.Split(output, "\n") {
+		remote = strings.TrimSpace(remote)
+		if remote == "" || !strings.HasPrefix(ref, remote+"/") {
+			continue
+		}
+		local = strings.TrimPrefix(ref, remote+"/")
+		return local, !m.RefExists(ctx, "refs/heads/"+local), true
+	}
+	return "", false, false
+}
+
+// Add creates a worktree named name under the worktree root and returns it.
+// While planning, the returned worktree only carries the name, branch and path it would get.
+func (m *Manager) Add(ctx context.Context, name string, opts AddOptions) (_ *Worktree, err error) {
+	branch, checkout, base := opts.Branch, opts.Checkout, opts.Base
+
+	if err := m.validateName(name); err != nil {
+		return nil, err
+	}
+
+	// Validate we're in a git repository
+	if _, err := m.Git(ctx, "rev-parse", "--git-dir"); err != nil {
+		return nil, ErrNotARepo
+	}
+
+	// Check if worktree already exists
+	worktrees, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, wt := range worktrees {
+		if wt.Name == name {
+			return nil, &WorktreeError{Name: name, Err: ErrWorktreeExists}
+		}
+	}
+
+	if checkout != "" && branch != "" {
+		return nil, fmt.Errorf("cannot use both -b and -B options")
+	}
+	if opts.Detach != "" && (branch != "" || checkout != "" || base != "") {
+		return nil, fmt.Errorf("cannot combine --detach with branch options")
+	}
+
+	// Determine the path for the worktree
+	worktreeBase, err := m.WorktreeBase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !m.fileOp("mkdir -p %s", worktreeBase) {
+		if err := os.MkdirAll(worktreeBase, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	// git worktree add creates the parent directories of a nested name
+	worktreePath := filepath.Join(worktreeBase, filepath.FromSlash(name))
+
+	args := []string{"worktree", "add"}
+	if opts.NoCheckout || len(opts.Sparse) > 0 {
+		// A sparse worktree is populated only after its sparse-checkout patterns are set
+		args = append(args, "--no-checkout")
+	}
+
+	// newBranch is the branch checked out in the new worktree, empty for detached HEADs
+	var newBranch string
+	// createdBranch is set when git creates newBranch, so an interrupted add can delete it again;
+	// Add refuses to create a branch that already exists, so it never deletes one it did not create
+	var createdBranch bool
+	// forkedFrom is the base recorded for a newly created branch
+	var forkedFrom string
+	if opts.Detach != "" {
+		// Check out an arbitrary revision without creating a branch
+		args = append(args, "--detach", worktreePath, opts.Detach)
+	} else if branch != "" {
+		// Create new branch
+		if m.RefExists(ctx, "refs/heads/"+branch) {
+			return nil, &BranchError{Name: branch, Err: ErrBranchExists}
+		}
+		args = append(args, worktreePath, "-b", branch)
+		if base != "" {
+			args = append(args, base)
+		}
+		newBranch, createdBranch = branch, true
+		forkedFrom = m.forkBase(ctx, base)
+	} else if checkout != "" {
+		// Checkout existing branch, creating a local tracking branch for remote refs like origin/feature
+		local, create, ok := m.resolveRemoteCheckout(ctx, checkout)
+		switch {
+		case ok && create:
+			args = append(args, "--track", "-b", local, worktreePath, checkout)
+			createdBranch = true
+		case ok:
+			args = append(args, worktreePath, local)
+		default:
+			args = append(args, worktreePath, checkout)
+			local = checkout
+		}
+		newBranch = local
+	} else {
+		// Default: create a branch named after the worktree
+		newBranch = BranchName(name)
+		if newBranch == "" {
+			return nil, fmt.Errorf("cannot derive a branch name from '%s'; pass -b", name)
+		}
+		if m.RefExists(ctx, "refs/heads/"+newBranch) {
+			return nil, &BranchError{Name: newBranch, Err: ErrBranchExists}
+		}
+		args = append(args, worktreePath, "-b", newBranch)
+		if base != "" {
+			args = append(args, base)
+		}
+		createdBranch = true
+		forkedFrom = m.forkBase(ctx, base)
+	}
+
+	// An add interrupted by ctx, for example by Ctrl-C, must not leave a half-populated worktree behind
+	_, statErr := os.Stat(worktreePath)
+	preexisting := statErr == nil
+	defer func() {
+		if err == nil || ctx.Err() == nil || m.Plan != nil {
+			return
+		}
+		m.discardPartial(context.WithoutCancel(ctx), name, worktreePath, newBranch, createdBranch, preexisting)
+		err = fmt.Errorf("interrupted; removed the partially created worktree '%s': %w", name, ctx.Err())
+	}()
+
+	// Execute git worktree add
+	if _, err := m.gitMutation(ctx, args...); err != nil {
+		return nil, err
+	}
+	if len(opts.Sparse) > 0 {
+		sparseArgs := append([]string{"-C", worktreePath, "sparse-checkout", "set", "--cone"}, opts.Sparse...)
+		if _, err := m.gitMutation(ctx, sparseArgs...); err != nil {
+			return nil, fmt.Errorf("created worktree '%s' but failed to set up sparse-checkout: %w", name, err)
+		}
+		if !opts.NoCheckout {
+			if _, err := m.gitMutation(ctx, "-C", worktreePath, "checkout"); err != nil {
+				return nil, fmt.Errorf("created worktree '%s' but failed to check out files: %w", name, err)
+			}
+		}
+	}
+
+	if forkedFrom != "" {
+		if err := m.SetMeta(ctx, name, MetaBase, forkedFrom); err != nil {
+			return nil, fmt.Errorf("created worktree '%s' but failed to record its base: %w", name, err)
+		}
+	}
+
+	created := &Worktree{Name: name, Branch: newBranch, Path: worktreePath, ReadOnly: opts.ReadOnly, NoCheckout: opts.NoCheckout, Base: forkedFrom}
+	if opts.Setup != nil {
+		if err := opts.Setup(created); err != nil {
+			if ctx.Err() != nil || m.Plan != nil {
+				return nil, err
+			}
+			// A failed setup aborts the add, so nothing it created is left behind
+			m.discardPartial(ctx, name, worktreePath, newBranch, createdBranch, preexisting)
+			return nil, fmt.Errorf("setup failed; removed the worktree '%s': %w", name, err)
+		}
+	}
+
+	if opts.ReadOnly {
+		if err := m.SetMeta(ctx, name, MetaReadOnly, "true"); err != nil {
+			return nil, err
+		}
+		if !m.fileOp("chmod -R a-w %s", worktreePath) {
+			if err := SetWritable(worktreePath, false); err != nil {
+				return nil, fmt.Errorf("created worktree '%s' but failed to make it read-only: %w", name, err)
+			}
+		}
+	}
+
+	if m.Plan != nil {
+		created.CreatedBranch = createdBranch
+		return created, nil
+	}
+	wt, err := m.Show(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	wt.CreatedBranch = createdBranch
+	return wt, nil
+}
+
+// discardPartial removes what an interrupted or failed Add created: the worktree directory unless it existed before,
+// git's administrative entry, the new branch and any recorded metadata. Failures are ignored since
+// any of these may not have been created yet.
+func (m *Manager) discardPartial(ctx context.Context, name, path, branch string, createdBranch, preexisting bool) {
+	if !preexisting {
+		_ = SetWritable(path, true)
+		_ = os.RemoveAll(path)
+	}
+	_, _ = m.Git(ctx, "worktree", "prune")
+	if createdBranch && branch != "" {
+		_, _ = m.Git(ctx, "branch", "-D", branch)
+	}
+	_ = m.ClearMeta(ctx, name)
+}
+
+// forkBase returns the base to record for a new branch: the explicit base, or the branch checked out
+// where wtm runs. It is empty when HEAD is detached, since a bare commit says nothing about where work goes back to.
+func (m *Manager) forkBase(ctx context.Context, base string) string {
+	if base != "" && base != "HEAD" {
+		return base
+	}
+	current, err := m.Git(ctx, "symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(current)
+}
+
+// Remove removes the named worktree and then deletes its branch according to opts
+func (m *Manager) Remove(ctx context.Context, name string, opts RemoveOptions) (*RemoveResult, error) {
+	target, err := m.Show(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return m.RemoveWorktree(ctx, target, opts)
+}
+
+// Changes returns the porcelain status lines of a worktree's uncommitted and untracked changes.
+// Untracked paths pass through FilterUntracked.
+func (m *Manager) Changes(ctx context.Context, target *Worktree) ([]string, error) {
+	// NUL-separated entries carry paths verbatim instead of C-quoting spaces and non-ASCII bytes
+	args := []string{"-C", target.Path, "status", "--porcelain", "-z"}
+	if m.FilterUntracked != nil {
+		// List files in untracked directories one by one, so the filter sees every path
+		args = append(args, "--untracked-files=all")
+	}
+	output, err := m.Git(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	var changes, untracked []string
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		switch {
+		case len(entry) < 4:
+		case strings.HasPrefix(entry, "?? ") && m.FilterUntracked != nil:
+			untracked = append(untracked, entry[3:])
+		default:
+			changes = append(changes, entry)
+			if strings.ContainsAny(entry[:2], "RC") {
+				// Renames and copies are followed by the source path
+				i++
+			}
+		}
+	}
+	if len(untracked) > 0 {
+		kept, err := m.FilterUntracked(target.Path, untracked)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range kept {
+			changes = append(changes, "?? "+path)
+		}
+	}
+	return changes, nil
+}
+
+// Stash saves a worktree's uncommitted and untracked changes as a stash whose message names the
+// worktree and branch. Stashes are shared by all worktrees, so they outlive the removed worktree.
+// It reports false when there was nothing to stash.
+func (m *Manager) Stash(ctx context.Context, target *Worktree) (bool, error) {
+	changes, err := m.Changes(ctx, target)
+	if err != nil || len(changes) == 0 {
+		return false, err
+	}
+	message := fmt.Sprintf("wtm: uncommitted changes of worktree '%s'", target.Name)
+	if target.Branch != "" {
+		message += fmt.Sprintf(" (branch %s)", target.Branch)
+	}
+	if _, err := m.gitMutation(ctx, "-C", target.Path, "stash", "push", "--include-untracked", "-m", message); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnpushedCommits returns the commits, one "<hash> <subject>" per entry, that only the worktree's branch
+// (or detached HEAD) points to: they are on no remote, tag or other local branch, so they become
+// unreachable once the branch is deleted or the detached worktree is removed.
+func (m *Manager) UnpushedCommits(ctx context.Context, target *Worktree) ([]string, error) {
+	tip, exclude := target.HEAD, []string{}
+	if target.Branch != "" {
+		tip = "refs/heads/" + target.Branch
+		exclude = append(exclude, "--exclude="+target.Branch)
+	}
+	if tip == "" {
+		return nil, nil
+	}
+	args := append([]string{"log", "--format=%h %s", tip, "--not"}, exclude...)
+	output, err := m.Git(ctx, append(args, "--branches", "--remotes", "--tags")...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits, nil
+}
+
+// RemoveWorktree removes a resolved worktree and then deletes its branch according to opts.
+// When only the branch deletion fails, the result is returned along with the error.
+func (m *Manager) RemoveWorktree(ctx context.Context, target *Worktree, opts RemoveOptions) (*RemoveResult, error) {
+	var stashed bool
+	switch {
+	case opts.Stash:
+		var err error
+		if stashed, err = m.Stash(ctx, target); err != nil {
+			return nil, fmt.Errorf("failed to stash changes of worktree '%s': %w", target.Name, err)
+		}
+	case !opts.DiscardChanges:
+		changes, err := m.Changes(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) > 0 {
+			return nil, &WorktreeError{Name: target.Name, Err: ErrWorktreeDirty}
+		}
+	}
+
+	if target.ReadOnly {
+		// Restore write permissions so git can delete the checkout
+		if !m.fileOp("chmod -R +w %s", target.Path) {
+			if err := SetWritable(target.Path, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if _, err := m.gitMutation(ctx, "worktree", "remove", "--force", target.Path); err != nil {
+		return nil, err
+	}
+	if err := m.ClearMeta(ctx, target.Name); err != nil {
+		return nil, err
+	}
+	if strings.Contains(target.Name, "/") {
+		m.removeEmptyParents(ctx, target.Path)
+	}
+	result := &RemoveResult{Worktree: *target, Stashed: stashed}
+
+	if opts.BranchDelete == BranchDeleteNone || target.Branch == "" {
+		return result, nil
+	}
+
+	flag := "-d" // default to safe deletion
+	if opts.BranchDelete == BranchDeleteForce {
+		flag = "-D" // force delete for unmerged branches
+	}
+	if _, err := m.gitMutation(ctx, "branch", flag, target.Branch); err != nil {
+		return result, fmt.Errorf("deleted worktree '%s' but failed to delete branch '%s': %w", target.Name, target.Branch, err)
+	}
+	result.DeletedBranch = target.Branch
+	return result, nil
+}
+
+// removeEmptyParents deletes the directories a nested worktree was created in, from the innermost
+// up to the worktree root, as long as nothing else is left in them
+func (m *Manager) removeEmptyParents(ctx context.Context, path string) {
+	base, err := m.WorktreeBase(ctx)
+	if err != nil {
+		return
+	}
+	base = NormalizePath(base)
+	for dir := NormalizePath(filepath.Dir(path)); dir != base && within(dir, base); dir = filepath.Dir(dir) {
+		if m.fileOp("rmdir %s", dir) {
+			continue
+		}
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// Stale lists worktree records and wtm metadata whose directories no longer exist
+func (m *Manager) Stale(ctx context.Context) ([]StaleWorktree, error) {
+	worktrees, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := m.Meta(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []StaleWorktree
+	seen := map[string]bool{}
+	for _, wt := range worktrees {
+		seen[wt.Name] = true
+		if _, err := os.Stat(wt.Path); err == nil {
+			continue
+		}
+		stale = append(stale, StaleWorktree{Name: wt.Name, Path: wt.Path, HasMeta: meta[wt.Name] != nil})
+	}
+	var names []string
+	for name := range meta {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stale = append(stale, StaleWorktree{Name: name, HasMeta: true})
+	}
+	return stale, nil
+}
+
+// Prune deletes the stale worktree records and metadata and returns what was pruned
+func (m *Manager) Prune(ctx context.Context) ([]StaleWorktree, error) {
+	stale, err := m.Stale(ctx)
+	if err != nil || len(stale) == 0 {
+		return stale, err
+	}
+	if _, err := m.gitMutation(ctx, "worktree", "prune"); err != nil {
+		return nil, err
+	}
+	for _, s := range stale {
+		if s.HasMeta {
+			if err := m.ClearMeta(ctx, s.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return stale, nil
+}
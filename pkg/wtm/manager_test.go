@@ -0,0 +1,767 @@
+package wtm
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// setupTestRepo creates a temporary git repository with one commit
+func setupTestRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.name", "Test User"},
+		{"config", "user.email", "test@example.com"},
+		{"commit", "--allow-empty", "-m", "Initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+	return dir
+}
+
+func TestManagerLifecycle(t *testing.T) {
+	ctx := t.Context()
+	m := New(setupTestRepo(t))
+
+	var setupPath string
+	wt, err := m.Add(ctx, "feature", AddOptions{Setup: func(wt *Worktree) error {
+		setupPath = wt.Path
+		return nil
+	}})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if wt.Branch != "feature" || setupPath != wt.Path {
+		t.Errorf("unexpected worktree %+v (setup saw %q)", wt, setupPath)
+	}
+	base, err := m.WorktreeBase(ctx)
+	if err != nil {
+		t.Fatalf("WorktreeBase failed: %v", err)
+	}
+	if NormalizePath(filepath.Dir(wt.Path)) != NormalizePath(base) {
+		t.Errorf("expected worktree under %s, got %s", base, wt.Path)
+	}
+
+	if _, err := m.Add(ctx, "feature", AddOptions{}); err == nil {
+		t.Error("expected adding a duplicate worktree to fail")
+	}
+
+	worktrees, err := m.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(worktrees) != 2 {
+		t.Fatalf("expected primary and feature worktrees, got %+v", worktrees)
+	}
+
+	res, err := m.Remove(ctx, "feature", RemoveOptions{BranchDelete: BranchDeleteSafe})
+	if err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if res.Worktree.Name != "feature" || res.DeletedBranch != "feature" {
+		t.Errorf("unexpected remove result %+v", res)
+	}
+	if m.RefExists(ctx, "refs/heads/feature") {
+		t.Error("expected the feature branch to be deleted")
+	}
+	if _, err := m.Show(ctx, "feature"); err == nil {
+		t.Error("expected the removed worktree to be gone")
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"feature", "fix-123", "Review PR"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", " ", "a/b", `a\b`, "-x", ".", "..", "-", "tab\there"} {
+		if err := ValidateName(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("ValidateName(%q) = %v, want ErrInvalidName", name, err)
+		}
+	}
+
+	m := New(setupTestRepo(t))
+	if _, err := m.Add(t.Context(), "a/b", AddOptions{}); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Add with invalid name = %v, want ErrInvalidName", err)
+	}
+
+	t.Run("windows", func(t *testing.T) {
+		defer func(old bool) { windowsNames = old }(windowsNames)
+		windowsNames = true
+		for _, name := range []string{"feature", "con-fix", "auxiliary"} {
+			if err := ValidateName(name); err != nil {
+				t.Errorf("ValidateName(%q) = %v, want nil", name, err)
+			}
+		}
+		for _, name := range []string{"a:b", "what?", "trailing.", "trailing ", "CON", "nul.txt", "Com1"} {
+			if err := ValidateName(name); !errors.Is(err, ErrInvalidName) {
+				t.Errorf("ValidateName(%q) = %v, want ErrInvalidName", name, err)
+			}
+		}
+	})
+}
+
+func TestManagerNestedNames(t *testing.T) {
+	ctx := t.Context()
+	m := New(setupTestRepo(t))
+	m.AllowNestedNames = true
+
+	for _, name := range []string{"team/alice/login-fix", "team/bob"} {
+		if err := ValidateNestedName(name); err != nil {
+			t.Errorf("ValidateNestedName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", "/abs", "team//x", "team/", "team/..", "team/-x", `team\x`} {
+		if err := ValidateNestedName(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("ValidateNestedName(%q) = %v, want ErrInvalidName", name, err)
+		}
+	}
+
+	wt, err := m.Add(ctx, "team/alice/login-fix", AddOptions{})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	base, err := m.WorktreeBase(ctx)
+	if err != nil {
+		t.Fatalf("WorktreeBase failed: %v", err)
+	}
+	if want := filepath.Join(base, "team", "alice", "login-fix"); NormalizePath(wt.Path) != NormalizePath(want) {
+		t.Errorf("expected worktree at %s, got %s", want, wt.Path)
+	}
+	if wt.Branch != "team/alice/login-fix" {
+		t.Errorf("expected branch team/alice/login-fix, got %q", wt.Branch)
+	}
+
+	shown, err := m.Show(ctx, "team/alice/login-fix")
+	if err != nil {
+		t.Fatalf("Show failed: %v", err)
+	}
+	if NormalizePath(shown.Path) != NormalizePath(wt.Path) {
+		t.Errorf("Show returned %s, want %s", shown.Path, wt.Path)
+	}
+	if path, err := m.Path(ctx, "team/alice/login-fix"); err != nil || NormalizePath(path) != NormalizePath(wt.Path) {
+		t.Errorf("Path = %q, %v; want %s", path, err, wt.Path)
+	}
+
+	if _, err := m.Remove(ctx, "team/alice/login-fix", RemoveOptions{}); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(base, "team")); !os.IsNotExist(err) {
+		t.Errorf("expected the empty parent directories to be removed, got %v", err)
+	}
+
+	m.AllowNestedNames = false
+	if _, err := m.Add(ctx, "team/bob", AddOptions{}); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Add with a nested name and AllowNestedNames unset = %v, want ErrInvalidName", err)
+	}
+}
+
+func TestWorktreeBaseLayouts(t *testing.T) {
+	ctx := t.Context()
+	dir := setupTestRepo(t)
+	repoRoot, err := New(dir).RepoRoot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := filepath.Dir(repoRoot)
+	abs := filepath.Join(t.TempDir(), "trees")
+
+	tests := []struct {
+		name   string
+		layout string
+		root   string
+		want   string
+	}{
+		{name: "nested default", want: filepath.Join(repoRoot, DefaultRoot)},
+		{name: "nested relative", layout: LayoutNested, root: "trees", want: filepath.Join(repoRoot, "trees")},
+		{name: "sibling default", layout: LayoutSibling, want: filepath.Join(parent, filepath.Base(repoRoot)+"-worktrees")},
+		{name: "sibling relative", layout: LayoutSibling, root: "trees", want: filepath.Join(parent, "trees")},
+		{name: "absolute", layout: LayoutSibling, root: abs, want: abs},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{Dir: dir, Root: tt.root, Layout: tt.layout}
+			base, err := m.WorktreeBase(ctx)
+			if err != nil {
+				t.Fatalf("WorktreeBase failed: %v", err)
+			}
+			if base != filepath.Clean(tt.want) {
+				t.Errorf("WorktreeBase = %s, want %s", base, tt.want)
+			}
+		})
+	}
+
+	if _, err := (&Manager{Dir: dir, Layout: "flat"}).WorktreeBase(ctx); err == nil {
+		t.Error("expected an unknown layout to be rejected")
+	}
+}
+
+func TestBranchName(t *testing.T) {
+	tests := map[string]string{
+		"feature":        "feature",
+		"Login Fix":      "Login-Fix",
+		"what?*now":      "whatnow",
+		"v1..2":          "v1.2",
+		"-topic.lock":    "topic",
+		"a.lock.":        "a",
+		"x.lock.lock":    "x",
+		"team/.hidden/x": "team/hidden/x",
+		"a  -  b":        "a-b",
+		"???":            "",
+	}
+	for name, want := range tests {
+		if got := BranchName(name); got != want {
+			t.Errorf("BranchName(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	m := New(setupTestRepo(t))
+	wt, err := m.Add(t.Context(), "Login Fix", AddOptions{})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if wt.Branch != "Login-Fix" {
+		t.Errorf("expected branch Login-Fix, got %q", wt.Branch)
+	}
+}
+
+func TestManagerSparseAdd(t *testing.T) {
+	ctx := t.Context()
+	dir := setupTestRepo(t)
+	for _, file := range []string{"apps/web/index.js", "apps/api/main.go", "README.md"} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := New(dir)
+	if _, err := m.Git(ctx, "add", "."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Git(ctx, "commit", "-m", "Add files"); err != nil {
+		t.Fatal(err)
+	}
+
+	wt, err := m.Add(ctx, "web", AddOptions{Sparse: []string{"apps/web"}})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	for file, want := range map[string]bool{"apps/web/index.js": true, "README.md": true, "apps/api/main.go": false} {
+		if _, err := os.Stat(filepath.Join(wt.Path, file)); (err == nil) != want {
+			t.Errorf("%s checked out = %v, want %v", file, err == nil, want)
+		}
+	}
+	if wt.NoCheckout {
+		t.Error("expected the sparse worktree to be checked out")
+	}
+}
+
+func TestManagerRecordsBase(t *testing.T) {
+	ctx := t.Context()
+	dir := setupTestRepo(t)
+	m := New(dir)
+	current, err := m.Git(ctx, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Git(ctx, "branch", "release"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts AddOptions
+		want string
+	}{
+		{name: "current", want: strings.TrimSpace(current)},
+		{name: "explicit", opts: AddOptions{Base: "release"}, want: "release"},
+		{name: "checkout", opts: AddOptions{Checkout: "release"}, want: ""},
+		{name: "detached", opts: AddOptions{Detach: "HEAD"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.Add(ctx, tt.name, tt.opts); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+			wt, err := m.Show(ctx, tt.name)
+			if err != nil {
+				t.Fatalf("Show failed: %v", err)
+			}
+			if wt.Base != tt.want {
+				t.Errorf("Base = %q, want %q", wt.Base, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagerRemoveStashesChanges(t *testing.T) {
+	ctx := t.Context()
+	m := New(setupTestRepo(t))
+
+	wt, err := m.Add(ctx, "wip", AddOptions{})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(wt.Path, "draft.txt"), []byte("draft"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := m.Remove(ctx, "wip", RemoveOptions{Stash: true})
+	if err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if !res.Stashed {
+		t.Error("expected the changes to be stashed")
+	}
+	list, err := m.Git(ctx, "stash", "list")
+	if err != nil {
+		t.Fatalf("git stash list failed: %v", err)
+	}
+	if !strings.Contains(list, "wtm: uncommitted changes of worktree 'wip' (branch wip)") {
+		t.Errorf("expected a descriptive stash, got %q", list)
+	}
+	if _, err := m.Git(ctx, "rev-parse", "--verify", "stash^3:draft.txt"); err != nil {
+		t.Errorf("expected the untracked file in the stash: %v", err)
+	}
+}
+
+func TestManagerChangesFilterUntracked(t *testing.T) {
+	ctx := t.Context()
+	m := New(setupTestRepo(t))
+	wt, err := m.Add(ctx, "gen", AddOptions{})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	// Spaces and non-ASCII bytes reach the filter unquoted
+	for _, f := range []string{"my notés.txt", "build out.log"} {
+		if err := os.WriteFile(filepath.Join(wt.Path, f), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m.FilterUntracked = func(worktreePath string, paths []string) ([]string, error) {
+		var kept []string
+		for _, p := range paths {
+			if !strings.HasSuffix(p, ".log") {
+				kept = append(kept, p)
+			}
+		}
+		return kept, nil
+	}
+	changes, err := m.Changes(ctx, wt)
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0] != "?? my notés.txt" {
+		t.Errorf("expected only my notés.txt, got %q", changes)
+	}
+
+	if err := os.Remove(filepath.Join(wt.Path, "my notés.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Remove(ctx, "gen", RemoveOptions{}); err != nil {
+		t.Errorf("expected a worktree with only filtered files to be clean, got %v", err)
+	}
+}
+
+func TestSetWritableRestoresModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows only has an owner write bit")
+	}
+	if umask() != 0o022 {
+		t.Skipf("expects umask 022, got %03o", umask())
+	}
+	root := t.TempDir()
+	modes := map[string]fs.FileMode{
+		"shared.txt":     0o644,
+		"private.txt":    0o600,
+		"run.sh":         0o755,
+		"sub":            0o755,
+		"sub/secret.txt": 0o400,
+	}
+	for _, name := range []string{"sub", "shared.txt", "private.txt", "run.sh", "sub/secret.txt"} {
+		p := filepath.Join(root, name)
+		if name == "sub" {
+			if err := os.Mkdir(p, 0o755); err != nil {
+				t.Fatal(err)
+			}
+		} else if err := os.WriteFile(p, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(p, modes[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetWritable(root, false); err != nil {
+		t.Fatalf("SetWritable(false) failed: %v", err)
+	}
+	if err := SetWritable(root, true); err != nil {
+		t.Fatalf("SetWritable(true) failed: %v", err)
+	}
+	for name, want := range modes {
+		if want == 0o400 {
+			// Files that were not writable at all become writable by their owner
+			want = 0o600
+		}
+		info, err := os.Stat(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s: mode %03o, want %03o", name, got, want)
+		}
+	}
+}
+
+func TestManagerUnpushedCommits(t *testing.T) {
+	ctx := t.Context()
+	m := New(setupTestRepo(t))
+
+	wt, err := m.Add(ctx, "feature", AddOptions{})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if commits, err := m.UnpushedCommits(ctx, wt); err != nil || len(commits) != 0 {
+		t.Fatalf("expected no commits unique to a new branch, got %v, %v", commits, err)
+	}
+
+	if _, err := m.Git(ctx, "-C", wt.Path, "commit", "--allow-empty", "-m", "local work"); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	commits, err := m.UnpushedCommits(ctx, wt)
+	if err != nil || len(commits) != 1 || !strings.HasSuffix(commits[0], " local work") {
+		t.Fatalf("expected the local commit, got %v, %v", commits, err)
+	}
+
+	// Another branch keeps the commit reachable
+	if _, err := m.Git(ctx, "branch", "backup", "feature"); err != nil {
+		t.Fatalf("branch failed: %v", err)
+	}
+	if commits, err := m.UnpushedCommits(ctx, wt); err != nil || len(commits) != 0 {
+		t.Errorf("expected no unreachable commits once backed up, got %v, %v", commits, err)
+	}
+}
+
+func TestManagerPlanAndPrune(t *testing.T) {
+	ctx := t.Context()
+	m := New(setupTestRepo(t))
+
+	m.Plan = &Plan{}
+	wt, err := m.Add(ctx, "planned", AddOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("planned Add failed: %v", err)
+	}
+	if _, err := os.Stat(wt.Path); !os.IsNotExist(err) {
+		t.Errorf("planning must not create %s", wt.Path)
+	}
+	if len(m.Plan.Steps) != 5 {
+		t.Errorf("expected mkdir, worktree add, base and read-only config, and chmod steps, got %q", m.Plan.Steps)
+	}
+	m.Plan = nil
+
+	wt, err = m.Add(ctx, "gone", AddOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := SetWritable(wt.Path, true); err != nil {
+		t.Fatalf("SetWritable failed: %v", err)
+	}
+	if err := os.RemoveAll(wt.Path); err != nil {
+		t.Fatalf("failed to delete worktree directory: %v", err)
+	}
+
+	pruned, err := m.Prune(ctx)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(pruned) != 1 || pruned[0].Name != "gone" || !pruned[0].HasMeta {
+		t.Errorf("unexpected pruned worktrees %+v", pruned)
+	}
+	meta, err := m.Meta(ctx)
+	if err != nil {
+		t.Fatalf("Meta failed: %v", err)
+	}
+	if len(meta) != 0 {
+		t.Errorf("expected metadata to be cleared, got %v", meta)
+	}
+}
+
+// recordingRunner delegates to ExecRunner and remembers every git invocation
+type recordingRunner struct {
+	calls [][]string
+}
+
+func (r *recordingRunner) Run(ctx context.Context, dir string, args ...string) (string, error) {
+	r.calls = append(r.calls, args)
+	return ExecRunner{}.Run(ctx, dir, args...)
+}
+
+func TestManagerUsesRunner(t *testing.T) {
+	ctx := t.Context()
+	runner := &recordingRunner{}
+	m := &Manager{Dir: setupTestRepo(t), Runner: runner}
+
+	if _, err := m.List(ctx); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(runner.calls) == 0 || runner.calls[0][0] != "worktree" {
+		t.Fatalf("expected List to go through the runner, got %q", runner.calls)
+	}
+
+	// Exit codes must survive the runner so missing config keys are not reported as errors
+	if err := m.UnsetMeta(ctx, "missing", MetaReadOnly); err != nil {
+		t.Errorf("UnsetMeta of a missing key failed: %v", err)
+	}
+	if err := m.ClearMeta(ctx, "missing"); err != nil {
+		t.Errorf("ClearMeta of a missing section failed: %v", err)
+	}
+}
+
+func TestExecRunnerSeparatesStderr(t *testing.T) {
+	ctx := t.Context()
+	dir := setupTestRepo(t)
+
+	// git switch reports on stderr; only stdout is output
+	output, err := ExecRunner{}.Run(ctx, dir, "switch", "-c", "feature")
+	if err != nil {
+		t.Fatalf("git switch failed: %v", err)
+	}
+	if output != "" {
+		t.Errorf("expected stderr to stay out of the output, got %q", output)
+	}
+
+	_, err = ExecRunner{}.Run(ctx, dir, "rev-parse", "--verify", "missing")
+	var gitErr *GitError
+	if !errors.As(err, &gitErr) || !strings.Contains(gitErr.Output, "Needed a single revision") {
+		t.Errorf("expected the error to carry git's stderr, got %v", err)
+	}
+}
+
+func TestExecRunnerPinsLocale(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the alias runs through sh")
+	}
+	t.Setenv("LC_ALL", "de_DE.UTF-8")
+	t.Setenv("LANG", "de_DE.UTF-8")
+
+	output, err := ExecRunner{}.Run(t.Context(), setupTestRepo(t), "-c", "alias.locale=!echo $LC_ALL", "locale")
+	if err != nil {
+		t.Fatalf("git alias failed: %v", err)
+	}
+	if got := strings.TrimSpace(output); got != "C" {
+		t.Errorf("expected git to run with LC_ALL=C, got %q", got)
+	}
+}
+
+func TestManagerHonorsCancellation(t *testing.T) {
+	m := New(setupTestRepo(t))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := m.List(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected List to fail with context.Canceled, got %v", err)
+	}
+	if _, err := m.Add(ctx, "feature", AddOptions{}); err == nil {
+		t.Error("expected Add to fail with a cancelled context")
+	}
+}
+
+func TestManagerAddInterruptedCleansUp(t *testing.T) {
+	m := New(setupTestRepo(t))
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	var path string
+	_, err := m.Add(ctx, "feature", AddOptions{Setup: func(wt *Worktree) error {
+		path = wt.Path
+		// Simulate Ctrl-C arriving while setup runs
+		cancel()
+		return ctx.Err()
+	}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Add to fail with context.Canceled, got %v", err)
+	}
+
+	check := t.Context()
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected %s to be removed, got %v", path, err)
+	}
+	if _, err := m.Show(check, "feature"); !errors.Is(err, ErrWorktreeNotFound) {
+		t.Errorf("expected the worktree to be gone, got %v", err)
+	}
+	if _, err := m.Git(check, "rev-parse", "--verify", "--quiet", "refs/heads/feature"); err == nil {
+		t.Error("expected the new branch to be deleted")
+	}
+	meta, err := m.Meta(check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta["feature"]) != 0 {
+		t.Errorf("expected no metadata for the worktree, got %v", meta["feature"])
+	}
+}
+
+func TestManagerAddRefusesExistingBranch(t *testing.T) {
+	ctx := t.Context()
+	m := New(setupTestRepo(t))
+	if _, err := m.Git(ctx, "branch", "feature"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts AddOptions
+	}{
+		{"feature", AddOptions{}},
+		{"other", AddOptions{Branch: "feature"}},
+	} {
+		_, err := m.Add(ctx, tc.name, tc.opts)
+		if !errors.Is(err, ErrBranchExists) {
+			t.Errorf("Add(%s, %+v): expected ErrBranchExists, got %v", tc.name, tc.opts, err)
+		}
+		if _, err := m.Show(ctx, tc.name); !errors.Is(err, ErrWorktreeNotFound) {
+			t.Errorf("expected no worktree '%s', got %v", tc.name, err)
+		}
+	}
+	if !m.RefExists(ctx, "refs/heads/feature") {
+		t.Error("expected the existing branch to be kept")
+	}
+}
+
+func TestManagerAddSetupFailureCleansUp(t *testing.T) {
+	ctx := t.Context()
+	m := New(setupTestRepo(t))
+
+	var path string
+	_, err := m.Add(ctx, "feature", AddOptions{Setup: func(wt *Worktree) error {
+		path = wt.Path
+		if err := m.SetMeta(ctx, wt.Name, MetaPort, "4000"); err != nil {
+			return err
+		}
+		return errors.New("setup broke")
+	}})
+	if err == nil || !strings.Contains(err.Error(), "setup broke") {
+		t.Fatalf("expected the setup error, got %v", err)
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected %s to be removed, got %v", path, err)
+	}
+	if _, err := m.Show(ctx, "feature"); !errors.Is(err, ErrWorktreeNotFound) {
+		t.Errorf("expected the worktree to be gone, got %v", err)
+	}
+	if m.RefExists(ctx, "refs/heads/feature") {
+		t.Error("expected the new branch to be deleted")
+	}
+	meta, err := m.Meta(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta["feature"]) != 0 {
+		t.Errorf("expected no metadata for the worktree, got %v", meta["feature"])
+	}
+}
+
+func TestManagerErrorKinds(t *testing.T) {
+	ctx := t.Context()
+	m := New(setupTestRepo(t))
+
+	if _, err := New(t.TempDir()).List(ctx); !errors.Is(err, ErrNotARepo) {
+		t.Errorf("expected ErrNotARepo outside a repository, got %v", err)
+	}
+	if _, err := m.Show(ctx, "missing"); !errors.Is(err, ErrWorktreeNotFound) || err.Error() != "worktree 'missing' not found" {
+		t.Errorf("expected ErrWorktreeNotFound, got %v", err)
+	}
+
+	wt, err := m.Add(ctx, "feature", AddOptions{})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := m.Add(ctx, "feature", AddOptions{}); !errors.Is(err, ErrWorktreeExists) {
+		t.Errorf("expected ErrWorktreeExists, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(wt.Path, "draft.txt"), []byte("draft"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Remove(ctx, "feature", RemoveOptions{}); !errors.Is(err, ErrWorktreeDirty) {
+		t.Fatalf("expected ErrWorktreeDirty, got %v", err)
+	}
+	if _, err := os.Stat(wt.Path); err != nil {
+		t.Fatalf("a dirty worktree must be kept: %v", err)
+	}
+
+	if _, err := m.Git(ctx, "-C", wt.Path, "commit", "--allow-empty", "-m", "unmerged"); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	res, err := m.Remove(ctx, "feature", RemoveOptions{BranchDelete: BranchDeleteSafe, DiscardChanges: true})
+	if !errors.Is(err, ErrBranchNotMerged) {
+		t.Fatalf("expected ErrBranchNotMerged, got %v", err)
+	}
+	var gitErr *GitError
+	if !errors.As(err, &gitErr) || gitErr.Output == "" {
+		t.Errorf("expected the git output to be kept, got %v", err)
+	}
+	if res == nil || res.DeletedBranch != "" {
+		t.Errorf("expected the worktree to be removed and the branch kept, got %+v", res)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	worktrees := []Worktree{
+		{Name: "repo", Bare: true},
+		{Name: "api-refactor", Branch: "feature/api-refactor"},
+		{Name: "apu", Branch: "apu"},
+		{Name: "web", Branch: "feature/web"},
+		{Name: "docs", Branch: "docs"},
+	}
+	tests := map[string][]string{
+		"api":         {"apu", "api-refactor"},
+		"API-refactr": {"api-refactor"},
+		"feature/":    {"feature/web", "feature/api-refactor"},
+		"wbe":         {"web"},
+		"repo":        nil,
+		"unrelated":   nil,
+	}
+	for query, want := range tests {
+		if got := Suggest(query, worktrees); !slices.Equal(got, want) {
+			t.Errorf("Suggest(%q) = %q, want %q", query, got, want)
+		}
+	}
+
+	err := &WorktreeError{Name: "api", Err: ErrWorktreeNotFound, Suggestions: []string{"apu", "api-refactor", "web"}}
+	if want := "worktree 'api' not found; did you mean 'apu', 'api-refactor' or 'web'?"; err.Error() != want {
+		t.Errorf("unexpected message %q, want %q", err.Error(), want)
+	}
+
+	m := New(setupTestRepo(t))
+	if _, err := m.Add(t.Context(), "feature", AddOptions{}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := m.Path(t.Context(), "featrue"); !errors.Is(err, ErrWorktreeNotFound) || !strings.Contains(err.Error(), "did you mean 'feature'?") {
+		t.Errorf("expected a suggestion for a misspelled name, got %v", err)
+	}
+}
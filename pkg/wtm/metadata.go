@@ -0,0 +1,110 @@
+package wtm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Per-worktree metadata lives in the repository's git config under wtm.<name>.<key>,
+// so git stays the single source of truth and removing a worktree removes its section.
+
+const (
+	// MetaReadOnly marks a worktree whose checkout was made read-only
+	MetaReadOnly = "readonly"
+	// MetaIssue records the URL of the issue a worktree was started from
+	MetaIssue = "issue"
+	// MetaBase records the branch or ref a worktree's branch was created from
+	MetaBase = "base"
+	// MetaPort records the first port of the block allocated to a worktree
+	MetaPort = "port"
+	// MetaLastUsed records when a worktree was last opened, jumped to or run in (RFC 3339)
+	MetaLastUsed = "lastused"
+	// MetaLabels records the comma-separated labels a worktree is grouped by
+	MetaLabels = "labels"
+	// MetaEphemeral marks a worktree that is removed after its command exits or by the next gc:
+	// "true" removes its branch too, "keep-branch" leaves a branch that existed before
+	MetaEphemeral = "ephemeral"
+	// MetaOwner records the PID of the process running a command in an ephemeral worktree,
+	// which gc leaves alone while that process is alive
+	MetaOwner = "owner"
+	// MetaTag records the tag a worktree was checked out at or branched from
+	MetaTag = "tag"
+	// MetaHidden leaves a worktree out of listings unless they are asked for all worktrees
+	MetaHidden = "hidden"
+)
+
+func metaKey(name, key string) string {
+	return fmt.Sprintf("wtm.%s.%s", name, key)
+}
+
+// SetMeta stores a metadata value for a worktree
+func (m *Manager) SetMeta(ctx context.Context, name, key, value string) error {
+	_, err := m.gitMutation(ctx, "config", metaKey(name, key), value)
+	return err
+}
+
+// UnsetMeta removes a single metadata value, ignoring keys that are not set
+func (m *Manager) UnsetMeta(ctx context.Context, name, key string) error {
+	if m.Plan != nil {
+		_, err := m.gitMutation(ctx, "config", "--unset", metaKey(name, key))
+		return err
+	}
+	err := m.gitQuiet(ctx, "config", "--unset", metaKey(name, key))
+	if isGitConfigMissing(err) {
+		return nil
+	}
+	return err
+}
+
+// ClearMeta removes every metadata value stored for a worktree
+func (m *Manager) ClearMeta(ctx context.Context, name string) error {
+	if m.Plan != nil {
+		_, err := m.gitMutation(ctx, "config", "--remove-section", "wtm."+name)
+		return err
+	}
+	err := m.gitQuiet(ctx, "config", "--remove-section", "wtm."+name)
+	if exitCode(err) == 128 {
+		// git reports a missing section as a fatal error
+		return nil
+	}
+	return err
+}
+
+// Meta returns all stored metadata keyed by worktree name and lower-cased key
+func (m *Manager) Meta(ctx context.Context) (map[string]map[string]string, error) {
+	meta := map[string]map[string]string{}
+
+	output, err := m.Git(ctx, "config", "--get-regexp", `^wtm\.`)
+	if err != nil {
+		if isGitConfigMissing(err) {
+			return meta, nil
+		}
+		return nil, err
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fullKey, value, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		rest := strings.TrimPrefix(fullKey, "wtm.")
+		idx := strings.LastIndex(rest, ".")
+		if idx <= 0 {
+			continue
+		}
+		name, key := rest[:idx], rest[idx+1:]
+		if meta[name] == nil {
+			meta[name] = map[string]string{}
+		}
+		meta[name][key] = value
+	}
+	return meta, nil
+}
+
+// isGitConfigMissing reports whether git config failed only because the key or section does not exist
+func isGitConfigMissing(err error) bool {
+	// git config exits 1 for a missing key and 5 when unsetting a missing key
+	code := exitCode(err)
+	return code == 1 || code == 5
+}
@@ -0,0 +1,138 @@
+package wtm
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// reservedNames cannot be used as worktree names because the CLI gives them special meaning
+var reservedNames = []string{".", "..", "-"}
+
+// windowsDeviceNames are reserved by Windows in every directory, with or without an extension
+var windowsDeviceNames = []string{
+	"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+}
+
+// windowsNames enables the Windows file name rules in ValidateName
+var windowsNames = runtime.GOOS == "windows"
+
+// NameError reports a worktree name that cannot be used. It matches ErrInvalidName.
+type NameError struct {
+	Name   string
+	Reason string
+}
+
+func (e *NameError) Error() string {
+	return fmt.Sprintf("invalid worktree name %q: %s", e.Name, e.Reason)
+}
+
+func (e *NameError) Is(target error) bool {
+	return target == ErrInvalidName
+}
+
+// ValidateName checks that name can be used as a worktree directory name
+func ValidateName(name string) error {
+	invalid := func(reason string) error {
+		return &NameError{Name: name, Reason: reason}
+	}
+	switch {
+	case strings.TrimSpace(name) == "":
+		return invalid("name is empty")
+	case strings.ContainsAny(name, `/\`):
+		return invalid("path separators are not allowed")
+	case strings.HasPrefix(name, "-"):
+		return invalid("names cannot start with '-'")
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return invalid("control characters are not allowed")
+	}
+	for _, reserved := range reservedNames {
+		if name == reserved {
+			return invalid(fmt.Sprintf("'%s' is reserved", reserved))
+		}
+	}
+	if windowsNames {
+		if reason := windowsNameProblem(name); reason != "" {
+			return invalid(reason)
+		}
+	}
+	return nil
+}
+
+// ValidateNestedName checks a name whose '/'-separated components become nested directories under
+// the worktree root, such as team/alice/login-fix
+func ValidateNestedName(name string) error {
+	if strings.Contains(name, `\`) {
+		return &NameError{Name: name, Reason: "use '/' to separate nested names"}
+	}
+	for _, component := range strings.Split(name, "/") {
+		var nameErr *NameError
+		if err := ValidateName(component); errors.As(err, &nameErr) {
+			if component == "" {
+				nameErr.Reason = "empty path components are not allowed"
+			}
+			return &NameError{Name: name, Reason: nameErr.Reason}
+		}
+	}
+	return nil
+}
+
+// validateName checks name with ValidateNestedName when the manager allows nested names,
+// and with ValidateName otherwise
+func (m *Manager) validateName(name string) error {
+	if m.AllowNestedNames {
+		return ValidateNestedName(name)
+	}
+	return ValidateName(name)
+}
+
+// windowsNameProblem explains why name cannot be a directory name on Windows, or returns ""
+func windowsNameProblem(name string) string {
+	if strings.ContainsAny(name, `<>:"|?*`) {
+		return `characters <>:"|?* are not allowed on Windows`
+	}
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return "names cannot end with '.' or a space on Windows"
+	}
+	stem, _, _ := strings.Cut(name, ".")
+	for _, device := range windowsDeviceNames {
+		if strings.EqualFold(strings.TrimSpace(stem), device) {
+			return fmt.Sprintf("'%s' is a reserved device name on Windows", device)
+		}
+	}
+	return ""
+}
+
+// BranchName turns a worktree name into a valid branch name: whitespace becomes '-', characters git
+// forbids in refs are dropped and each '/'-separated component is trimmed of leading and trailing
+// dots and dashes. It returns "" when nothing usable remains.
+func BranchName(name string) string {
+	var components []string
+	for _, component := range strings.Split(name, "/") {
+		component = strings.Join(strings.Fields(component), "-")
+		component = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) || strings.ContainsRune(`~^:?*[\`, r) {
+				return -1
+			}
+			return r
+		}, component)
+		for _, bad := range []string{"..", "@{", "--"} {
+			for strings.Contains(component, bad) {
+				component = strings.ReplaceAll(component, bad, bad[:1])
+			}
+		}
+		// Trimming one end can expose the other, as in "a.lock." or "x.lock.lock"
+		for trimmed := ""; trimmed != component; {
+			trimmed = component
+			component = strings.Trim(strings.TrimSuffix(component, ".lock"), ".-")
+		}
+		if component != "" && component != "@" {
+			components = append(components, component)
+		}
+	}
+	return strings.Join(components, "/")
+}
@@ -0,0 +1,36 @@
+package wtm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Plan records the side effects an operation would perform instead of executing them.
+// Read-only git queries still run so that names, branches and paths are fully resolved.
+type Plan struct {
+	Command string
+	Steps   []string
+}
+
+// Record appends a step to the plan
+func (p *Plan) Record(format string, a ...any) {
+	p.Steps = append(p.Steps, fmt.Sprintf(format, a...))
+}
+
+// RecordCommand appends an external command to the plan, quoted for a POSIX shell
+func (p *Plan) RecordCommand(name string, args ...string) {
+	p.Record("%s", ShellJoin(append([]string{name}, args...)))
+}
+
+// ShellJoin quotes arguments so a recorded command can be pasted into a POSIX shell
+func ShellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;#~!") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,50 @@
+package wtm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// GitRunner executes git for a Manager. ExecRunner is the default; other implementations,
+// such as an in-process backend, can serve environments without a git binary or batch queries.
+type GitRunner interface {
+	// Run runs git with args in dir (empty for the process working directory) and returns its output.
+	// It must stop when ctx is done. A failed command should return a *GitError whose Err has an
+	// ExitCode() int method, so callers can classify the failure.
+	Run(ctx context.Context, dir string, args ...string) (string, error)
+}
+
+// ExecRunner runs the git binary found on PATH
+type ExecRunner struct{}
+
+// Run implements GitRunner
+func (ExecRunner) Run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// GitError classifies failures by git's English messages, so translations must stay off
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	// Warnings on stderr must not end up in the output callers parse
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", &GitError{Args: args, Err: ctxErr}
+		}
+		return "", &GitError{Args: args, Output: strings.TrimSpace(stderr.String() + "\n" + string(output)), Err: err}
+	}
+	return string(output), nil
+}
+
+// exitCode returns the exit status carried by a GitRunner error, or -1 if there is none
+func exitCode(err error) int {
+	var coder interface{ ExitCode() int }
+	if !errors.As(err, &coder) {
+		return -1
+	}
+	return coder.ExitCode()
+}
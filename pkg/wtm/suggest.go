@@ -0,0 +1,79 @@
+package wtm
+
+import (
+	"slices"
+	"strings"
+)
+
+// maxSuggestions caps the names offered when a worktree is not found
+const maxSuggestions = 3
+
+// Suggest returns up to three worktree names or branches that are close to query: those it is a
+// prefix of, and those within a small edit distance. Closer matches come first. Matching ignores case.
+func Suggest(query string, worktrees []Worktree) []string {
+	q := strings.ToLower(query)
+	if q == "" {
+		return nil
+	}
+	maxDistance := min(max(len([]rune(q))/3, 1), 3)
+
+	type match struct {
+		value    string
+		distance int
+	}
+	var matches []match
+	for _, wt := range worktrees {
+		if wt.Bare {
+			continue
+		}
+		for _, candidate := range []string{wt.Name, wt.Branch} {
+			if candidate == "" || slices.ContainsFunc(matches, func(m match) bool { return m.value == candidate }) {
+				continue
+			}
+			c := strings.ToLower(candidate)
+			d := editDistance(q, c)
+			if d <= maxDistance || (len(q) >= 2 && strings.HasPrefix(c, q)) {
+				matches = append(matches, match{candidate, d})
+			}
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b match) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.value, b.value)
+	})
+	var suggestions []string
+	for _, m := range matches[:min(len(matches), maxSuggestions)] {
+		suggestions = append(suggestions, m.value)
+	}
+	return suggestions
+}
+
+// editDistance is the optimal string alignment distance between a and b in runes: the Levenshtein
+// distance with a swap of two adjacent characters counting as one edit, the most common typo
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package wtm
+
+import (
+	"io/fs"
+	"sync"
+	"syscall"
+)
+
+// umask returns the process umask. Reading it means setting it, so it is read once and restored
+// right away; the umask of a process does not change while wtm runs.
+var umask = sync.OnceValue(func() fs.FileMode {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return fs.FileMode(mask)
+})
@@ -0,0 +1,10 @@
+//go:build windows
+
+package wtm
+
+import "io/fs"
+
+// umask returns 0, since Windows only honors the owner's write bit
+func umask() fs.FileMode {
+	return 0
+}
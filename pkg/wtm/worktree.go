@@ -0,0 +1,291 @@
+package wtm
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Worktree represents a git worktree
+type Worktree struct {
+	Name       string    `json:"name"`
+	Branch     string    `json:"branch"`
+	Path       string    `json:"path"`
+	HEAD       string    `json:"head"`
+	Created    time.Time `json:"created"`
+	ReadOnly   bool      `json:"readOnly,omitempty"`
+	NoCheckout bool      `json:"noCheckout,omitempty"`
+	// Issue is the URL of the issue the worktree was started from
+	Issue string `json:"issue,omitempty"`
+	// Base is the branch or ref the worktree's branch was created from, when wtm created it
+	Base string `json:"base,omitempty"`
+	// Port is the first port of the block allocated to the worktree, 0 when none was
+	Port int `json:"port,omitempty"`
+	// LastUsed is when the worktree was last opened, jumped to or run in; zero when never recorded
+	LastUsed time.Time `json:"lastUsed,omitzero"`
+	// Labels are free-form tags given at creation, used to group listings
+	Labels []string `json:"labels,omitempty"`
+	// Ephemeral marks a throwaway worktree that the next gc removes
+	Ephemeral bool `json:"ephemeral,omitempty"`
+	// Tag is the tag the worktree was checked out at or its branch was created from
+	Tag string `json:"tag,omitempty"`
+	// Hidden marks a worktree left out of listings unless all worktrees are asked for
+	Hidden bool `json:"hidden,omitempty"`
+	// Bare marks the bare repository entry of a bare-clone layout, which has no files checked out
+	Bare bool `json:"bare,omitempty"`
+	// Repo is the registered repository name, set only when listing across repositories
+	Repo string `json:"repo,omitempty"`
+	// CreatedBranch is set on the worktree returned by Add when Add created its branch
+	CreatedBranch bool `json:"-"`
+}
+
+// List retrieves all worktrees from git, including the primary one
+func (m *Manager) List(ctx context.Context) ([]Worktree, error) {
+	output, err := m.Git(ctx, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	worktrees := parseWorktreeList(output)
+	if err := m.nameNested(ctx, worktrees); err != nil {
+		return nil, err
+	}
+	if m.Logger != nil {
+		m.Logger.DebugContext(ctx, "parsed worktrees", "worktrees", worktrees)
+	}
+
+	meta, err := m.Meta(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get creation time and stored metadata for each worktree
+	for i := range worktrees {
+		info, err := os.Stat(worktrees[i].Path)
+		if err == nil {
+			worktrees[i].Created = info.ModTime()
+		}
+		worktrees[i].ReadOnly = meta[worktrees[i].Name][MetaReadOnly] == "true"
+		worktrees[i].Issue = meta[worktrees[i].Name][MetaIssue]
+		worktrees[i].Base = meta[worktrees[i].Name][MetaBase]
+		worktrees[i].Tag = meta[worktrees[i].Name][MetaTag]
+		worktrees[i].Port, _ = strconv.Atoi(meta[worktrees[i].Name][MetaPort])
+		worktrees[i].LastUsed, _ = time.Parse(time.RFC3339, meta[worktrees[i].Name][MetaLastUsed])
+		worktrees[i].Ephemeral = meta[worktrees[i].Name][MetaEphemeral] != ""
+		worktrees[i].Hidden = meta[worktrees[i].Name][MetaHidden] == "true"
+		if labels := meta[worktrees[i].Name][MetaLabels]; labels != "" {
+			worktrees[i].Labels = strings.Split(labels, ",")
+		}
+		worktrees[i].NoCheckout = !isCheckedOut(worktrees[i].Path)
+	}
+
+	return worktrees, nil
+}
+
+// parseWorktreeList parses the output of git worktree list --porcelain into worktrees without metadata
+func parseWorktreeList(output string) []Worktree {
+	var worktrees []Worktree
+	var current Worktree
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if current.Path != "" {
+				worktrees = append(worktrees, current)
+				current = Worktree{}
+			}
+			continue
+		}
+
+		if line == "bare" {
+			current.Bare = true
+			continue
+		}
+
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) < 2 {
+			continue
+		}
+
+		key := parts[0]
+		value := parts[1]
+
+		switch key {
+		case "worktree":
+			// git reports forward slashes on Windows too, e.g. C:/src/repo
+			current.Path = filepath.FromSlash(value)
+			// Extract name from path (last segment)
+			current.Name = filepath.Base(current.Path)
+		case "HEAD":
+			current.HEAD = value
+		case "branch":
+			// Extract branch name from refs/heads/branch-name
+			if strings.HasPrefix(value, "refs/heads/") {
+				current.Branch = strings.TrimPrefix(value, "refs/heads/")
+			} else {
+				current.Branch = value
+			}
+		}
+	}
+
+	// Add last worktree if exists
+	if current.Path != "" {
+		worktrees = append(worktrees, current)
+	}
+	return worktrees
+}
+
+// nameNested renames worktrees below the worktree root after their path relative to it, so a worktree
+// added as team/alice/login-fix keeps that name instead of its last path segment. It does nothing
+// unless AllowNestedNames is set.
+func (m *Manager) nameNested(ctx context.Context, worktrees []Worktree) error {
+	if !m.AllowNestedNames {
+		return nil
+	}
+	base, err := m.WorktreeBase(ctx)
+	if err != nil {
+		return err
+	}
+	base = NormalizePath(base)
+	for i := range worktrees {
+		path := NormalizePath(worktrees[i].Path)
+		if path == base || !within(path, base) {
+			continue
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			continue
+		}
+		worktrees[i].Name = filepath.ToSlash(rel)
+	}
+	return nil
+}
+
+// within reports whether p is root or a path below it
+func within(p, root string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Path returns the absolute path of the named worktree. Unlike Show it only runs git worktree list,
+// skipping metadata and file system checks, so it is cheap enough for shell prompts and cd helpers.
+func (m *Manager) Path(ctx context.Context, name string) (string, error) {
+	output, err := m.Git(ctx, "worktree", "list", "--porcelain")
+	if err != nil {
+		return "", err
+	}
+	worktrees := parseWorktreeList(output)
+	if err := m.nameNested(ctx, worktrees); err != nil {
+		return "", err
+	}
+	for _, wt := range worktrees {
+		if wt.Name == name {
+			return wt.Path, nil
+		}
+	}
+	return "", notFound(name, worktrees)
+}
+
+// Show resolves a worktree by name
+func (m *Manager) Show(ctx context.Context, name string) (*Worktree, error) {
+	worktrees, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range worktrees {
+		if worktrees[i].Name == name {
+			return &worktrees[i], nil
+		}
+	}
+	return nil, notFound(name, worktrees)
+}
+
+// notFound reports that no worktree is called name, suggesting close names and branches
+func notFound(name string, worktrees []Worktree) error {
+	return &WorktreeError{Name: name, Err: ErrWorktreeNotFound, Suggestions: Suggest(name, worktrees)}
+}
+
+// Current returns the worktree containing the manager's directory
+func (m *Manager) Current(ctx context.Context) (*Worktree, error) {
+	top, err := m.Git(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("not inside a worktree")
+	}
+	top = NormalizePath(strings.TrimSpace(top))
+
+	worktrees, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range worktrees {
+		if NormalizePath(worktrees[i].Path) == top {
+			return &worktrees[i], nil
+		}
+	}
+	return nil, fmt.Errorf("current directory is not inside a known worktree")
+}
+
+// isCheckedOut reports whether a worktree has been populated. A worktree created with
+// --no-checkout has no index file in its administrative directory until files are checked out.
+func isCheckedOut(worktreePath string) bool {
+	data, err := os.ReadFile(filepath.Join(worktreePath, ".git"))
+	if err != nil {
+		// The primary worktree has a .git directory, and missing worktrees are not reported as unpopulated
+		return true
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return true
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(worktreePath, gitDir)
+	}
+	_, err = os.Stat(filepath.Join(gitDir, "index"))
+	return err == nil
+}
+
+// SetWritable adds or removes write permission on every file and directory in a worktree.
+// Making it writable adds write permission, as allowed by the umask, for each class that can
+// read an entry, which restores the modes git checked the files out with.
+func SetWritable(root string, writable bool) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mode := info.Mode().Perm()
+		if writable {
+			mode |= 0o200 | (mode&0o444)>>1&^umask()
+		} else {
+			mode &^= 0o222
+		}
+		return os.Chmod(p, mode)
+	})
+}
+
+// NormalizePath resolves symlinks and junctions and cleans a path so worktree paths can be compared.
+// On Windows the drive letter is upper-cased, since git and the shell disagree on its case.
+func NormalizePath(p string) string {
+	if p == "" {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		p = resolved
+	}
+	p = filepath.Clean(p)
+	if vol := filepath.VolumeName(p); len(vol) == 2 && vol[1] == ':' {
+		p = strings.ToUpper(vol) + p[2:]
+	}
+	return p
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+// Plan records the side effects a command would perform instead of executing them
+type Plan = wtm.Plan
+
+// activePlan is set while a command runs in plan mode
+var activePlan *Plan
+
+func planning() bool {
+	return activePlan != nil
+}
+
+// runGitMutation runs a git command that changes repository state, or records it when planning
+func runGitMutation(ctx context.Context, args ...string) (string, error) {
+	if planning() {
+		activePlan.RecordCommand("git", args...)
+		return "", nil
+	}
+	return runGitCommand(ctx, args...)
+}
+
+// runMutation runs an external command that changes state outside git, or records it when planning
+func runMutation(ctx context.Context, name string, args ...string) error {
+	if planning() {
+		activePlan.RecordCommand(name, args...)
+		return nil
+	}
+	start := time.Now()
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	logCommand(ctx, name, args, start, err)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, string(output))
+	}
+	return nil
+}
+
+// planFileOp records a file operation when planning and reports whether the caller should skip it
+func planFileOp(format string, a ...any) bool {
+	if !planning() {
+		return false
+	}
+	activePlan.Record(format, a...)
+	return true
+}
+
+// report prints a progress message for an operation that actually ran; it is silent while planning
+func report(format string, a ...any) {
+	if planning() {
+		return
+	}
+	fmt.Printf(format, a...)
+}
+
+// shellJoin quotes arguments so a recorded command can be pasted into a POSIX shell
+func shellJoin(args []string) string {
+	return wtm.ShellJoin(args)
+}
+
+// runPlanned executes fn in plan mode and returns the recorded plan
+func runPlanned(command string, fn func() error) (*Plan, error) {
+	p := &Plan{Command: command, Steps: []string{}}
+	activePlan = p
+	defer func() { activePlan = nil }()
+
+	if err := fn(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// printPlan prints the recorded steps in execution order
+func printPlan(p *Plan) {
+	fmt.Printf("Plan for: %s\n", p.Command)
+	if len(p.Steps) == 0 {
+		fmt.Println("  (no changes)")
+	}
+	for i, step := range p.Steps {
+		fmt.Printf("  %d. %s\n", i+1, step)
+	}
+}
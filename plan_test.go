@@ -0,0 +1,160 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/choplin/wtm/pkg/wtm"
+)
+
+func TestRunPlannedAddDoesNotCreateWorktree(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	p, err := runPlanned("wtm add planned", func() error {
+		return AddWorktree(ctx, "planned", AddOptions{Base: "HEAD"})
+	})
+	if err != nil {
+		t.Fatalf("runPlanned failed: %v", err)
+	}
+
+	if len(p.Steps) != 3 {
+		t.Fatalf("expected 3 steps, got %v", p.Steps)
+	}
+	if !strings.HasPrefix(p.Steps[0], "mkdir -p ") {
+		t.Errorf("expected mkdir step first, got %q", p.Steps[0])
+	}
+	if !strings.HasPrefix(p.Steps[1], "git worktree add ") || !strings.HasSuffix(p.Steps[1], "-b planned HEAD") {
+		t.Errorf("unexpected git step: %q", p.Steps[1])
+	}
+	if !strings.HasPrefix(p.Steps[2], "git config wtm.planned.base ") {
+		t.Errorf("expected the base to be recorded, got %q", p.Steps[2])
+	}
+
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		t.Fatalf("getWorktrees failed: %v", err)
+	}
+	for _, wt := range worktrees {
+		if wt.Name == "planned" {
+			t.Error("plan mode must not create the worktree")
+		}
+	}
+	if planning() {
+		t.Error("plan mode should be reset after runPlanned returns")
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	got := shellJoin([]string{"git", "commit", "-m", "it's done", ""})
+	want := `git commit -m 'it'\''s done' ''`
+	if got != want {
+		t.Errorf("shellJoin = %s, want %s", got, want)
+	}
+}
+
+func TestDryRunFlag(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "doomed", AddOptions{Base: "HEAD"}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	root := newRootCmd()
+	root.SetArgs([]string{"--dry-run", "remove", "-f", "doomed"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("dry-run remove failed: %v", err)
+	}
+	if planning() {
+		t.Error("plan mode should be reset after the command finishes")
+	}
+	if _, err := findWorktree(ctx, "doomed"); err != nil {
+		t.Errorf("dry-run must not remove the worktree: %v", err)
+	}
+
+	root = newRootCmd()
+	root.SetArgs([]string{"--dry-run", "list"})
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected --dry-run to be rejected for list, got %v", err)
+	}
+}
+
+func TestPruneWorktreesClearsStaleMetadata(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "gone", AddOptions{Base: "HEAD", ReadOnly: true}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "gone")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if err := wtm.SetWritable(wt.Path, true); err != nil {
+		t.Fatalf("SetWritable failed: %v", err)
+	}
+	if err := os.RemoveAll(wt.Path); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+
+	p, err := runPlanned("wtm prune", func() error { return PruneWorktrees(ctx) })
+	if err != nil {
+		t.Fatalf("planned prune failed: %v", err)
+	}
+	want := []string{"git worktree prune", "git config --remove-section wtm.gone"}
+	if strings.Join(p.Steps, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected steps %v, got %v", want, p.Steps)
+	}
+
+	if err := PruneWorktrees(ctx); err != nil {
+		t.Fatalf("PruneWorktrees failed: %v", err)
+	}
+	meta, err := loadWorktreeMeta(ctx)
+	if err != nil {
+		t.Fatalf("loadWorktreeMeta failed: %v", err)
+	}
+	if _, ok := meta["gone"]; ok {
+		t.Error("expected metadata for the pruned worktree to be removed")
+	}
+	if _, err := findWorktree(ctx, "gone"); err == nil {
+		t.Error("expected the worktree record to be pruned")
+	}
+}
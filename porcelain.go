@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// The porcelain format is the stable interface for scripts; the table, plain and pretty formats are
+// for humans and may change. It starts with a version line, followed by one record per worktree.
+// A record is a sequence of "key value" lines, or bare "key" lines for flags, and every record is
+// preceded by an empty line. With -z every line ends with a NUL instead of a newline, so records are
+// preceded by an extra NUL.
+//
+// Within a version, keys keep their meaning and order, and new keys are only ever appended to the
+// end of a record, so consumers must ignore keys they do not know.
+const porcelainHeader = "# wtm porcelain v1"
+
+// porcelainLine is one line of a porcelain record; flags have no value
+type porcelainLine struct {
+	key   string
+	value string
+	flag  bool
+}
+
+// porcelainRecord lists the lines describing a worktree. primaryPath marks the primary worktree,
+// and status lines are only included when status is given.
+func porcelainRecord(wt Worktree, primaryPath string, status *WorktreeStatus) []porcelainLine {
+	lines := []porcelainLine{{key: "worktree", value: wt.Name}}
+	if wt.Repo != "" {
+		lines = append(lines, porcelainLine{key: "repo", value: wt.Repo})
+	}
+	lines = append(lines, porcelainLine{key: "path", value: wt.Path})
+	if wt.Branch != "" {
+		lines = append(lines, porcelainLine{key: "branch", value: wt.Branch})
+	} else {
+		lines = append(lines, porcelainLine{key: "detached", flag: true})
+	}
+	lines = append(lines, porcelainLine{key: "head", value: wt.HEAD})
+	if !wt.Created.IsZero() {
+		lines = append(lines, porcelainLine{key: "created", value: wt.Created.UTC().Format(time.RFC3339)})
+	}
+	flags := []struct {
+		key string
+		set bool
+	}{
+		{"primary", primaryPath != "" && normalizePath(wt.Path) == primaryPath},
+		{"bare", wt.Bare},
+		{"readonly", wt.ReadOnly},
+		{"no-checkout", wt.NoCheckout},
+	}
+	for _, f := range flags {
+		if f.set {
+			lines = append(lines, porcelainLine{key: f.key, flag: true})
+		}
+	}
+	for _, kv := range [][2]string{{"base", wt.Base}, {"port", formatPort(wt.Port)}, {"issue", wt.Issue}} {
+		if kv[1] != "" {
+			lines = append(lines, porcelainLine{key: kv[0], value: kv[1]})
+		}
+	}
+
+	if status != nil {
+		lines = append(lines,
+			porcelainLine{key: "status.dirty", value: strconv.FormatBool(status.Dirty)},
+			porcelainLine{key: "status.changes", value: strconv.Itoa(len(status.Changes))},
+		)
+		if status.Upstream != "" {
+			lines = append(lines,
+				porcelainLine{key: "status.upstream", value: status.Upstream},
+				porcelainLine{key: "status.ahead", value: strconv.Itoa(status.Ahead)},
+				porcelainLine{key: "status.behind", value: strconv.Itoa(status.Behind)},
+			)
+		}
+		if status.Base != "" {
+			lines = append(lines,
+				porcelainLine{key: "status.base", value: status.Base},
+				porcelainLine{key: "status.baseahead", value: strconv.Itoa(status.BaseAhead)},
+				porcelainLine{key: "status.basebehind", value: strconv.Itoa(status.BaseBehind)},
+			)
+		}
+	}
+	return lines
+}
+
+// printPorcelain prints the version line followed by the given records
+func printPorcelain(records [][]porcelainLine, nul bool) {
+	end := "\n"
+	if nul {
+		end = "\x00"
+	}
+	fmt.Print(porcelainHeader + end)
+	for _, record := range records {
+		fmt.Print(end)
+		for _, line := range record {
+			if line.flag {
+				fmt.Print(line.key + end)
+			} else {
+				fmt.Print(line.key + " " + line.value + end)
+			}
+		}
+	}
+}
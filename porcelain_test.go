@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPorcelainOutput(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "api", AddOptions{Branch: "feature/api"}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "api")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+
+	t.Run("list", func(t *testing.T) {
+		out, err := captureStdout(t, func() error {
+			return ListWorktrees(ctx, ListOptions{Format: "porcelain"})
+		})
+		if err != nil {
+			t.Fatalf("ListWorktrees failed: %v", err)
+		}
+		records := strings.Split(out, "\n\n")
+		if len(records) != 3 || records[0] != porcelainHeader {
+			t.Fatalf("expected a header and two records, got:\n%s", out)
+		}
+		if !strings.Contains(records[1]+"\n", "\nprimary\n") {
+			t.Errorf("expected the first record to be the primary worktree, got:\n%s", records[1])
+		}
+		want := "worktree api\npath " + wt.Path + "\nbranch feature/api\nhead " + wt.HEAD + "\n"
+		if !strings.HasPrefix(records[2], want) {
+			t.Errorf("unexpected record:\nwant prefix: %q\ngot:         %q", want, records[2])
+		}
+		if strings.Contains(records[2], "status.") {
+			t.Errorf("list records must not include status, got:\n%s", records[2])
+		}
+	})
+
+	t.Run("show with status", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(wt.Path, "wip.txt"), []byte("wip"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(filepath.Join(wt.Path, "wip.txt"))
+
+		out, err := captureStdout(t, func() error {
+			return ShowWorktree(ctx, "api", ShowOptions{Format: "porcelain", NullTerminated: true})
+		})
+		if err != nil {
+			t.Fatalf("ShowWorktree failed: %v", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+		if lines[0] != porcelainHeader || lines[1] != "" || lines[2] != "worktree api" {
+			t.Fatalf("unexpected NUL-terminated output: %q", out)
+		}
+		for _, want := range []string{"status.dirty true", "status.changes 1", "base " + wt.Base} {
+			found := false
+			for _, line := range lines {
+				found = found || line == want
+			}
+			if !found {
+				t.Errorf("expected line %q in %q", want, lines)
+			}
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+const (
+	defaultPortBlockSize = 10
+	maxPort              = 65535
+)
+
+// PortsConfig assigns every new worktree its own block of ports, so dev servers can run side by side
+type PortsConfig struct {
+	// Base is the first port handed out; port allocation is disabled when it is 0
+	Base int `toml:"base"`
+	// BlockSize is the number of consecutive ports reserved per worktree; defaults to 10
+	BlockSize int `toml:"blockSize"`
+}
+
+func (c PortsConfig) blockSize() int {
+	if c.BlockSize > 0 {
+		return c.BlockSize
+	}
+	return defaultPortBlockSize
+}
+
+// allocatePort returns the first port of the lowest free block, or 0 when allocation is disabled.
+// A block is free when it does not overlap the block recorded for any existing worktree.
+func allocatePort(ctx context.Context, cfg PortsConfig) (int, error) {
+	if cfg.Base <= 0 {
+		return 0, nil
+	}
+	size := cfg.blockSize()
+
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for port := cfg.Base; port+size-1 <= maxPort; port += size {
+		free := true
+		for _, wt := range worktrees {
+			if wt.Port > 0 && port < wt.Port+size && wt.Port < port+size {
+				free = false
+				break
+			}
+		}
+		if free {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port block of %d left above %d", size, cfg.Base)
+}
+
+// formatPort renders an allocated port, or "" when there is none
+func formatPort(port int) string {
+	if port == 0 {
+		return ""
+	}
+	return strconv.Itoa(port)
+}
+
+// recordPort stores the port allocated to a worktree
+func recordPort(ctx context.Context, name string, port int) error {
+	return setWorktreeMeta(ctx, name, metaPort, strconv.Itoa(port))
+}
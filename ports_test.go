@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAllocatePort(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	config := "[ports]\nbase = 4000\nblockSize = 5\n\n[direnv]\ntemplate = \"export PORT={{.Port}}\\n\"\n"
+	if err := os.WriteFile(configFile, []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	port := func(name string) int {
+		t.Helper()
+		wt, err := findWorktree(ctx, name)
+		if err != nil {
+			t.Fatalf("findWorktree failed: %v", err)
+		}
+		return wt.Port
+	}
+
+	for _, name := range []string{"one", "two", "three"} {
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree %s failed: %v", name, err)
+		}
+	}
+	for name, want := range map[string]int{"one": 4000, "two": 4005, "three": 4010} {
+		if got := port(name); got != want {
+			t.Errorf("port of %s = %d, want %d", name, got, want)
+		}
+	}
+
+	t.Run("freed blocks are reused", func(t *testing.T) {
+		if err := RemoveWorktree(ctx, "two", RemoveOptions{Force: true, DiscardChanges: true}); err != nil {
+			t.Fatalf("RemoveWorktree failed: %v", err)
+		}
+		if err := AddWorktree(ctx, "four", AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+		if got := port("four"); got != 4005 {
+			t.Errorf("port of four = %d, want 4005", got)
+		}
+	})
+
+	t.Run("exposed to templates and env", func(t *testing.T) {
+		wt, err := findWorktree(ctx, "three")
+		if err != nil {
+			t.Fatalf("findWorktree failed: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(wt.Path, envrcFile))
+		if err != nil {
+			t.Fatalf("expected .envrc to be written: %v", err)
+		}
+		if string(data) != "export PORT=4010\n" {
+			t.Errorf("unexpected .envrc content: %q", data)
+		}
+
+		out, err := captureStdout(t, func() error {
+			return PrintEnv(ctx, "three", EnvOptions{})
+		})
+		if err != nil {
+			t.Fatalf("PrintEnv failed: %v", err)
+		}
+		if !strings.Contains(out, "WTM_PORT=4010\n") {
+			t.Errorf("expected WTM_PORT in env, got:\n%s", out)
+		}
+	})
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroup runs cmd in a process group of its own and makes cancelling it kill the whole group,
+// so processes started by a shell command do not outlive it and hold its output open
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// processAlive reports whether a process with the given PID exists; a process owned by another
+// user that cannot be signalled still counts
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+const (
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+)
+
+// killProcessGroup is a no-op on Windows, where cancelling cmd only kills the process itself;
+// cmd.WaitDelay still bounds how long its children can hold the output open
+func killProcessGroup(cmd *exec.Cmd) {}
+
+// processAlive reports whether a process with the given PID is still running
+func processAlive(pid int) bool {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		// A process that exists but cannot be opened is reported as access denied
+		return errors.Is(err, syscall.ERROR_ACCESS_DENIED)
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RebaseOptions groups configuration for rebasing a worktree's branch
+type RebaseOptions struct {
+	// Onto is the ref to rebase onto; defaults to the worktree's base branch
+	Onto string
+	// Abort aborts a rebase in progress
+	Abort bool
+	// Continue continues a rebase in progress after conflicts were resolved
+	Continue bool
+}
+
+// RebaseWorktree rebases a worktree's branch onto its base, or onto opts.Onto, inside the worktree.
+// A conflicting rebase is left in progress so it can be resolved and continued, or aborted.
+func RebaseWorktree(ctx context.Context, name string, opts RebaseOptions) error {
+	wt, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case opts.Abort:
+		if _, err := runGitMutation(ctx, "-C", wt.Path, "rebase", "--abort"); err != nil {
+			return fmt.Errorf("failed to abort rebase in '%s': %w", wt.Name, err)
+		}
+		report("✓ Aborted rebase in '%s'\n", wt.Name)
+		return nil
+	case opts.Continue:
+		// Keep the commit messages as they are instead of opening an editor
+		if _, err := runGitMutation(ctx, "-C", wt.Path, "-c", "core.editor=true", "rebase", "--continue"); err != nil {
+			return rebaseConflictError(ctx, wt, err)
+		}
+		report("✓ Rebased '%s'\n", wt.Name)
+		return nil
+	}
+
+	if wt.Branch == "" {
+		return fmt.Errorf("worktree '%s' is in detached HEAD state; nothing to rebase", wt.Name)
+	}
+	onto, err := resolveCompareBase(ctx, wt, opts.Onto)
+	if err != nil {
+		return err
+	}
+	if onto == wt.Branch {
+		return fmt.Errorf("cannot rebase '%s' onto itself", wt.Branch)
+	}
+	changes, err := newManager(ctx).Changes(ctx, wt)
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("worktree '%s' has uncommitted changes; commit or stash them first", wt.Name)
+	}
+
+	if _, err := runGitMutation(ctx, "-C", wt.Path, "rebase", onto); err != nil {
+		return rebaseConflictError(ctx, wt, err)
+	}
+	report("✓ Rebased '%s' (%s) onto %s\n", wt.Name, wt.Branch, onto)
+	return nil
+}
+
+// rebaseConflictError explains a stopped rebase, listing the conflicted files and how to go on
+func rebaseConflictError(ctx context.Context, wt *Worktree, err error) error {
+	out, _ := runGitCommand(ctx, "-C", wt.Path, "diff", "--name-only", "--diff-filter=U")
+	conflicted := strings.Fields(out)
+	if len(conflicted) == 0 {
+		return fmt.Errorf("rebase of '%s' failed: %w", wt.Name, err)
+	}
+	return fmt.Errorf("rebase of '%s' stopped with conflicts in:\n  %s\nresolve them in %s and stage them, then run 'wtm rebase %s --continue', or 'wtm rebase %s --abort'",
+		wt.Name, strings.Join(conflicted, "\n  "), wt.Path, wt.Name, wt.Name)
+}
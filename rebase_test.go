@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRebaseWorktree(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	// commitFile commits a file in dir
+	commitFile := func(dir, file, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", file}, {"commit", "-m", "Update " + file}} {
+			if _, err := runGitCommand(ctx, append([]string{"-C", dir}, args...)...); err != nil {
+				t.Fatalf("git %v failed: %v", args, err)
+			}
+		}
+	}
+
+	if err := AddWorktree(ctx, "feature", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "feature")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	base, err := defaultCompareBase(ctx)
+	if err != nil {
+		t.Fatalf("defaultCompareBase failed: %v", err)
+	}
+	commitFile(wt.Path, "shared.txt", "theirs")
+	commitFile(repoPath, "base.txt", "base")
+
+	t.Run("clean rebase", func(t *testing.T) {
+		if _, err := captureStdout(t, func() error {
+			return RebaseWorktree(ctx, "feature", RebaseOptions{})
+		}); err != nil {
+			t.Fatalf("RebaseWorktree failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(wt.Path, "base.txt")); err != nil {
+			t.Errorf("expected the base commit in the worktree: %v", err)
+		}
+	})
+
+	t.Run("conflict, then abort", func(t *testing.T) {
+		commitFile(repoPath, "shared.txt", "ours")
+		_, err := captureStdout(t, func() error {
+			return RebaseWorktree(ctx, "feature", RebaseOptions{})
+		})
+		if err == nil || !strings.Contains(err.Error(), "shared.txt") || !strings.Contains(err.Error(), "--continue") {
+			t.Fatalf("expected a conflict error listing shared.txt, got %v", err)
+		}
+		if _, err := captureStdout(t, func() error {
+			return RebaseWorktree(ctx, "feature", RebaseOptions{Abort: true})
+		}); err != nil {
+			t.Fatalf("abort failed: %v", err)
+		}
+		if status, _ := runGitCommand(ctx, "-C", wt.Path, "status", "--porcelain"); strings.TrimSpace(status) != "" {
+			t.Errorf("expected a clean worktree after abort, got %q", status)
+		}
+	})
+
+	t.Run("conflict, then continue", func(t *testing.T) {
+		if _, err := captureStdout(t, func() error {
+			return RebaseWorktree(ctx, "feature", RebaseOptions{})
+		}); err == nil {
+			t.Fatal("expected a conflict")
+		}
+		if err := os.WriteFile(filepath.Join(wt.Path, "shared.txt"), []byte("resolved"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := runGitCommand(ctx, "-C", wt.Path, "add", "shared.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := captureStdout(t, func() error {
+			return RebaseWorktree(ctx, "feature", RebaseOptions{Continue: true})
+		}); err != nil {
+			t.Fatalf("continue failed: %v", err)
+		}
+		if _, err := runGitCommand(ctx, "-C", wt.Path, "merge-base", "--is-ancestor", base, "HEAD"); err != nil {
+			t.Errorf("expected the branch to be rebased onto %s: %v", base, err)
+		}
+	})
+
+	t.Run("dirty worktree", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(wt.Path, "wip.txt"), []byte("wip"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(filepath.Join(wt.Path, "wip.txt"))
+		if err := RebaseWorktree(ctx, "feature", RebaseOptions{Onto: base}); err == nil || !strings.Contains(err.Error(), "uncommitted") {
+			t.Errorf("expected an uncommitted changes error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// RecentOptions controls wtm recent
+type RecentOptions struct {
+	// Limit caps the number of worktrees listed; zero lists all of them
+	Limit int
+	// Names prints only the names, one per line, for switchers such as fzf
+	Names bool
+}
+
+// markUsed records that a worktree was just used, so wtm recent can list it first. Failing to record
+// it must not fail the command the user ran, so errors are only logged.
+func markUsed(ctx context.Context, wt *Worktree) {
+	if planning() || wt.Bare {
+		return
+	}
+	err := setWorktreeMeta(ctx, wt.Name, metaLastUsed, time.Now().UTC().Format(time.RFC3339Nano))
+	if err != nil && logger != nil {
+		logger.DebugContext(ctx, "failed to record worktree use", "name", wt.Name, "error", err)
+	}
+}
+
+// recentWorktrees returns the worktrees with a recorded use, most recently used first
+func recentWorktrees(ctx context.Context) ([]Worktree, error) {
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var used []Worktree
+	for _, wt := range worktrees {
+		if !wt.LastUsed.IsZero() {
+			used = append(used, wt)
+		}
+	}
+	slices.SortStableFunc(used, func(a, b Worktree) int {
+		return b.LastUsed.Compare(a.LastUsed)
+	})
+	return used, nil
+}
+
+// previousWorktreeName is accepted in place of a worktree name for the previously used worktree,
+// like cd - and git checkout -
+const previousWorktreeName = "-"
+
+// previousWorktree returns the most recently used worktree other than the one containing the current
+// directory, so that jumping back and forth between two worktrees alternates like cd -
+func previousWorktree(ctx context.Context) (*Worktree, error) {
+	used, err := recentWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Outside every worktree, the most recently used one is the previous one
+	current, _ := currentWorktree(ctx)
+	for i := range used {
+		if current != nil && normalizePath(used[i].Path) == normalizePath(current.Path) {
+			continue
+		}
+		return &used[i], nil
+	}
+	return nil, fmt.Errorf("cannot resolve '-': no other worktree has been used yet (uses are recorded by wtm jump, open, run, exec and tmux)")
+}
+
+// ListRecent prints the most recently used worktrees first
+func ListRecent(ctx context.Context, opts RecentOptions) error {
+	worktrees, err := recentWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	if opts.Limit > 0 && len(worktrees) > opts.Limit {
+		worktrees = worktrees[:opts.Limit]
+	}
+	if err := useTimeConfig(ctx); err != nil {
+		return err
+	}
+
+	if opts.Names {
+		for _, wt := range worktrees {
+			fmt.Println(wt.Name)
+		}
+		return nil
+	}
+	if len(worktrees) == 0 {
+		fmt.Println("No recently used worktrees.")
+		return nil
+	}
+	rows := make([][]string, len(worktrees))
+	for i, wt := range worktrees {
+		rows[i] = []string{wt.Name, formatBranch(wt), formatTime(wt.LastUsed)}
+	}
+	printTable([]string{"NAME", "BRANCH", "USED"}, rows)
+	return nil
+}
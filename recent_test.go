@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListRecent(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	for _, name := range []string{"api", "web", "docs"} {
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree %s failed: %v", name, err)
+		}
+	}
+
+	output, err := captureStdout(t, func() error {
+		return ListRecent(ctx, RecentOptions{})
+	})
+	if err != nil {
+		t.Fatalf("ListRecent failed: %v", err)
+	}
+	if !strings.Contains(output, "No recently used worktrees") {
+		t.Errorf("expected no recent worktrees before any use, got %q", output)
+	}
+
+	yesterday := time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+	if err := setWorktreeMeta(ctx, "api", metaLastUsed, yesterday); err != nil {
+		t.Fatalf("setWorktreeMeta failed: %v", err)
+	}
+	if _, err := captureStdout(t, func() error { return Jump(ctx, "web") }); err != nil {
+		t.Fatalf("Jump failed: %v", err)
+	}
+
+	output, err = captureStdout(t, func() error {
+		return ListRecent(ctx, RecentOptions{Names: true})
+	})
+	if err != nil {
+		t.Fatalf("ListRecent failed: %v", err)
+	}
+	if output != "web\napi\n" {
+		t.Errorf("expected the jumped-to worktree first and unused ones left out, got %q", output)
+	}
+
+	output, err = captureStdout(t, func() error {
+		return ListRecent(ctx, RecentOptions{Limit: 1})
+	})
+	if err != nil {
+		t.Fatalf("ListRecent failed: %v", err)
+	}
+	if !strings.Contains(output, "USED") || !strings.Contains(output, "web") || strings.Contains(output, "api") {
+		t.Errorf("expected a table with only the most recent worktree, got %q", output)
+	}
+}
+
+func TestPreviousWorktree(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	for _, name := range []string{"api", "web"} {
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree %s failed: %v", name, err)
+		}
+	}
+	if err := PrintPath(ctx, "-"); err == nil {
+		t.Error("expected '-' to fail before any worktree was used")
+	}
+
+	api, err := findWorktree(ctx, "api")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	web, err := findWorktree(ctx, "web")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	for _, name := range []string{"api", "web"} {
+		if _, err := captureStdout(t, func() error { return Jump(ctx, name) }); err != nil {
+			t.Fatalf("Jump %s failed: %v", name, err)
+		}
+	}
+
+	output, err := captureStdout(t, func() error { return PrintPath(ctx, "-") })
+	if err != nil {
+		t.Fatalf("PrintPath - failed: %v", err)
+	}
+	if output != web.Path+"\n" {
+		t.Errorf("expected '-' outside the used worktrees to be the last one used, got %q", output)
+	}
+
+	// Standing in web, '-' goes back to api, and jumping there makes web the previous one
+	if err := os.Chdir(web.Path); err != nil {
+		t.Fatalf("Failed to change to worktree: %v", err)
+	}
+	output, err = captureStdout(t, func() error { return Jump(ctx, "-") })
+	if err != nil {
+		t.Fatalf("Jump - failed: %v", err)
+	}
+	if output != api.Path+"\n" {
+		t.Errorf("expected '-' to jump back to api, got %q", output)
+	}
+	if err := os.Chdir(api.Path); err != nil {
+		t.Fatalf("Failed to change to worktree: %v", err)
+	}
+	if wt, err := findWorktree(ctx, "-"); err != nil || wt.Name != "web" {
+		t.Errorf("expected '-' to alternate back to web, got %v, %v", wt, err)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	toml "github.com/pelletier/go-toml/v2"
+)
+
+// registryFileName is the global list of repositories managed by wtm, stored next to config.toml
+const registryFileName = "repos.toml"
+
+// Registry lists the repositories registered by wtm init and wtm clone
+type Registry struct {
+	Repos []RegisteredRepo `toml:"repos"`
+}
+
+// RegisteredRepo is a repository known to wtm
+type RegisteredRepo struct {
+	// Name identifies the repository in repo:name references; it defaults to the directory name
+	Name string `toml:"name"`
+	// Path is the absolute repository root
+	Path string `toml:"path"`
+}
+
+func registryFilePath() (string, error) {
+	configPath, err := configFilePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), registryFileName), nil
+}
+
+// loadRegistry reads the registry; a missing file is an empty registry
+func loadRegistry() (Registry, error) {
+	var reg Registry
+	path, err := registryFilePath()
+	if err != nil {
+		return reg, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return reg, nil
+		}
+		return reg, err
+	}
+	if err := toml.Unmarshal(data, &reg); err != nil {
+		return reg, fmt.Errorf("invalid registry %s: %w", path, err)
+	}
+	return reg, nil
+}
+
+func saveRegistry(reg Registry) error {
+	path, err := registryFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := toml.Marshal(reg)
+	if err != nil {
+		return err
+	}
+	if planFileOp("write %s", path) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// registerRepo adds a repository root to the registry, keeping an existing entry for the same path.
+// Directory names that are already taken get a numeric suffix.
+func registerRepo(repoRoot string) (RegisteredRepo, error) {
+	reg, err := loadRegistry()
+	if err != nil {
+		return RegisteredRepo{}, err
+	}
+
+	taken := map[string]bool{}
+	for _, repo := range reg.Repos {
+		if normalizePath(repo.Path) == normalizePath(repoRoot) {
+			return repo, nil
+		}
+		taken[repo.Name] = true
+	}
+
+	name := filepath.Base(repoRoot)
+	for i := 2; taken[name]; i++ {
+		name = fmt.Sprintf("%s-%d", filepath.Base(repoRoot), i)
+	}
+	repo := RegisteredRepo{Name: name, Path: repoRoot}
+	reg.Repos = append(reg.Repos, repo)
+	return repo, saveRegistry(reg)
+}
+
+// findRegisteredRepo looks up a repository by its registry name
+func findRegisteredRepo(name string) (*RegisteredRepo, error) {
+	reg, err := loadRegistry()
+	if err != nil {
+		return nil, err
+	}
+	for i := range reg.Repos {
+		if reg.Repos[i].Name == name {
+			return &reg.Repos[i], nil
+		}
+	}
+	return nil, fmt.Errorf("repository '%s' is not registered (see wtm init)", name)
+}
+
+// useRepoRef resolves a repo:name reference by pointing wtm at the registered repository
+// and returns the worktree name; plain names are returned unchanged
+func useRepoRef(ref string) (string, error) {
+	repoName, name, ok := strings.Cut(ref, ":")
+	if !ok {
+		return ref, nil
+	}
+	repo, err := findRegisteredRepo(repoName)
+	if err != nil {
+		return "", err
+	}
+	if err := setRepoDir(repo.Path); err != nil {
+		return "", err
+	}
+	resetConfigCache()
+	return name, nil
+}
+
+// ListAllRepos lists the worktrees of every registered repository
+func ListAllRepos(ctx context.Context, opts ListOptions) error {
+	if opts.NullTerminated && !lineFormats[opts.Format] {
+		return fmt.Errorf("-z requires --format plain, --porcelain, --names or --paths")
+	}
+	// Reject bad --since and --before values once rather than skipping every repository
+	if _, err := filterByTime(ctx, nil, opts); err != nil {
+		return err
+	}
+	if err := useTimeConfig(ctx); err != nil {
+		return err
+	}
+
+	reg, err := loadRegistry()
+	if err != nil {
+		return err
+	}
+
+	previous := repoDir
+	defer func() {
+		repoDir = previous
+		resetConfigCache()
+	}()
+
+	var all []Worktree
+	primaries := map[string]string{}
+	for _, repo := range reg.Repos {
+		if err := setRepoDir(repo.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Skipped %s: %v\n", repo.Name, err)
+			continue
+		}
+		worktrees, err := getWorktrees(ctx)
+		if err == nil {
+			worktrees, err = visibleWorktrees(ctx, worktrees, opts.All)
+		}
+		if err == nil {
+			worktrees, err = filterByTime(ctx, worktrees, opts)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipped %s: %v\n", repo.Name, err)
+			continue
+		}
+		primaries[repo.Name] = normalizePath(repo.Path)
+		for _, wt := range worktrees {
+			wt.Repo = repo.Name
+			all = append(all, wt)
+		}
+	}
+
+	switch opts.Format {
+	case "table":
+		if len(all) == 0 {
+			return nil
+		}
+		rows := make([][]string, len(all))
+		for i, wt := range all {
+			rows[i] = []string{wt.Repo, formatWorktreeName(wt, primaries[wt.Repo]), formatBranch(wt), formatTime(wt.Created)}
+		}
+		printTable([]string{"REPO", "NAME", "BRANCH", "CREATED"}, rows)
+	case "plain":
+		for _, wt := range all {
+			printPlainRecord([]string{wt.Repo + ":" + formatWorktreeName(wt, primaries[wt.Repo]), formatBranch(wt), wt.Path}, opts.NullTerminated)
+		}
+	case "porcelain":
+		records := make([][]porcelainLine, len(all))
+		for i, wt := range all {
+			records[i] = porcelainRecord(wt, primaries[wt.Repo], nil)
+		}
+		printPorcelain(records, opts.NullTerminated)
+	case "names", "paths":
+		for _, wt := range all {
+			printListValue(wt, wt.Repo+":"+wt.Name, opts)
+		}
+	case "json":
+		printJSONFormat(all)
+	default:
+		return fmt.Errorf("unknown format: %s", opts.Format)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// renderFiles renders each configured template into a new worktree. files maps the destination,
+// relative to the worktree, to its template, which is looked up in the worktree first (where a tracked
+// template is checked out) and then in the repository root. Existing destinations are left alone.
+func renderFiles(files map[string]string, data worktreeTemplateData) error {
+	dests := make([]string, 0, len(files))
+	for dest := range files {
+		dests = append(dests, dest)
+	}
+	slices.Sort(dests)
+
+	for _, dest := range dests {
+		target, err := worktreeFilePath(data.Path, dest)
+		if err != nil {
+			return fmt.Errorf("invalid renderFiles destination: %w", err)
+		}
+		if _, err := os.Stat(target); err == nil {
+			fmt.Printf("Skipped %s: file already exists in the worktree\n", dest)
+			continue
+		}
+
+		text, err := readRenderTemplate(files[dest], data)
+		if err != nil {
+			return err
+		}
+		content, err := renderTemplate(dest, text, data)
+		if err != nil {
+			return fmt.Errorf("invalid template for %s: %w", dest, err)
+		}
+		if planFileOp("write %s", target) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// worktreeFilePath resolves a relative path inside a worktree, refusing paths that leave it
+func worktreeFilePath(root, rel string) (string, error) {
+	if filepath.IsAbs(rel) || !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return "", fmt.Errorf("%s is not a path inside the worktree", rel)
+	}
+	return filepath.Join(root, filepath.FromSlash(rel)), nil
+}
+
+// readRenderTemplate reads a template from the worktree, or from the repository root when the worktree lacks it
+func readRenderTemplate(file string, data worktreeTemplateData) (string, error) {
+	file = strings.TrimSpace(file)
+	candidates := []string{file}
+	if !filepath.IsAbs(file) {
+		candidates = []string{filepath.Join(data.Path, file), filepath.Join(data.RepoRoot, file)}
+	}
+	for _, candidate := range candidates {
+		content, err := os.ReadFile(candidate)
+		if err == nil {
+			return string(content), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("template file not found: %s", file)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddWorktreeRendersFiles(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	// A tracked template is read from the new worktree, an untracked one from the repository root
+	if err := os.WriteFile(filepath.Join(repoPath, ".env.local.tmpl"), []byte("NAME={{.Name}}\nPORT={{.Port}}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"add", ".env.local.tmpl"}, {"commit", "-m", "Add env template"}} {
+		if _, err := runGitCommand(ctx, args...); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "dev.tmpl"), []byte("branch: {{.Branch}}\nroot: {{.RepoRoot}}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	config := "[ports]\nbase = 5000\n\n[renderFiles]\n\".env.local\" = \".env.local.tmpl\"\n\"config/dev.yml\" = \"dev.tmpl\"\n"
+	if err := os.WriteFile(configFile, []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	if err := AddWorktree(ctx, "api", AddOptions{Branch: "feature/api"}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "api")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	root, err := getRepoRoot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for file, want := range map[string]string{
+		".env.local":     "NAME=api\nPORT=5000\n",
+		"config/dev.yml": "branch: feature/api\nroot: " + root + "\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(wt.Path, file))
+		if err != nil {
+			t.Errorf("expected %s to be rendered: %v", file, err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("unexpected %s content:\nwant: %q\ngot:  %q", file, want, string(data))
+		}
+	}
+}
+
+func TestWorktreeFilePath(t *testing.T) {
+	if _, err := worktreeFilePath("/wt", "config/dev.yml"); err != nil {
+		t.Errorf("expected a nested path to be accepted: %v", err)
+	}
+	for _, rel := range []string{"../outside", "/etc/passwd", "a/../../b"} {
+		if _, err := worktreeFilePath("/wt", rel); err == nil {
+			t.Errorf("expected %q to be rejected", rel)
+		}
+	}
+}
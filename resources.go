@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/choplin/wtm/pkg/wtm"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// MCP resources expose worktree state for clients that read or subscribe instead of calling tools
+
+const (
+	worktreesURI         = "wtm://worktrees"
+	worktreeURIPrefix    = worktreesURI + "/"
+	worktreeURITemplate  = worktreeURIPrefix + "{name}"
+	worktreeStatusSuffix = "/status"
+)
+
+// WorktreeStatus is the working tree state served by wtm://worktrees/{name}/status
+type WorktreeStatus struct {
+	Name     string   `json:"name"`
+	Branch   string   `json:"branch"`
+	Dirty    bool     `json:"dirty"`
+	Changes  []string `json:"changes"`
+	Upstream string   `json:"upstream,omitempty"`
+	Ahead    int      `json:"ahead"`
+	Behind   int      `json:"behind"`
+	// Base is the branch the worktree is compared with; BaseAhead and BaseBehind count commits relative to it
+	Base       string `json:"base,omitempty"`
+	BaseAhead  int    `json:"baseAhead"`
+	BaseBehind int    `json:"baseBehind"`
+}
+
+func worktreeURI(name string) string {
+	return worktreeURIPrefix + name
+}
+
+func registerResources(server *mcp.Server) {
+	server.AddResource(&mcp.Resource{
+		URI:         worktreesURI,
+		Name:        "worktrees",
+		Description: "All git worktrees in the current repository.",
+		MIMEType:    "application/json",
+	}, resourceInMCPRepo(handleWorktreesResource))
+
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: worktreeURITemplate,
+		Name:        "worktree",
+		Description: "Details of a single worktree by name.",
+		MIMEType:    "application/json",
+	}, resourceInMCPRepo(handleWorktreeResource))
+
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: worktreeURITemplate + worktreeStatusSuffix,
+		Name:        "worktree-status",
+		Description: "Uncommitted changes and upstream divergence of a worktree.",
+		MIMEType:    "application/json",
+	}, resourceInMCPRepo(handleWorktreeStatusResource))
+}
+
+func handleWorktreesResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResource(req.Params.URI, worktrees)
+}
+
+func handleWorktreeResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	name := strings.TrimPrefix(req.Params.URI, worktreeURIPrefix)
+	wt, err := findWorktree(ctx, name)
+	if errors.Is(err, wtm.ErrWorktreeNotFound) {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jsonResource(req.Params.URI, wt)
+}
+
+func handleWorktreeStatusResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	name := strings.TrimSuffix(strings.TrimPrefix(req.Params.URI, worktreeURIPrefix), worktreeStatusSuffix)
+	wt, err := findWorktree(ctx, name)
+	if errors.Is(err, wtm.ErrWorktreeNotFound) {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+	if err != nil {
+		return nil, err
+	}
+	status, err := worktreeStatus(ctx, wt)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResource(req.Params.URI, status)
+}
+
+// worktreeStatus reads the working tree and upstream state of a worktree
+func worktreeStatus(ctx context.Context, wt *Worktree) (*WorktreeStatus, error) {
+	output, err := runGitCommand(ctx, "-C", wt.Path, "status", "--porcelain=v2", "-z", "--branch", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := loadIgnoreMatcher(wt.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &WorktreeStatus{Name: wt.Name, Branch: wt.Branch, Changes: []string{}}
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		line := entries[i]
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "# branch.upstream "):
+			status.Upstream = strings.TrimPrefix(line, "# branch.upstream ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			fields := strings.Fields(strings.TrimPrefix(line, "# branch.ab "))
+			if len(fields) == 2 {
+				status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[0], "+"))
+				status.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "-"))
+			}
+		case strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "? ") && isIgnoredPath(m, porcelainPath(line)):
+			// Untracked files matched by .wtmignore do not make the worktree dirty
+		default:
+			status.Changes = append(status.Changes, porcelainPath(line))
+			if line[0] == '2' {
+				// Renames and copies are followed by the source path
+				i++
+			}
+		}
+	}
+	status.Dirty = len(status.Changes) > 0
+
+	// Divergence from the base is informational; a worktree without a resolvable base simply reports none
+	if base, err := resolveCompareBase(ctx, wt, ""); err == nil && base != wt.Branch {
+		if counts, err := runGitCommand(ctx, "-C", wt.Path, "rev-list", "--left-right", "--count", "HEAD..."+base); err == nil {
+			if fields := strings.Fields(counts); len(fields) == 2 {
+				status.Base = base
+				status.BaseAhead, _ = strconv.Atoi(fields[0])
+				status.BaseBehind, _ = strconv.Atoi(fields[1])
+			}
+		}
+	}
+	return status, nil
+}
+
+// porcelainPath extracts the path from a porcelain v2 -z entry; the path is the last field and may contain spaces
+func porcelainPath(line string) string {
+	// Number of space-separated fields before the path for each entry type
+	skip := map[byte]int{'1': 8, '2': 9, 'u': 10, '?': 1, '!': 1}
+	n, ok := skip[line[0]]
+	if !ok {
+		return line
+	}
+	fields := strings.SplitN(line, " ", n+1)
+	if len(fields) <= n {
+		return line
+	}
+	return fields[n]
+}
+
+func jsonResource(uri string, v any) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{
+		{URI: uri, MIMEType: "application/json", Text: string(data)},
+	}}, nil
+}
+
+// notifyWorktreeChanged tells subscribed clients that the listing and the given worktree's resources changed
+func notifyWorktreeChanged(ctx context.Context, server *mcp.Server, name string) {
+	uris := []string{worktreesURI}
+	if name != "" {
+		uris = append(uris, worktreeURI(name), worktreeURI(name)+worktreeStatusSuffix)
+	}
+	for _, uri := range uris {
+		_ = server.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: uri})
+	}
+}
+
+// withResourceUpdates wraps a mutating tool so a successful call notifies resource subscribers
+func withResourceUpdates[In, Out any](server *mcp.Server, worktreeName func(In) string, h mcp.ToolHandlerFor[In, Out]) mcp.ToolHandlerFor[In, Out] {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input In) (*mcp.CallToolResult, Out, error) {
+		result, output, err := h(ctx, req, input)
+		// A branch that is not fully merged is kept after its worktree was removed
+		if err == nil || errors.Is(err, wtm.ErrBranchNotMerged) {
+			notifyWorktreeChanged(ctx, server, worktreeName(input))
+		}
+		return result, output, err
+	}
+}
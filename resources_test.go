@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestMCPWorktreeResources(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := newMCPServer()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+
+	var mu sync.Mutex
+	updated := map[string]bool{}
+	client := mcp.NewClient(&mcp.Implementation{Name: "wtm-test-client", Version: "0.0.1"}, &mcp.ClientOptions{
+		ResourceUpdatedHandler: func(ctx context.Context, req *mcp.ResourceUpdatedNotificationRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			updated[req.Params.URI] = true
+		},
+	})
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer session.Close()
+
+	if err := session.Subscribe(ctx, &mcp.SubscribeParams{URI: worktreesURI}); err != nil {
+		t.Fatalf("resources/subscribe: %v", err)
+	}
+
+	if _, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "wtm_add",
+		Arguments: map[string]any{"name": "feature"},
+	}); err != nil {
+		t.Fatalf("wtm_add: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		got := updated[worktreesURI]
+		mu.Unlock()
+		if got {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected a resource update notification after wtm_add")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	wt, err := findWorktree(ctx, "feature")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(wt.Path, "new file.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	res, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "wtm://worktrees/feature/status"})
+	if err != nil {
+		t.Fatalf("resources/read status: %v", err)
+	}
+	var status WorktreeStatus
+	if err := json.Unmarshal([]byte(res.Contents[0].Text), &status); err != nil {
+		t.Fatalf("invalid status JSON: %v", err)
+	}
+	if !status.Dirty || len(status.Changes) != 1 || status.Changes[0] != "new file.txt" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	res, err = session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "wtm://worktrees/feature"})
+	if err != nil {
+		t.Fatalf("resources/read worktree: %v", err)
+	}
+	var detail Worktree
+	if err := json.Unmarshal([]byte(res.Contents[0].Text), &detail); err != nil {
+		t.Fatalf("invalid worktree JSON: %v", err)
+	}
+	if detail.Branch != "feature" {
+		t.Errorf("expected branch feature, got %q", detail.Branch)
+	}
+
+	if _, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "wtm://worktrees/missing"}); err == nil {
+		t.Error("expected an error for an unknown worktree")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// RunTask runs a named task from the [tasks] config table inside a worktree.
+// An empty name selects the worktree containing the current directory; extra args are appended to the command.
+func RunTask(ctx context.Context, task, name string, extra []string) error {
+	command, err := lookupTask(ctx, task)
+	if err != nil {
+		return err
+	}
+	if len(extra) > 0 {
+		command = command + " " + shellJoin(extra)
+	}
+
+	var target *Worktree
+	if name == "" {
+		target, err = currentWorktree(ctx)
+	} else {
+		target, err = findWorktree(ctx, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	markUsed(ctx, target)
+	cmd := shellCommand(ctx, command)
+	cmd.Dir = target.Path
+	if err := setWorktreeEnv(ctx, cmd, target); err != nil {
+		return err
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("task '%s' failed in worktree '%s': %w", task, target.Name, err)
+	}
+	return nil
+}
+
+// RunTaskAll runs a named task in every worktree using the foreach machinery
+func RunTaskAll(ctx context.Context, task string, extra []string, opts ForeachOptions) error {
+	command, err := lookupTask(ctx, task)
+	if err != nil {
+		return err
+	}
+	if len(extra) > 0 {
+		command = command + " " + shellJoin(extra)
+	}
+	return Foreach(ctx, command, opts)
+}
+
+func lookupTask(ctx context.Context, task string) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	command := strings.TrimSpace(cfg.Tasks[task])
+	if command == "" {
+		return "", fmt.Errorf("unknown task '%s' (define it under [tasks] in the config file)", task)
+	}
+	return command, nil
+}
+
+// ListTasks prints the configured tasks
+func ListTasks(ctx context.Context) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Tasks) == 0 {
+		fmt.Println("No tasks defined (add them under [tasks] in the config file)")
+		return nil
+	}
+
+	names := make([]string, 0, len(cfg.Tasks))
+	width := 0
+	for name := range cfg.Tasks {
+		names = append(names, name)
+		width = max(width, len(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%-*s  %s\n", width, name, cfg.Tasks[name])
+	}
+	return nil
+}
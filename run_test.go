@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTask(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	marker := filepath.Join(t.TempDir(), "task.out")
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	config := "[tasks]\nwhere = \"pwd > " + marker + "; echo >> " + marker + "\"\n"
+	if err := os.WriteFile(configFile, []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	if err := AddWorktree(ctx, "tasks", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "tasks")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+
+	readMarker := func() string {
+		t.Helper()
+		data, err := os.ReadFile(marker)
+		if err != nil {
+			t.Fatalf("expected task to run: %v", err)
+		}
+		return strings.SplitN(string(data), "\n", 2)[0]
+	}
+
+	t.Run("run task in named worktree", func(t *testing.T) {
+		if err := RunTask(ctx, "where", "tasks", nil); err != nil {
+			t.Fatalf("RunTask failed: %v", err)
+		}
+		if got := readMarker(); normalizePath(got) != normalizePath(wt.Path) {
+			t.Errorf("expected task to run in %s, got %s", wt.Path, got)
+		}
+	})
+
+	t.Run("run task in current worktree", func(t *testing.T) {
+		if err := RunTask(ctx, "where", "", nil); err != nil {
+			t.Fatalf("RunTask failed: %v", err)
+		}
+		if got := readMarker(); normalizePath(got) != normalizePath(repoPath) {
+			t.Errorf("expected task to run in %s, got %s", repoPath, got)
+		}
+	})
+
+	t.Run("unknown task should fail", func(t *testing.T) {
+		if err := RunTask(ctx, "missing", "tasks", nil); err == nil {
+			t.Error("Expected error for unknown task, got nil")
+		}
+	})
+}
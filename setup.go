@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// updateSubmodules initializes and checks out the submodules of a new worktree, which git leaves empty
+func updateSubmodules(ctx context.Context, wt *Worktree) error {
+	if !planning() {
+		if _, err := os.Stat(filepath.Join(wt.Path, ".gitmodules")); err != nil {
+			return nil
+		}
+	}
+	_, err := runGitMutation(ctx, "-C", wt.Path, "submodule", "update", "--init", "--recursive")
+	return err
+}
+
+// pullLFS installs the Git LFS hooks for a new worktree and replaces its pointer files with their content
+func pullLFS(ctx context.Context, wt *Worktree) error {
+	if _, err := exec.LookPath("git-lfs"); err != nil {
+		fmt.Fprintln(os.Stderr, "Skipped git lfs pull: git-lfs is not installed")
+		return nil
+	}
+	if _, err := runGitMutation(ctx, "-C", wt.Path, "lfs", "install", "--local"); err != nil {
+		return err
+	}
+	_, err := runGitMutation(ctx, "-C", wt.Path, "lfs", "pull")
+	return err
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddWorktreeRecurseSubmodules(t *testing.T) {
+	ctx := t.Context()
+
+	// Allow cloning the submodule from a local path
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	subPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, subPath)
+	if err := os.WriteFile(filepath.Join(subPath, "lib.txt"), []byte("lib"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	for _, step := range []struct {
+		dir  string
+		args []string
+	}{
+		{subPath, []string{"add", "."}},
+		{subPath, []string{"commit", "-m", "Add lib"}},
+		{repoPath, []string{"submodule", "add", subPath, "lib"}},
+		{repoPath, []string{"commit", "-m", "Add submodule"}},
+	} {
+		cmd := exec.Command("git", step.args...)
+		cmd.Dir = step.dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", step.args, err, output)
+		}
+	}
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "plain", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	if err := AddWorktree(ctx, "with-submodules", AddOptions{RecurseSubmodules: true}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	for name, want := range map[string]bool{"plain": false, "with-submodules": true} {
+		wt, err := findWorktree(ctx, name)
+		if err != nil {
+			t.Fatalf("findWorktree failed: %v", err)
+		}
+		_, err = os.Stat(filepath.Join(wt.Path, "lib", "lib.txt"))
+		if (err == nil) != want {
+			t.Errorf("%s: submodule checked out = %v, want %v", name, err == nil, want)
+		}
+	}
+}
+
+func TestAddWorktreePullsLFS(t *testing.T) {
+	ctx := t.Context()
+
+	// A stub git-lfs records how git invokes it
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "calls.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + logPath + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "git-lfs"), []byte(script), 0o755); err != nil {
+		t.Fatalf("Failed to write stub: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[setup]\nlfs = true\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	if err := AddWorktree(ctx, "lfs", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("git-lfs was not run: %v", err)
+	}
+	if calls := strings.Split(strings.TrimSpace(string(data)), "\n"); len(calls) != 2 || calls[0] != "install --local" || calls[1] != "pull" {
+		t.Errorf("unexpected git-lfs calls: %q", calls)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cdFileEnv names the file a shell helper passes to wtm to learn which directory to cd to
+// afterwards; a child process cannot change the directory of the shell that started it
+const cdFileEnv = "WTM_CD_FILE"
+
+// requestShellCD asks the calling shell helper to change to dir once wtm exits
+func requestShellCD(dir string) error {
+	file := os.Getenv(cdFileEnv)
+	if file == "" {
+		return fmt.Errorf("cannot change the shell's directory: %s is not set", cdFileEnv)
+	}
+	if planFileOp("write %s to %s", dir, file) {
+		return nil
+	}
+	return os.WriteFile(file, []byte(dir+"\n"), 0o644)
+}
+
+// pathWithin reports whether p is root or a directory below it
+func pathWithin(p, root string) bool {
+	rel, err := filepath.Rel(normalizePath(root), normalizePath(p))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// insideWorktree reports whether the current directory of the process, and so of the shell that
+// started it, is inside the worktree
+func insideWorktree(target *Worktree) bool {
+	cwd, err := os.Getwd()
+	return err == nil && pathWithin(cwd, target.Path)
+}
+
+// checkNotInside refuses to remove the worktree the shell is standing in, which would leave the shell
+// in a deleted directory, unless andCD lets wtm move back to the primary worktree first
+func checkNotInside(target *Worktree, andCD bool) error {
+	if !insideWorktree(target) {
+		return nil
+	}
+	if andCD {
+		if os.Getenv(cdFileEnv) == "" {
+			return fmt.Errorf("--and-cd needs the wtm shell helper, which sets %s (see README)", cdFileEnv)
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot remove worktree '%s': the current directory is inside it; cd to another worktree first, or pass --and-cd through the wtm shell helper to move to the primary worktree", target.Name)
+}
+
+// leaveWorktree moves wtm out of a worktree that is about to be removed, into the primary worktree,
+// and asks the shell helper to follow
+func leaveWorktree(ctx context.Context, target *Worktree) error {
+	if !insideWorktree(target) {
+		return nil
+	}
+	primary, err := getRepoRoot(ctx)
+	if err != nil {
+		return err
+	}
+	if err := requestShellCD(primary); err != nil {
+		return err
+	}
+	if planning() {
+		return nil
+	}
+	if err := os.Chdir(primary); err != nil {
+		return err
+	}
+	if repoDir == "" || pathWithin(repoDir, target.Path) {
+		repoDir = primary
+	}
+	return nil
+}
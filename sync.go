@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SyncOptions groups configuration for updating worktrees against their base
+type SyncOptions struct {
+	// Merge merges the base into each branch instead of rebasing onto it
+	Merge bool
+	// NoFetch skips fetching from the remotes first
+	NoFetch bool
+}
+
+// syncResult is one row of the sync summary
+type syncResult struct {
+	Worktree Worktree
+	Base     string
+	Result   string
+	Conflict bool
+}
+
+// SyncWorktrees fetches and then rebases (or merges) every worktree matching pattern onto its base branch,
+// or the base's upstream when it has one. Dirty, detached and read-only worktrees are skipped, conflicts
+// are aborted, and a summary table is printed.
+func SyncWorktrees(ctx context.Context, pattern string, opts SyncOptions) error {
+	targets, err := matchWorktrees(ctx, pattern)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no worktrees match '%s'", pattern)
+	}
+
+	if !opts.NoFetch {
+		remotes, err := runGitCommand(ctx, "remote")
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(remotes) != "" {
+			if _, err := runGitMutation(ctx, "fetch", "--all", "--prune"); err != nil {
+				return fmt.Errorf("fetch failed: %w", err)
+			}
+		}
+	}
+
+	var results []syncResult
+	var conflicts []string
+	for i := range targets {
+		res, err := syncWorktree(ctx, &targets[i], opts)
+		if err != nil {
+			return err
+		}
+		if res.Conflict {
+			conflicts = append(conflicts, res.Worktree.Name)
+		}
+		results = append(results, res)
+	}
+
+	if !planning() {
+		rows := make([][]string, len(results))
+		for i, r := range results {
+			rows[i] = []string{r.Worktree.Name, formatBranch(r.Worktree), r.Base, r.Result}
+		}
+		printTable([]string{"NAME", "BRANCH", "BASE", "RESULT"}, rows)
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("conflicts in %d worktree(s), left unchanged: %s", len(conflicts), strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+// syncWorktree updates a single worktree; conflicts are reported in the result rather than as an error
+func syncWorktree(ctx context.Context, wt *Worktree, opts SyncOptions) (syncResult, error) {
+	res := syncResult{Worktree: *wt}
+	switch {
+	case wt.Branch == "":
+		res.Result = "skipped: detached HEAD"
+		return res, nil
+	case wt.ReadOnly:
+		res.Result = "skipped: read-only"
+		return res, nil
+	}
+
+	base, err := resolveCompareBase(ctx, wt, "")
+	if err != nil {
+		return res, err
+	}
+	if base == wt.Branch {
+		res.Base, res.Result = base, "skipped: is the base"
+		return res, nil
+	}
+	// Prefer the freshly fetched upstream of the base, e.g. origin/main over a stale local main
+	if upstream, err := runGitCommand(ctx, "rev-parse", "--abbrev-ref", base+"@{upstream}"); err == nil {
+		base = strings.TrimSpace(upstream)
+	}
+	res.Base = base
+
+	changes, err := newManager(ctx).Changes(ctx, wt)
+	if err != nil {
+		return res, err
+	}
+	if len(changes) > 0 {
+		res.Result = "skipped: uncommitted changes"
+		return res, nil
+	}
+	if _, err := runGitCommand(ctx, "-C", wt.Path, "merge-base", "--is-ancestor", base, "HEAD"); err == nil {
+		res.Result = "up to date"
+		return res, nil
+	}
+
+	if opts.Merge {
+		if _, err := runGitMutation(ctx, "-C", wt.Path, "merge", "--no-edit", base); err != nil {
+			runGitCommand(ctx, "-C", wt.Path, "merge", "--abort")
+			res.Result, res.Conflict = "conflict (merge aborted)", true
+			return res, nil
+		}
+		res.Result = "merged"
+		return res, nil
+	}
+	if _, err := runGitMutation(ctx, "-C", wt.Path, "rebase", base); err != nil {
+		runGitCommand(ctx, "-C", wt.Path, "rebase", "--abort")
+		res.Result, res.Conflict = "conflict (rebase aborted)", true
+		return res, nil
+	}
+	res.Result = "rebased"
+	return res, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSyncWorktrees(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	// commitFile commits a file in dir
+	commitFile := func(dir, file, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", file}, {"commit", "-m", "Update " + file}} {
+			if _, err := runGitCommand(ctx, append([]string{"-C", dir}, args...)...); err != nil {
+				t.Fatalf("git %v failed: %v", args, err)
+			}
+		}
+	}
+	add := func(name string) *Worktree {
+		t.Helper()
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+		wt, err := findWorktree(ctx, name)
+		if err != nil {
+			t.Fatalf("findWorktree failed: %v", err)
+		}
+		return wt
+	}
+
+	clean := add("clean")
+	commitFile(clean.Path, "clean.txt", "feature")
+	dirty := add("dirty")
+	if err := os.WriteFile(filepath.Join(dirty.Path, "wip.txt"), []byte("wip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	conflict := add("conflict")
+	commitFile(conflict.Path, "shared.txt", "theirs")
+
+	// Advance the base so every worktree is behind it
+	commitFile(repoPath, "base.txt", "base")
+	commitFile(repoPath, "shared.txt", "ours")
+
+	out, syncErr := captureStdout(t, func() error {
+		return SyncWorktrees(ctx, "*", SyncOptions{NoFetch: true})
+	})
+	if syncErr == nil || !strings.Contains(syncErr.Error(), "conflict") {
+		t.Errorf("expected a conflict error, got %v", syncErr)
+	}
+	for _, want := range []string{"rebased", "skipped: uncommitted changes", "conflict (rebase aborted)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in summary, got:\n%s", want, out)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(clean.Path, "base.txt")); err != nil {
+		t.Errorf("expected the clean worktree to be rebased onto the base: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dirty.Path, "base.txt")); err == nil {
+		t.Error("expected the dirty worktree to be left alone")
+	}
+	if _, err := os.Stat(filepath.Join(conflict.Path, ".git", "rebase-merge")); err == nil {
+		t.Error("expected the conflicting rebase to be aborted")
+	}
+	if status, _ := runGitCommand(ctx, "-C", conflict.Path, "status", "--porcelain"); strings.TrimSpace(status) != "" {
+		t.Errorf("expected the conflicting worktree to be clean, got %q", status)
+	}
+
+	t.Run("up to date", func(t *testing.T) {
+		out, err := captureStdout(t, func() error {
+			return SyncWorktrees(ctx, "clean", SyncOptions{NoFetch: true, Merge: true})
+		})
+		if err != nil {
+			t.Fatalf("SyncWorktrees failed: %v", err)
+		}
+		if !strings.Contains(out, "up to date") {
+			t.Errorf("expected up to date, got:\n%s", out)
+		}
+	})
+}
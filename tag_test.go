@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAddWorktreeAtTag(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+	if _, err := runGitCommand(ctx, "tag", "v1.2.0"); err != nil {
+		t.Fatalf("git tag failed: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "", AddOptions{Tag: "v9.9.9"}); err == nil {
+		t.Error("expected a missing tag to be rejected")
+	}
+	if err := AddWorktree(ctx, "", AddOptions{Tag: "v1.2.0", Detach: "HEAD"}); err == nil {
+		t.Error("expected --tag with --detach to be rejected")
+	}
+
+	// Without -b the tag is checked out detached and names the worktree
+	if _, err := captureStdout(t, func() error {
+		return AddWorktree(ctx, "", AddOptions{Tag: "v1.2.0"})
+	}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	release, err := findWorktree(ctx, "v1.2.0")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if release.Branch != "" || release.Tag != "v1.2.0" {
+		t.Errorf("expected a detached worktree at v1.2.0, got branch %q tag %q", release.Branch, release.Tag)
+	}
+	if got := formatBranch(*release); got != "(tag v1.2.0)" {
+		t.Errorf("expected (tag v1.2.0), got %q", got)
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return AddWorktree(ctx, "hotfix", AddOptions{Tag: "v1.2.0", Branch: "hotfix/1.2.1"})
+	}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	hotfix, err := findWorktree(ctx, "hotfix")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if hotfix.Branch != "hotfix/1.2.1" || hotfix.Tag != "v1.2.0" || hotfix.Base != "v1.2.0" {
+		t.Errorf("expected branch hotfix/1.2.1 from v1.2.0, got %+v", hotfix)
+	}
+
+	output, err := captureStdout(t, func() error {
+		return ListWorktrees(ctx, ListOptions{Format: "table"})
+	})
+	if err != nil {
+		t.Fatalf("ListWorktrees failed: %v", err)
+	}
+	if !strings.Contains(output, "hotfix/1.2.1 (from v1.2.0)") || !strings.Contains(output, "(tag v1.2.0)") {
+		t.Errorf("expected the tags in the listing, got:\n%s", output)
+	}
+}
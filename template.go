@@ -0,0 +1,27 @@
+package main
+
+import (
+	"path"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are available to every user-supplied template in the config
+var templateFuncs = template.FuncMap{
+	"quote": func(s string) string { return shellJoin([]string{s}) },
+	// base returns the last '/'-separated segment, e.g. of a branch name
+	"base": path.Base,
+}
+
+// renderTemplate renders a user-supplied text/template against data
+func renderTemplate(name, text string, data any) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
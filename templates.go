@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// WorktreeTemplate bundles the settings for one kind of worktree, applied by `wtm add --template <name>`
+type WorktreeTemplate struct {
+	// Base is the starting point for the new branch
+	Base string `toml:"base"`
+	// SparseProfile checks out only the directories of a profile from the [sparse] config
+	SparseProfile string `toml:"sparseProfile"`
+	// CopyFiles lists files or directories, relative to the repository root, copied into the new worktree
+	// (e.g. untracked ".env" files)
+	CopyFiles []string `toml:"copyFiles"`
+	// SetupCommands are shell commands run in the new worktree once it is set up, with the default policy
+	SetupCommands []string `toml:"setupCommands"`
+	// Setup lists setup commands with their own execution policy, run after SetupCommands
+	Setup []SetupCommand `toml:"setup"`
+}
+
+// SetupCommand is a setup command together with how it is run
+type SetupCommand struct {
+	Command string `toml:"command"`
+	// Timeout stops the command after this long (e.g. "2m"); the default is defaultSetupTimeout, "0" disables it
+	Timeout string `toml:"timeout"`
+	// OnFailure is "abort" (the default) to fail wtm add, or "warn" to report the failure and carry on
+	OnFailure string `toml:"onFailure"`
+	// Output is "stream" (the default) to pass output through, or "capture" to show it only when the command fails
+	Output string `toml:"output"`
+}
+
+// defaultSetupTimeout keeps a hanging setup command from blocking wtm add, or the MCP server, forever
+const defaultSetupTimeout = 10 * time.Minute
+
+// setupWaitDelay bounds how long a stopped command may keep its output pipes open through child processes
+const setupWaitDelay = 5 * time.Second
+
+// timeout parses the command's timeout; zero means none
+func (c SetupCommand) timeout() (time.Duration, error) {
+	if c.Timeout == "" {
+		return defaultSetupTimeout, nil
+	}
+	d, err := time.ParseDuration(c.Timeout)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid timeout '%s' for setup command '%s'", c.Timeout, c.Command)
+	}
+	return d, nil
+}
+
+// validate checks the policy before the worktree is created, so a typo does not surface halfway through
+func (c SetupCommand) validate() error {
+	if strings.TrimSpace(c.Command) == "" {
+		return fmt.Errorf("setup entry without a command")
+	}
+	if c.OnFailure != "" && c.OnFailure != "abort" && c.OnFailure != "warn" {
+		return fmt.Errorf("invalid onFailure '%s' for setup command '%s' (use abort or warn)", c.OnFailure, c.Command)
+	}
+	if c.Output != "" && c.Output != "stream" && c.Output != "capture" {
+		return fmt.Errorf("invalid output '%s' for setup command '%s' (use stream or capture)", c.Output, c.Command)
+	}
+	_, err := c.timeout()
+	return err
+}
+
+// setupSteps returns every setup command of the template in the order they run
+func (t WorktreeTemplate) setupSteps() []SetupCommand {
+	steps := make([]SetupCommand, 0, len(t.SetupCommands)+len(t.Setup))
+	for _, command := range t.SetupCommands {
+		steps = append(steps, SetupCommand{Command: command})
+	}
+	return append(steps, t.Setup...)
+}
+
+// applyTemplate fills the options the user did not set from the named template
+func applyTemplate(cfg Config, name string, opts *AddOptions) (WorktreeTemplate, error) {
+	tmpl, ok := cfg.Templates[name]
+	if !ok {
+		return WorktreeTemplate{}, fmt.Errorf("unknown template '%s'", name)
+	}
+	for _, step := range tmpl.Setup {
+		if err := step.validate(); err != nil {
+			return WorktreeTemplate{}, fmt.Errorf("template '%s': %w", name, err)
+		}
+	}
+	// A base only applies to branches created by wtm add
+	if opts.Base == "" && opts.Checkout == "" && opts.Detach == "" && opts.Review == 0 {
+		opts.Base = tmpl.Base
+	}
+	if opts.SparseProfile == "" {
+		opts.SparseProfile = tmpl.SparseProfile
+	}
+	return tmpl, nil
+}
+
+// copyFiles copies files and directories from the repository root into a new worktree at the same
+// relative path. Missing sources and existing destinations are skipped.
+func copyFiles(files []string, data worktreeTemplateData) error {
+	for _, file := range files {
+		target, err := worktreeFilePath(data.Path, file)
+		if err != nil {
+			return fmt.Errorf("invalid copyFiles entry: %w", err)
+		}
+		src := filepath.Join(data.RepoRoot, filepath.FromSlash(file))
+		info, err := os.Stat(src)
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("Skipped %s: not found in the repository root\n", file)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(target); err == nil {
+			fmt.Printf("Skipped %s: file already exists in the worktree\n", file)
+			continue
+		}
+		if planFileOp("copy %s to %s", src, target) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			err = copyDir(src, target)
+		} else {
+			err = copyFile(src, target, info.Mode().Perm())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runSetupCommands runs a template's setup commands in order inside the new worktree. A failing command
+// stops the rest unless its policy is to warn.
+func runSetupCommands(ctx context.Context, wt *Worktree, steps []SetupCommand) error {
+	for _, step := range steps {
+		if planFileOp("run in %s: %s", wt.Path, step.Command) {
+			continue
+		}
+		if err := checkMCPShellCommand(ctx, step.Command); err != nil {
+			return fmt.Errorf("did not run setup command '%s': %w", step.Command, err)
+		}
+		err := runSetupCommand(ctx, wt, step)
+		if err == nil {
+			continue
+		}
+		if step.OnFailure == "warn" && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Warning: setup command '%s' failed in worktree '%s': %v\n", step.Command, wt.Name, err)
+			continue
+		}
+		return fmt.Errorf("setup command '%s' failed: %w", step.Command, err)
+	}
+	return nil
+}
+
+// runSetupCommand runs one setup command under its timeout, printing captured output only when it fails
+func runSetupCommand(ctx context.Context, wt *Worktree, step SetupCommand) error {
+	timeout, err := step.timeout()
+	if err != nil {
+		return err
+	}
+	runCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := shellCommand(runCtx, step.Command)
+	cmd.Dir = wt.Path
+	cmd.WaitDelay = setupWaitDelay
+	killProcessGroup(cmd)
+	if err := setWorktreeEnv(ctx, cmd, wt); err != nil {
+		return err
+	}
+	var captured bytes.Buffer
+	if step.Output == "capture" {
+		cmd.Stdout = &captured
+		cmd.Stderr = &captured
+	} else {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+
+	err = cmd.Run()
+	if err != nil && captured.Len() > 0 {
+		os.Stderr.Write(captured.Bytes())
+	}
+	if err != nil && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
+
+// describeTemplate summarizes what a template sets up, for completion descriptions
+func describeTemplate(tmpl WorktreeTemplate) string {
+	var parts []string
+	if tmpl.Base != "" {
+		parts = append(parts, "base "+tmpl.Base)
+	}
+	if tmpl.SparseProfile != "" {
+		parts = append(parts, "sparse "+tmpl.SparseProfile)
+	}
+	if n := len(tmpl.CopyFiles); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d copied file(s)", n))
+	}
+	if n := len(tmpl.setupSteps()); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d setup command(s)", n))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// templateNames returns the configured template names in sorted order
+func templateNames(cfg Config) []string {
+	names := make([]string, 0, len(cfg.Templates))
+	for name := range cfg.Templates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddWithTemplate(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+	if output, err := exec.Command("git", "branch", "release").CombinedOutput(); err != nil {
+		t.Fatalf("git branch failed: %v: %s", err, output)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, ".env"), []byte("TOKEN=secret\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	config := `[templates.review]
+base = "release"
+copyFiles = [".env", "missing.txt"]
+setupCommands = ["echo ready > setup.out"]
+`
+	if err := os.WriteFile(configFile, []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	output, err := captureStdout(t, func() error {
+		return AddWorktree(ctx, "fix-123", AddOptions{Template: "review"})
+	})
+	if err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	if !strings.Contains(output, "Skipped missing.txt: not found in the repository root") {
+		t.Errorf("expected the missing file to be reported, got:\n%s", output)
+	}
+
+	wt, err := findWorktree(ctx, "fix-123")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if wt.Base != "release" {
+		t.Errorf("Base = %q, want release", wt.Base)
+	}
+	env, err := os.ReadFile(filepath.Join(wt.Path, ".env"))
+	if err != nil || string(env) != "TOKEN=secret\n" {
+		t.Errorf("expected .env to be copied, got %q (%v)", env, err)
+	}
+	if info, err := os.Stat(filepath.Join(wt.Path, ".env")); err == nil && info.Mode().Perm() != 0o600 {
+		t.Errorf("expected .env to keep mode 0600, got %v", info.Mode().Perm())
+	}
+	if setup, err := os.ReadFile(filepath.Join(wt.Path, "setup.out")); err != nil || strings.TrimSpace(string(setup)) != "ready" {
+		t.Errorf("expected the setup command to run in the worktree, got %q (%v)", setup, err)
+	}
+
+	// Explicit options win over the template
+	if err := AddWorktree(ctx, "fix-456", AddOptions{Template: "review", Base: "HEAD"}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	if wt, err := findWorktree(ctx, "fix-456"); err != nil || wt.Base != "master" {
+		t.Errorf("expected the explicit base to win, got %+v (%v)", wt, err)
+	}
+
+	if err := AddWorktree(ctx, "other", AddOptions{Template: "nope"}); err == nil || !strings.Contains(err.Error(), "unknown template 'nope'") {
+		t.Errorf("expected an unknown template error, got %v", err)
+	}
+}
+
+func TestSetupCommandPolicy(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	config := `[[templates.sandbox.setup]]
+command = "sleep 30"
+timeout = "200ms"
+onFailure = "warn"
+
+[[templates.sandbox.setup]]
+command = "echo noisy; exit 1"
+output = "capture"
+onFailure = "warn"
+
+[[templates.sandbox.setup]]
+command = "echo done > setup.out"
+
+[[templates.strict.setup]]
+command = "exit 2"
+
+[[templates.typo.setup]]
+command = "true"
+onFailure = "ignore"
+`
+	if err := os.WriteFile(configFile, []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	start := time.Now()
+	output, err := captureStdout(t, func() error {
+		return AddWorktree(ctx, "sandbox", AddOptions{Template: "sandbox"})
+	})
+	if err != nil {
+		t.Fatalf("expected failing setup commands to only warn, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the timeout to stop the hanging command, took %s", elapsed)
+	}
+	if strings.Contains(output, "noisy") {
+		t.Errorf("expected captured output to stay off stdout, got:\n%s", output)
+	}
+	wt, err := findWorktree(ctx, "sandbox")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wt.Path, "setup.out")); err != nil {
+		t.Errorf("expected the commands after the failures to run: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "strict", AddOptions{Template: "strict"}); err == nil || !strings.Contains(err.Error(), "setup command 'exit 2' failed") {
+		t.Errorf("expected the failing setup command to abort, got %v", err)
+	}
+	if _, err := findWorktree(ctx, "strict"); err == nil {
+		t.Error("expected an aborted setup to remove the worktree")
+	}
+	if refExists(ctx, "refs/heads/strict") {
+		t.Error("expected an aborted setup to delete the branch created for the worktree")
+	}
+
+	if err := AddWorktree(ctx, "typo", AddOptions{Template: "typo"}); err == nil || !strings.Contains(err.Error(), "invalid onFailure 'ignore'") {
+		t.Errorf("expected an invalid policy to be rejected, got %v", err)
+	}
+	if _, err := findWorktree(ctx, "typo"); err == nil {
+		t.Error("expected an invalid policy to be rejected before creating the worktree")
+	}
+}
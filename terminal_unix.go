@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import "os"
+
+// isTerminal reports whether f is a character device such as a terminal, rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// isTerminal reports whether f is a console. The character device check used elsewhere is not enough
+// on Windows, where NUL is a character device too.
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeFields are the values wtm list --time-field accepts
+var timeFields = []string{"created", "commit"}
+
+// relativeTime matches the day, week and year units time.ParseDuration lacks, as in 2w or 10d
+var relativeTime = regexp.MustCompile(`^(\d+)([dwy])$`)
+
+// parseTimeBound turns a --since or --before value into an instant: a duration before now such as 2w,
+// 10d or 36h, or a date (2006-01-02) or timestamp (RFC 3339)
+func parseTimeBound(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if m := relativeTime.FindStringSubmatch(value); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch m[2] {
+		case "d":
+			return now.AddDate(0, 0, -n), nil
+		case "w":
+			return now.AddDate(0, 0, -7*n), nil
+		default:
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use a duration such as 2w, 10d or 36h, or a date such as 2024-01-01", value)
+}
+
+// filterByTime keeps worktrees whose creation or last commit time is at or after Since and before Before.
+// Worktrees without a known time are left out once either bound is set.
+func filterByTime(ctx context.Context, worktrees []Worktree, opts ListOptions) ([]Worktree, error) {
+	if opts.Since == "" && opts.Before == "" {
+		return worktrees, nil
+	}
+	field := opts.TimeField
+	if field == "" {
+		field = "created"
+	}
+	if field != "created" && field != "commit" {
+		return nil, fmt.Errorf("unknown --time-field value %q (valid: %s)", field, strings.Join(timeFields, ", "))
+	}
+	now := time.Now()
+	var since, before time.Time
+	var err error
+	if opts.Since != "" {
+		if since, err = parseTimeBound(opts.Since, now); err != nil {
+			return nil, fmt.Errorf("--since: %w", err)
+		}
+	}
+	if opts.Before != "" {
+		if before, err = parseTimeBound(opts.Before, now); err != nil {
+			return nil, fmt.Errorf("--before: %w", err)
+		}
+	}
+
+	var kept []Worktree
+	for _, wt := range worktrees {
+		if wt.Bare {
+			continue
+		}
+		t := wt.Created
+		if field == "commit" {
+			t = lastCommitTime(ctx, wt)
+		}
+		if t.IsZero() || (!since.IsZero() && t.Before(since)) || (!before.IsZero() && !t.Before(before)) {
+			continue
+		}
+		kept = append(kept, wt)
+	}
+	return kept, nil
+}
+
+// lastCommitTime returns the committer date of a worktree's HEAD, or the zero time when it has none
+func lastCommitTime(ctx context.Context, wt Worktree) time.Time {
+	output, err := runGitCommand(ctx, "-C", wt.Path, "log", "-1", "--format=%ct", "HEAD")
+	if err != nil {
+		return time.Time{}
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
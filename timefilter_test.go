@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2w", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{"10d", time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)},
+		{"1y", time.Date(2023, 3, 15, 12, 0, 0, 0, time.UTC)},
+		{"36h", time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{"2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeBound(tt.value, now)
+		if err != nil {
+			t.Errorf("parseTimeBound(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTimeBound(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	date, err := parseTimeBound("2024-01-01", now)
+	if err != nil || date.Year() != 2024 || date.YearDay() != 1 || date.Hour() != 0 {
+		t.Errorf("expected local midnight of 2024-01-01, got %v (%v)", date, err)
+	}
+	for _, value := range []string{"", "soon", "2 weeks", "-3d"} {
+		if _, err := parseTimeBound(value, now); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestFilterByTime(t *testing.T) {
+	now := time.Now()
+	worktrees := []Worktree{
+		{Name: "fresh", Created: now.Add(-time.Hour)},
+		{Name: "week", Created: now.AddDate(0, 0, -8)},
+		{Name: "ancient", Created: now.AddDate(-1, 0, -1)},
+		{Name: "unknown"},
+	}
+	names := func(opts ListOptions) []string {
+		t.Helper()
+		kept, err := filterByTime(t.Context(), worktrees, opts)
+		if err != nil {
+			t.Fatalf("filterByTime failed: %v", err)
+		}
+		var out []string
+		for _, wt := range kept {
+			out = append(out, wt.Name)
+		}
+		return out
+	}
+
+	if got := names(ListOptions{}); len(got) != 4 {
+		t.Errorf("expected no filtering without bounds, got %q", got)
+	}
+	if got := names(ListOptions{Since: "1w"}); len(got) != 1 || got[0] != "fresh" {
+		t.Errorf("expected only fresh since 1w, got %q", got)
+	}
+	if got := names(ListOptions{Before: "1w"}); len(got) != 2 || got[0] != "week" || got[1] != "ancient" {
+		t.Errorf("expected week and ancient before 1w, got %q", got)
+	}
+	if got := names(ListOptions{Since: "30d", Before: "1d"}); len(got) != 1 || got[0] != "week" {
+		t.Errorf("expected only week between 30d and 1d, got %q", got)
+	}
+
+	if _, err := filterByTime(t.Context(), worktrees, ListOptions{Since: "1w", TimeField: "modified"}); err == nil {
+		t.Error("expected an unknown --time-field to be rejected")
+	}
+	if _, err := filterByTime(t.Context(), worktrees, ListOptions{Before: "later"}); err == nil {
+		t.Error("expected an invalid --before to be rejected")
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// TimeConfig controls how tables show times
+type TimeConfig struct {
+	// Absolute prints timestamps instead of relative times such as "3 days ago", as --absolute-time does
+	Absolute bool `toml:"absolute"`
+	// Format is the Go time layout of absolute timestamps (e.g. "2006-01-02 15:04"); defaults to RFC 3339
+	Format string `toml:"format"`
+}
+
+// absoluteTime prints timestamps in tables, set by the global --absolute-time flag
+var absoluteTime bool
+
+// timeLayout is the layout formatTime prints; empty prints relative times
+var timeLayout string
+
+// useTimeConfig chooses between relative and absolute times before a table is printed
+func useTimeConfig(ctx context.Context) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	timeLayout = ""
+	if absoluteTime || cfg.Time.Absolute {
+		timeLayout = time.RFC3339
+		if layout := strings.TrimSpace(cfg.Time.Format); layout != "" {
+			timeLayout = layout
+		}
+	}
+	return nil
+}
+
+// formatTime formats a time for a table column in the layout chosen by useTimeConfig
+func formatTime(t time.Time) string {
+	if timeLayout == "" || t.IsZero() {
+		return formatTimeAgo(t)
+	}
+	return t.Local().Format(timeLayout)
+}
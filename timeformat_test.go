@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFormatTime(t *testing.T) {
+	defer func() { absoluteTime, timeLayout = false, "" }()
+
+	created := time.Now().Add(-3 * 24 * time.Hour)
+	if got := formatTime(created); got != "3 days ago" {
+		t.Errorf("expected a relative time by default, got %q", got)
+	}
+
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	absoluteTime = true
+	if err := useTimeConfig(ctx); err != nil {
+		t.Fatalf("useTimeConfig failed: %v", err)
+	}
+	if got, want := formatTime(created), created.Local().Format(time.RFC3339); got != want {
+		t.Errorf("expected %q with --absolute-time, got %q", want, got)
+	}
+	if got := formatTime(time.Time{}); got != "unknown" {
+		t.Errorf("expected an unknown time to stay unknown, got %q", got)
+	}
+
+	timeLayout = "2006-01-02 15:04"
+	if got, want := formatTime(created), created.Local().Format("2006-01-02 15:04"); got != want {
+		t.Errorf("expected %q with a configured layout, got %q", want, got)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// tmuxSessionName converts a worktree name into a valid tmux session or window name
+func tmuxSessionName(name string) string {
+	// tmux treats '.' and ':' as target separators
+	return strings.NewReplacer(".", "_", ":", "_").Replace(name)
+}
+
+func insideTmux() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// TmuxWorktree switches to a tmux session (or, with window, a window in the current session)
+// named after the worktree, creating it with the worktree as working directory if needed
+func TmuxWorktree(ctx context.Context, name string, window bool) error {
+	target, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("tmux"); err != nil {
+		return errors.New("tmux is not installed")
+	}
+
+	markUsed(ctx, target)
+	if window {
+		return tmuxWindow(ctx, target)
+	}
+
+	if err := ensureTmuxSession(ctx, target); err != nil {
+		return err
+	}
+	session := "=" + tmuxSessionName(target.Name)
+	if insideTmux() {
+		return runMutation(ctx, "tmux", "switch-client", "-t", session)
+	}
+	if planning() {
+		activePlan.Record("tmux attach-session -t %s", session)
+		return nil
+	}
+
+	// Attaching takes over the terminal, so it needs the caller's stdio
+	cmd := exec.CommandContext(ctx, "tmux", "attach-session", "-t", session)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// ensureTmuxSession creates a detached session for the worktree unless one already exists
+func ensureTmuxSession(ctx context.Context, wt *Worktree) error {
+	session := tmuxSessionName(wt.Name)
+	if exec.CommandContext(ctx, "tmux", "has-session", "-t", "="+session).Run() == nil {
+		return nil
+	}
+	if err := runMutation(ctx, "tmux", "new-session", "-d", "-s", session, "-c", wt.Path); err != nil {
+		return fmt.Errorf("failed to create tmux session '%s': %w", session, err)
+	}
+	return nil
+}
+
+func tmuxWindow(ctx context.Context, wt *Worktree) error {
+	if !insideTmux() {
+		return errors.New("--window requires running inside tmux")
+	}
+	window := tmuxSessionName(wt.Name)
+	if tmuxWindowExists(ctx, window) {
+		return runMutation(ctx, "tmux", "select-window", "-t", ":"+window)
+	}
+	return runMutation(ctx, "tmux", "new-window", "-n", window, "-c", wt.Path)
+}
+
+// tmuxWindowExists reports whether the current tmux session has a window with the given name
+func tmuxWindowExists(ctx context.Context, window string) bool {
+	output, err := exec.CommandContext(ctx, "tmux", "list-windows", "-F", "#{window_name}").Output()
+	if err != nil {
+		return false
+	}
+	for _, name := range strings.Split(string(output), "\n") {
+		if name == window {
+			return true
+		}
+	}
+	return false
+}
+
+// autoCreateTmuxSession creates a detached session for a new worktree when tmux.autoCreate is enabled
+func autoCreateTmuxSession(ctx context.Context, name, path string) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if !cfg.Tmux.AutoCreate {
+		return nil
+	}
+	if _, err := exec.LookPath("tmux"); err != nil {
+		fmt.Fprintln(os.Stderr, "Skipped tmux session: tmux is not installed")
+		return nil
+	}
+	return ensureTmuxSession(ctx, &Worktree{Name: name, Path: path})
+}
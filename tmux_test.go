@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTmuxSessionName(t *testing.T) {
+	if got := tmuxSessionName("release-1.2:hotfix"); got != "release-1_2_hotfix" {
+		t.Errorf("tmuxSessionName = %q, want %q", got, "release-1_2_hotfix")
+	}
+}
+
+func TestAddWorktreeAutoCreatesTmuxSession(t *testing.T) {
+	ctx := t.Context()
+
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux is not installed")
+	}
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	// Use a private tmux server so the test never touches the user's sessions
+	t.Setenv("TMUX_TMPDIR", t.TempDir())
+	t.Setenv("TMUX", "")
+	defer exec.Command("tmux", "kill-server").Run()
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[tmux]\nautoCreate = true\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	if err := AddWorktree(ctx, "tmux.test", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	output, err := exec.Command("tmux", "display-message", "-p", "-t", "=tmux_test:", "#{pane_current_path}").Output()
+	if err != nil {
+		t.Fatalf("expected tmux session 'tmux_test' to exist: %v", err)
+	}
+	wt, err := findWorktree(ctx, "tmux.test")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if normalizePath(strings.TrimSpace(string(output))) != normalizePath(wt.Path) {
+		t.Errorf("expected session directory %q, got %q", wt.Path, strings.TrimSpace(string(output)))
+	}
+}
+
+func TestTmuxWindowDryRun(t *testing.T) {
+	ctx := t.Context()
+
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux is not installed")
+	}
+
+	// Use a private tmux server so the test never touches the user's sessions
+	t.Setenv("TMUX_TMPDIR", t.TempDir())
+	t.Setenv("TMUX", "")
+	defer exec.Command("tmux", "kill-server").Run()
+
+	tmux := func(args ...string) string {
+		t.Helper()
+		output, err := exec.Command("tmux", args...).Output()
+		if err != nil {
+			t.Fatalf("tmux %v failed: %v", args, err)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	tmux("new-session", "-d", "-s", "dry", "-n", "api")
+	tmux("new-window", "-t", "=dry:", "-n", "other")
+	// Commands run as if from inside the session
+	socket := tmux("display-message", "-p", "-t", "=dry:", "#{socket_path}")
+	t.Setenv("TMUX", socket+",0,0")
+
+	wt := &Worktree{Name: "api", Path: t.TempDir()}
+	p, err := runPlanned("wtm tmux api --window", func() error {
+		return tmuxWindow(ctx, wt)
+	})
+	if err != nil {
+		t.Fatalf("tmuxWindow failed: %v", err)
+	}
+	if len(p.Steps) != 1 || !strings.Contains(p.Steps[0], "tmux select-window -t :api") {
+		t.Errorf("expected select-window to be planned, got %q", p.Steps)
+	}
+	if active := tmux("display-message", "-p", "-t", "=dry:", "#{window_name}"); active != "other" {
+		t.Errorf("expected the dry run to leave window 'other' active, got %q", active)
+	}
+
+	p, err = runPlanned("wtm tmux web --window", func() error {
+		return tmuxWindow(ctx, &Worktree{Name: "web", Path: wt.Path})
+	})
+	if err != nil || len(p.Steps) != 1 || !strings.Contains(p.Steps[0], "tmux new-window -n web") {
+		t.Errorf("expected new-window to be planned, got %v, %v", p, err)
+	}
+}
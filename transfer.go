@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TransferOptions groups configuration for moving uncommitted changes between worktrees
+type TransferOptions struct {
+	// Untracked also moves untracked files that are neither git-ignored nor wtm-ignored
+	Untracked bool
+	// Keep leaves the changes in the source worktree instead of stashing them there
+	Keep bool
+}
+
+// transferablePaths lists the paths TransferChanges moves out of a worktree: tracked files that differ
+// from HEAD and, with untracked, the untracked files that are neither git-ignored nor wtm-ignored
+func transferablePaths(ctx context.Context, wt *Worktree, untracked bool) (tracked, others []string, err error) {
+	// Renames are split into a deletion and an addition so the stash pathspec covers both sides
+	output, err := runGitCommand(ctx, "-C", wt.Path, "diff", "--name-only", "-z", "--no-renames", "HEAD")
+	if err != nil {
+		return nil, nil, err
+	}
+	if untracked {
+		if others, err = listUntrackedFiles(ctx, wt.Path); err != nil {
+			return nil, nil, err
+		}
+	}
+	return nulFields(output), others, nil
+}
+
+// TransferChanges moves the uncommitted changes of one worktree to another. The changes are applied as a
+// patch against the destination's working tree; when it does not apply, or an untracked file already
+// exists there, nothing is changed in either worktree. Afterwards the moved paths are stashed in the
+// source worktree, so they can still be restored there, unless Keep is set.
+func TransferChanges(ctx context.Context, fromName, toName string, opts TransferOptions) error {
+	from, err := findWorktree(ctx, fromName)
+	if err != nil {
+		return err
+	}
+	to, err := findWorktree(ctx, toName)
+	if err != nil {
+		return err
+	}
+	if from.Bare || to.Bare {
+		return fmt.Errorf("cannot transfer changes to or from the bare repository")
+	}
+	if normalizePath(from.Path) == normalizePath(to.Path) {
+		return fmt.Errorf("'%s' and '%s' are the same worktree", fromName, toName)
+	}
+
+	trackedPaths, untracked, err := transferablePaths(ctx, from, opts.Untracked)
+	if err != nil {
+		return err
+	}
+	if len(trackedPaths) == 0 && len(untracked) == 0 {
+		return fmt.Errorf("worktree '%s' has no uncommitted changes to transfer", from.Name)
+	}
+
+	// Check everything before touching either worktree
+	for _, rel := range untracked {
+		if _, err := os.Lstat(filepath.Join(to.Path, filepath.FromSlash(rel))); err == nil {
+			return fmt.Errorf("'%s' already exists in worktree '%s'; nothing was transferred", rel, to.Name)
+		}
+	}
+	if len(trackedPaths) > 0 {
+		patch, err := os.CreateTemp("", "wtm-transfer-*.patch")
+		if err != nil {
+			return err
+		}
+		patch.Close()
+		defer os.Remove(patch.Name())
+		if _, err := runGitCommand(ctx, "-C", from.Path, "diff", "--binary", "--no-renames", "--output="+patch.Name(), "HEAD"); err != nil {
+			return err
+		}
+		if _, err := runGitCommand(ctx, "-C", to.Path, "apply", "--check", patch.Name()); err != nil {
+			return fmt.Errorf("changes of '%s' do not apply cleanly to '%s'; nothing was transferred: %w", from.Name, to.Name, err)
+		}
+		if _, err := runGitMutation(ctx, "-C", to.Path, "apply", patch.Name()); err != nil {
+			return err
+		}
+	}
+
+	for _, rel := range untracked {
+		src := filepath.Join(from.Path, filepath.FromSlash(rel))
+		dst := filepath.Join(to.Path, filepath.FromSlash(rel))
+		if planFileOp("copy %s to %s", src, dst) {
+			continue
+		}
+		info, err := os.Lstat(src)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(src)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, dst); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := copyFile(src, dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	count := len(trackedPaths) + len(untracked)
+	if opts.Keep {
+		report("✓ Copied %d changed file(s) from %s to %s\n", count, from.Name, to.Name)
+		return nil
+	}
+	args := []string{"-C", from.Path, "stash", "push", "-m", fmt.Sprintf("wtm: changes transferred from '%s' to '%s'", from.Name, to.Name)}
+	if len(untracked) > 0 {
+		args = append(args, "--include-untracked")
+	}
+	args = append(args, "--")
+	args = append(args, trackedPaths...)
+	args = append(args, untracked...)
+	if _, err := runGitMutation(ctx, args...); err != nil {
+		return fmt.Errorf("transferred the changes to '%s' but could not remove them from '%s': %w", to.Name, from.Name, err)
+	}
+	report("✓ Moved %d changed file(s) from %s to %s (the originals are in git stash list)\n", count, from.Name, to.Name)
+	return nil
+}
+
+// nulFields splits the output of a git command run with -z; paths in it are not quoted
+func nulFields(output string) []string {
+	var fields []string
+	for _, field := range strings.Split(output, "\x00") {
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransferChanges(t *testing.T) {
+	ctx := t.Context()
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	for _, name := range []string{"wrong", "right"} {
+		if _, err := captureStdout(t, func() error {
+			return AddWorktree(ctx, name, AddOptions{})
+		}); err != nil {
+			t.Fatalf("AddWorktree %s failed: %v", name, err)
+		}
+	}
+	wrong, err := findWorktree(ctx, "wrong")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	right, err := findWorktree(ctx, "right")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+
+	if err := TransferChanges(ctx, "wrong", "right", TransferOptions{}); err == nil {
+		t.Error("expected transferring a clean worktree to fail")
+	}
+
+	if err := os.WriteFile(filepath.Join(wrong.Path, "README.md"), []byte("# changed\n"), 0o644); err != nil {
+		t.Fatalf("Failed to modify file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(wrong.Path, "notes.txt"), []byte("todo\n"), 0o644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	// A conflicting untracked file in the destination aborts before anything changes
+	if err := os.WriteFile(filepath.Join(right.Path, "notes.txt"), []byte("mine\n"), 0o644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := TransferChanges(ctx, "wrong", "right", TransferOptions{Untracked: true}); err == nil {
+		t.Fatal("expected an existing untracked file to abort the transfer")
+	}
+	if changes, _ := newManager(ctx).Changes(ctx, wrong); len(changes) != 2 {
+		t.Errorf("expected the source to keep both changes, got %q", changes)
+	}
+	if err := os.Remove(filepath.Join(right.Path, "notes.txt")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return TransferChanges(ctx, "wrong", "right", TransferOptions{Untracked: true})
+	}); err != nil {
+		t.Fatalf("TransferChanges failed: %v", err)
+	}
+	for name, want := range map[string]string{"README.md": "# changed\n", "notes.txt": "todo\n"} {
+		data, err := os.ReadFile(filepath.Join(right.Path, name))
+		if err != nil || string(data) != want {
+			t.Errorf("expected %s in the destination to be %q, got %q (%v)", name, want, data, err)
+		}
+	}
+	if changes, _ := newManager(ctx).Changes(ctx, wrong); len(changes) != 0 {
+		t.Errorf("expected the source to be clean, got %q", changes)
+	}
+	stashes, err := runGitCommand(ctx, "stash", "list")
+	if err != nil || !strings.Contains(stashes, "transferred from 'wrong' to 'right'") {
+		t.Errorf("expected the originals to be stashed, got %q (%v)", stashes, err)
+	}
+
+	// Changes to the same file do not apply on top of the destination's own edits
+	if err := os.WriteFile(filepath.Join(wrong.Path, "README.md"), []byte("# other\n"), 0o644); err != nil {
+		t.Fatalf("Failed to modify file: %v", err)
+	}
+	if err := TransferChanges(ctx, "wrong", "right", TransferOptions{Keep: true}); err == nil {
+		t.Error("expected a conflicting patch to abort the transfer")
+	}
+	if data, _ := os.ReadFile(filepath.Join(right.Path, "README.md")); string(data) != "# changed\n" {
+		t.Errorf("expected the destination to be untouched, got %q", data)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	defaultWatchInterval = 2 * time.Second
+	clearScreen          = "\033[H\033[2J"
+)
+
+// watch calls render every interval until ctx is done, clearing the screen first when stdout is a
+// terminal. A failing render is reported on the screen and retried at the next tick rather than
+// ending the watch, since the usual cause is a git command racing with another process.
+func watch(ctx context.Context, title string, interval time.Duration, render func() error) error {
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	tty := isTerminal(os.Stdout)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if tty {
+			fmt.Print(clearScreen)
+		}
+		fmt.Printf("Every %s: %s    %s\n\n", interval, title, time.Now().Format("2006-01-02 15:04:05"))
+		if err := render(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		if !tty {
+			fmt.Println()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	renders := 0
+	out, err := captureStdout(t, func() error {
+		return watch(ctx, "wtm list", time.Millisecond, func() error {
+			renders++
+			switch renders {
+			case 2:
+				return errors.New("index.lock exists")
+			case 3:
+				cancel()
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	if renders != 3 {
+		t.Errorf("expected 3 renders, got %d", renders)
+	}
+	if got := strings.Count(out, "Every 1ms: wtm list"); got != 3 {
+		t.Errorf("expected a header per render, got %d in:\n%s", got, out)
+	}
+	if !strings.Contains(out, "Error: index.lock exists") {
+		t.Errorf("expected the failed render to be reported, got:\n%s", out)
+	}
+
+	if err := watch(t.Context(), "wtm list", 0, func() error { return nil }); err == nil {
+		t.Error("expected a non-positive interval to be rejected")
+	}
+}
@@ -2,35 +2,76 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
+
+	"github.com/choplin/wtm/pkg/wtm"
 )
 
 // Worktree represents a git worktree
-type Worktree struct {
-	Name    string    `json:"name"`
-	Branch  string    `json:"branch"`
-	Path    string    `json:"path"`
-	HEAD    string    `json:"head"`
-	Created time.Time `json:"created"`
+type Worktree = wtm.Worktree
+
+// AddOptions groups configuration for creating a worktree
+type AddOptions struct {
+	// Branch creates a new branch with this name (defaults to the worktree name)
+	Branch string
+	// Checkout uses an existing branch instead of creating one
+	Checkout string
+	// Base is the starting point for a new branch (defaults to the current HEAD)
+	Base string
+	// From uses the branch, or the detached commit, of another worktree as the base
+	From string
+	// Detach checks out this commit, tag or ref in detached HEAD mode without creating a branch
+	Detach string
+	// Tag checks out a tag, detached or, with Branch, on a new branch from it, and records the tag
+	Tag string
+	// Review checks out a pull request (GitHub) or merge request (GitLab) by number
+	Review int
+	// Issue starts work on a GitHub issue: the name and branch are rendered from its title
+	// and the issue URL is recorded in the worktree metadata
+	Issue int
+	// ReadOnly removes write permissions from the checkout and marks it read-only for wtm commands
+	ReadOnly bool
+	// NoCheckout creates the worktree without populating files, e.g. to configure sparse-checkout first
+	NoCheckout bool
+	// Sparse limits the checkout to these directories
+	Sparse []string
+	// SparseProfile adds the directories of a named profile from the [sparse] config
+	SparseProfile string
+	// RecurseSubmodules initializes submodules in the new worktree, as does setup.submodules in the config
+	RecurseSubmodules bool
+	// Template applies a named template from the [templates] config; explicit options take precedence
+	Template string
+	// Labels tag the worktree for wtm list --group-by label
+	Labels []string
+	// SkipSpaceCheck creates the worktree even when the checkout looks larger than the free disk space
+	SkipSpaceCheck bool
+	// Ephemeral marks the worktree for removal, with the branch created for it, by the next wtm gc
+	Ephemeral bool
+
+	// owner is the PID recorded for an ephemeral worktree whose command this process runs
+	owner int
 }
 
 // BranchDeleteMode indicates how to handle the associated branch once the worktree is removed
-type BranchDeleteMode int
+type BranchDeleteMode = wtm.BranchDeleteMode
 
 const (
-	// BranchDeleteNone leaves the branch untouched
-	BranchDeleteNone BranchDeleteMode = iota
-	// BranchDeleteSafe deletes the branch via `git branch -d`, failing if it is not fully merged
-	BranchDeleteSafe
-	// BranchDeleteForce deletes the branch via `git branch -D`, even if it is not merged
-	BranchDeleteForce
+	BranchDeleteNone  = wtm.BranchDeleteNone
+	BranchDeleteSafe  = wtm.BranchDeleteSafe
+	BranchDeleteForce = wtm.BranchDeleteForce
 )
 
 // RemoveOptions groups configuration for removing a worktree
@@ -39,192 +80,586 @@ type RemoveOptions struct {
 	Force bool
 	// BranchDelete controls whether and how to delete the associated branch after removing the worktree
 	BranchDelete BranchDeleteMode
+	// After is a shell command run inside the worktree first; the worktree is only removed if it exits zero
+	After string
+	// Stash saves uncommitted and untracked changes as a stash before removing the worktree
+	Stash bool
+	// DiscardChanges removes a worktree even if it has uncommitted or untracked changes
+	DiscardChanges bool
+	// AndCD allows removing the worktree containing the current directory: wtm moves to the primary
+	// worktree first and tells the shell helper to follow through $WTM_CD_FILE
+	AndCD bool
 }
 
-func runGitCommand(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("%w: %s", err, string(output))
+// shellCommand builds a command that runs a shell snippet with the platform shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// runAfterCommand runs the --after guard inside the worktree, streaming its output
+func runAfterCommand(ctx context.Context, target *Worktree, command string) error {
+	if planFileOp("run in %s: %s", target.Path, command) {
+		return nil
 	}
-	return string(output), nil
+	cmd := shellCommand(ctx, command)
+	cmd.Dir = target.Path
+	if err := setWorktreeEnv(ctx, cmd, target); err != nil {
+		return err
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("kept worktree '%s': after command '%s' failed: %w", target.Name, command, err)
+	}
+	return nil
 }
 
-func resolveWorktreeBase() (string, error) {
-	cfg, err := loadConfig()
+// repoDir is the directory git commands run in, set by the global -C/--repo flag.
+// Empty means the process working directory.
+var repoDir string
+
+// setRepoDir points every subsequent repository operation at dir
+func setRepoDir(dir string) error {
+	abs, err := filepath.Abs(dir)
 	if err != nil {
-		return "", err
+		return err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return fmt.Errorf("cannot use repository '%s': %w", dir, err)
 	}
+	if !info.IsDir() {
+		return fmt.Errorf("cannot use repository '%s': not a directory", dir)
+	}
+	repoDir = abs
+	return nil
+}
 
-	root := strings.TrimSpace(cfg.WorktreeRoot)
-	if root == "" {
-		root = defaultWorktreeRoot
+// repoDirKey carries the repository of a single MCP call, so concurrent calls on different
+// repositories do not share repoDir
+type repoDirKey struct{}
+
+// withRepoDir runs the repository operations of ctx in dir instead of repoDir
+func withRepoDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, repoDirKey{}, dir)
+}
+
+// selectedRepoDir returns the directory git commands of ctx run in; empty means the process working directory
+func selectedRepoDir(ctx context.Context) string {
+	if dir, ok := ctx.Value(repoDirKey{}).(string); ok {
+		return dir
 	}
+	return repoDir
+}
 
-	repoRoot, err := getRepoRoot()
-	if err != nil {
-		return "", err
+// workingDir returns the directory repository operations are resolved against
+func workingDir(ctx context.Context) (string, error) {
+	if dir := selectedRepoDir(ctx); dir != "" {
+		return dir, nil
 	}
-	var base string
-	if filepath.IsAbs(root) {
-		base = root
-	} else {
-		base = filepath.Join(repoRoot, root)
+	return os.Getwd()
+}
+
+// repoCommand prepares an external command that runs in the selected repository
+func repoCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = selectedRepoDir(ctx)
+	return cmd
+}
+
+// gitBackend runs git for newManager; builds with the gogit tag answer read queries with go-git
+var gitBackend wtm.GitRunner = wtm.ExecRunner{}
+
+// newManager returns a library manager bound to the selected repository and the active plan.
+// Untracked files matched by .wtmignore do not count as changes.
+func newManager(ctx context.Context) *wtm.Manager {
+	return &wtm.Manager{
+		Dir:             selectedRepoDir(ctx),
+		Plan:            activePlan,
+		Logger:          logger,
+		Runner:          timedRunner{m: toolMetrics},
+		FilterUntracked: filterIgnoredPaths,
 	}
+}
+
+// worktreeManager returns a manager that also knows the configured worktree root
+func worktreeManager(ctx context.Context) (*wtm.Manager, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m := newManager(ctx)
+	m.Root = cfg.WorktreeRoot
+	m.Layout = cfg.Layout
+	m.AllowNestedNames = cfg.AllowNestedNames
+	return m, nil
+}
 
-	return filepath.Clean(base), nil
+func runGitCommand(ctx context.Context, args ...string) (string, error) {
+	return newManager(ctx).Git(ctx, args...)
 }
 
-func getRepoRoot() (string, error) {
-	commonDir, err := runGitCommand("rev-parse", "--git-common-dir")
+func resolveWorktreeBase(ctx context.Context) (string, error) {
+	m, err := worktreeManager(ctx)
 	if err != nil {
 		return "", err
 	}
+	return m.WorktreeBase(ctx)
+}
 
-	commonDir = strings.TrimSpace(commonDir)
-	if !filepath.IsAbs(commonDir) {
-		cwd, err := os.Getwd()
-		if err != nil {
-			return "", err
-		}
-		commonDir = filepath.Join(cwd, commonDir)
-	}
+func getRepoRoot(ctx context.Context) (string, error) {
+	return newManager(ctx).RepoRoot(ctx)
+}
 
-	repoRoot := filepath.Clean(filepath.Join(commonDir, ".."))
-	return repoRoot, nil
+// gitCommonDir returns the absolute path of the repository's shared git directory
+func gitCommonDir(ctx context.Context) (string, error) {
+	return newManager(ctx).CommonDir(ctx)
+}
+
+func refExists(ctx context.Context, ref string) bool {
+	return newManager(ctx).RefExists(ctx, ref)
 }
 
 // AddWorktree creates a new worktree
-func AddWorktree(name, branch, checkout, base string) error {
-	// Validate we're in a git repository
-	if _, err := runGitCommand("rev-parse", "--git-dir"); err != nil {
-		return fmt.Errorf("not in a git repository")
-	}
+func AddWorktree(ctx context.Context, name string, opts AddOptions) error {
+	_, err := addWorktree(ctx, name, opts)
+	return err
+}
 
-	// Check if worktree already exists
-	worktrees, err := getWorktrees()
+// worktreeBaseRef resolves --from: the branch checked out in the named worktree, or its commit when
+// the worktree is detached. Uncommitted changes in that worktree are not carried over.
+func worktreeBaseRef(ctx context.Context, name string) (string, error) {
+	wt, err := findWorktree(ctx, name)
 	if err != nil {
-		return err
+		return "", err
 	}
-	for _, wt := range worktrees {
-		if wt.Name == name {
-			return fmt.Errorf("worktree '%s' already exists", name)
+	if wt.Bare {
+		return "", fmt.Errorf("worktree '%s' is the bare repository and has no branch to start from", name)
+	}
+	if wt.Branch != "" {
+		return wt.Branch, nil
+	}
+	return wt.HEAD, nil
+}
+
+// addWorktree creates a worktree and returns it; an empty name is inferred from the branch
+func addWorktree(ctx context.Context, name string, opts AddOptions) (*Worktree, error) {
+	if opts.Review > 0 && (opts.Checkout != "" || opts.Base != "") {
+		return nil, fmt.Errorf("cannot combine --pr/--mr with -B or --base")
+	}
+	if opts.Detach != "" && opts.Review > 0 {
+		return nil, fmt.Errorf("cannot combine --detach with branch options")
+	}
+	if opts.Issue > 0 && (opts.Review > 0 || opts.Checkout != "" || opts.Detach != "") {
+		return nil, fmt.Errorf("cannot combine --issue with --pr/--mr, -B or --detach")
+	}
+	if opts.From != "" {
+		if opts.Base != "" || opts.Checkout != "" || opts.Detach != "" || opts.Review > 0 {
+			return nil, fmt.Errorf("cannot combine --from with --base, -B, --detach or --pr/--mr")
+		}
+		base, err := worktreeBaseRef(ctx, opts.From)
+		if err != nil {
+			return nil, err
+		}
+		opts.Base = base
+	}
+	if opts.Tag != "" {
+		if opts.Base != "" || opts.Checkout != "" || opts.Detach != "" || opts.Review > 0 || opts.Issue > 0 {
+			return nil, fmt.Errorf("cannot combine --tag with --base, --from, -B, --detach, --pr/--mr or --issue")
+		}
+		if !refExists(ctx, "refs/tags/"+opts.Tag) {
+			return nil, fmt.Errorf("tag '%s' not found (fetch it with: git fetch origin tag %s)", opts.Tag, opts.Tag)
+		}
+		if opts.Branch != "" {
+			opts.Base = "refs/tags/" + opts.Tag
+		} else {
+			opts.Detach = opts.Tag
 		}
 	}
 
-	// Determine the path for the worktree
-	worktreeBase, err := resolveWorktreeBase()
+	cfg, err := loadConfig(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if err := os.MkdirAll(worktreeBase, 0o755); err != nil {
-		return err
+	var issue *issueInfo
+	if opts.Issue > 0 {
+		if issue, err = findIssue(ctx, opts.Issue); err != nil {
+			return nil, err
+		}
+		issueName, err := renderIssueName(cfg.Names, issue)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			name = issueName
+		}
+		if opts.Branch == "" {
+			opts.Branch = wtm.BranchName(issueName)
+		}
+	}
+	var tmpl WorktreeTemplate
+	if opts.Template != "" {
+		if tmpl, err = applyTemplate(cfg, opts.Template, &opts); err != nil {
+			return nil, err
+		}
+	}
+	if name == "" {
+		if name, err = inferWorktreeName(cfg.Names, opts); err != nil {
+			return nil, err
+		}
+	}
+	name = cfg.Names.sanitize(name)
+	validate := wtm.ValidateName
+	if cfg.AllowNestedNames {
+		validate = wtm.ValidateNestedName
+	}
+	if err := validate(name); err != nil {
+		return nil, err
+	}
+	labels, err := normalizeLabels(opts.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.SparseProfile != "" {
+		paths, ok := cfg.Sparse[opts.SparseProfile]
+		if !ok {
+			return nil, fmt.Errorf("unknown sparse profile '%s'", opts.SparseProfile)
+		}
+		opts.Sparse = append(slices.Clone(opts.Sparse), paths...)
 	}
-	worktreePath := filepath.Join(worktreeBase, name)
 
-	// Build git worktree add command
-	var args []string
+	m, err := worktreeManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	worktreeBase, err := m.WorktreeBase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDiskQuota(ctx, worktreeBase); err != nil {
+		return nil, err
+	}
+	if !opts.NoCheckout && !opts.SkipSpaceCheck {
+		if err := checkCheckoutSpace(ctx, worktreeBase, opts.Sparse); err != nil {
+			return nil, err
+		}
+	}
+	port, err := allocatePort(ctx, cfg.Ports)
+	if err != nil {
+		return nil, err
+	}
 
-	if checkout != "" && branch != "" {
-		return fmt.Errorf("cannot use both -b and -B options")
+	addOpts := wtm.AddOptions{
+		Branch:     opts.Branch,
+		Checkout:   opts.Checkout,
+		Base:       opts.Base,
+		Detach:     opts.Detach,
+		ReadOnly:   opts.ReadOnly,
+		NoCheckout: opts.NoCheckout,
+		Sparse:     opts.Sparse,
+		Setup: func(wt *Worktree) error {
+			if port > 0 {
+				if err := recordPort(ctx, wt.Name, port); err != nil {
+					return fmt.Errorf("failed to record the port: %w", err)
+				}
+				wt.Port = port
+			}
+			data, err := newWorktreeTemplateData(ctx, wt)
+			if err != nil {
+				return err
+			}
+			if err := writeEnvrc(ctx, data); err != nil {
+				return fmt.Errorf("failed to write %s: %w", envrcFile, err)
+			}
+			if err := renderFiles(cfg.RenderFiles, data); err != nil {
+				return fmt.Errorf("failed to render files: %w", err)
+			}
+			if err := copyFiles(tmpl.CopyFiles, data); err != nil {
+				return fmt.Errorf("failed to copy files: %w", err)
+			}
+			if err := installHooks(ctx, wt); err != nil {
+				return fmt.Errorf("failed to install hooks: %w", err)
+			}
+			if (opts.RecurseSubmodules || cfg.Setup.Submodules) && !opts.NoCheckout {
+				if err := updateSubmodules(ctx, wt); err != nil {
+					return fmt.Errorf("failed to update submodules: %w", err)
+				}
+			}
+			if cfg.Setup.LFS && !opts.NoCheckout {
+				if err := pullLFS(ctx, wt); err != nil {
+					return fmt.Errorf("failed to pull LFS objects: %w", err)
+				}
+			}
+			return runSetupCommands(ctx, wt, tmpl.setupSteps())
+		},
+	}
+	if opts.Branch == "" && opts.Checkout == "" && opts.Detach == "" && opts.Review == 0 && cfg.Names.BranchTemplate != "" {
+		branch, err := renderTemplate("branchTemplate", cfg.Names.BranchTemplate, struct{ Name string }{name})
+		if err != nil {
+			return nil, fmt.Errorf("invalid names.branchTemplate: %w", err)
+		}
+		if addOpts.Branch = wtm.BranchName(branch); addOpts.Branch == "" {
+			return nil, fmt.Errorf("names.branchTemplate rendered an empty branch name for '%s'", name)
+		}
+	}
+	if opts.Review > 0 {
+		// Fetch the pull/merge request head into a local branch and check it out
+		local, err := fetchReview(ctx, opts.Review, opts.Branch)
+		if err != nil {
+			return nil, err
+		}
+		addOpts.Branch, addOpts.Checkout = "", local
 	}
 
-	if branch != "" {
-		// Create new branch
-		args = []string{"worktree", "add", worktreePath, "-b", branch}
-		if base != "" {
-			args = append(args, base)
+	wt, err := m.Add(ctx, name, addOpts)
+	if err != nil {
+		if opts.Review > 0 {
+			// The fetched branch was created for this worktree only
+			_, _ = runGitMutation(ctx, "branch", "-D", addOpts.Checkout)
 		}
-	} else if checkout != "" {
-		// Checkout existing branch
-		args = []string{"worktree", "add", worktreePath, checkout}
-	} else {
-		// Default: create branch with same name as worktree
-		args = []string{"worktree", "add", worktreePath, "-b", name}
-		if base != "" {
-			args = append(args, base)
+		return nil, err
+	}
+	if opts.Review > 0 {
+		wt.CreatedBranch = true
+	}
+	if issue != nil {
+		if err := setWorktreeMeta(ctx, wt.Name, metaIssue, issue.URL); err != nil {
+			return nil, err
+		}
+		wt.Issue = issue.URL
+	}
+	if opts.Tag != "" {
+		if err := setWorktreeMeta(ctx, wt.Name, metaTag, opts.Tag); err != nil {
+			return nil, err
+		}
+		wt.Tag = opts.Tag
+		// Record the tag's short name as the base rather than the refs/tags/ path it was created from
+		if wt.Branch != "" {
+			if err := setWorktreeMeta(ctx, wt.Name, metaBase, opts.Tag); err != nil {
+				return nil, err
+			}
+			wt.Base = opts.Tag
+		}
+	}
+	if len(labels) > 0 {
+		if err := setWorktreeMeta(ctx, wt.Name, metaLabels, strings.Join(labels, ",")); err != nil {
+			return nil, err
+		}
+		wt.Labels = labels
+	}
+	if opts.Ephemeral {
+		if err := markEphemeral(ctx, wt, opts); err != nil {
+			return nil, err
 		}
 	}
 
-	// Execute git worktree add
-	if _, err := runGitCommand(args...); err != nil {
-		return err
+	if err := autoCreateTmuxSession(ctx, name, wt.Path); err != nil {
+		return nil, err
 	}
 
-	// Get the created worktree info for success message
-	worktrees, err = getWorktrees()
-	if err != nil {
-		return err
+	if planning() {
+		return wt, nil
 	}
 
-	for _, wt := range worktrees {
-		if wt.Name == name {
-			fmt.Printf("✓ Created worktree: %s\n", wt.Name)
-			fmt.Printf("  Branch: %s\n", wt.Branch)
-			fmt.Printf("  Path: %s\n", wt.Path)
-			return nil
+	fmt.Printf("✓ Created worktree: %s\n", wt.Name)
+	fmt.Printf("  Branch: %s\n", formatBranch(*wt))
+	fmt.Printf("  Path: %s\n", wt.Path)
+	if modes := worktreeModes(*wt); len(modes) > 0 {
+		fmt.Printf("  Mode: %s\n", strings.Join(modes, ", "))
+	}
+	if wt.Issue != "" {
+		fmt.Printf("  Issue: %s\n", wt.Issue)
+	}
+	if len(wt.Labels) > 0 {
+		fmt.Printf("  Labels: %s\n", strings.Join(wt.Labels, ", "))
+	}
+	if wt.Port > 0 {
+		fmt.Printf("  Port: %d\n", wt.Port)
+	}
+	if wt.Ephemeral {
+		fmt.Println("  Ephemeral: removed by the next wtm gc")
+	}
+	return wt, nil
+}
+
+// inferWorktreeName derives a worktree name from the -B or -b branch: names.nameTemplate when set,
+// otherwise the last path segment (origin/feature/login-fix becomes login-fix)
+func inferWorktreeName(cfg NamesConfig, opts AddOptions) (string, error) {
+	branch := opts.Checkout
+	if branch == "" {
+		branch = opts.Branch
+	}
+	if branch == "" {
+		branch = opts.Tag
+	}
+	if branch == "" {
+		return "", fmt.Errorf("a worktree name is required unless -b, -B or --tag is given")
+	}
+	if cfg.NameTemplate != "" {
+		name, err := renderTemplate("nameTemplate", cfg.NameTemplate, struct{ Branch string }{branch})
+		if err != nil {
+			return "", fmt.Errorf("invalid names.nameTemplate: %w", err)
 		}
+		return name, nil
 	}
+	return branch[strings.LastIndex(branch, "/")+1:], nil
+}
 
-	return nil
+// ListOptions groups configuration for printing worktrees
+type ListOptions struct {
+	// Format is table, plain, json, porcelain, names or paths
+	Format string
+	// NullTerminated ends every plain field with a NUL byte instead of separating fields with spaces
+	// and records with newlines, so paths with spaces or newlines survive xargs -0
+	NullTerminated bool
+	// GroupBy groups the table by branch prefix, label or base branch
+	GroupBy string
+	// Tree prints the groups as a tree instead of a table, grouping by prefix unless GroupBy is set
+	Tree bool
+	// All includes worktrees hidden with wtm hide or matched by the ignore config
+	All bool
+	// Since and Before keep worktrees whose TimeField is within the bounds: a duration before now
+	// such as 2w, or a date
+	Since, Before string
+	// TimeField is created (the default) or commit, the time of the last commit
+	TimeField string
+}
+
+// ShowOptions groups configuration for printing a single worktree
+type ShowOptions struct {
+	// Format is pretty or json
+	Format string
+	// Field prints only these comma-separated fields; status.* paths read the working tree status
+	Field string
+	// Tab prints multiple fields on one tab-separated line instead of one per line
+	Tab bool
+	// NullTerminated ends each field with a NUL byte instead of a newline
+	NullTerminated bool
 }
 
 // ListWorktrees lists all worktrees
-func ListWorktrees(format string) error {
-	worktrees, err := getWorktrees()
+func ListWorktrees(ctx context.Context, opts ListOptions) error {
+	if opts.NullTerminated && !lineFormats[opts.Format] {
+		return fmt.Errorf("-z requires --format plain, --porcelain, --names or --paths")
+	}
+	grouped := opts.GroupBy != "" || opts.Tree
+	if grouped && opts.Format != "table" {
+		return fmt.Errorf("--group-by and --tree require the table format")
+	}
+
+	worktrees, err := getWorktrees(ctx)
 	if err != nil {
 		return err
 	}
+	if worktrees, err = visibleWorktrees(ctx, worktrees, opts.All); err != nil {
+		return err
+	}
+	if worktrees, err = filterByTime(ctx, worktrees, opts); err != nil {
+		return err
+	}
+	if err := useTimeConfig(ctx); err != nil {
+		return err
+	}
 
 	var primaryPath string
-	if format == "table" || format == "plain" {
-		path, err := getRepoRoot()
+	if opts.Format == "table" || opts.Format == "plain" || opts.Format == "porcelain" {
+		path, err := getRepoRoot(ctx)
 		if err != nil {
 			return err
 		}
 		primaryPath = normalizePath(path)
 	}
 
-	switch format {
+	if grouped {
+		by := opts.GroupBy
+		if by == "" {
+			by = "prefix"
+		}
+		groups, err := groupWorktrees(worktrees, by)
+		if err != nil {
+			return err
+		}
+		if opts.Tree {
+			printTree(groups, primaryPath)
+		} else {
+			printGroupedTable(groups, primaryPath)
+		}
+		return nil
+	}
+
+	switch opts.Format {
 	case "table":
 		printTableFormat(worktrees, primaryPath)
 	case "plain":
-		printPlainFormat(worktrees, primaryPath)
+		printPlainFormat(worktrees, primaryPath, opts.NullTerminated)
+	case "porcelain":
+		records := make([][]porcelainLine, len(worktrees))
+		for i, wt := range worktrees {
+			records[i] = porcelainRecord(wt, primaryPath, nil)
+		}
+		printPorcelain(records, opts.NullTerminated)
+	case "names", "paths":
+		for _, wt := range worktrees {
+			printListValue(wt, wt.Name, opts)
+		}
 	case "json":
 		printJSONFormat(worktrees)
 	default:
-		return fmt.Errorf("unknown format: %s", format)
+		return fmt.Errorf("unknown format: %s", opts.Format)
 	}
 
 	return nil
 }
 
-// ShowWorktree shows detailed information about a worktree
-func ShowWorktree(name, format, field string) error {
-	worktrees, err := getWorktrees()
-	if err != nil {
-		return err
+// lineFormats are the list formats whose lines -z terminates with NUL bytes instead
+var lineFormats = map[string]bool{"plain": true, "porcelain": true, "names": true, "paths": true}
+
+// printListValue prints the name or path of a worktree on a line of its own for --names and --paths.
+// Bare repository entries have no checkout to work in and are left out.
+func printListValue(wt Worktree, name string, opts ListOptions) {
+	if wt.Bare {
+		return
+	}
+	value := name
+	if opts.Format == "paths" {
+		value = wt.Path
 	}
+	printPlainRecord([]string{value}, opts.NullTerminated)
+}
 
-	var target *Worktree
-	for _, wt := range worktrees {
-		if wt.Name == name {
-			target = &wt
-			break
-		}
+// ShowWorktree shows detailed information about a worktree
+func ShowWorktree(ctx context.Context, name string, opts ShowOptions) error {
+	if opts.NullTerminated && opts.Field == "" && opts.Format != "porcelain" {
+		return fmt.Errorf("-z requires --field or --porcelain")
 	}
 
-	if target == nil {
-		return fmt.Errorf("worktree '%s' not found", name)
+	target, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
 	}
 
-	if field != "" {
-		return printField(target, field)
+	if opts.Field != "" {
+		return printFields(ctx, target, opts)
 	}
 
-	switch format {
+	switch opts.Format {
 	case "pretty":
 		printPrettyFormat(target)
+	case "porcelain":
+		status, err := worktreeStatus(ctx, target)
+		if err != nil {
+			return err
+		}
+		repoRoot, err := getRepoRoot(ctx)
+		if err != nil {
+			return err
+		}
+		printPorcelain([][]porcelainLine{porcelainRecord(*target, normalizePath(repoRoot), status)}, opts.NullTerminated)
 	case "json":
 		data, err := json.MarshalIndent(target, "", "  ")
 		if err != nil {
@@ -232,29 +667,96 @@ func ShowWorktree(name, format, field string) error {
 		}
 		fmt.Println(string(data))
 	default:
-		return fmt.Errorf("unknown format: %s", format)
+		return fmt.Errorf("unknown format: %s", opts.Format)
 	}
 
 	return nil
 }
 
-// RemoveWorktree removes a worktree and optionally deletes its branch
-func RemoveWorktree(name string, opts RemoveOptions) error {
-	worktrees, err := getWorktrees()
+// currentWorktreeName is accepted in place of a worktree name for the worktree containing the current directory
+const currentWorktreeName = "."
+
+// findWorktree resolves a worktree by name, "." for the one containing the current directory, or "-"
+// for the previously used one. A name that matches no worktree may also be the branch checked out in one.
+func findWorktree(ctx context.Context, name string) (*Worktree, error) {
+	switch name {
+	case currentWorktreeName:
+		wt, err := currentWorktree(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve '.': %w", err)
+		}
+		return wt, nil
+	case previousWorktreeName:
+		return previousWorktree(ctx)
+	}
+	worktrees, err := getWorktrees(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for i := range worktrees {
+		if worktrees[i].Name == name {
+			return &worktrees[i], nil
+		}
 	}
+	if wt := worktreeWithBranch(worktrees, name); wt != nil {
+		return wt, nil
+	}
+	return nil, &wtm.WorktreeError{Name: name, Err: wtm.ErrWorktreeNotFound, Suggestions: wtm.Suggest(name, worktrees)}
+}
 
-	var target *Worktree
-	for _, wt := range worktrees {
-		if wt.Name == name {
-			target = &wt
-			break
+// findWorktreeByBranch resolves the worktree that has a branch checked out, ignoring worktree names
+func findWorktreeByBranch(ctx context.Context, branch string) (*Worktree, error) {
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if wt := worktreeWithBranch(worktrees, branch); wt != nil {
+		return wt, nil
+	}
+	return nil, fmt.Errorf("no worktree has branch '%s' checked out: %w", branch, wtm.ErrWorktreeNotFound)
+}
+
+// worktreeWithBranch returns the worktree with branch checked out; git checks out a branch in at most one
+func worktreeWithBranch(worktrees []Worktree, branch string) *Worktree {
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+	for i := range worktrees {
+		if branch != "" && worktrees[i].Branch == branch {
+			return &worktrees[i]
 		}
 	}
+	return nil
+}
+
+// resolveBranchName returns the name of the worktree a --by-branch argument resolves to
+func resolveBranchName(ctx context.Context, branch string) (string, error) {
+	wt, err := findWorktreeByBranch(ctx, branch)
+	if err != nil {
+		return "", err
+	}
+	return wt.Name, nil
+}
+
+// currentWorktree returns the worktree containing the current directory
+func currentWorktree(ctx context.Context) (*Worktree, error) {
+	m, err := worktreeManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.Current(ctx)
+}
 
-	if target == nil {
-		return fmt.Errorf("worktree '%s' not found", name)
+// RemoveWorktree removes a worktree and optionally deletes its branch
+func RemoveWorktree(ctx context.Context, name string, opts RemoveOptions) error {
+	target, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := checkNotInside(target, opts.AndCD); err != nil {
+		return err
+	}
+
+	if err := warnUnpushed(ctx, target, opts.BranchDelete); err != nil {
+		return err
 	}
 
 	// Confirm unless force flag is set
@@ -263,114 +765,270 @@ func RemoveWorktree(name string, opts RemoveOptions) error {
 		if target.Branch != "" {
 			prompt = fmt.Sprintf("%s (branch: %s)", prompt, target.Branch)
 		}
-		switch opts.BranchDelete {
-		case BranchDeleteSafe:
-			prompt = fmt.Sprintf("%s and delete branch?", prompt)
-		case BranchDeleteForce:
-			prompt = fmt.Sprintf("%s and force delete branch?", prompt)
-		default:
-			prompt = fmt.Sprintf("%s?", prompt)
-		}
-		fmt.Printf("%s [y/N]: ", prompt)
-		reader := bufio.NewReader(os.Stdin)
-		response, err := reader.ReadString('\n')
+		ok, err := confirm(ctx, withBranchDeleteSuffix(prompt, opts.BranchDelete))
 		if err != nil {
 			return err
 		}
-		response = strings.TrimSpace(strings.ToLower(response))
-		if response != "y" && response != "yes" {
-			fmt.Println("Aborted")
-			return nil
+		if !ok {
+			return errAborted
+		}
+		if !opts.Stash && !opts.DiscardChanges && !planning() && !assumeYes {
+			// Offer to keep uncommitted work, or to discard it explicitly
+			changes, err := newManager(ctx).Changes(ctx, target)
+			if err != nil {
+				return err
+			}
+			if len(changes) > 0 {
+				if opts.Stash, err = confirm(ctx, fmt.Sprintf("Worktree '%s' has %d uncommitted changes. Stash them first?", target.Name, len(changes))); err != nil {
+					return err
+				}
+				if !opts.Stash {
+					if opts.DiscardChanges, err = confirm(ctx, "Discard them?"); err != nil {
+						return err
+					}
+					if !opts.DiscardChanges {
+						return errAborted
+					}
+				}
+			}
 		}
 	}
 
-	// Remove worktree
-	if _, err := runGitCommand("worktree", "remove", "--force", target.Path); err != nil {
-		return err
+	return removeWorktreeTarget(ctx, target, opts)
+}
+
+// RemoveWorktreesByPattern removes every non-primary worktree whose name matches a glob pattern
+func RemoveWorktreesByPattern(ctx context.Context, pattern string, opts RemoveOptions) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern '%s': %w", pattern, err)
 	}
-	fmt.Printf("✓ Removed worktree: %s\n", target.Name)
 
-	branchMode := opts.BranchDelete // determine whether a branch deletion has been requested
-	if branchMode == BranchDeleteNone {
-		return nil
+	targets, err := matchWorktrees(ctx, pattern)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no worktrees match pattern '%s'", pattern)
 	}
 
-	branchName := target.Branch
-	if branchName == "" {
-		fmt.Println("Skipped branch deletion: no branch information found for worktree.")
-		return nil
+	for i := range targets {
+		if err := checkNotInside(&targets[i], opts.AndCD); err != nil {
+			return err
+		}
+		if err := warnUnpushed(ctx, &targets[i], opts.BranchDelete); err != nil {
+			return err
+		}
 	}
 
-	flag := "-d" // default to safe deletion
-	if branchMode == BranchDeleteForce {
-		flag = "-D" // force delete for unmerged branches
+	// Confirm once for the whole batch unless force flag is set
+	if !opts.Force {
+		fmt.Printf("Worktrees matching '%s':\n", pattern)
+		for _, wt := range targets {
+			if wt.Branch != "" {
+				fmt.Printf("  %s (branch: %s)\n", wt.Name, wt.Branch)
+			} else {
+				fmt.Printf("  %s\n", wt.Name)
+			}
+		}
+		prompt := fmt.Sprintf("Remove %d worktree(s)", len(targets))
+		ok, err := confirm(ctx, withBranchDeleteSuffix(prompt, opts.BranchDelete))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errAborted
+		}
 	}
 
-	if _, err := runGitCommand("branch", flag, branchName); err != nil {
-		return fmt.Errorf("deleted worktree '%s' but failed to delete branch '%s': %w", target.Name, branchName, err)
+	var failed []string
+	for i := range targets {
+		if err := removeWorktreeTarget(ctx, &targets[i], opts); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", targets[i].Name, err)
+			failed = append(failed, targets[i].Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to remove %d worktree(s): %s", len(failed), strings.Join(failed, ", "))
 	}
-	fmt.Printf("✓ Deleted branch: %s\n", branchName)
 	return nil
 }
 
-// getWorktrees retrieves all worktrees from git
-func getWorktrees() ([]Worktree, error) {
-	output, err := runGitCommand("worktree", "list", "--porcelain")
+// matchWorktrees returns the non-primary worktrees whose names match a glob pattern
+func matchWorktrees(ctx context.Context, pattern string) ([]Worktree, error) {
+	worktrees, err := getWorktrees(ctx)
 	if err != nil {
 		return nil, err
 	}
+	repoRoot, err := getRepoRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	primaryPath := normalizePath(repoRoot)
 
-	var worktrees []Worktree
-	var current Worktree
-
-	for _, line := range strings.Split(output, "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			if current.Path != "" {
-				worktrees = append(worktrees, current)
-				current = Worktree{}
-			}
+	var matched []Worktree
+	for _, wt := range worktrees {
+		if wt.Bare || normalizePath(wt.Path) == primaryPath {
 			continue
 		}
+		if ok, _ := path.Match(pattern, wt.Name); ok {
+			matched = append(matched, wt)
+		}
+	}
+	return matched, nil
+}
 
-		parts := strings.SplitN(line, " ", 2)
-		if len(parts) < 2 {
-			continue
+// isGlobPattern reports whether name contains glob metacharacters
+func isGlobPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// maxUnpushedShown caps the commits listed by warnUnpushed
+const maxUnpushedShown = 10
+
+// warnUnpushed lists the commits that removing a worktree would make unreachable: those only on its branch
+// when the branch is deleted, or only on its detached HEAD
+func warnUnpushed(ctx context.Context, target *Worktree, mode BranchDeleteMode) error {
+	if target.Branch != "" && mode == BranchDeleteNone {
+		return nil
+	}
+	commits, err := newManager(ctx).UnpushedCommits(ctx, target)
+	if err != nil || len(commits) == 0 {
+		return err
+	}
+
+	where := "detached HEAD"
+	if target.Branch != "" {
+		where = "branch " + target.Branch
+	}
+	fmt.Fprintf(os.Stderr, "⚠ %d commit(s) on %s of worktree '%s' are not on any remote or other branch and will become unreachable:\n", len(commits), where, target.Name)
+	for i, commit := range commits {
+		if i == maxUnpushedShown {
+			fmt.Fprintf(os.Stderr, "    ... and %d more\n", len(commits)-maxUnpushedShown)
+			break
 		}
+		fmt.Fprintf(os.Stderr, "    %s\n", commit)
+	}
+	return nil
+}
 
-		key := parts[0]
-		value := parts[1]
-
-		switch key {
-		case "worktree":
-			current.Path = value
-			// Extract name from path (last segment)
-			current.Name = filepath.Base(value)
-		case "HEAD":
-			current.HEAD = value
-		case "branch":
-			// Extract branch name from refs/heads/branch-name
-			if strings.HasPrefix(value, "refs/heads/") {
-				current.Branch = strings.TrimPrefix(value, "refs/heads/")
-			} else {
-				current.Branch = value
-			}
+func withBranchDeleteSuffix(prompt string, mode BranchDeleteMode) string {
+	switch mode {
+	case BranchDeleteSafe:
+		return fmt.Sprintf("%s and delete branch?", prompt)
+	case BranchDeleteForce:
+		return fmt.Sprintf("%s and force delete branch?", prompt)
+	default:
+		return fmt.Sprintf("%s?", prompt)
+	}
+}
+
+// assumeYes answers every confirmation affirmatively, set by the global -y/--yes flag
+var assumeYes bool
+
+// stdin is where confirmations are read from
+var stdin = os.Stdin
+
+// confirm asks a yes/no question on stdin and reports whether the user agreed.
+// Without a terminal to ask, it fails instead of waiting on input that never comes,
+// and it stops waiting when ctx is cancelled, for example by Ctrl-C.
+func confirm(ctx context.Context, prompt string) (bool, error) {
+	if planning() || assumeYes {
+		return true, nil
+	}
+	if !isTerminal(stdin) {
+		return false, fmt.Errorf("%s: confirmation required but stdin is not a terminal; pass --yes or --force", strings.TrimSuffix(prompt, "?"))
+	}
+	fmt.Printf("%s [y/N]: ", prompt)
+
+	type answer struct {
+		response string
+		err      error
+	}
+	answers := make(chan answer, 1)
+	go func() {
+		response, err := bufio.NewReader(stdin).ReadString('\n')
+		answers <- answer{response, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		// End the prompt line so the error is not printed after it
+		fmt.Println()
+		return false, fmt.Errorf("%w: interrupted", errAborted)
+	case a := <-answers:
+		if a.err != nil {
+			return false, a.err
 		}
+		response := strings.TrimSpace(strings.ToLower(a.response))
+		return response == "y" || response == "yes", nil
 	}
+}
 
-	// Add last worktree if exists
-	if current.Path != "" {
-		worktrees = append(worktrees, current)
+// removeWorktreeTarget removes a resolved worktree and then deletes its branch according to opts
+func removeWorktreeTarget(ctx context.Context, target *Worktree, opts RemoveOptions) error {
+	if opts.After != "" {
+		if err := runAfterCommand(ctx, target, opts.After); err != nil {
+			return err
+		}
+	}
+	if opts.AndCD {
+		if err := leaveWorktree(ctx, target); err != nil {
+			return err
+		}
 	}
 
-	// Get creation time for each worktree
-	for i := range worktrees {
-		info, err := os.Stat(worktrees[i].Path)
-		if err == nil {
-			worktrees[i].Created = info.ModTime()
+	res, err := newManager(ctx).RemoveWorktree(ctx, target, wtm.RemoveOptions{
+		BranchDelete:   opts.BranchDelete,
+		Stash:          opts.Stash,
+		DiscardChanges: opts.DiscardChanges,
+	})
+	if errors.Is(err, wtm.ErrWorktreeDirty) {
+		return fmt.Errorf("%w; commit them, or pass --stash or --discard-changes", err)
+	}
+	if res != nil {
+		if res.Stashed {
+			report("✓ Stashed uncommitted changes (restore with: git stash list)\n")
 		}
+		report("✓ Removed worktree: %s\n", target.Name)
+	}
+	if err != nil {
+		return err
 	}
 
+	if opts.BranchDelete != BranchDeleteNone && target.Branch == "" {
+		fmt.Println("Skipped branch deletion: no branch information found for worktree.")
+	}
+	if res.DeletedBranch != "" {
+		report("✓ Deleted branch: %s\n", res.DeletedBranch)
+	}
+	return nil
+}
+
+// getWorktrees retrieves all worktrees from git
+func getWorktrees(ctx context.Context) ([]Worktree, error) {
+	m, err := worktreeManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !listCacheEnabled(ctx) {
+		return m.List(ctx)
+	}
+	commonDir, err := gitCommonDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings, configFiles, err := listCacheSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if worktrees, ok := cachedWorktrees(commonDir, settings); ok {
+		return worktrees, nil
+	}
+	worktrees, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	writeListCache(commonDir, settings, configFiles, worktrees)
 	return worktrees, nil
 }
 
@@ -385,11 +1043,24 @@ func printTableFormat(worktrees []Worktree, primaryPath string) {
 	for i, wt := range worktrees {
 		rows[i] = []string{
 			formatWorktreeName(wt, primaryPath),
-			wt.Branch,
-			formatTimeAgo(wt.Created),
+			formatBranch(wt),
+			formatTime(wt.Created),
 		}
 	}
+	printTable(headers, rows)
+}
+
+// printTable prints rows under headers with every column padded to its widest value
+func printTable(headers []string, rows [][]string) {
+	widths := columnWidths(headers, rows)
+	printTableRow(headers, widths)
+	for _, row := range rows {
+		printTableRow(row, widths)
+	}
+}
 
+// columnWidths returns the width of the widest header or value in each column
+func columnWidths(headers []string, rows [][]string) []int {
 	widths := make([]int, len(headers))
 	for colIdx, header := range headers {
 		width := utf8.RuneCountInString(header)
@@ -400,11 +1071,7 @@ func printTableFormat(worktrees []Worktree, primaryPath string) {
 		}
 		widths[colIdx] = width
 	}
-
-	printTableRow(headers, widths)
-	for _, row := range rows {
-		printTableRow(row, widths)
-	}
+	return widths
 }
 
 func printTableRow(values []string, widths []int) {
@@ -418,27 +1085,70 @@ func printTableRow(values []string, widths []int) {
 }
 
 // printPlainFormat prints worktrees in plain format
-func printPlainFormat(worktrees []Worktree, primaryPath string) {
+func printPlainFormat(worktrees []Worktree, primaryPath string, nul bool) {
 	for _, wt := range worktrees {
-		fmt.Printf("%s %s %s\n", formatWorktreeName(wt, primaryPath), wt.Branch, wt.Path)
+		printPlainRecord([]string{formatWorktreeName(wt, primaryPath), formatBranch(wt), wt.Path}, nul)
+	}
+}
+
+// printPlainRecord prints fields separated by spaces on one line, or each terminated by a NUL byte
+func printPlainRecord(fields []string, nul bool) {
+	if nul {
+		for _, field := range fields {
+			fmt.Print(field + "\x00")
+		}
+		return
 	}
+	fmt.Println(strings.Join(fields, " "))
 }
 
 func formatWorktreeName(wt Worktree, primaryPath string) string {
+	name := wt.Name
 	if primaryPath != "" && normalizePath(wt.Path) == primaryPath {
-		return fmt.Sprintf("%s (primary)", wt.Name)
+		name = fmt.Sprintf("%s (primary)", name)
+	}
+	for _, mode := range worktreeModes(wt) {
+		name = fmt.Sprintf("%s (%s)", name, mode)
 	}
-	return wt.Name
+	return name
 }
 
-func normalizePath(p string) string {
-	if p == "" {
-		return ""
+// worktreeModes lists the special states of a worktree shown next to its name
+func worktreeModes(wt Worktree) []string {
+	var modes []string
+	if wt.Bare {
+		modes = append(modes, "bare")
 	}
-	if resolved, err := filepath.EvalSymlinks(p); err == nil {
-		p = resolved
+	if wt.ReadOnly {
+		modes = append(modes, "read-only")
 	}
-	return filepath.Clean(p)
+	if wt.NoCheckout {
+		modes = append(modes, "no checkout")
+	}
+	if wt.Ephemeral {
+		modes = append(modes, "ephemeral")
+	}
+	if wt.Hidden {
+		modes = append(modes, "hidden")
+	}
+	return modes
+}
+
+// formatBranch returns the branch name, or a detached HEAD marker for worktrees without a branch
+func formatBranch(wt Worktree) string {
+	switch {
+	case wt.Branch == "" && wt.Tag != "":
+		return fmt.Sprintf("(tag %s)", wt.Tag)
+	case wt.Branch == "":
+		return "(detached)"
+	case wt.Tag != "":
+		return fmt.Sprintf("%s (from %s)", wt.Branch, wt.Tag)
+	}
+	return wt.Branch
+}
+
+func normalizePath(p string) string {
+	return wtm.NormalizePath(p)
 }
 
 // printJSONFormat prints worktrees in JSON format
@@ -454,29 +1164,120 @@ func printJSONFormat(worktrees []Worktree) {
 // printPrettyFormat prints a single worktree in pretty format
 func printPrettyFormat(wt *Worktree) {
 	fmt.Printf("Name:     %s\n", wt.Name)
-	fmt.Printf("Branch:   %s\n", wt.Branch)
+	fmt.Printf("Branch:   %s\n", formatBranch(*wt))
 	fmt.Printf("Path:     %s\n", wt.Path)
 	fmt.Printf("HEAD:     %s\n", wt.HEAD)
 	fmt.Printf("Created:  %s\n", wt.Created.Format("2006-01-02 15:04:05"))
+	if modes := worktreeModes(*wt); len(modes) > 0 {
+		fmt.Printf("Mode:     %s\n", strings.Join(modes, ", "))
+	}
+	if wt.Issue != "" {
+		fmt.Printf("Issue:    %s\n", wt.Issue)
+	}
+	if wt.Base != "" {
+		fmt.Printf("Base:     %s\n", wt.Base)
+	}
+	if wt.Tag != "" {
+		fmt.Printf("Tag:      %s\n", wt.Tag)
+	}
+	if wt.Port > 0 {
+		fmt.Printf("Port:     %d\n", wt.Port)
+	}
+	if len(wt.Labels) > 0 {
+		fmt.Printf("Labels:   %s\n", strings.Join(wt.Labels, ", "))
+	}
+	if wt.Ephemeral {
+		fmt.Println("Ephemeral: yes (removed by the next wtm gc)")
+	}
+	if wt.Hidden {
+		fmt.Println("Hidden:   yes (shown by wtm list --all)")
+	}
+}
+
+// printFields prints the requested fields of a worktree one per line, tab-separated or NUL-terminated
+func printFields(ctx context.Context, wt *Worktree, opts ShowOptions) error {
+	var values []string
+	var status *WorktreeStatus
+	for _, field := range strings.Split(opts.Field, ",") {
+		field = strings.TrimSpace(field)
+		if sub, ok := strings.CutPrefix(field, "status."); ok && status == nil {
+			if !slices.Contains(statusFields, sub) {
+				return fmt.Errorf("unknown field: %s", field)
+			}
+			var err error
+			if status, err = worktreeStatus(ctx, wt); err != nil {
+				return err
+			}
+		}
+		value, err := fieldValue(wt, status, field)
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+	}
+
+	switch {
+	case opts.NullTerminated:
+		printPlainRecord(values, true)
+	case opts.Tab:
+		fmt.Println(strings.Join(values, "\t"))
+	default:
+		for _, value := range values {
+			fmt.Println(value)
+		}
+	}
+	return nil
 }
 
-// printField prints a specific field of a worktree
-func printField(wt *Worktree, field string) error {
+// statusFields are the nested fields available as status.<field>
+var statusFields = []string{"dirty", "upstream", "ahead", "behind", "base", "baseahead", "basebehind"}
+
+// fieldValue returns a single field of a worktree; status is only consulted for status.* fields
+func fieldValue(wt *Worktree, status *WorktreeStatus, field string) (string, error) {
 	switch field {
 	case "name":
-		fmt.Println(wt.Name)
+		return wt.Name, nil
 	case "branch":
-		fmt.Println(wt.Branch)
+		return wt.Branch, nil
 	case "path":
-		fmt.Println(wt.Path)
+		return wt.Path, nil
 	case "head":
-		fmt.Println(wt.HEAD)
+		return wt.HEAD, nil
 	case "created":
-		fmt.Println(wt.Created.Format(time.RFC3339))
+		return wt.Created.Format(time.RFC3339), nil
+	case "readonly":
+		return strconv.FormatBool(wt.ReadOnly), nil
+	case "issue":
+		return wt.Issue, nil
+	case "base":
+		return wt.Base, nil
+	case "tag":
+		return wt.Tag, nil
+	case "port":
+		return formatPort(wt.Port), nil
+	case "labels":
+		return strings.Join(wt.Labels, ","), nil
+	case "ephemeral":
+		return strconv.FormatBool(wt.Ephemeral), nil
+	case "hidden":
+		return strconv.FormatBool(wt.Hidden), nil
+	case "status.dirty":
+		return strconv.FormatBool(status.Dirty), nil
+	case "status.upstream":
+		return status.Upstream, nil
+	case "status.ahead":
+		return strconv.Itoa(status.Ahead), nil
+	case "status.behind":
+		return strconv.Itoa(status.Behind), nil
+	case "status.base":
+		return status.Base, nil
+	case "status.baseahead":
+		return strconv.Itoa(status.BaseAhead), nil
+	case "status.basebehind":
+		return strconv.Itoa(status.BaseBehind), nil
 	default:
-		return fmt.Errorf("unknown field: %s", field)
+		return "", fmt.Errorf("unknown field: %s", field)
 	}
-	return nil
 }
 
 // formatTimeAgo formats a time as a relative time string
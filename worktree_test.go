@@ -99,6 +99,8 @@ func captureStdout(t *testing.T, fn func() error) (string, error) {
 }
 
 func TestAddWorktree(t *testing.T) {
+	ctx := t.Context()
+
 	repoPath := setupTestRepo(t)
 	defer cleanupTestRepo(t, repoPath)
 
@@ -114,13 +116,13 @@ func TestAddWorktree(t *testing.T) {
 	}
 
 	t.Run("add worktree with default branch name", func(t *testing.T) {
-		err := AddWorktree("feature-1", "", "", "")
+		err := AddWorktree(ctx, "feature-1", AddOptions{})
 		if err != nil {
 			t.Fatalf("AddWorktree failed: %v", err)
 		}
 
 		// Verify worktree was created
-		worktrees, err := getWorktrees()
+		worktrees, err := getWorktrees(ctx)
 		if err != nil {
 			t.Fatalf("getWorktrees failed: %v", err)
 		}
@@ -153,12 +155,12 @@ func TestAddWorktree(t *testing.T) {
 	})
 
 	t.Run("add worktree with custom branch name", func(t *testing.T) {
-		err := AddWorktree("api", "feature/api-refactoring", "", "")
+		err := AddWorktree(ctx, "api", AddOptions{Branch: "feature/api-refactoring"})
 		if err != nil {
 			t.Errorf("AddWorktree failed: %v", err)
 		}
 
-		worktrees, err := getWorktrees()
+		worktrees, err := getWorktrees(ctx)
 		if err != nil {
 			t.Errorf("getWorktrees failed: %v", err)
 		}
@@ -178,8 +180,101 @@ func TestAddWorktree(t *testing.T) {
 		}
 	})
 
+	t.Run("add worktree from remote branch creates tracking branch", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"remote", "add", "origin", repoPath},
+			{"update-ref", "refs/remotes/origin/feature/login", "HEAD"},
+		} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = repoPath
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v failed: %v: %s", args, err, out)
+			}
+		}
+
+		if err := AddWorktree(ctx, "review", AddOptions{Checkout: "origin/feature/login"}); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+
+		worktrees, err := getWorktrees(ctx)
+		if err != nil {
+			t.Fatalf("getWorktrees failed: %v", err)
+		}
+		for _, wt := range worktrees {
+			if wt.Name == "review" && wt.Branch != "feature/login" {
+				t.Errorf("Expected branch 'feature/login', got '%s'", wt.Branch)
+			}
+		}
+
+		cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "feature/login@{upstream}")
+		cmd.Dir = repoPath
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git rev-parse upstream failed: %v", err)
+		}
+		if strings.TrimSpace(string(output)) != "origin/feature/login" {
+			t.Errorf("Expected upstream 'origin/feature/login', got %q", strings.TrimSpace(string(output)))
+		}
+	})
+
+	t.Run("add detached worktree at a revision", func(t *testing.T) {
+		cmd := exec.Command("git", "tag", "v1.0.0")
+		cmd.Dir = repoPath
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git tag failed: %v: %s", err, out)
+		}
+
+		if err := AddWorktree(ctx, "release-1.0", AddOptions{Detach: "v1.0.0"}); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+
+		worktrees, err := getWorktrees(ctx)
+		if err != nil {
+			t.Fatalf("getWorktrees failed: %v", err)
+		}
+		for _, wt := range worktrees {
+			if wt.Name == "release-1.0" {
+				if wt.Branch != "" {
+					t.Errorf("Expected detached worktree without branch, got '%s'", wt.Branch)
+				}
+				if formatBranch(wt) != "(detached)" {
+					t.Errorf("Expected '(detached)' marker, got '%s'", formatBranch(wt))
+				}
+			}
+		}
+
+		if err := AddWorktree(ctx, "invalid", AddOptions{Detach: "v1.0.0", Branch: "x"}); err == nil {
+			t.Error("Expected error when combining --detach with -b, got nil")
+		}
+	})
+
+	t.Run("add worktree without checkout", func(t *testing.T) {
+		if err := AddWorktree(ctx, "lazy", AddOptions{NoCheckout: true}); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+
+		worktrees, err := getWorktrees(ctx)
+		if err != nil {
+			t.Fatalf("getWorktrees failed: %v", err)
+		}
+		for _, wt := range worktrees {
+			if wt.Name != "lazy" {
+				if wt.NoCheckout {
+					t.Errorf("Expected worktree %q to be checked out", wt.Name)
+				}
+				continue
+			}
+			if !wt.NoCheckout {
+				t.Error("Expected worktree 'lazy' to be reported as not checked out")
+			}
+			if _, err := os.Stat(filepath.Join(wt.Path, "README.md")); !os.IsNotExist(err) {
+				t.Errorf("Expected README.md to be absent, got err=%v", err)
+			}
+		}
+	})
+
 	t.Run("add duplicate worktree should fail", func(t *testing.T) {
-		err := AddWorktree("feature-1", "", "", "")
+		err := AddWorktree(ctx, "feature-1", AddOptions{})
 		if err == nil {
 			t.Error("Expected error when adding duplicate worktree, got nil")
 		}
@@ -187,6 +282,8 @@ func TestAddWorktree(t *testing.T) {
 }
 
 func TestListWorktrees(t *testing.T) {
+	ctx := t.Context()
+
 	repoPath := setupTestRepo(t)
 	defer cleanupTestRepo(t, repoPath)
 
@@ -201,15 +298,15 @@ func TestListWorktrees(t *testing.T) {
 	}
 
 	// Create test worktrees
-	AddWorktree("test-1", "", "", "")
-	AddWorktree("test-2", "", "", "")
+	AddWorktree(ctx, "test-1", AddOptions{})
+	AddWorktree(ctx, "test-2", AddOptions{})
 
 	primaryName := filepath.Base(repoPath)
 	expected := primaryName + " (primary)"
 
 	t.Run("list in table format", func(t *testing.T) {
 		output, err := captureStdout(t, func() error {
-			return ListWorktrees("table")
+			return ListWorktrees(ctx, ListOptions{Format: "table"})
 		})
 		if err != nil {
 			t.Errorf("ListWorktrees failed: %v", err)
@@ -224,7 +321,7 @@ func TestListWorktrees(t *testing.T) {
 
 	t.Run("list in plain format", func(t *testing.T) {
 		output, err := captureStdout(t, func() error {
-			return ListWorktrees("plain")
+			return ListWorktrees(ctx, ListOptions{Format: "plain"})
 		})
 		if err != nil {
 			t.Errorf("ListWorktrees failed: %v", err)
@@ -234,15 +331,57 @@ func TestListWorktrees(t *testing.T) {
 		}
 	})
 
+	t.Run("list with NUL-terminated fields", func(t *testing.T) {
+		output, err := captureStdout(t, func() error {
+			return ListWorktrees(ctx, ListOptions{Format: "plain", NullTerminated: true})
+		})
+		if err != nil {
+			t.Fatalf("ListWorktrees failed: %v", err)
+		}
+		if strings.Contains(output, "\n") {
+			t.Errorf("expected no newlines in -z output, got %q", output)
+		}
+		fields := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+		if len(fields) != 9 || fields[0] != expected {
+			t.Errorf("expected 3 records of 3 NUL-terminated fields, got %q", fields)
+		}
+		if err := ListWorktrees(ctx, ListOptions{Format: "table", NullTerminated: true}); err == nil {
+			t.Error("expected -z to require --format plain")
+		}
+	})
+
+	t.Run("list names and paths only", func(t *testing.T) {
+		output, err := captureStdout(t, func() error {
+			return ListWorktrees(ctx, ListOptions{Format: "names"})
+		})
+		if err != nil {
+			t.Fatalf("ListWorktrees failed: %v", err)
+		}
+		if want := primaryName + "\ntest-1\ntest-2\n"; output != want {
+			t.Errorf("expected names only %q, got %q", want, output)
+		}
+
+		output, err = captureStdout(t, func() error {
+			return ListWorktrees(ctx, ListOptions{Format: "paths", NullTerminated: true})
+		})
+		if err != nil {
+			t.Fatalf("ListWorktrees failed: %v", err)
+		}
+		paths := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+		if len(paths) != 3 || normalizePath(paths[0]) != normalizePath(repoPath) || filepath.Base(paths[1]) != "test-1" {
+			t.Errorf("expected 3 NUL-terminated paths, got %q", paths)
+		}
+	})
+
 	t.Run("list in json format", func(t *testing.T) {
-		err := ListWorktrees("json")
+		err := ListWorktrees(ctx, ListOptions{Format: "json"})
 		if err != nil {
 			t.Errorf("ListWorktrees failed: %v", err)
 		}
 	})
 
 	t.Run("unknown format should fail", func(t *testing.T) {
-		err := ListWorktrees("unknown")
+		err := ListWorktrees(ctx, ListOptions{Format: "unknown"})
 		if err == nil {
 			t.Error("Expected error for unknown format, got nil")
 		}
@@ -308,6 +447,8 @@ func TestPrintTableFormatAlignsColumns(t *testing.T) {
 }
 
 func TestShowWorktree(t *testing.T) {
+	ctx := t.Context()
+
 	repoPath := setupTestRepo(t)
 	defer cleanupTestRepo(t, repoPath)
 
@@ -322,17 +463,17 @@ func TestShowWorktree(t *testing.T) {
 	}
 
 	// Create test worktree
-	AddWorktree("show-test", "", "", "")
+	AddWorktree(ctx, "show-test", AddOptions{})
 
 	t.Run("show in pretty format", func(t *testing.T) {
-		err := ShowWorktree("show-test", "pretty", "")
+		err := ShowWorktree(ctx, "show-test", ShowOptions{Format: "pretty"})
 		if err != nil {
 			t.Errorf("ShowWorktree failed: %v", err)
 		}
 	})
 
 	t.Run("show in json format", func(t *testing.T) {
-		err := ShowWorktree("show-test", "json", "")
+		err := ShowWorktree(ctx, "show-test", ShowOptions{Format: "json"})
 		if err != nil {
 			t.Errorf("ShowWorktree failed: %v", err)
 		}
@@ -341,15 +482,72 @@ func TestShowWorktree(t *testing.T) {
 	t.Run("show specific field", func(t *testing.T) {
 		fields := []string{"name", "branch", "path", "head"}
 		for _, field := range fields {
-			err := ShowWorktree("show-test", "", field)
+			err := ShowWorktree(ctx, "show-test", ShowOptions{Field: field})
 			if err != nil {
 				t.Errorf("ShowWorktree with field '%s' failed: %v", field, err)
 			}
 		}
 	})
 
+	t.Run("show multiple and nested fields", func(t *testing.T) {
+		output, err := captureStdout(t, func() error {
+			return ShowWorktree(ctx, "show-test", ShowOptions{Field: "name,branch,status.dirty,status.ahead", Tab: true})
+		})
+		if err != nil {
+			t.Fatalf("ShowWorktree failed: %v", err)
+		}
+		if want := "show-test\tshow-test\tfalse\t0\n"; output != want {
+			t.Errorf("expected %q, got %q", want, output)
+		}
+
+		output, err = captureStdout(t, func() error {
+			return ShowWorktree(ctx, "show-test", ShowOptions{Field: "name,path"})
+		})
+		if err != nil {
+			t.Fatalf("ShowWorktree failed: %v", err)
+		}
+		if lines := strings.Split(strings.TrimSpace(output), "\n"); len(lines) != 2 || lines[0] != "show-test" {
+			t.Errorf("expected one field per line, got %q", output)
+		}
+
+		if err := ShowWorktree(ctx, "show-test", ShowOptions{Field: "status.unknown"}); err == nil {
+			t.Error("expected error for unknown status field")
+		}
+	})
+
+	t.Run("show the current worktree with .", func(t *testing.T) {
+		wt, err := findWorktree(ctx, "show-test")
+		if err != nil {
+			t.Fatalf("findWorktree failed: %v", err)
+		}
+		if err := os.Chdir(wt.Path); err != nil {
+			t.Fatalf("Failed to change to worktree: %v", err)
+		}
+		defer os.Chdir(repoPath)
+
+		output, err := captureStdout(t, func() error {
+			return ShowWorktree(ctx, ".", ShowOptions{Field: "name"})
+		})
+		if err != nil {
+			t.Fatalf("ShowWorktree . failed: %v", err)
+		}
+		if output != "show-test\n" {
+			t.Errorf("expected . to resolve to show-test, got %q", output)
+		}
+	})
+
+	t.Run("misspelled worktree name suggests close names", func(t *testing.T) {
+		err := ShowWorktree(ctx, "show-tset", ShowOptions{Format: "pretty"})
+		if exitCode(err) != exitNotFound || !strings.Contains(err.Error(), "did you mean 'show-test'?") {
+			t.Errorf("expected a not found error suggesting show-test, got %v", err)
+		}
+		if _, _, err := handleShowWorktree(ctx, nil, ShowWorktreeInput{Name: "show-tset"}); err == nil || !strings.Contains(err.Error(), "did you mean 'show-test'?") {
+			t.Errorf("expected the MCP error to suggest show-test, got %v", err)
+		}
+	})
+
 	t.Run("show non-existent worktree should fail", func(t *testing.T) {
-		err := ShowWorktree("non-existent", "pretty", "")
+		err := ShowWorktree(ctx, "non-existent", ShowOptions{Format: "pretty"})
 		if err == nil {
 			t.Error("Expected error for non-existent worktree, got nil")
 		}
@@ -357,6 +555,8 @@ func TestShowWorktree(t *testing.T) {
 }
 
 func TestRemoveWorktree(t *testing.T) {
+	ctx := t.Context()
+
 	repoPath := setupTestRepo(t)
 	defer cleanupTestRepo(t, repoPath)
 
@@ -371,17 +571,17 @@ func TestRemoveWorktree(t *testing.T) {
 	}
 
 	t.Run("remove worktree with force flag", func(t *testing.T) {
-		if err := AddWorktree("remove-test", "", "", ""); err != nil {
+		if err := AddWorktree(ctx, "remove-test", AddOptions{}); err != nil {
 			t.Fatalf("AddWorktree failed: %v", err)
 		}
 
-		err := RemoveWorktree("remove-test", RemoveOptions{Force: true})
+		err := RemoveWorktree(ctx, "remove-test", RemoveOptions{Force: true})
 		if err != nil {
 			t.Errorf("RemoveWorktree failed: %v", err)
 		}
 
 		// Verify worktree was removed
-		worktrees, err := getWorktrees()
+		worktrees, err := getWorktrees(ctx)
 		if err != nil {
 			t.Errorf("getWorktrees failed: %v", err)
 		}
@@ -395,11 +595,11 @@ func TestRemoveWorktree(t *testing.T) {
 
 	t.Run("remove worktree and delete branch safely", func(t *testing.T) {
 		const name = "remove-branch-safe"
-		if err := AddWorktree(name, "", "", ""); err != nil {
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
 			t.Fatalf("AddWorktree failed: %v", err)
 		}
 
-		if err := RemoveWorktree(name, RemoveOptions{Force: true, BranchDelete: BranchDeleteSafe}); err != nil {
+		if err := RemoveWorktree(ctx, name, RemoveOptions{Force: true, BranchDelete: BranchDeleteSafe}); err != nil {
 			t.Fatalf("RemoveWorktree with branch delete failed: %v", err)
 		}
 
@@ -416,11 +616,11 @@ func TestRemoveWorktree(t *testing.T) {
 
 	t.Run("remove worktree with force branch deletion", func(t *testing.T) {
 		const name = "remove-branch-force"
-		if err := AddWorktree(name, "", "", ""); err != nil {
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
 			t.Fatalf("AddWorktree failed: %v", err)
 		}
 
-		worktrees, err := getWorktrees()
+		worktrees, err := getWorktrees(ctx)
 		if err != nil {
 			t.Fatalf("getWorktrees failed: %v", err)
 		}
@@ -453,7 +653,7 @@ func TestRemoveWorktree(t *testing.T) {
 			t.Fatalf("git commit failed: %v", err)
 		}
 
-		if err := RemoveWorktree(name, RemoveOptions{Force: true, BranchDelete: BranchDeleteForce}); err != nil {
+		if err := RemoveWorktree(ctx, name, RemoveOptions{Force: true, BranchDelete: BranchDeleteForce}); err != nil {
 			t.Fatalf("RemoveWorktree with force branch delete failed: %v", err)
 		}
 
@@ -470,11 +670,11 @@ func TestRemoveWorktree(t *testing.T) {
 
 	t.Run("remove worktree safe branch deletion fails on unmerged branch", func(t *testing.T) {
 		const name = "remove-branch-safe-fail"
-		if err := AddWorktree(name, "", "", ""); err != nil {
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
 			t.Fatalf("AddWorktree failed: %v", err)
 		}
 
-		worktrees, err := getWorktrees()
+		worktrees, err := getWorktrees(ctx)
 		if err != nil {
 			t.Fatalf("getWorktrees failed: %v", err)
 		}
@@ -507,7 +707,7 @@ func TestRemoveWorktree(t *testing.T) {
 			t.Fatalf("git commit failed: %v", err)
 		}
 
-		err = RemoveWorktree(name, RemoveOptions{Force: true, BranchDelete: BranchDeleteSafe})
+		err = RemoveWorktree(ctx, name, RemoveOptions{Force: true, BranchDelete: BranchDeleteSafe})
 		if err == nil {
 			t.Fatal("expected error when deleting branch with unmerged commits")
 		}
@@ -532,8 +732,41 @@ func TestRemoveWorktree(t *testing.T) {
 		}
 	})
 
+	t.Run("remove worktree only when after command succeeds", func(t *testing.T) {
+		const name = "remove-after"
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+
+		err := RemoveWorktree(ctx, name, RemoveOptions{Force: true, After: "test -f missing.txt"})
+		if err == nil {
+			t.Fatal("expected error when after command fails")
+		}
+		if !strings.Contains(err.Error(), "after command") {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		worktrees, err := getWorktrees(ctx)
+		if err != nil {
+			t.Fatalf("getWorktrees failed: %v", err)
+		}
+		found := false
+		for _, wt := range worktrees {
+			if wt.Name == name {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("expected worktree %q to be kept after failed command", name)
+		}
+
+		if err := RemoveWorktree(ctx, name, RemoveOptions{Force: true, After: "test -f README.md"}); err != nil {
+			t.Fatalf("RemoveWorktree with passing after command failed: %v", err)
+		}
+	})
+
 	t.Run("remove non-existent worktree should fail", func(t *testing.T) {
-		err := RemoveWorktree("non-existent", RemoveOptions{Force: true})
+		err := RemoveWorktree(ctx, "non-existent", RemoveOptions{Force: true})
 		if err == nil {
 			t.Error("Expected error for non-existent worktree, got nil")
 		}
@@ -541,6 +774,8 @@ func TestRemoveWorktree(t *testing.T) {
 }
 
 func TestGetWorktrees(t *testing.T) {
+	ctx := t.Context()
+
 	repoPath := setupTestRepo(t)
 	defer cleanupTestRepo(t, repoPath)
 
@@ -555,7 +790,7 @@ func TestGetWorktrees(t *testing.T) {
 	}
 
 	t.Run("get worktrees from empty repo", func(t *testing.T) {
-		worktrees, err := getWorktrees()
+		worktrees, err := getWorktrees(ctx)
 		if err != nil {
 			t.Errorf("getWorktrees failed: %v", err)
 		}
@@ -567,10 +802,10 @@ func TestGetWorktrees(t *testing.T) {
 	})
 
 	t.Run("get worktrees after adding some", func(t *testing.T) {
-		AddWorktree("wt1", "", "", "")
-		AddWorktree("wt2", "", "", "")
+		AddWorktree(ctx, "wt1", AddOptions{})
+		AddWorktree(ctx, "wt2", AddOptions{})
 
-		worktrees, err := getWorktrees()
+		worktrees, err := getWorktrees(ctx)
 		if err != nil {
 			t.Errorf("getWorktrees failed: %v", err)
 		}
@@ -581,3 +816,450 @@ func TestGetWorktrees(t *testing.T) {
 		}
 	})
 }
+
+func TestRemoveWorktreesByPattern(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	for _, name := range []string{"spike-a", "spike-b", "keep"} {
+		if err := AddWorktree(ctx, name, AddOptions{}); err != nil {
+			t.Fatalf("AddWorktree %s failed: %v", name, err)
+		}
+	}
+
+	t.Run("remove matching worktrees and branches", func(t *testing.T) {
+		err := RemoveWorktreesByPattern(ctx, "spike-*", RemoveOptions{Force: true, BranchDelete: BranchDeleteSafe})
+		if err != nil {
+			t.Fatalf("RemoveWorktreesByPattern failed: %v", err)
+		}
+
+		worktrees, err := getWorktrees(ctx)
+		if err != nil {
+			t.Fatalf("getWorktrees failed: %v", err)
+		}
+		names := map[string]bool{}
+		for _, wt := range worktrees {
+			names[wt.Name] = true
+		}
+		if names["spike-a"] || names["spike-b"] {
+			t.Errorf("expected spike worktrees to be removed, got %v", names)
+		}
+		if !names["keep"] {
+			t.Error("expected worktree 'keep' to remain")
+		}
+
+		cmd := exec.Command("git", "branch", "--list", "spike-*")
+		cmd.Dir = repoPath
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git branch --list failed: %v", err)
+		}
+		if strings.TrimSpace(string(output)) != "" {
+			t.Errorf("expected spike branches to be deleted, got %q", strings.TrimSpace(string(output)))
+		}
+	})
+
+	t.Run("pattern without matches should fail", func(t *testing.T) {
+		if err := RemoveWorktreesByPattern(ctx, "nothing-*", RemoveOptions{Force: true}); err == nil {
+			t.Error("Expected error for pattern without matches, got nil")
+		}
+	})
+
+	t.Run("primary worktree is never matched", func(t *testing.T) {
+		matched, err := matchWorktrees(ctx, "*")
+		if err != nil {
+			t.Fatalf("matchWorktrees failed: %v", err)
+		}
+		if len(matched) != 1 || matched[0].Name != "keep" {
+			t.Errorf("expected only 'keep' to match, got %v", matched)
+		}
+	})
+}
+
+func TestAddReadOnlyWorktree(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "inspect", AddOptions{ReadOnly: true}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	worktrees, err := getWorktrees(ctx)
+	if err != nil {
+		t.Fatalf("getWorktrees failed: %v", err)
+	}
+	var target *Worktree
+	for i := range worktrees {
+		if worktrees[i].Name == "inspect" {
+			target = &worktrees[i]
+		}
+	}
+	if target == nil {
+		t.Fatal("worktree 'inspect' was not created")
+	}
+	if !target.ReadOnly {
+		t.Error("expected worktree to be marked read-only")
+	}
+
+	info, err := os.Stat(filepath.Join(target.Path, "README.md"))
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if info.Mode().Perm()&0o222 != 0 {
+		t.Errorf("expected README.md to lose write permissions, got %v", info.Mode().Perm())
+	}
+
+	if err := RemoveWorktree(ctx, "inspect", RemoveOptions{Force: true}); err != nil {
+		t.Fatalf("RemoveWorktree failed: %v", err)
+	}
+	meta, err := loadWorktreeMeta(ctx)
+	if err != nil {
+		t.Fatalf("loadWorktreeMeta failed: %v", err)
+	}
+	if _, ok := meta["inspect"]; ok {
+		t.Error("expected metadata to be cleared after removal")
+	}
+}
+
+func TestRepoFlagRunsOutsideRepository(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+	defer func() { repoDir = "" }()
+
+	// Start from a directory that is not a git repository
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	root := newRootCmd()
+	root.SetArgs([]string{"-C", repoPath, "add", "elsewhere"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("wtm -C add failed: %v", err)
+	}
+
+	wt, err := findWorktree(ctx, "elsewhere")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if !strings.HasPrefix(normalizePath(wt.Path), normalizePath(repoPath)) {
+		t.Errorf("expected worktree under %s, got %s", repoPath, wt.Path)
+	}
+
+	root = newRootCmd()
+	root.SetArgs([]string{"-C", filepath.Join(repoPath, "missing"), "list"})
+	if err := root.Execute(); err == nil {
+		t.Error("expected an error for a missing -C directory")
+	}
+}
+
+func TestRemoveWorktreeNonInteractive(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if _, err := captureStdout(t, func() error { return AddWorktree(ctx, "feature", AddOptions{}) }); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	// A pipe stands in for stdin in scripts and CI
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+	originalStdin := stdin
+	stdin = r
+	defer func() { stdin = originalStdin }()
+
+	err = RemoveWorktree(ctx, "feature", RemoveOptions{})
+	if err == nil || !strings.Contains(err.Error(), "not a terminal") {
+		t.Fatalf("expected a non-interactive error, got %v", err)
+	}
+	if _, err := findWorktree(ctx, "feature"); err != nil {
+		t.Fatalf("worktree must be kept without confirmation: %v", err)
+	}
+
+	root := newRootCmd()
+	root.SetArgs([]string{"--yes", "remove", "feature"})
+	if _, err := captureStdout(t, root.Execute); err != nil {
+		t.Fatalf("remove --yes failed: %v", err)
+	}
+	assumeYes = false
+	if _, err := findWorktree(ctx, "feature"); err == nil {
+		t.Error("expected --yes to confirm the removal")
+	}
+}
+
+func TestInferWorktreeName(t *testing.T) {
+	tests := []struct {
+		cfg  NamesConfig
+		opts AddOptions
+		want string
+	}{
+		{NamesConfig{}, AddOptions{Checkout: "origin/feature/login-fix"}, "login-fix"},
+		{NamesConfig{}, AddOptions{Branch: "topic"}, "topic"},
+		{NamesConfig{NameTemplate: "review-{{base .Branch}}"}, AddOptions{Checkout: "feature/login"}, "review-login"},
+	}
+	for _, tt := range tests {
+		got, err := inferWorktreeName(tt.cfg, tt.opts)
+		if err != nil || got != tt.want {
+			t.Errorf("inferWorktreeName(%+v, %+v) = %q, %v; want %q", tt.cfg, tt.opts, got, err, tt.want)
+		}
+	}
+	if _, err := inferWorktreeName(NamesConfig{}, AddOptions{}); err == nil {
+		t.Error("expected an error without a branch")
+	}
+}
+
+func TestAddWorktreeBranchTemplate(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[names]\nlowercase = true\nbranchTemplate = \"alice/{{.Name}}\"\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("WTM_CONFIG_FILE", configFile)
+	resetConfigCache()
+	defer resetConfigCache()
+
+	if err := AddWorktree(ctx, "Login Fix", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "login fix")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if wt.Branch != "alice/login-fix" {
+		t.Errorf("expected branch alice/login-fix, got %q", wt.Branch)
+	}
+}
+
+func TestAddWorktreeFrom(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "api", AddOptions{Branch: "feature/api"}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	api, err := findWorktree(ctx, "api")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	cmd := exec.Command("git", "commit", "--allow-empty", "-m", "Work in progress")
+	cmd.Dir = api.Path
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit failed: %v\n%s", err, output)
+	}
+	if api, err = findWorktree(ctx, "api"); err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "api-tests", AddOptions{From: "api"}); err != nil {
+		t.Fatalf("AddWorktree --from failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "api-tests")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if wt.HEAD != api.HEAD {
+		t.Errorf("expected api-tests to start at %s, got %s", api.HEAD, wt.HEAD)
+	}
+	if wt.Base != "feature/api" {
+		t.Errorf("expected the base to be recorded as feature/api, got %q", wt.Base)
+	}
+
+	if err := AddWorktree(ctx, "other", AddOptions{From: "api", Base: "master"}); err == nil {
+		t.Error("expected --from with --base to fail")
+	}
+	if err := AddWorktree(ctx, "other", AddOptions{From: "missing"}); exitCode(err) != exitNotFound {
+		t.Errorf("expected a not found error for an unknown --from worktree, got %v", err)
+	}
+}
+
+func TestRemoveCurrentWorktree(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	if err := AddWorktree(ctx, "current", AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	wt, err := findWorktree(ctx, "current")
+	if err != nil {
+		t.Fatalf("findWorktree failed: %v", err)
+	}
+	if err := os.Chdir(wt.Path); err != nil {
+		t.Fatalf("Failed to change to worktree: %v", err)
+	}
+
+	err = RemoveWorktree(ctx, "current", RemoveOptions{Force: true})
+	if err == nil || !strings.Contains(err.Error(), "--and-cd") {
+		t.Fatalf("expected removing the current worktree to fail with guidance, got %v", err)
+	}
+	if err := RemoveWorktree(ctx, "current", RemoveOptions{Force: true, AndCD: true}); err == nil {
+		t.Fatal("expected --and-cd without the shell helper to fail")
+	}
+
+	cdFile := filepath.Join(t.TempDir(), "cd")
+	t.Setenv(cdFileEnv, cdFile)
+	if _, err := captureStdout(t, func() error {
+		return RemoveWorktree(ctx, "current", RemoveOptions{Force: true, AndCD: true})
+	}); err != nil {
+		t.Fatalf("RemoveWorktree --and-cd failed: %v", err)
+	}
+	defer func() { repoDir = "" }()
+
+	if _, err := os.Stat(wt.Path); !os.IsNotExist(err) {
+		t.Errorf("expected the worktree directory to be removed, got %v", err)
+	}
+	data, err := os.ReadFile(cdFile)
+	if err != nil {
+		t.Fatalf("expected the shell helper file to be written: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); normalizePath(got) != normalizePath(repoPath) {
+		t.Errorf("expected the shell to be sent to %s, got %s", repoPath, got)
+	}
+	if cwd, _ := os.Getwd(); normalizePath(cwd) != normalizePath(repoPath) {
+		t.Errorf("expected wtm to move to the primary worktree, got %s", cwd)
+	}
+}
+
+func TestFindWorktreeByBranch(t *testing.T) {
+	ctx := t.Context()
+
+	repoPath := setupTestRepo(t)
+	defer cleanupTestRepo(t, repoPath)
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to test repo: %v", err)
+	}
+
+	for name, branch := range map[string]string{"api": "feature/api-refactoring", "web": "api"} {
+		if _, err := captureStdout(t, func() error { return AddWorktree(ctx, name, AddOptions{Branch: branch}) }); err != nil {
+			t.Fatalf("AddWorktree %s failed: %v", name, err)
+		}
+	}
+
+	wt, err := findWorktree(ctx, "feature/api-refactoring")
+	if err != nil {
+		t.Fatalf("findWorktree by branch failed: %v", err)
+	}
+	if wt.Name != "api" {
+		t.Errorf("expected the branch to resolve to the api worktree, got %s", wt.Name)
+	}
+	if wt, err = findWorktree(ctx, "api"); err != nil || wt.Name != "api" {
+		t.Errorf("expected a worktree name to win over a branch of another worktree, got %v, %v", wt, err)
+	}
+
+	if wt, err = findWorktreeByBranch(ctx, "api"); err != nil || wt.Name != "web" {
+		t.Errorf("expected --by-branch to ignore worktree names, got %v, %v", wt, err)
+	}
+	if _, err := findWorktreeByBranch(ctx, "missing"); exitCode(err) != exitNotFound {
+		t.Errorf("expected a not found error for an unknown branch, got %v", err)
+	}
+
+	output, err := captureStdout(t, func() error {
+		return ShowWorktree(ctx, "feature/api-refactoring", ShowOptions{Field: "name"})
+	})
+	if err != nil {
+		t.Fatalf("ShowWorktree by branch failed: %v", err)
+	}
+	if output != "api\n" {
+		t.Errorf("expected show to resolve the branch, got %q", output)
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return RemoveWorktree(ctx, "feature/api-refactoring", RemoveOptions{Force: true})
+	}); err != nil {
+		t.Fatalf("RemoveWorktree by branch failed: %v", err)
+	}
+	if _, err := findWorktree(ctx, "feature/api-refactoring"); exitCode(err) != exitNotFound {
+		t.Errorf("expected the api worktree to be removed, got %v", err)
+	}
+}